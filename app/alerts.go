@@ -0,0 +1,630 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// Kinds of alerting rules
+const (
+	// MetricRule fires when a metric of a node crosses a threshold.
+	MetricRule = "metric"
+	// IncreaseRule fires when a numeric Latest value of a node increases,
+	// and resolves once it hasn't increased for the rule's duration.
+	IncreaseRule = "increase"
+	// ConnectionRule fires while a matching node is connected to a matching
+	// destination (or to the Internet).
+	ConnectionRule = "connection"
+	// AbsentRule fires when a matching node which has been seen before is
+	// missing from the topology, until it has been missing for the rule's
+	// ForgetAfter.
+	AbsentRule = "absent"
+)
+
+// Alert states
+const (
+	AlertPending  = "pending"
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+const (
+	internetDestination = "internet"
+	webhookTimeout      = 10 * time.Second
+	alertsReceiver      = "scope"
+	defaultForgetAfter  = 24 * time.Hour
+)
+
+// AlertRule is a declarative alerting rule, evaluated against a rendered
+// topology (e.g. "containers", "pods" or "hosts").
+type AlertRule struct {
+	Name        string            `yaml:"name"`
+	Kind        string            `yaml:"kind"`
+	Topology    string            `yaml:"topology"`
+	Match       map[string]string `yaml:"match"`
+	For         time.Duration     `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+
+	// For metric rules
+	Metric    string  `yaml:"metric"`
+	Op        string  `yaml:"op"`
+	Threshold float64 `yaml:"threshold"`
+
+	// For increase rules
+	Key string `yaml:"key"`
+
+	// For connection rules; To is either "internet" or empty, in which case
+	// ToMatch selects the destination nodes.
+	To      string            `yaml:"to"`
+	ToMatch map[string]string `yaml:"toMatch"`
+
+	// For absent rules: how long a node may be missing before it is
+	// forgotten, e.g. as it was deleted on purpose. Defaults to a day.
+	ForgetAfter time.Duration `yaml:"forgetAfter"`
+}
+
+// AlertRules is the format of the alerting rules file.
+type AlertRules struct {
+	Rules []AlertRule `yaml:"rules"`
+}
+
+// ParseAlertRules parses and validates YAML alerting rules.
+func ParseAlertRules(buf []byte) ([]AlertRule, error) {
+	var rules AlertRules
+	if err := yaml.UnmarshalStrict(buf, &rules); err != nil {
+		return nil, err
+	}
+	names := map[string]struct{}{}
+	for _, rule := range rules.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("duplicate rule name: %s", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+	}
+	return rules.Rules, nil
+}
+
+func (r AlertRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule without a name")
+	}
+	if _, ok := topologyRegistry.get(r.Topology); !ok {
+		return fmt.Errorf("rule %s: unknown topology %q", r.Name, r.Topology)
+	}
+	switch r.Kind {
+	case MetricRule:
+		if r.Metric == "" {
+			return fmt.Errorf("rule %s: missing metric", r.Name)
+		}
+		if _, ok := comparisons[r.Op]; !ok {
+			return fmt.Errorf("rule %s: unknown operator %q", r.Name, r.Op)
+		}
+	case IncreaseRule:
+		if r.Key == "" {
+			return fmt.Errorf("rule %s: missing key", r.Name)
+		}
+	case ConnectionRule:
+		if r.To != "" && r.To != internetDestination {
+			return fmt.Errorf("rule %s: unknown destination %q", r.Name, r.To)
+		}
+	case AbsentRule:
+		if r.ForgetAfter < 0 || (r.ForgetAfter > 0 && r.ForgetAfter <= r.For) {
+			return fmt.Errorf("rule %s: forgetAfter must be longer than for", r.Name)
+		}
+	default:
+		return fmt.Errorf("rule %s: unknown kind %q", r.Name, r.Kind)
+	}
+	return nil
+}
+
+var comparisons = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// Alert is an alert which is pending, firing or has just been resolved. It
+// serialises the same way as alerts in Alertmanager webhook payloads.
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+
+	activeSince time.Time
+	rule        string
+}
+
+// AlertWebhookMessage is the Alertmanager-compatible webhook payload.
+type AlertWebhookMessage struct {
+	Version           string            `json:"version"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// AlerterConfig configures an Alerter.
+type AlerterConfig struct {
+	RulesFile      string
+	WebhookURL     string
+	ExternalURL    string
+	Interval       time.Duration // how often to evaluate the rules, besides on shortcut reports
+	ReloadInterval time.Duration
+}
+
+// Alerter evaluates alerting rules against each merged report, keeps track
+// of active alerts and notifies a webhook when alerts fire or resolve.
+type Alerter struct {
+	sync.Mutex
+	cfg          AlerterConfig
+	client       *http.Client
+	rules        []AlertRule
+	rulesModTime time.Time
+	active       map[string]*Alert              // by fingerprint
+	seen         map[string]map[string]seenNode // rule -> node ID -> node, for absent rules
+	previous     map[string]map[string]float64  // rule -> node ID -> value, for increase rules
+	quit         chan struct{}
+}
+
+// seenNode is a node last seen matching an absent rule.
+type seenNode struct {
+	label    string
+	lastSeen time.Time
+}
+
+// NewAlerter makes a new Alerter, loading the rules from the configured file.
+func NewAlerter(cfg AlerterConfig) (*Alerter, error) {
+	a := &Alerter{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: webhookTimeout},
+		active:   map[string]*Alert{},
+		seen:     map[string]map[string]seenNode{},
+		previous: map[string]map[string]float64{},
+		quit:     make(chan struct{}),
+	}
+	if cfg.RulesFile != "" {
+		if _, err := a.reload(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Start evaluates the rules periodically and every time the reporter has a
+// shortcut report, and periodically reloads the rules file if it changed.
+func (a *Alerter) Start(rep Reporter) {
+	go a.loop(rep)
+}
+
+// Stop the Alerter.
+func (a *Alerter) Stop() {
+	close(a.quit)
+}
+
+func (a *Alerter) loop(rep Reporter) {
+	ctx := context.Background()
+	wait := make(chan struct{}, 1)
+	rep.WaitOn(ctx, wait)
+	defer rep.UnWait(ctx, wait)
+
+	var tick, reload <-chan time.Time
+	if a.cfg.Interval > 0 {
+		ticker := time.NewTicker(a.cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	if a.cfg.RulesFile != "" && a.cfg.ReloadInterval > 0 {
+		ticker := time.NewTicker(a.cfg.ReloadInterval)
+		defer ticker.Stop()
+		reload = ticker.C
+	}
+
+	evaluate := func() {
+		rpt, err := rep.Report(ctx, time.Now())
+		if err != nil {
+			log.Errorf("alerts: error generating report: %v", err)
+			return
+		}
+		rpt.UnsafeRemovePartMergedNodes(ctx)
+		a.notify(a.Evaluate(ctx, rpt, time.Now()))
+	}
+
+	for {
+		select {
+		case <-wait:
+			evaluate()
+		case <-tick:
+			evaluate()
+		case <-reload:
+			if changed, err := a.reload(); err != nil {
+				log.Errorf("alerts: error reloading rules, keeping previous rules: %v", err)
+			} else if changed {
+				log.Infof("alerts: reloaded rules from %s", a.cfg.RulesFile)
+			}
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// reload reads the rules file if it was modified since it was last read.
+func (a *Alerter) reload() (bool, error) {
+	info, err := os.Stat(a.cfg.RulesFile)
+	if err != nil {
+		return false, err
+	}
+	a.Lock()
+	unchanged := info.ModTime().Equal(a.rulesModTime)
+	a.Unlock()
+	if unchanged {
+		return false, nil
+	}
+	buf, err := ioutil.ReadFile(a.cfg.RulesFile)
+	if err != nil {
+		return false, err
+	}
+	rules, err := ParseAlertRules(buf)
+	if err != nil {
+		return false, err
+	}
+	a.SetRules(rules)
+	a.Lock()
+	a.rulesModTime = info.ModTime()
+	a.Unlock()
+	return true, nil
+}
+
+// SetRules replaces the alerting rules. The state of rules which no longer
+// exist is discarded; their active alerts are dropped without notification.
+func (a *Alerter) SetRules(rules []AlertRule) {
+	a.Lock()
+	defer a.Unlock()
+	names := map[string]struct{}{}
+	for _, rule := range rules {
+		names[rule.Name] = struct{}{}
+	}
+	for fingerprint, alert := range a.active {
+		if _, ok := names[alert.rule]; !ok {
+			delete(a.active, fingerprint)
+		}
+	}
+	for name := range a.seen {
+		if _, ok := names[name]; !ok {
+			delete(a.seen, name)
+		}
+	}
+	for name := range a.previous {
+		if _, ok := names[name]; !ok {
+			delete(a.previous, name)
+		}
+	}
+	a.rules = rules
+}
+
+// Alerts returns the pending and firing alerts, sorted by fingerprint.
+func (a *Alerter) Alerts() []Alert {
+	a.Lock()
+	defer a.Unlock()
+	result := []Alert{}
+	for _, alert := range a.active {
+		result = append(result, *alert)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Fingerprint < result[j].Fingerprint })
+	return result
+}
+
+// Evaluate the rules against a report, returning the alerts which started
+// firing or were resolved.
+func (a *Alerter) Evaluate(ctx context.Context, rpt report.Report, now time.Time) []Alert {
+	a.Lock()
+	defer a.Unlock()
+
+	rendered := map[string]report.Nodes{}
+	nodesFor := func(topologyID string) report.Nodes {
+		if nodes, ok := rendered[topologyID]; ok {
+			return nodes
+		}
+		renderer, filter, err := topologyRegistry.RendererForTopology(topologyID, nil, rpt)
+		if err != nil {
+			log.Errorf("alerts: %v", err)
+			return report.Nodes{}
+		}
+		nodes := render.Render(ctx, rpt, renderer, filter).Nodes
+		rendered[topologyID] = nodes
+		return nodes
+	}
+
+	changed := []Alert{}
+	for _, rule := range a.rules {
+		conditions := a.conditions(rule, rpt, nodesFor(rule.Topology), now)
+		for _, alert := range a.active {
+			if alert.rule != rule.Name {
+				continue
+			}
+			if _, ok := conditions[alert.Fingerprint]; ok {
+				continue
+			}
+			// An increase stays active until the value hasn't changed for the rule's duration
+			if rule.Kind == IncreaseRule && now.Sub(alert.activeSince) < rule.For {
+				continue
+			}
+			if alert.Status == AlertFiring {
+				alert.Status = AlertResolved
+				alert.EndsAt = now
+				changed = append(changed, *alert)
+			}
+			delete(a.active, alert.Fingerprint)
+		}
+		for fingerprint, alert := range conditions {
+			alert := alert
+			existing, ok := a.active[fingerprint]
+			if !ok {
+				alert.activeSince = now
+				a.active[fingerprint] = &alert
+				existing = &alert
+			} else if rule.Kind == IncreaseRule {
+				existing.activeSince = now
+			}
+			existing.Annotations = alert.Annotations
+			if existing.Status == AlertPending && (rule.Kind == IncreaseRule || now.Sub(existing.activeSince) >= rule.For) {
+				existing.Status = AlertFiring
+				existing.StartsAt = now
+				changed = append(changed, *existing)
+			}
+		}
+	}
+	return changed
+}
+
+// conditions returns the pending alerts for all the nodes (or edges)
+// currently matching the rule.
+func (a *Alerter) conditions(rule AlertRule, rpt report.Report, nodes report.Nodes, now time.Time) map[string]Alert {
+	result := map[string]Alert{}
+	add := func(labels, annotations map[string]string) {
+		alert := a.makeAlert(rule, labels, annotations)
+		result[alert.Fingerprint] = alert
+	}
+
+	switch rule.Kind {
+	case MetricRule:
+		compare := comparisons[rule.Op]
+		for id, node := range nodes {
+			if !matchesNode(node, rule.Match) {
+				continue
+			}
+			metric, ok := node.Metrics.Lookup(rule.Metric)
+			if !ok {
+				continue
+			}
+			sample, ok := metric.LastSample()
+			if !ok || !compare(sample.Value, rule.Threshold) {
+				continue
+			}
+			add(nodeLabels(rpt, id, node), map[string]string{
+				"value": strconv.FormatFloat(sample.Value, 'f', -1, 64),
+			})
+		}
+
+	case IncreaseRule:
+		previous := a.previous[rule.Name]
+		current := map[string]float64{}
+		for id, node := range nodes {
+			if !matchesNode(node, rule.Match) {
+				continue
+			}
+			v, ok := node.Latest.Lookup(rule.Key)
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			current[id] = value
+			if before, ok := previous[id]; ok && value > before {
+				add(nodeLabels(rpt, id, node), map[string]string{
+					"previous": strconv.FormatFloat(before, 'f', -1, 64),
+					"value":    v,
+				})
+			}
+		}
+		a.previous[rule.Name] = current
+
+	case ConnectionRule:
+		for id, node := range nodes {
+			if node.Topology == render.Pseudo || !matchesNode(node, rule.Match) {
+				continue
+			}
+			for _, dstID := range node.Adjacency {
+				dst, ok := nodes[dstID]
+				if !ok || !matchesDestination(rule, dstID, dst) {
+					continue
+				}
+				labels := nodeLabels(rpt, id, node)
+				labels["destination_id"] = dstID
+				labels["destination"] = nodeLabel(rpt, dst)
+				add(labels, nil)
+			}
+		}
+
+	case AbsentRule:
+		seen, ok := a.seen[rule.Name]
+		if !ok {
+			seen = map[string]seenNode{}
+			a.seen[rule.Name] = seen
+		}
+		for id, node := range nodes {
+			if node.Topology != render.Pseudo && matchesNode(node, rule.Match) {
+				seen[id] = seenNode{label: nodeLabel(rpt, node), lastSeen: now}
+			}
+		}
+		forgetAfter := rule.ForgetAfter
+		if forgetAfter == 0 {
+			forgetAfter = defaultForgetAfter
+		}
+		for id, node := range seen {
+			if _, ok := nodes[id]; ok {
+				continue
+			}
+			if now.Sub(node.lastSeen) >= forgetAfter {
+				delete(seen, id)
+				continue
+			}
+			add(map[string]string{"node_id": id, "node": node.label, "topology": rule.Topology}, nil)
+		}
+	}
+	return result
+}
+
+func (a *Alerter) makeAlert(rule AlertRule, nodeLabels, extraAnnotations map[string]string) Alert {
+	labels := map[string]string{"alertname": rule.Name}
+	for k, v := range nodeLabels {
+		labels[k] = v
+	}
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+	annotations := map[string]string{}
+	for k, v := range rule.Annotations {
+		annotations[k] = v
+	}
+	for k, v := range extraAnnotations {
+		annotations[k] = v
+	}
+	generatorURL := ""
+	if a.cfg.ExternalURL != "" {
+		generatorURL = strings.TrimSuffix(a.cfg.ExternalURL, "/") + apiTopologyURL + rule.Topology
+	}
+	return Alert{
+		Status:       AlertPending,
+		Labels:       labels,
+		Annotations:  annotations,
+		GeneratorURL: generatorURL,
+		Fingerprint:  fingerprint(labels),
+		rule:         rule.Name,
+	}
+}
+
+func fingerprint(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00", k, labels[k])
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func nodeLabels(rpt report.Report, id string, node report.Node) map[string]string {
+	return map[string]string{
+		"node_id":  id,
+		"node":     nodeLabel(rpt, node),
+		"topology": node.Topology,
+	}
+}
+
+func nodeLabel(rpt report.Report, node report.Node) string {
+	if summary, ok := detailed.MakeBasicNodeSummary(rpt, node); ok {
+		return summary.Label
+	}
+	return node.ID
+}
+
+func matchesNode(node report.Node, match map[string]string) bool {
+	for k, v := range match {
+		if value, ok := node.Latest.Lookup(k); !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesDestination(rule AlertRule, id string, node report.Node) bool {
+	if rule.To == internetDestination {
+		return id == render.IncomingInternetID || id == render.OutgoingInternetID
+	}
+	if len(rule.ToMatch) == 0 {
+		return true
+	}
+	return node.Topology != render.Pseudo && matchesNode(node, rule.ToMatch)
+}
+
+// notify sends the alerts which changed to the webhook, if any.
+func (a *Alerter) notify(alerts []Alert) {
+	if len(alerts) == 0 || a.cfg.WebhookURL == "" {
+		return
+	}
+	msg := AlertWebhookMessage{
+		Version:           "4",
+		Status:            AlertResolved,
+		Receiver:          alertsReceiver,
+		GroupLabels:       map[string]string{},
+		CommonLabels:      map[string]string{},
+		CommonAnnotations: map[string]string{},
+		ExternalURL:       a.cfg.ExternalURL,
+		Alerts:            alerts,
+	}
+	for _, alert := range alerts {
+		if alert.Status == AlertFiring {
+			msg.Status = AlertFiring
+		}
+	}
+	buf := bytes.Buffer{}
+	if err := codec.NewEncoder(&buf, &codec.JsonHandle{}).Encode(msg); err != nil {
+		log.Errorf("alerts: error encoding webhook message: %v", err)
+		return
+	}
+	resp, err := a.client.Post(a.cfg.WebhookURL, "application/json", &buf)
+	if err != nil {
+		log.Errorf("alerts: error sending webhook: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Errorf("alerts: webhook returned %s", resp.Status)
+	}
+}
+
+// RegisterAlertRoutes registers the alerts API with a http mux.
+func RegisterAlertRoutes(router *mux.Router, a *Alerter) {
+	get := router.Methods("GET").Subrouter()
+	get.Handle("/api/alerts", gzipHandler(requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		respondWith(ctx, w, http.StatusOK, a.Alerts())
+	})))
+}
@@ -0,0 +1,180 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func mustParseAlertRules(t *testing.T, rules string) []app.AlertRule {
+	result, err := app.ParseAlertRules([]byte(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestParseAlertRules(t *testing.T) {
+	rules := mustParseAlertRules(t, `
+rules:
+- name: ContainerHighCPU
+  kind: metric
+  topology: containers
+  metric: docker_cpu_total_usage
+  op: ">"
+  threshold: 90
+  for: 2m
+  labels:
+    severity: warning
+- name: ProdToInternet
+  kind: connection
+  topology: pods
+  match:
+    kubernetes_namespace: prod
+  to: internet
+`)
+	equals(t, 2, len(rules))
+	equals(t, 2*time.Minute, rules[0].For)
+	equals(t, "warning", rules[0].Labels["severity"])
+	equals(t, "prod", rules[1].Match["kubernetes_namespace"])
+
+	for _, invalid := range []string{
+		"rules:\n- name: foo\n  kind: metric\n  topology: containers\n  op: \">\"\n",
+		"rules:\n- name: foo\n  kind: metric\n  topology: containers\n  metric: bar\n  op: \"~\"\n",
+		"rules:\n- name: foo\n  kind: absent\n  topology: foobar\n",
+		"rules:\n- name: foo\n  kind: bar\n  topology: hosts\n",
+		"rules:\n- name: foo\n  kind: absent\n  topology: hosts\n- name: foo\n  kind: absent\n  topology: hosts\n",
+		"rules:\n- name: foo\n  kind: absent\n  topology: hosts\n  unknown: field\n",
+		"rules:\n- name: foo\n  kind: absent\n  topology: hosts\n  for: 5m\n  forgetAfter: 1m\n",
+	} {
+		if _, err := app.ParseAlertRules([]byte(invalid)); err == nil {
+			t.Errorf("Expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestAlerterMetricRule(t *testing.T) {
+	alerter, err := app.NewAlerter(app.AlerterConfig{})
+	ok(t, err)
+	alerter.SetRules(mustParseAlertRules(t, `
+rules:
+- name: ContainerCPU
+  kind: metric
+  topology: containers
+  metric: docker_cpu_total_usage
+  op: ">"
+  threshold: 0.04
+  for: 2m
+`))
+	ctx := context.Background()
+	now := fixture.Now
+
+	// The server container is above the threshold, but not for long enough
+	equals(t, 0, len(alerter.Evaluate(ctx, fixture.Report, now)))
+	alerts := alerter.Alerts()
+	equals(t, 1, len(alerts))
+	equals(t, app.AlertPending, alerts[0].Status)
+	equals(t, fixture.ServerContainerNodeID, alerts[0].Labels["node_id"])
+	equals(t, "0.05", alerts[0].Annotations["value"])
+
+	changed := alerter.Evaluate(ctx, fixture.Report, now.Add(2*time.Minute))
+	equals(t, 1, len(changed))
+	equals(t, app.AlertFiring, changed[0].Status)
+	equals(t, "ContainerCPU", changed[0].Labels["alertname"])
+
+	rpt := fixture.Report.Copy()
+	node := rpt.Container.Nodes[fixture.ServerContainerNodeID]
+	node.Metrics = node.Metrics.Copy()
+	delete(node.Metrics, docker.CPUTotalUsage)
+	rpt.Container.Nodes[fixture.ServerContainerNodeID] = node
+	changed = alerter.Evaluate(ctx, rpt, now.Add(3*time.Minute))
+	equals(t, 1, len(changed))
+	equals(t, app.AlertResolved, changed[0].Status)
+	equals(t, now.Add(3*time.Minute), changed[0].EndsAt)
+	equals(t, 0, len(alerter.Alerts()))
+}
+
+func TestAlerterAbsentRule(t *testing.T) {
+	alerter, err := app.NewAlerter(app.AlerterConfig{})
+	ok(t, err)
+	alerter.SetRules(mustParseAlertRules(t, `
+rules:
+- name: HostMissing
+  kind: absent
+  topology: hosts
+  for: 1m
+  forgetAfter: 10m
+`))
+	ctx := context.Background()
+	now := fixture.Now
+
+	equals(t, 0, len(alerter.Evaluate(ctx, fixture.Report, now)))
+	equals(t, 0, len(alerter.Alerts()))
+
+	// Both hosts go missing
+	rpt := report.MakeReport()
+	equals(t, 0, len(alerter.Evaluate(ctx, rpt, now.Add(time.Minute))))
+	changed := alerter.Evaluate(ctx, rpt, now.Add(2*time.Minute))
+	equals(t, 2, len(changed))
+	equals(t, app.AlertFiring, changed[0].Status)
+
+	changed = alerter.Evaluate(ctx, fixture.Report, now.Add(3*time.Minute))
+	equals(t, 2, len(changed))
+	equals(t, app.AlertResolved, changed[0].Status)
+
+	// Hosts missing for longer than forgetAfter are forgotten
+	equals(t, 0, len(alerter.Evaluate(ctx, rpt, now.Add(4*time.Minute))))
+	equals(t, 2, len(alerter.Evaluate(ctx, rpt, now.Add(5*time.Minute))))
+	changed = alerter.Evaluate(ctx, rpt, now.Add(13*time.Minute))
+	equals(t, 2, len(changed))
+	equals(t, app.AlertResolved, changed[0].Status)
+	equals(t, 0, len(alerter.Evaluate(ctx, rpt, now.Add(14*time.Minute))))
+	equals(t, 0, len(alerter.Alerts()))
+}
+
+func TestAlerterWebhook(t *testing.T) {
+	received := make(chan app.AlertWebhookMessage, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg app.AlertWebhookMessage
+		if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		select {
+		case received <- msg:
+		default:
+		}
+	}))
+	defer ts.Close()
+
+	alerter, err := app.NewAlerter(app.AlerterConfig{WebhookURL: ts.URL, Interval: 10 * time.Millisecond})
+	ok(t, err)
+	alerter.SetRules(mustParseAlertRules(t, `
+rules:
+- name: HostLoad
+  kind: metric
+  topology: hosts
+  metric: load1
+  op: ">="
+  threshold: 0
+`))
+	alerter.Start(app.StaticCollector(fixture.Report))
+	defer alerter.Stop()
+
+	select {
+	case msg := <-received:
+		equals(t, "4", msg.Version)
+		equals(t, app.AlertFiring, msg.Status)
+		equals(t, 2, len(msg.Alerts))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for webhook")
+	}
+}
@@ -1,14 +1,27 @@
 package app
 
 import (
+	"crypto/rand"
 	"net/http"
 	"time"
 
 	"context"
 
+	"github.com/weaveworks/scope/render"
 	"github.com/weaveworks/scope/report"
 )
 
+// anonymizerSecret is shared between requests, so the pseudonyms stay the
+// same for as long as the app runs. Each request has an Anonymizer of its
+// own, as an Anonymizer remembers every name it has replaced.
+var anonymizerSecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}()
+
 // Raw report handler
 func makeRawReportHandler(rep Reporter) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -19,7 +32,11 @@ func makeRawReportHandler(rep Reporter) CtxHandlerFunc {
 			return
 		}
 		censorCfg := report.GetCensorConfigFromRequest(r)
-		respondWithReport(ctx, w, r, report.CensorRawReport(rawReport, censorCfg))
+		rawReport = report.CensorRawReport(rawReport, censorCfg)
+		if r.URL.Query().Get("anonymize") == "true" {
+			rawReport = report.NewAnonymizer(anonymizerSecret, render.IsWellKnownName).Anonymize(rawReport)
+		}
+		respondWithReport(ctx, w, r, rawReport)
 	}
 }
 
@@ -43,6 +60,15 @@ func makeProbeHandler(rep Reporter) CtxHandlerFunc {
 			respondWith(ctx, w, http.StatusOK, hasProbes)
 			return
 		}
+		if lister, ok := probeLister(rep); ok {
+			probes, err := lister.Probes(ctx, time.Now())
+			if err != nil {
+				respondWith(ctx, w, http.StatusInternalServerError, err)
+				return
+			}
+			respondWith(ctx, w, http.StatusOK, probes)
+			return
+		}
 		rpt, err := rep.Report(ctx, time.Now())
 		if err != nil {
 			respondWith(ctx, w, http.StatusInternalServerError, err)
@@ -63,3 +89,14 @@ func makeProbeHandler(rep Reporter) CtxHandlerFunc {
 		respondWith(ctx, w, http.StatusOK, result)
 	}
 }
+
+func probeLister(rep Reporter) (ProbeLister, bool) {
+	if webReporter, ok := rep.(WebReporter); ok {
+		rep = webReporter.Reporter
+	}
+	if recording, ok := rep.(*RecordingCollector); ok {
+		rep = recording.Collector
+	}
+	lister, ok := rep.(ProbeLister)
+	return lister, ok
+}
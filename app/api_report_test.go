@@ -2,6 +2,7 @@ package app_test
 
 import (
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -33,3 +34,35 @@ func TestAPIReport(t *testing.T) {
 		t.Fatalf("JSON parse error: %s", err)
 	}
 }
+
+func TestAPIReportAnonymize(t *testing.T) {
+	ts := topologyServer()
+	defer ts.Close()
+
+	var r report.Report
+	body := getRawJSON(t, ts, "/api/report?anonymize=true")
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&r); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(r.Host.Nodes) != len(fixture.Report.Host.Nodes) {
+		t.Errorf("got %d hosts, want %d", len(r.Host.Nodes), len(fixture.Report.Host.Nodes))
+	}
+	if _, ok := r.Host.Nodes[fixture.ClientHostNodeID]; ok {
+		t.Errorf("host %s wasn't anonymized", fixture.ClientHostNodeID)
+	}
+	if strings.Contains(string(body), fixture.ClientHostName) {
+		t.Errorf("report still contains hostname %s", fixture.ClientHostName)
+	}
+
+	// Pseudonyms are the same across requests
+	var again report.Report
+	body = getRawJSON(t, ts, "/api/report?anonymize=true")
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&again); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	for id := range r.Host.Nodes {
+		if _, ok := again.Host.Nodes[id]; !ok {
+			t.Errorf("host %s was anonymized differently", id)
+		}
+	}
+}
@@ -30,6 +30,8 @@ const (
 	containersByImageID    = "containers-by-image"
 	podsID                 = "pods"
 	kubeControllersID      = "kube-controllers"
+	replicaSetsID          = "replica-sets"
+	customResourcesID      = "custom-resources"
 	servicesID             = "services"
 	hostsID                = "hosts"
 	weaveID                = "weave"
@@ -56,6 +58,14 @@ var (
 			{Value: "hide", Label: "Hide storage", filter: render.IsPodComponent, filterPseudo: false},
 		},
 	}
+	limitsFilter = APITopologyOptionGroup{
+		ID:      "limits",
+		Default: "all",
+		Options: []APITopologyOption{
+			{Value: "all", Label: "All pods", filter: nil, filterPseudo: false},
+			{Value: "none", Label: "Pods without limits", filter: render.IsPodWithoutLimits, filterPseudo: false},
+		},
+	}
 	snapshotFilter = APITopologyOptionGroup{
 		ID:      "snapshot",
 		Default: "hide",
@@ -80,7 +90,42 @@ func namespaceFilters(namespaces []string, noneLabel string) APITopologyOptionGr
 // updateFilters updates the available filters based on the current report.
 func updateFilters(rpt report.Report, topologies []APITopologyDesc) []APITopologyDesc {
 	topologies = updateKubeFilters(rpt, topologies)
+	topologies = updateCustomResourceFilters(rpt, topologies)
 	topologies = updateSwarmFilters(rpt, topologies)
+	topologies = updateClusterFilters(rpt, topologies)
+	return topologies
+}
+
+// updateClusterFilters adds a cluster selector to the topologies backed by
+// probe nodes, when the report federates several clusters.
+func updateClusterFilters(rpt report.Report, topologies []APITopologyDesc) []APITopologyDesc {
+	clusters := map[string]struct{}{}
+	for _, n := range rpt.Host.Nodes {
+		if cluster, ok := n.Latest.Lookup(report.FederationCluster); ok {
+			clusters[cluster] = struct{}{}
+		}
+	}
+	if len(clusters) == 0 {
+		return topologies
+	}
+	names := []string{}
+	for cluster := range clusters {
+		names = append(names, cluster)
+	}
+	sort.Strings(names)
+	options := APITopologyOptionGroup{ID: "cluster", Default: "", SelectType: "union", NoneLabel: "All Clusters"}
+	for _, cluster := range names {
+		options.Options = append(options.Options, APITopologyOption{
+			Value: cluster, Label: cluster, filter: render.IsCluster(cluster), filterPseudo: false,
+		})
+	}
+	topologies = append([]APITopologyDesc{}, topologies...) // Make a copy so we can make changes safely
+	for i, t := range topologies {
+		switch t.id {
+		case processesID, containersID, podsID, kubeControllersID, replicaSetsID, customResourcesID, servicesID, hostsID, ecsTasksID, ecsServicesID, swarmServicesID:
+			topologies[i] = mergeTopologyFilters(t, []APITopologyOptionGroup{options})
+		}
+	}
 	return topologies
 }
 
@@ -126,7 +171,7 @@ func updateKubeFilters(rpt report.Report, topologies []APITopologyDesc) []APITop
 	sort.Strings(ns)
 	topologies = append([]APITopologyDesc{}, topologies...) // Make a copy so we can make changes safely
 	for i, t := range topologies {
-		if t.id == containersID || t.id == podsID || t.id == servicesID || t.id == kubeControllersID {
+		if t.id == containersID || t.id == podsID || t.id == servicesID || t.id == kubeControllersID || t.id == replicaSetsID || t.id == customResourcesID {
 			topologies[i] = mergeTopologyFilters(t, []APITopologyOptionGroup{
 				namespaceFilters(ns, "All Namespaces"),
 			})
@@ -135,6 +180,38 @@ func updateKubeFilters(rpt report.Report, topologies []APITopologyDesc) []APITop
 	return topologies
 }
 
+// updateCustomResourceFilters adds a selector of the kinds of custom
+// resources in the report to the custom resources topology.
+func updateCustomResourceFilters(rpt report.Report, topologies []APITopologyDesc) []APITopologyDesc {
+	kinds := map[string]struct{}{}
+	for _, n := range rpt.CustomResource.Nodes {
+		if kind, ok := n.Latest.Lookup(kubernetes.NodeType); ok {
+			kinds[kind] = struct{}{}
+		}
+	}
+	if len(kinds) == 0 {
+		return topologies
+	}
+	names := []string{}
+	for kind := range kinds {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	options := APITopologyOptionGroup{ID: "kind", Default: "", SelectType: "union", NoneLabel: "All Kinds"}
+	for _, kind := range names {
+		options.Options = append(options.Options, APITopologyOption{
+			Value: kind, Label: kind, filter: render.IsKubernetesType(kind), filterPseudo: false,
+		})
+	}
+	topologies = append([]APITopologyDesc{}, topologies...) // Make a copy so we can make changes safely
+	for i, t := range topologies {
+		if t.id == customResourcesID {
+			topologies[i] = mergeTopologyFilters(t, []APITopologyOptionGroup{options})
+		}
+	}
+	return topologies
+}
+
 // mergeTopologyFilters recursively merges in new options on a topology description
 func mergeTopologyFilters(t APITopologyDesc, options []APITopologyOptionGroup) APITopologyDesc {
 	t.Options = append(append([]APITopologyOptionGroup{}, t.Options...), options...)
@@ -180,6 +257,22 @@ func MakeRegistry() *Registry {
 				{Value: "both", Label: "Both", filter: nil, filterPseudo: false},
 			},
 		},
+		{
+			ID:      "health",
+			Default: "all",
+			Options: []APITopologyOption{
+				{Value: "all", Label: "All containers", filter: nil, filterPseudo: false},
+				{Value: "unhealthy", Label: "Unhealthy containers", filter: render.IsUnhealthy, filterPseudo: false},
+			},
+		},
+		{
+			ID:      "image",
+			Default: "any",
+			Options: []APITopologyOption{
+				{Value: "any", Label: "Any image", filter: nil, filterPseudo: false},
+				{Value: "updated", Label: "Image updated since start", filter: render.HasNewerImage, filterPseudo: false},
+			},
+		},
 		{
 			ID:      "pseudo",
 			Default: "hide",
@@ -246,7 +339,7 @@ func MakeRegistry() *Registry {
 			renderer:    render.PodRenderer,
 			Name:        "Pods",
 			Rank:        3,
-			Options:     []APITopologyOptionGroup{snapshotFilter, storageFilter, unmanagedFilter},
+			Options:     []APITopologyOptionGroup{snapshotFilter, storageFilter, limitsFilter, unmanagedFilter},
 			HideIfEmpty: true,
 		},
 		APITopologyDesc{
@@ -257,6 +350,13 @@ func MakeRegistry() *Registry {
 			Options:     []APITopologyOptionGroup{unmanagedFilter},
 			HideIfEmpty: true,
 		},
+		APITopologyDesc{
+			id:          replicaSetsID,
+			parent:      podsID,
+			renderer:    render.ReplicaSetRenderer,
+			Name:        "Replica sets",
+			HideIfEmpty: true,
+		},
 		APITopologyDesc{
 			id:          servicesID,
 			parent:      podsID,
@@ -265,6 +365,13 @@ func MakeRegistry() *Registry {
 			Options:     []APITopologyOptionGroup{unmanagedFilter},
 			HideIfEmpty: true,
 		},
+		APITopologyDesc{
+			id:          customResourcesID,
+			parent:      podsID,
+			renderer:    render.CustomResourceRenderer,
+			Name:        "Custom resources",
+			HideIfEmpty: true,
+		},
 		APITopologyDesc{
 			id:          ecsTasksID,
 			renderer:    render.ECSTaskRenderer,
@@ -5,6 +5,7 @@ import (
 	"context"
 	"net/http/httptest"
 	"net/url"
+	"sort"
 	"testing"
 	"time"
 
@@ -250,3 +251,63 @@ func TestAPITopologyAddsKubernetes(t *testing.T) {
 		t.Error("Could not find pods topology")
 	}
 }
+
+func TestRendererForTopologyClusterFilter(t *testing.T) {
+	input := fixture.Report.Copy()
+	for id, n := range input.Host.Nodes {
+		cluster := "eu"
+		if id == fixture.ServerHostNodeID {
+			cluster = "us"
+		}
+		input.Host.Nodes[id] = n.WithLatests(map[string]string{report.FederationCluster: cluster})
+	}
+
+	topologyRegistry := app.MakeRegistry()
+	urlvalues := url.Values{}
+	urlvalues.Set("cluster", "us")
+	renderer, filter, err := topologyRegistry.RendererForTopology("hosts", urlvalues, input)
+	if err != nil {
+		t.Fatalf("Topology Registry Report error: %s", err)
+	}
+	have := render.Render(context.Background(), input, renderer, filter).Nodes
+	_, client := have[fixture.ClientHostNodeID]
+	_, server := have[fixture.ServerHostNodeID]
+	if client || !server {
+		t.Errorf("Expected only the server host, got %v", have)
+	}
+}
+
+func TestRendererForTopologyCustomResourceKindFilter(t *testing.T) {
+	input := fixture.Report.Copy()
+	rolloutID := report.MakeCustomResourceNodeID("rollout")
+	widgetID := report.MakeCustomResourceNodeID("widget")
+	input.CustomResource = report.MakeTopology()
+	for id, kind := range map[string]string{rolloutID: "Rollout", widgetID: "Widget"} {
+		input.CustomResource.AddNode(report.MakeNodeWith(id, map[string]string{
+			kubernetes.NodeType: kind,
+		}).WithTopology(report.CustomResource))
+	}
+	input.Pod.Nodes[fixture.ClientPodNodeID] = input.Pod.Nodes[fixture.ClientPodNodeID].WithParent(report.CustomResource, rolloutID)
+	input.Pod.Nodes[fixture.ServerPodNodeID] = input.Pod.Nodes[fixture.ServerPodNodeID].WithParent(report.CustomResource, widgetID)
+
+	topologyRegistry := app.MakeRegistry()
+	for kind, want := range map[string][]string{
+		"":        {rolloutID, widgetID},
+		"Rollout": {rolloutID},
+	} {
+		urlvalues := url.Values{}
+		urlvalues.Set("kind", kind)
+		renderer, filter, err := topologyRegistry.RendererForTopology("custom-resources", urlvalues, input)
+		if err != nil {
+			t.Fatalf("Topology Registry Report error: %s", err)
+		}
+		have := []string{}
+		for id, n := range render.Render(context.Background(), input, renderer, filter).Nodes {
+			if n.Topology == report.CustomResource {
+				have = append(have, id)
+			}
+		}
+		sort.Strings(have)
+		equals(t, want, have)
+	}
+}
@@ -32,6 +32,13 @@ type APINode struct {
 	Node detailed.Node `json:"node"`
 }
 
+// APITopologyComparison is returned by the /api/compare/{name} handler.
+type APITopologyComparison struct {
+	A time.Time `json:"a"`
+	B time.Time `json:"b"`
+	detailed.Comparison
+}
+
 // RenderContextForReporter creates the rendering context for the given reporter.
 func RenderContextForReporter(rep Reporter, r report.Report) detailed.RenderContext {
 	rc := detailed.RenderContext{Report: r}
@@ -82,6 +89,65 @@ func handleNode(ctx context.Context, renderer render.Renderer, transformer rende
 	respondWith(ctx, w, http.StatusOK, APINode{Node: detailed.CensorNode(rawNode, censorCfg)})
 }
 
+// Comparison of the full topology at two points in time.
+func handleTopologyCompare(ctx context.Context, rep Reporter, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWith(ctx, w, http.StatusBadRequest, err)
+		return
+	}
+	topologyID := mux.Vars(r)["topology"]
+	if _, ok := topologyRegistry.get(topologyID); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Form.Get("a") == "" {
+		respondWith(ctx, w, http.StatusBadRequest, errors.New("missing timestamp: a"))
+		return
+	}
+	a, err := time.Parse(time.RFC3339, r.Form.Get("a"))
+	if err != nil {
+		respondWith(ctx, w, http.StatusBadRequest, errors.Wrap(err, "invalid timestamp a"))
+		return
+	}
+	b := time.Now()
+	if r.Form.Get("b") != "" {
+		if b, err = time.Parse(time.RFC3339, r.Form.Get("b")); err != nil {
+			respondWith(ctx, w, http.StatusBadRequest, errors.Wrap(err, "invalid timestamp b"))
+			return
+		}
+	}
+
+	censorCfg := report.GetCensorConfigFromRequest(r)
+	summariesAt := func(timestamp time.Time) (detailed.NodeSummaries, error) {
+		rpt, err := rep.Report(ctx, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		rpt.UnsafeRemovePartMergedNodes(ctx)
+		renderer, filter, err := topologyRegistry.RendererForTopology(topologyID, r.Form, rpt)
+		if err != nil {
+			return nil, err
+		}
+		nodes := render.Render(ctx, rpt, renderer, filter).Nodes
+		return detailed.CensorNodeSummaries(detailed.Summaries(ctx, RenderContextForReporter(rep, rpt), nodes), censorCfg), nil
+	}
+	before, err := summariesAt(a)
+	if err != nil {
+		respondWith(ctx, w, http.StatusInternalServerError, err)
+		return
+	}
+	after, err := summariesAt(b)
+	if err != nil {
+		respondWith(ctx, w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWith(ctx, w, http.StatusOK, APITopologyComparison{
+		A:          a,
+		B:          b,
+		Comparison: detailed.CompareTopologies(before, after),
+	})
+}
+
 // Websocket for the full topology.
 func handleWebsocket(
 	ctx context.Context,
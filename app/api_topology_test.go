@@ -153,3 +153,29 @@ func TestAPITopologyWebsocket(t *testing.T) {
 }
 
 func newu64(value uint64) *uint64 { return &value }
+
+func TestAPITopologyCompare(t *testing.T) {
+	ts := topologyServer()
+	defer ts.Close()
+	is404(t, ts, "/api/compare/foobar?a=2017-01-01T00:00:00Z")
+
+	res, _ := checkGet(t, ts, "/api/compare/containers")
+	equals(t, 400, res.StatusCode)
+	res, _ = checkGet(t, ts, "/api/compare/containers?a=yesterday")
+	equals(t, 400, res.StatusCode)
+	// A node named compare is a node like any other
+	is404(t, ts, "/api/topology/containers/compare?a=2017-01-01T00:00:00Z")
+
+	body := getRawJSON(t, ts, "/api/compare/containers?a=2017-01-01T00:00:00Z&b=2017-01-02T00:00:00Z")
+	var comparison app.APITopologyComparison
+	decoder := codec.NewDecoderBytes(body, &codec.JsonHandle{})
+	if err := decoder.Decode(&comparison); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	// The static collector returns the same report for both timestamps
+	equals(t, 0, len(comparison.Added))
+	equals(t, 0, len(comparison.Removed))
+	equals(t, 0, len(comparison.Changed))
+	equals(t, 0, len(comparison.AddedAdjacencies))
+	equals(t, 0, len(comparison.RemovedAdjacencies))
+}
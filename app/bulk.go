@@ -0,0 +1,202 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// bulkConcurrency bounds the number of control requests of a bulk control
+// in flight at any time.
+var bulkConcurrency = 8
+
+// BulkControlRequest is the body of a bulk control request. It either
+// lists the nodes to run the control on, or names a group node in an API
+// topology, such as an image in containers-by-image, whose children the
+// control is run on. Group controls can only be run on group nodes.
+type BulkControlRequest struct {
+	Control  string            `json:"control"`
+	NodeIDs  []string          `json:"nodeIds,omitempty"`
+	Topology string            `json:"topology,omitempty"`
+	GroupID  string            `json:"groupId,omitempty"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+// BulkControlResult is the outcome of a bulk control on a single node.
+type BulkControlResult struct {
+	NodeID   string         `json:"nodeId"`
+	ProbeID  string         `json:"probeId,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Response *xfer.Response `json:"response,omitempty"`
+}
+
+// BulkControlResponse is the response to a bulk control request.
+type BulkControlResponse struct {
+	Control string              `json:"control"`
+	Results []BulkControlResult `json:"results"`
+}
+
+// Failed returns the number of nodes the control failed on.
+func (r BulkControlResponse) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+type bulkError struct {
+	status int
+	err    error
+}
+
+func (e bulkError) Error() string {
+	return e.err.Error()
+}
+
+// handleBulkControl runs a control on many nodes at once.
+func handleBulkControl(cr ControlRouter, pr PipeRouter, rep Reporter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var req BulkControlRequest
+		defer r.Body.Close()
+		if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&req); err != nil {
+			respondWith(ctx, w, http.StatusBadRequest, err)
+			return
+		}
+		result, err := runBulkControl(ctx, cr, pr, rep, req)
+		if err != nil {
+			respondWith(ctx, w, err.status, err.Error())
+			return
+		}
+		respondWith(ctx, w, http.StatusOK, result)
+	}
+}
+
+// runBulkControl expands req to the nodes it targets, checks its arguments
+// and fans the control out to the probes owning those nodes. Nothing would
+// use the pipes of controls opening one, such as exec shells, so those fail,
+// and their pipes are closed through pr, if not nil.
+func runBulkControl(ctx context.Context, cr ControlRouter, pr PipeRouter, rep Reporter, req BulkControlRequest) (BulkControlResponse, *bulkError) {
+	badRequest := func(format string, args ...interface{}) *bulkError {
+		return &bulkError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+	}
+	if req.Control == "" {
+		return BulkControlResponse{}, badRequest("Missing control")
+	}
+	if (len(req.NodeIDs) == 0) == (req.GroupID == "") {
+		return BulkControlResponse{}, badRequest("Exactly one of nodeIds and groupId must be given")
+	}
+
+	rpt, err := rep.Report(ctx, mtime.Now())
+	if err != nil {
+		return BulkControlResponse{}, &bulkError{http.StatusInternalServerError, err}
+	}
+
+	control := req.Control
+	gc, isGroupControl := detailed.LookupGroupControl(control)
+	if isGroupControl {
+		if req.GroupID == "" {
+			return BulkControlResponse{}, badRequest("Group control %s needs a groupId", control)
+		}
+		control = gc.ChildControl
+	}
+	spec, topologyID, ok := findControl(rpt, control)
+	if !ok {
+		return BulkControlResponse{}, badRequest("Unknown control: %s", control)
+	}
+	args, err := spec.CheckArgs(req.Args)
+	if err != nil {
+		return BulkControlResponse{}, badRequest("%v", err)
+	}
+
+	nodeIDs := req.NodeIDs
+	if req.GroupID != "" {
+		if !isGroupControl {
+			gc = detailed.GroupControl{ChildTopology: topologyID, ChildControl: control}
+		}
+		renderer, _, err := topologyRegistry.RendererForTopology(req.Topology, nil, rpt)
+		if err != nil {
+			return BulkControlResponse{}, &bulkError{http.StatusNotFound, err}
+		}
+		group, ok := renderer.Render(ctx, rpt).Nodes[req.GroupID]
+		if !ok {
+			return BulkControlResponse{}, &bulkError{http.StatusNotFound, fmt.Errorf("Node not found: %s", req.GroupID)}
+		}
+		nodeIDs = detailed.GroupControlTargets(rpt, group, gc)
+	}
+
+	topology, _ := rpt.Topology(topologyID)
+	results := make([]BulkControlResult, len(nodeIDs))
+	sem := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for i, nodeID := range nodeIDs {
+		results[i].NodeID = nodeID
+		probeID, err := controlProbeID(topology, nodeID, spec)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].ProbeID = probeID
+		wg.Add(1)
+		go func(result *BulkControlResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res, err := cr.Handle(ctx, result.ProbeID, xfer.Request{
+				NodeID:      result.NodeID,
+				Control:     control,
+				ControlArgs: args,
+			})
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			if res.Pipe != "" {
+				if pr != nil {
+					if err := pr.Delete(ctx, res.Pipe); err != nil {
+						log.Warnf("Error closing pipe %s of bulk control %s: %v", res.Pipe, control, err)
+					}
+				}
+				result.Error = fmt.Sprintf("Control %s opens a pipe, so can't be run in bulk", control)
+				return
+			}
+			result.Error = res.Error
+			result.Response = &res
+		}(&results[i])
+	}
+	wg.Wait()
+	return BulkControlResponse{Control: control, Results: results}, nil
+}
+
+// controlProbeID returns the ID of the probe to run control on node nodeID
+// of topology, checking the control is active on the node.
+func controlProbeID(topology report.Topology, nodeID string, control report.Control) (string, error) {
+	node, ok := topology.Nodes[nodeID]
+	if !ok {
+		return "", fmt.Errorf("Node not found: %s", nodeID)
+	}
+	probeID, ok := node.Latest.Lookup(report.ControlProbeID)
+	if !ok {
+		return "", fmt.Errorf("Node %s has no probe", nodeID)
+	}
+	if control.ProbeID != "" {
+		probeID = control.ProbeID
+	}
+	for _, id := range node.ActiveControls() {
+		if id == control.ID {
+			return probeID, nil
+		}
+	}
+	return "", fmt.Errorf("Control %s is not available on node %s", control.ID, nodeID)
+}
@@ -0,0 +1,181 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+func bulkReport() report.Report {
+	rpt := report.MakeReport()
+	rpt.Container.Controls.AddControl(report.Control{ID: report.DockerRestartContainer})
+	rpt.Container.Controls.AddControl(report.Control{ID: report.DockerStopContainer})
+	rpt.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("img1"), map[string]string{
+		report.DockerImageID:   "img1",
+		report.DockerImageName: "nginx:latest",
+	}).WithTopology(report.ContainerImage))
+	for _, c := range []struct {
+		id, probeID string
+		controls    []string
+	}{
+		{"c1", "probe1", []string{report.DockerRestartContainer, report.DockerStopContainer}},
+		{"c2", "probe2", []string{report.DockerRestartContainer, report.DockerStopContainer}},
+		{"c3", "probe2", []string{report.DockerStopContainer}},
+	} {
+		rpt.Container.AddNode(report.MakeNodeWith(report.MakeContainerNodeID(c.id), map[string]string{
+			report.DockerContainerID: c.id,
+			report.DockerImageID:     "img1",
+			report.ControlProbeID:    c.probeID,
+		}).WithLatestActiveControls(c.controls...).WithTopology(report.Container))
+	}
+	return rpt
+}
+
+func TestBulkControl(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		cr       = app.NewLocalControlRouter()
+		mtx      sync.Mutex
+		received = map[string]string{}
+	)
+	for _, probeID := range []string{"probe1", "probe2"} {
+		probeID := probeID
+		cr.Register(ctx, probeID, func(req xfer.Request) xfer.Response {
+			mtx.Lock()
+			defer mtx.Unlock()
+			received[req.NodeID] = probeID + ":" + req.Control
+			return xfer.Response{}
+		})
+	}
+	router := mux.NewRouter()
+	app.RegisterControlRoutes(router, cr, nil, app.StaticCollector(bulkReport()))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	post := func(path, body string) (int, app.BulkControlResponse) {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result app.BulkControlResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, result
+	}
+	reset := func() map[string]string {
+		mtx.Lock()
+		defer mtx.Unlock()
+		result := received
+		received = map[string]string{}
+		return result
+	}
+
+	// Listed nodes: c3 doesn't have the control, c4 doesn't exist.
+	status, result := post("/api/control/bulk", `{"control": "docker_restart_container", "nodeIds": ["c1;<container>", "c3;<container>", "c4;<container>"]}`)
+	if status != http.StatusOK {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if len(result.Results) != 3 || result.Failed() != 2 || result.Results[0].ProbeID != "probe1" || result.Results[0].Error != "" {
+		t.Errorf("Unexpected results %+v", result.Results)
+	}
+	if have := reset(); len(have) != 1 || have["c1;<container>"] != "probe1:docker_restart_container" {
+		t.Errorf("Unexpected requests %v", have)
+	}
+
+	// Group nodes, through the bulk endpoint and a group control.
+	imageID := report.MakeContainerImageNodeID("nginx")
+	for _, tc := range []struct {
+		path, body string
+		want       []string
+	}{
+		{
+			"/api/control/bulk",
+			`{"control": "docker_stop_container", "topology": "containers-by-image", "groupId": "` + imageID + `"}`,
+			[]string{"c1;<container>", "c2;<container>", "c3;<container>"},
+		},
+		{
+			"/api/control/containers-by-image/" + imageID + "/group_docker_restart_container",
+			``,
+			[]string{"c1;<container>", "c2;<container>"},
+		},
+	} {
+		status, result := post(tc.path, tc.body)
+		if status != http.StatusOK || result.Failed() != 0 {
+			t.Errorf("%s: unexpected response %d %+v", tc.path, status, result)
+		}
+		have := []string{}
+		for nodeID := range reset() {
+			have = append(have, nodeID)
+		}
+		sort.Strings(have)
+		if strings.Join(have, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tc.path, tc.want, have)
+		}
+	}
+
+	for _, body := range []string{
+		`{"nodeIds": ["c1;<container>"]}`,
+		`{"control": "docker_restart_container"}`,
+		`{"control": "group_docker_restart_container", "nodeIds": ["c1;<container>"]}`,
+		`{"control": "unknown", "nodeIds": ["c1;<container>"]}`,
+	} {
+		if status, _ := post("/api/control/bulk", body); status != http.StatusBadRequest {
+			t.Errorf("%s: expected a bad request, got %d", body, status)
+		}
+	}
+	if status, _ := post("/api/control/bulk", `{"control": "docker_stop_container", "topology": "containers-by-image", "groupId": "missing"}`); status != http.StatusNotFound {
+		t.Errorf("Expected not found, got %d", status)
+	}
+}
+
+func TestBulkControlPipes(t *testing.T) {
+	var (
+		ctx = context.Background()
+		cr  = app.NewLocalControlRouter()
+		pr  = app.NewLocalPipeRouter()
+		rpt = report.MakeReport()
+	)
+	defer pr.Stop()
+	rpt.Container.Controls.AddControl(report.Control{ID: report.DockerExecContainer})
+	rpt.Container.AddNode(report.MakeNodeWith(report.MakeContainerNodeID("c1"), map[string]string{
+		report.ControlProbeID: "probe1",
+	}).WithLatestActiveControls(report.DockerExecContainer).WithTopology(report.Container))
+	cr.Register(ctx, "probe1", func(req xfer.Request) xfer.Response {
+		return xfer.Response{Pipe: "pipe1"}
+	})
+	router := mux.NewRouter()
+	app.RegisterControlRoutes(router, cr, pr, app.StaticCollector(rpt))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/control/bulk", "application/json", strings.NewReader(`{"control": "docker_exec_container", "nodeIds": ["c1;<container>"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result app.BulkControlResponse
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed() != 1 || result.Results[0].Response != nil {
+		t.Errorf("Expected the control to fail, got %+v", result.Results)
+	}
+	if exists, _ := pr.Exists(ctx, "pipe1"); exists {
+		t.Errorf("Expected the pipe to be closed")
+	}
+}
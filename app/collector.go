@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -283,10 +284,11 @@ func (c StaticCollector) UnWait(context.Context, chan struct{}) {}
 // directory) as reports.  If there are multiple files, and they all
 // have names representing "nanoseconds since epoch" timestamps,
 // e.g. "1488557088545489008.msgpack.gz", then the collector will
-// return merged reports resulting from replaying the file reports in
-// a loop at a sequence and speed determined by the timestamps.
-// Otherwise the collector always returns the merger of all reports.
-func NewFileCollector(path string, window time.Duration) (Collector, error) {
+// return merged reports resulting from replaying the file reports
+// at a sequence and speed determined by the timestamps, as configured
+// by config, and the collector is a *ReplayingCollector. Otherwise the
+// collector always returns the merger of all reports.
+func NewFileCollector(path string, window time.Duration, config ReplayConfig) (Collector, error) {
 	var (
 		timestamps []time.Time
 		reports    []report.Report
@@ -316,13 +318,30 @@ func NewFileCollector(path string, window time.Duration) (Collector, error) {
 		return nil, err
 	}
 	if len(reports) > 1 && allTimestamped {
+		// Recordings are split into segments, which needn't sort the
+		// same as the reports in them.
+		sort.Sort(byTimestamp{timestamps, reports})
 		collector := NewCollector(window)
-		go replay(collector, timestamps, reports)
-		return collector, nil
+		return &ReplayingCollector{
+			Collector: collector,
+			Replayer:  NewReplayer(collector, timestamps, reports, config),
+		}, nil
 	}
 	return StaticCollector(NewFastMerger().Merge(reports).Upgrade()), nil
 }
 
+type byTimestamp struct {
+	timestamps []time.Time
+	reports    []report.Report
+}
+
+func (s byTimestamp) Len() int           { return len(s.timestamps) }
+func (s byTimestamp) Less(i, j int) bool { return s.timestamps[i].Before(s.timestamps[j]) }
+func (s byTimestamp) Swap(i, j int) {
+	s.timestamps[i], s.timestamps[j] = s.timestamps[j], s.timestamps[i]
+	s.reports[i], s.reports[j] = s.reports[j], s.reports[i]
+}
+
 func timestampFromFilepath(path string) (time.Time, error) {
 	name := filepath.Base(path)
 	for {
@@ -338,30 +357,3 @@ func timestampFromFilepath(path string) (time.Time, error) {
 	}
 	return time.Unix(0, nanosecondsSinceEpoch), nil
 }
-
-func replay(a Adder, timestamps []time.Time, reports []report.Report) {
-	// calculate delays between report n and n+1
-	l := len(timestamps)
-	delays := make([]time.Duration, l, l)
-	for i, t := range timestamps[0 : l-1] {
-		delays[i] = timestamps[i+1].Sub(t)
-		if delays[i] < 0 {
-			panic(fmt.Errorf("replay timestamps are not in order! %v", timestamps))
-		}
-	}
-	// We don't know how long to wait before looping round, so make a
-	// good guess.
-	delays[l-1] = timestamps[l-1].Sub(timestamps[0]) / time.Duration(l)
-
-	due := time.Now()
-	for {
-		for i, r := range reports {
-			a.Add(nil, r, nil)
-			due = due.Add(delays[i])
-			delay := due.Sub(time.Now())
-			if delay > 0 {
-				time.Sleep(delay)
-			}
-		}
-	}
-}
@@ -1,33 +1,94 @@
 package app
 
 import (
+	"fmt"
 	"net/http"
 	"net/rpc"
+	"sync"
+	"time"
 
 	"context"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	"github.com/ugorji/go/codec"
 
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
 )
 
 // RegisterControlRoutes registers the various control routes with a http mux.
-func RegisterControlRoutes(router *mux.Router, cr ControlRouter) {
+// The arguments of control requests are checked against the controls in the
+// reports of rep, if not nil. Bulk and group controls need rep. The kinds of
+// the pipes opened by controls are recorded in pr, if not nil.
+func RegisterControlRoutes(router *mux.Router, cr ControlRouter, pr PipeRouter, rep Reporter) {
 	router.
 		Methods("GET").
 		Path("/api/control/ws").
 		HandlerFunc(requestContextDecorator(handleProbeWS(cr)))
+	if rep != nil {
+		router.
+			Methods("POST").
+			Path("/api/control/bulk").
+			HandlerFunc(requestContextDecorator(handleBulkControl(cr, pr, rep)))
+	}
 	router.
 		Methods("POST").
 		Name("api_control_probeid_nodeid_control").
 		MatcherFunc(URLMatcher("/api/control/{probeID}/{nodeID}/{control}")).
-		HandlerFunc(requestContextDecorator(handleControl(cr)))
+		HandlerFunc(requestContextDecorator(handleControl(cr, pr, newControlSpecs(rep))))
+}
+
+// controlSpecsTTL is how long the control definitions of the reports are
+// kept before being looked up again.
+var controlSpecsTTL = time.Minute
+
+// controlSpecs caches the definitions of the controls of the reports, by
+// ID, so checking the arguments of a control doesn't take merging a full
+// report. Definitions are those of the probes' code, the same for all the
+// users of a multitenant app.
+type controlSpecs struct {
+	sync.Mutex
+	rep      Reporter
+	controls map[string]report.Control
+	updated  time.Time
+}
+
+func newControlSpecs(rep Reporter) *controlSpecs {
+	if rep == nil {
+		return nil
+	}
+	return &controlSpecs{rep: rep}
+}
+
+// lookup returns the definition of the control with the given ID.
+func (s *controlSpecs) lookup(ctx context.Context, id string) (report.Control, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if now := mtime.Now(); s.controls == nil || now.Sub(s.updated) > controlSpecsTTL {
+		rpt, err := s.rep.Report(ctx, now)
+		if err != nil {
+			return report.Control{}, false, err
+		}
+		controls := map[string]report.Control{}
+		rpt.WalkTopologies(func(t *report.Topology) {
+			for id, c := range t.Controls {
+				if _, ok := controls[id]; !ok {
+					controls[id] = c
+				}
+			}
+		})
+		s.controls, s.updated = controls, now
+	}
+	control, ok := s.controls[id]
+	return control, ok, nil
 }
 
 // handleControl routes control requests from the client to the appropriate
-// probe.  Its is blocking.
-func handleControl(cr ControlRouter) CtxHandlerFunc {
+// probe.  Its is blocking. Group controls are run as bulk controls on the
+// children of the group node, with probeID naming its API topology.
+func handleControl(cr ControlRouter, pr PipeRouter, specs *controlSpecs) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		var (
 			vars        = mux.Vars(r)
@@ -46,6 +107,25 @@ func handleControl(cr ControlRouter) CtxHandlerFunc {
 			}
 		}
 
+		if _, ok := detailed.LookupGroupControl(control); ok && specs != nil {
+			handleGroupControl(ctx, w, cr, pr, specs.rep, BulkControlRequest{
+				Control:  control,
+				Topology: probeID,
+				GroupID:  nodeID,
+				Args:     controlArgs,
+			})
+			return
+		}
+
+		if specs != nil {
+			var err error
+			controlArgs, err = checkControlArgs(ctx, specs, control, controlArgs)
+			if err != nil {
+				respondWith(ctx, w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
 		result, err := cr.Handle(ctx, probeID, xfer.Request{
 			NodeID:      nodeID,
 			Control:     control,
@@ -59,15 +139,70 @@ func handleControl(cr ControlRouter) CtxHandlerFunc {
 			respondWith(ctx, w, http.StatusBadRequest, result.Error)
 			return
 		}
+		if kind := PipeKindOf(result); kind != TTYPipe && pr != nil {
+			if err := pr.SetKind(ctx, result.Pipe, kind); err != nil {
+				respondWith(ctx, w, http.StatusInternalServerError, err)
+				return
+			}
+		}
 		respondWith(ctx, w, http.StatusOK, result)
 	}
 }
 
+// checkControlArgs checks args against the arguments of the control, as
+// reported by the probes, filling in defaults. Controls not found in the
+// reports, such as those resizing terminals, are passed through.
+func checkControlArgs(ctx context.Context, specs *controlSpecs, id string, args map[string]string) (map[string]string, error) {
+	control, found, err := specs.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return args, nil
+	}
+	return control.CheckArgs(args)
+}
+
+// findControl returns the control with the given ID, and the ID of the
+// topology it is in, from the first topology of rpt having it.
+func findControl(rpt report.Report, id string) (report.Control, string, bool) {
+	var (
+		control    report.Control
+		topologyID string
+		found      bool
+	)
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		if c, ok := t.Controls[id]; ok && !found {
+			control, topologyID, found = c, name, true
+		}
+	})
+	return control, topologyID, found
+}
+
+// handleGroupControl runs a group control from the client, failing it
+// if it fails on any of the nodes.
+func handleGroupControl(ctx context.Context, w http.ResponseWriter, cr ControlRouter, pr PipeRouter, rep Reporter, req BulkControlRequest) {
+	result, err := runBulkControl(ctx, cr, pr, rep, req)
+	if err != nil {
+		respondWith(ctx, w, err.status, err.Error())
+		return
+	}
+	if failed := result.Failed(); failed > 0 {
+		for _, r := range result.Results {
+			if r.Error != "" {
+				respondWith(ctx, w, http.StatusBadRequest, fmt.Sprintf("Failed on %d of %d nodes: %s", failed, len(result.Results), r.Error))
+				return
+			}
+		}
+	}
+	respondWith(ctx, w, http.StatusOK, result)
+}
+
 // handleProbeWS accepts websocket connections from the probe and registers
 // them in the control router, such that HandleControl calls can find them.
 func handleProbeWS(cr ControlRouter) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-		probeID := r.Header.Get(xfer.ScopeProbeIDHeader)
+		probeID := ProbeIdentity(r)
 		if probeID == "" {
 			respondWith(ctx, w, http.StatusBadRequest, xfer.ScopeProbeIDHeader)
 			return
@@ -1,11 +1,15 @@
 package app_test
 
 import (
+	"context"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,11 +19,14 @@ import (
 	"github.com/weaveworks/scope/app"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/appclient"
+	"github.com/weaveworks/scope/report"
 )
 
 func TestControl(t *testing.T) {
 	router := mux.NewRouter()
-	app.RegisterControlRoutes(router, app.NewLocalControlRouter())
+	pr := app.NewLocalPipeRouter()
+	defer pr.Stop()
+	app.RegisterControlRoutes(router, app.NewLocalControlRouter(), pr, nil)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
@@ -41,7 +48,9 @@ func TestControl(t *testing.T) {
 		}
 
 		return xfer.Response{
-			Value: "foo",
+			Value:      "foo",
+			Pipe:       "pipeid",
+			BinaryPipe: true,
 		}
 	})
 	url := url.URL{Scheme: "http", Host: ip + ":" + port}
@@ -76,4 +85,78 @@ func TestControl(t *testing.T) {
 	if response.Value != "foo" {
 		t.Fatalf("'%s' != 'foo'", response.Value)
 	}
+	if kind, err := pr.Kind(context.Background(), "pipeid"); err != nil || kind != app.BinaryPipe {
+		t.Fatalf("Expected a binary pipe, got %v, %v", kind, err)
+	}
+}
+
+// countingReporter counts the reports asked of it.
+type countingReporter struct {
+	app.Reporter
+	reports int32
+}
+
+func (r *countingReporter) Report(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	atomic.AddInt32(&r.reports, 1)
+	return r.Reporter.Report(ctx, timestamp)
+}
+
+func TestControlArgs(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Host.Controls.AddControl(report.Control{
+		ID: "scale",
+		Args: []report.ControlArg{
+			{Name: "replicas", Type: report.IntArg, Min: report.ArgLimit(0), Required: true},
+			{Name: "strategy", Type: report.EnumArg, Choices: []string{"fast", "slow"}, Default: "slow"},
+		},
+	})
+	rep := &countingReporter{Reporter: app.StaticCollector(rpt)}
+	router := mux.NewRouter()
+	app.RegisterControlRoutes(router, app.NewLocalControlRouter(), nil, rep)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ip, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan map[string]string, 1)
+	controlHandler := xfer.ControlHandlerFunc(func(req xfer.Request) xfer.Response {
+		received <- req.ControlArgs
+		return xfer.Response{}
+	})
+	url := url.URL{Scheme: "http", Host: ip + ":" + port}
+	client, err := appclient.NewAppClient(appclient.ProbeConfig{ProbeID: "foo"}, ip+":"+port, url, controlHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ControlConnection()
+	defer client.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	post := func(args string) (int, string) {
+		resp, err := http.Post(server.URL+"/api/control/foo/nodeid/scale", "application/json", strings.NewReader(args))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, body := post(`{"replicas": "-1"}`); status != http.StatusBadRequest || !strings.Contains(body, "must be at least 0") {
+		t.Errorf("Expected a bad request, got %d %s", status, body)
+	}
+	if status, body := post(`{"replicas": "2"}`); status != http.StatusOK {
+		t.Fatalf("Unexpected response %d %s", status, body)
+	}
+	want := map[string]string{"replicas": "2", "strategy": "slow"}
+	if args := <-received; !reflect.DeepEqual(want, args) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+	// The control definitions are looked up once, not on every request
+	if reports := atomic.LoadInt32(&rep.reports); reports != 1 {
+		t.Errorf("Expected one report, got %d", reports)
+	}
 }
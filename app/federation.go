@@ -0,0 +1,605 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+// FederateScheme is the collector URL scheme for federating upstream apps.
+const FederateScheme = "federate://"
+
+// federationTimeout bounds requests and websocket handshakes to upstream
+// apps. Pulling reports is bounded by the pull interval instead.
+const federationTimeout = 10 * time.Second
+
+// federatedControlTimeout bounds control requests to upstream apps, which
+// take as long as the controls themselves, e.g. stopping a container.
+var federatedControlTimeout = 2 * time.Minute
+
+// Upstream is a Scope app whose reports are federated, labelled with the
+// name of the cluster it runs in.
+type Upstream struct {
+	Cluster string
+	URL     url.URL
+}
+
+// ParseUpstreams parses a federated collector URL, of the form
+// federate://cluster1=http://app1:4040,cluster2=https://app2:4040. If the
+// cluster name is omitted, the upstream's host is used.
+func ParseUpstreams(collectorURL string) ([]Upstream, error) {
+	if !strings.HasPrefix(collectorURL, FederateScheme) {
+		return nil, fmt.Errorf("federated collector URL must start with %s", FederateScheme)
+	}
+	result := []Upstream{}
+	seen := map[string]struct{}{}
+	for _, spec := range strings.Split(strings.TrimPrefix(collectorURL, FederateScheme), ",") {
+		if spec == "" {
+			continue
+		}
+		var cluster string
+		if i := strings.Index(spec, "="); i >= 0 {
+			cluster, spec = spec[:i], spec[i+1:]
+		}
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("invalid upstream URL %q: scheme must be http or https", spec)
+		}
+		if cluster == "" {
+			cluster = u.Host
+		}
+		if strings.ContainsAny(cluster, "/"+report.ScopeDelim) {
+			return nil, fmt.Errorf("invalid cluster name %q", cluster)
+		}
+		if _, ok := seen[cluster]; ok {
+			return nil, fmt.Errorf("duplicate cluster %q", cluster)
+		}
+		seen[cluster] = struct{}{}
+		result = append(result, Upstream{Cluster: cluster, URL: *u})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no upstream apps in %q", collectorURL)
+	}
+	return result, nil
+}
+
+type federatedReport struct {
+	report    report.Report
+	timestamp time.Time
+}
+
+// FederatedCollector is a Collector which periodically pulls reports from
+// upstream apps, and merges them into a single view. Node IDs which embed a
+// host ID are namespaced with the cluster name, so hosts with the same name
+// in different clusters don't clash. Controls and pipes are routed back to
+// the upstream the node came from, through ControlRouter and PipeRouter.
+type FederatedCollector struct {
+	upstreams map[string]Upstream
+	interval  time.Duration
+	window    time.Duration
+	client    *http.Client
+	controls  *http.Client
+	dialer    websocket.Dialer
+	merger    Merger
+	quit      chan struct{}
+	done      sync.WaitGroup
+
+	mtx     sync.Mutex
+	reports map[string]federatedReport
+	probes  map[string]string // probe ID -> cluster
+	pipes   map[string]*federatedPipe
+	waitableCondition
+}
+
+// federatedPipe is a pipe opened through an upstream app. The upstream app
+// closes pipes whose UI end isn't connected for pipeTimeout, and forgets
+// them gcTimeout later, and so do we.
+type federatedPipe struct {
+	cluster   string
+	kind      PipeKind
+	connected bool
+	lastUsed  time.Time
+}
+
+// NewFederatedCollector returns a collector which pulls a report from every
+// upstream each interval. Reports older than the window are discarded.
+func NewFederatedCollector(upstreams []Upstream, interval, window time.Duration) *FederatedCollector {
+	c := &FederatedCollector{
+		upstreams: map[string]Upstream{},
+		interval:  interval,
+		window:    window,
+		client:    &http.Client{Timeout: federationTimeout},
+		controls:  &http.Client{Timeout: federatedControlTimeout},
+		dialer:    websocket.Dialer{HandshakeTimeout: federationTimeout},
+		merger:    NewFastMerger(),
+		quit:      make(chan struct{}),
+		reports:   map[string]federatedReport{},
+		probes:    map[string]string{},
+		pipes:     map[string]*federatedPipe{},
+		waitableCondition: waitableCondition{
+			waiters: map[chan struct{}]struct{}{},
+		},
+	}
+	for _, upstream := range upstreams {
+		c.upstreams[upstream.Cluster] = upstream
+	}
+	c.done.Add(1)
+	go c.loop()
+	return c
+}
+
+func (c *FederatedCollector) loop() {
+	defer c.done.Done()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.pullAll()
+		c.forgetPipes()
+		select {
+		case <-ticker.C:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *FederatedCollector) pullAll() {
+	var wg sync.WaitGroup
+	for _, upstream := range c.upstreams {
+		wg.Add(1)
+		go func(upstream Upstream) {
+			defer wg.Done()
+			if err := c.pull(upstream); err != nil {
+				log.Errorf("Error pulling report from cluster %s: %v", upstream.Cluster, err)
+			}
+		}(upstream)
+	}
+	wg.Wait()
+	c.Broadcast()
+}
+
+func (c *FederatedCollector) pull(upstream Upstream) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+	defer cancel()
+	req, err := c.request(ctx, upstream, "GET", "/api/report", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/msgpack")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	rpt, err := report.MakeFromBinary(ctx, resp.Body, false, true)
+	if err != nil {
+		return err
+	}
+	namespaceReport(rpt, upstream.Cluster)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.reports[upstream.Cluster] = federatedReport{report: *rpt, timestamp: mtime.Now()}
+	rpt.WalkTopologies(func(t *report.Topology) {
+		for _, n := range t.Nodes {
+			if probeID, ok := n.Latest.Lookup(report.ControlProbeID); ok {
+				c.probes[probeID] = upstream.Cluster
+			}
+		}
+	})
+	return nil
+}
+
+// url returns the URL of an already escaped path on an upstream app.
+func (u Upstream) url(path string) (url.URL, error) {
+	result := u.URL
+	result.User = nil
+	result.RawPath = strings.TrimSuffix(result.EscapedPath(), "/") + path
+	unescaped, err := url.PathUnescape(result.RawPath)
+	if err != nil {
+		return url.URL{}, err
+	}
+	result.Path = unescaped
+	return result, nil
+}
+
+// authorize passes on the credentials in the upstream URL as basic
+// authentication.
+func (u Upstream) authorize(headers http.Header) {
+	if user := u.URL.User; user != nil {
+		password, _ := user.Password()
+		req := http.Request{Header: headers}
+		req.SetBasicAuth(user.Username(), password)
+	}
+}
+
+// request makes a request to an upstream app.
+func (c *FederatedCollector) request(ctx context.Context, upstream Upstream, method, path string, body io.Reader) (*http.Request, error) {
+	u, err := upstream.url(path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	upstream.authorize(req.Header)
+	return req.WithContext(ctx), nil
+}
+
+// namespaceReport prefixes the host IDs in the report with the cluster
+// name, and labels every node with its cluster. Addresses which aren't
+// scoped by host are left alone, so connections between clusters are
+// still matched up.
+func namespaceReport(rpt *report.Report, cluster string) {
+	var (
+		prefix = cluster + "/"
+		now    = mtime.Now()
+	)
+	namespaceID := func(topology, id string) string {
+		switch topology {
+		case report.Host:
+			if hostID, ok := report.ParseHostNodeID(id); ok {
+				return report.MakeHostNodeID(prefix + hostID)
+			}
+		case report.Process:
+			return prefix + id
+		case report.Endpoint:
+			if !strings.HasPrefix(id, report.ScopeDelim) {
+				return prefix + id
+			}
+		}
+		return id
+	}
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		*t = t.WithMetadataTemplates(report.MetadataTemplates{
+			report.FederationCluster: {ID: report.FederationCluster, Label: "Cluster", From: report.FromLatest, Priority: 0},
+		})
+		nodes := make(report.Nodes, len(t.Nodes))
+		for id, n := range t.Nodes {
+			n.ID = namespaceID(name, id)
+			adjacency := report.MakeIDList()
+			for _, a := range n.Adjacency {
+				adjacency = adjacency.Add(namespaceID(name, a))
+			}
+			n.Adjacency = adjacency
+			parents := report.MakeSets()
+			for _, key := range n.Parents.Keys() {
+				ids, _ := n.Parents.Lookup(key)
+				namespaced := report.MakeStringSet()
+				for _, parent := range ids {
+					namespaced = namespaced.Add(namespaceID(key, parent))
+				}
+				parents = parents.Add(key, namespaced)
+			}
+			n.Parents = parents
+			if hostNodeID, ts, ok := n.Latest.LookupEntry(report.HostNodeID); ok {
+				n = n.WithLatest(report.HostNodeID, ts, namespaceID(report.Host, hostNodeID))
+			}
+			nodes[n.ID] = n.WithLatest(report.FederationCluster, now, cluster)
+		}
+		t.Nodes = nodes
+	})
+}
+
+// Add implements Adder. Federated apps don't accept reports from probes.
+func (c *FederatedCollector) Add(context.Context, report.Report, []byte) error {
+	return fmt.Errorf("federated app does not accept reports; point probes at an upstream app")
+}
+
+// Report implements Reporter, merging the latest report of every upstream.
+func (c *FederatedCollector) Report(_ context.Context, timestamp time.Time) (report.Report, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	reports := []report.Report{}
+	for _, r := range c.reports {
+		if r.timestamp.After(timestamp.Add(-c.window)) && !r.timestamp.After(timestamp) {
+			reports = append(reports, r.report)
+		}
+	}
+	return c.merger.Merge(reports), nil
+}
+
+// HasReports implements Reporter
+func (c *FederatedCollector) HasReports(_ context.Context, timestamp time.Time) (bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, r := range c.reports {
+		if r.timestamp.After(timestamp.Add(-c.window)) && !r.timestamp.After(timestamp) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasHistoricReports implements Reporter
+func (c *FederatedCollector) HasHistoricReports() bool {
+	return false
+}
+
+// AdminSummary implements Reporter
+func (c *FederatedCollector) AdminSummary(context.Context, time.Time) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	clusters := []string{}
+	for cluster := range c.upstreams {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	var b strings.Builder
+	for _, cluster := range clusters {
+		r, ok := c.reports[cluster]
+		if !ok {
+			fmt.Fprintf(&b, "%s: no report\n", cluster)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %v: %s\n", cluster, r.timestamp.Format(time.StampMilli), r.report.Summary())
+	}
+	return b.String(), nil
+}
+
+// Close stops pulling reports.
+func (c *FederatedCollector) Close() {
+	close(c.quit)
+	c.done.Wait()
+}
+
+func (c *FederatedCollector) upstreamForProbe(probeID string) (Upstream, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	cluster, ok := c.probes[probeID]
+	if !ok {
+		return Upstream{}, fmt.Errorf("unknown probe %s", probeID)
+	}
+	return c.upstreams[cluster], nil
+}
+
+func (c *FederatedCollector) upstreamForPipe(pipeID string) (Upstream, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	p, ok := c.pipes[pipeID]
+	if !ok {
+		return Upstream{}, fmt.Errorf("unknown pipe %s", pipeID)
+	}
+	return c.upstreams[p.cluster], nil
+}
+
+// usePipe records the UI end of a pipe being connected, or disconnected.
+func (c *FederatedCollector) usePipe(pipeID string, connected bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if p, ok := c.pipes[pipeID]; ok {
+		p.connected = connected
+		p.lastUsed = mtime.Now()
+	}
+}
+
+// forgetPipes forgets the pipes the upstream apps have forgotten, as their
+// UI end hasn't been connected for long enough.
+func (c *FederatedCollector) forgetPipes() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	now := mtime.Now()
+	for id, p := range c.pipes {
+		if !p.connected && now.Sub(p.lastUsed) >= pipeTimeout+gcTimeout {
+			delete(c.pipes, id)
+		}
+	}
+}
+
+// ControlRouter returns a ControlRouter which forwards control requests to
+// the upstream app of the probe they are addressed to.
+func (c *FederatedCollector) ControlRouter() ControlRouter {
+	return federatedControlRouter{c}
+}
+
+type federatedControlRouter struct {
+	collector *FederatedCollector
+}
+
+func (cr federatedControlRouter) Handle(ctx context.Context, probeID string, req xfer.Request) (xfer.Response, error) {
+	upstream, err := cr.collector.upstreamForProbe(probeID)
+	if err != nil {
+		return xfer.Response{}, err
+	}
+	body := &bytes.Buffer{}
+	if err := codec.NewEncoder(body, &codec.JsonHandle{}).Encode(req.ControlArgs); err != nil {
+		return xfer.Response{}, err
+	}
+	nodeID := strings.TrimPrefix(req.NodeID, upstream.Cluster+"/")
+	// The control route unescapes its parts as query components
+	path := fmt.Sprintf("/api/control/%s/%s/%s", url.QueryEscape(probeID), url.QueryEscape(nodeID), url.QueryEscape(req.Control))
+	httpReq, err := cr.collector.request(ctx, upstream, "POST", path, body)
+	if err != nil {
+		return xfer.Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := cr.collector.controls.Do(httpReq)
+	if err != nil {
+		return xfer.Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var msg string
+		if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&msg); err != nil {
+			msg = resp.Status
+		}
+		return xfer.Response{Error: msg}, nil
+	}
+	var res xfer.Response
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&res); err != nil {
+		return xfer.Response{}, err
+	}
+	if res.Pipe != "" {
+		cr.collector.mtx.Lock()
+		cr.collector.pipes[res.Pipe] = &federatedPipe{cluster: upstream.Cluster, lastUsed: mtime.Now()}
+		cr.collector.mtx.Unlock()
+	}
+	return res, nil
+}
+
+func (federatedControlRouter) Register(context.Context, string, xfer.ControlHandlerFunc) (int64, error) {
+	return 0, fmt.Errorf("federated app does not accept probe connections")
+}
+
+func (federatedControlRouter) Deregister(context.Context, string, int64) error {
+	return nil
+}
+
+// PipeRouter returns a PipeRouter which connects the UI end of pipes to
+// the upstream app which created them.
+func (c *FederatedCollector) PipeRouter() PipeRouter {
+	return &federatedPipeRouter{
+		collector: c,
+		active:    map[string]xfer.Pipe{},
+	}
+}
+
+type federatedPipeRouter struct {
+	sync.Mutex
+	collector *FederatedCollector
+	active    map[string]xfer.Pipe
+}
+
+func (pr *federatedPipeRouter) Exists(ctx context.Context, id string) (bool, error) {
+	upstream, err := pr.collector.upstreamForPipe(id)
+	if err != nil {
+		return false, nil
+	}
+	req, err := pr.collector.request(ctx, upstream, "GET", "/api/pipe/"+url.PathEscape(id)+"/check", nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := pr.collector.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusNoContent, nil
+}
+
+func (pr *federatedPipeRouter) Get(ctx context.Context, id string, e End) (xfer.Pipe, io.ReadWriter, error) {
+	if e != UIEnd {
+		return nil, nil, fmt.Errorf("federated app does not accept probe connections")
+	}
+	upstream, err := pr.collector.upstreamForPipe(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := upstream.url("/api/pipe/" + url.PathEscape(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	headers := http.Header{}
+	upstream.authorize(headers)
+	conn, _, err := xfer.DialWS(&pr.collector.dialer, u.String(), headers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := xfer.NewPipe()
+	ui, remote := p.Ends()
+	p.OnClose(func() {
+		conn.Close()
+	})
+	go func() {
+		if _, err := p.CopyToWebsocket(remote, conn); err != nil {
+			log.Errorf("Error copying pipe %s to cluster %s: %v", id, upstream.Cluster, err)
+		}
+		p.Close()
+	}()
+
+	pr.Lock()
+	pr.active[id] = p
+	pr.Unlock()
+	pr.collector.usePipe(id, true)
+	return p, ui, nil
+}
+
+func (pr *federatedPipeRouter) Release(_ context.Context, id string, _ End) error {
+	pr.Lock()
+	p, ok := pr.active[id]
+	delete(pr.active, id)
+	pr.Unlock()
+	if ok {
+		p.Close()
+		pr.collector.usePipe(id, false)
+	}
+	return nil
+}
+
+func (pr *federatedPipeRouter) Delete(ctx context.Context, id string) error {
+	pr.Release(ctx, id, UIEnd)
+	upstream, err := pr.collector.upstreamForPipe(id)
+	if err != nil {
+		return nil
+	}
+	req, err := pr.collector.request(ctx, upstream, "DELETE", "/api/pipe/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := pr.collector.client.Do(req)
+	if err != nil {
+		return err
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	pr.collector.mtx.Lock()
+	delete(pr.collector.pipes, id)
+	pr.collector.mtx.Unlock()
+	return nil
+}
+
+// SetKind records the kind of a pipe for this app's routes; the upstream app
+// enforces it on the pipe itself.
+func (pr *federatedPipeRouter) SetKind(_ context.Context, id string, kind PipeKind) error {
+	pr.collector.mtx.Lock()
+	defer pr.collector.mtx.Unlock()
+	if p, ok := pr.collector.pipes[id]; ok {
+		p.kind = kind
+	}
+	return nil
+}
+
+func (pr *federatedPipeRouter) Kind(_ context.Context, id string) (PipeKind, error) {
+	pr.collector.mtx.Lock()
+	defer pr.collector.mtx.Unlock()
+	if p, ok := pr.collector.pipes[id]; ok {
+		return p.kind, nil
+	}
+	return TTYPipe, nil
+}
+
+func (pr *federatedPipeRouter) Stop() {
+	pr.Lock()
+	defer pr.Unlock()
+	for id, p := range pr.active {
+		p.Close()
+		delete(pr.active, id)
+	}
+}
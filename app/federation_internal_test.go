@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+func TestFederatedControlTimeout(t *testing.T) {
+	oldTimeout := federatedControlTimeout
+	defer func() { federatedControlTimeout = oldTimeout }()
+	federatedControlTimeout = 100 * time.Millisecond
+
+	// An upstream app which never answers control requests
+	hung := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/control/") {
+			http.NotFound(w, r)
+			return
+		}
+		<-hung
+	}))
+	defer upstream.Close()
+	defer close(hung)
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := NewFederatedCollector([]Upstream{{Cluster: "eu", URL: *u}}, time.Hour, time.Minute)
+	defer collector.Close()
+	collector.mtx.Lock()
+	collector.probes["probe"] = "eu"
+	collector.mtx.Unlock()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := collector.ControlRouter().Handle(context.Background(), "probe", xfer.Request{NodeID: "node", Control: "control"})
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Expected the control to time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Control request to a hung app didn't time out")
+	}
+}
+
+func TestFederatedPipesForgotten(t *testing.T) {
+	collector := NewFederatedCollector(nil, time.Hour, time.Minute)
+	defer collector.Close()
+	pr := collector.PipeRouter()
+	ctx := context.Background()
+
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+	collector.mtx.Lock()
+	for _, id := range []string{"abandoned", "connected"} {
+		collector.pipes[id] = &federatedPipe{cluster: "eu", lastUsed: now}
+	}
+	collector.mtx.Unlock()
+	pr.SetKind(ctx, "abandoned", BinaryPipe)
+	collector.usePipe("connected", true)
+
+	// The upstream app forgets a pipe gcTimeout after timing it out
+	mtime.NowForce(now.Add(pipeTimeout + gcTimeout))
+	collector.forgetPipes()
+	if kind, _ := pr.Kind(ctx, "abandoned"); kind != TTYPipe {
+		t.Errorf("Expected the kind of the pipe to be forgotten, got %v", kind)
+	}
+	if _, err := collector.upstreamForPipe("abandoned"); err == nil {
+		t.Errorf("Expected the abandoned pipe to be forgotten")
+	}
+	if _, err := collector.upstreamForPipe("connected"); err != nil {
+		t.Errorf("Expected the connected pipe to be kept, got %v", err)
+	}
+
+	// Once disconnected, it's forgotten in turn
+	collector.usePipe("connected", false)
+	collector.forgetPipes()
+	if _, err := collector.upstreamForPipe("connected"); err != nil {
+		t.Errorf("Expected the pipe to be kept, got %v", err)
+	}
+	mtime.NowForce(now.Add(2 * (pipeTimeout + gcTimeout)))
+	collector.forgetPipes()
+	if _, err := collector.upstreamForPipe("connected"); err == nil {
+		t.Errorf("Expected the disconnected pipe to be forgotten")
+	}
+}
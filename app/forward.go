@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+const forwardDialTimeout = 10 * time.Second
+
+// RegisterForwardRoutes registers the routes proxying HTTP requests, and
+// websockets, to the ports forwarded by the port-forward controls:
+// /api/forward/{pipeID}/path is proxied to /path on the forwarded port. Only
+// the pipes of port-forward controls, as recorded in pr, are proxied to.
+func RegisterForwardRoutes(router *mux.Router, pr PipeRouter) {
+	f := &forwarder{
+		pr:    pr,
+		muxes: map[string]*xfer.Mux{},
+	}
+	router.PathPrefix("/api/forward/{pipeID}/").
+		Name("api_forward_pipeid").
+		HandlerFunc(requestContextDecorator(f.handle))
+}
+
+// forwarder multiplexes the proxied connections over the UI end of the
+// pipes. Each request holds a reference to the pipe, so pipes no longer
+// used are timed out and closed, like any other.
+type forwarder struct {
+	sync.Mutex
+	pr    PipeRouter
+	muxes map[string]*xfer.Mux
+}
+
+func (f *forwarder) handle(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["pipeID"]
+	kind, err := f.pr.Kind(ctx, id)
+	if err != nil {
+		respondWith(ctx, w, http.StatusInternalServerError, err)
+		return
+	} else if kind != ForwardPipe {
+		http.NotFound(w, r)
+		return
+	}
+	exists, err := f.pr.Exists(ctx, id)
+	if err != nil {
+		respondWith(ctx, w, http.StatusInternalServerError, err)
+		return
+	} else if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	_, endIO, err := f.pr.Get(ctx, id, UIEnd)
+	if err != nil {
+		// this usually means the pipe has been closed
+		log.Debugf("Error getting pipe %s: %v", id, err)
+		http.NotFound(w, r)
+		return
+	}
+	defer f.pr.Release(ctx, id, UIEnd)
+
+	m := f.mux(id, endIO)
+	prefix := "/api/forward/" + id
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = id
+			if i := strings.Index(req.URL.Path, prefix); i >= 0 {
+				req.URL.Path = req.URL.Path[i+len(prefix):]
+			}
+			req.URL.RawPath = ""
+		},
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return openForward(ctx, m)
+			},
+			DisableKeepAlives: true,
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+func (f *forwarder) mux(id string, rw io.ReadWriter) *xfer.Mux {
+	f.Lock()
+	defer f.Unlock()
+	if m, ok := f.muxes[id]; ok {
+		return m
+	}
+	m := xfer.NewMux(rw)
+	f.muxes[id] = m
+	go func() {
+		<-m.Done()
+		f.Lock()
+		delete(f.muxes, id)
+		f.Unlock()
+	}()
+	return m
+}
+
+// openForward opens a connection through m, unless that takes longer than
+// forwardDialTimeout, e.g. as the probe end of the pipe isn't connected.
+func openForward(ctx context.Context, m *xfer.Mux) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, forwardDialTimeout)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		conn, err := m.Open()
+		opened <- result{conn, err}
+	}()
+	select {
+	case r := <-opened:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-opened; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
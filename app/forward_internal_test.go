@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+// muxListener serves the connections forwarded through a pipe, as the
+// target of a port-forward would.
+type muxListener struct {
+	*xfer.Mux
+}
+
+func (l muxListener) Close() error   { return nil }
+func (l muxListener) Addr() net.Addr { return &net.TCPAddr{} }
+
+func TestForward(t *testing.T) {
+	pr := NewLocalPipeRouter()
+	defer pr.Stop()
+	router := mux.NewRouter()
+	RegisterForwardRoutes(router, pr)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	pipe, probe, err := pr.Get(ctx, "pipe", ProbeEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Release(ctx, "pipe", ProbeEnd)
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Only the pipes of port-forward controls are proxied to
+	if status, _ := get("/api/forward/pipe/"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 on a TTY pipe, got %d", status)
+	}
+	if err := pr.SetKind(ctx, "pipe", ForwardPipe); err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(muxListener{xfer.NewMux(probe)}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.RequestURI())
+	}))
+
+	for _, path := range []string{"/", "/metrics?name=x"} {
+		if status, body := get("/api/forward/pipe" + path); status != http.StatusOK || body != "GET "+path {
+			t.Errorf("%s: unexpected response %d %q", path, status, body)
+		}
+	}
+
+	// Closed pipes are not found
+	pipe.Close()
+	if status, _ := get("/api/forward/pipe/"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 on a closed pipe, got %d", status)
+	}
+}
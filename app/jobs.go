@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+// RegisterJobRoutes registers the routes to list, inspect and cancel the
+// jobs running long-running controls with a http mux.
+func RegisterJobRoutes(router *mux.Router, rep Reporter, cr ControlRouter) {
+	router.
+		Methods("GET").
+		Path("/api/jobs").
+		HandlerFunc(requestContextDecorator(handleJobs(rep)))
+	router.
+		Methods("GET").
+		Path("/api/jobs/{id}").
+		HandlerFunc(requestContextDecorator(handleJob(rep)))
+	router.
+		Methods("POST").
+		Path("/api/jobs/{id}/cancel").
+		HandlerFunc(requestContextDecorator(handleCancelJob(rep, cr)))
+}
+
+// handleJobs lists the jobs, oldest first, optionally only those of a node
+// (?node=<node ID>) or in a state (?state=running).
+func handleJobs(rep Reporter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		rpt, err := rep.Report(ctx, mtime.Now())
+		if err != nil {
+			respondWith(ctx, w, http.StatusInternalServerError, err)
+			return
+		}
+		var (
+			nodeID = r.FormValue("node")
+			state  = r.FormValue("state")
+			jobs   = []report.ControlJob{}
+		)
+		for _, job := range rpt.ControlJobs {
+			if (nodeID == "" || job.NodeID == nodeID) && (state == "" || job.State == state) {
+				jobs = append(jobs, job)
+			}
+		}
+		sort.Slice(jobs, func(i, j int) bool {
+			if !jobs[i].Started.Equal(jobs[j].Started) {
+				return jobs[i].Started.Before(jobs[j].Started)
+			}
+			return jobs[i].ID < jobs[j].ID
+		})
+		respondWith(ctx, w, http.StatusOK, jobs)
+	}
+}
+
+func handleJob(rep Reporter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		job, err := lookupJob(ctx, rep, mux.Vars(r)["id"])
+		if err != nil {
+			respondWith(ctx, w, http.StatusInternalServerError, err)
+			return
+		}
+		if job == nil {
+			http.NotFound(w, r)
+			return
+		}
+		respondWith(ctx, w, http.StatusOK, job)
+	}
+}
+
+// handleCancelJob asks the probe running a job to cancel it.
+func handleCancelJob(rep Reporter, cr ControlRouter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		job, err := lookupJob(ctx, rep, mux.Vars(r)["id"])
+		if err != nil {
+			respondWith(ctx, w, http.StatusInternalServerError, err)
+			return
+		}
+		if job == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if job.Done() {
+			respondWith(ctx, w, http.StatusBadRequest, fmt.Sprintf("Job %s is already %s", job.ID, job.State))
+			return
+		}
+		result, err := cr.Handle(ctx, job.ProbeID, xfer.Request{
+			NodeID:  job.ID,
+			Control: report.CancelJob,
+		})
+		if err != nil {
+			respondWith(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if result.Error != "" {
+			respondWith(ctx, w, http.StatusBadRequest, result.Error)
+			return
+		}
+		respondWith(ctx, w, http.StatusOK, result)
+	}
+}
+
+func lookupJob(ctx context.Context, rep Reporter, id string) (*report.ControlJob, error) {
+	rpt, err := rep.Report(ctx, mtime.Now())
+	if err != nil {
+		return nil, err
+	}
+	job, ok := rpt.ControlJobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
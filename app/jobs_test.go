@@ -0,0 +1,112 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestJobs(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		t0        = time.Now()
+		cr        = app.NewLocalControlRouter()
+		cancelled = make(chan string, 1)
+	)
+	rpt := report.MakeReport()
+	rpt.ControlJobs = report.ControlJobs{
+		"a": {ID: "a", ProbeID: "probe", NodeID: "n1", State: report.JobRunning, Started: t0},
+		"b": {ID: "b", ProbeID: "probe", NodeID: "n1", State: report.JobSucceeded, Started: t0.Add(-time.Second)},
+		"c": {ID: "c", ProbeID: "probe", NodeID: "n2", State: report.JobRunning, Started: t0.Add(time.Second)},
+	}
+	cr.Register(ctx, "probe", func(req xfer.Request) xfer.Response {
+		if req.Control == report.CancelJob {
+			cancelled <- req.NodeID
+		}
+		return xfer.Response{}
+	})
+	router := mux.NewRouter()
+	app.RegisterJobRoutes(router, app.StaticCollector(rpt), cr)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path string, result interface{}) int {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	for path, want := range map[string][]string{
+		"/api/jobs":                        {"b", "a", "c"},
+		"/api/jobs?node=n1":                {"b", "a"},
+		"/api/jobs?state=running":          {"a", "c"},
+		"/api/jobs?node=n2&state=finished": {},
+	} {
+		var jobs []report.ControlJob
+		if status := get(path, &jobs); status != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", path, status)
+		}
+		have := []string{}
+		for _, job := range jobs {
+			have = append(have, job.ID)
+		}
+		if len(have) != len(want) {
+			t.Errorf("%s: expected %v, got %v", path, want, have)
+			continue
+		}
+		for i := range want {
+			if have[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", path, want, have)
+				break
+			}
+		}
+	}
+
+	var job report.ControlJob
+	if status := get("/api/jobs/a", &job); status != http.StatusOK || job.NodeID != "n1" {
+		t.Errorf("Unexpected job %d %+v", status, job)
+	}
+	if status := get("/api/jobs/unknown", &job); status != http.StatusNotFound {
+		t.Errorf("Expected not found, got %d", status)
+	}
+
+	for id, want := range map[string]int{
+		"a":       http.StatusOK,
+		"b":       http.StatusBadRequest,
+		"unknown": http.StatusNotFound,
+	} {
+		resp, err := http.Post(server.URL+"/api/jobs/"+id+"/cancel", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Cancelling %s: expected %d, got %d", id, want, resp.StatusCode)
+		}
+	}
+	select {
+	case id := <-cancelled:
+		if id != "a" {
+			t.Errorf("Expected job a to be cancelled, got %s", id)
+		}
+	default:
+		t.Errorf("Expected job a to be cancelled")
+	}
+}
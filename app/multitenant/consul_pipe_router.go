@@ -36,6 +36,7 @@ type consulPipe struct {
 	CreatedAt, DeletedAt time.Time
 	UIAddr, ProbeAddr    string // Addrs where each end is connected
 	UIRef, ProbeRef      int    // Ref counts
+	Kind                 app.PipeKind
 }
 
 func (c *consulPipe) setAddrFor(e app.End, addr string) {
@@ -374,6 +375,42 @@ func (pr *consulPipeRouter) Delete(ctx context.Context, id string) error {
 	})
 }
 
+func (pr *consulPipeRouter) SetKind(ctx context.Context, id string, kind app.PipeKind) error {
+	userID, err := pr.userIDer(ctx)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s%s-%s", pr.prefix, userID, id)
+	return pr.client.CAS(ctx, key, &consulPipe{}, func(in interface{}) (interface{}, bool, error) {
+		var pipe *consulPipe
+		if in == nil {
+			pipe = &consulPipe{
+				CreatedAt: mtime.Now(),
+			}
+		} else {
+			pipe = in.(*consulPipe)
+		}
+		pipe.Kind = kind
+		return pipe, false, nil
+	})
+}
+
+func (pr *consulPipeRouter) Kind(ctx context.Context, id string) (app.PipeKind, error) {
+	userID, err := pr.userIDer(ctx)
+	if err != nil {
+		return app.TTYPipe, err
+	}
+	key := fmt.Sprintf("%s%s-%s", pr.prefix, userID, id)
+	consulPipe := consulPipe{}
+	err = pr.client.Get(ctx, key, &consulPipe)
+	if err == ErrNotFound {
+		return app.TTYPipe, nil
+	} else if err != nil {
+		return app.TTYPipe, err
+	}
+	return consulPipe.Kind, nil
+}
+
 // A bridgeConnection represents a connection between two pipe router replicas.
 // They are created & destroyed in response to events from consul, which in turn
 // are triggered when UIs or Probes connect to various pipe routers.
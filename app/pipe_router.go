@@ -35,12 +35,38 @@ func (e End) String() string {
 	return "probe"
 }
 
+// PipeKind is what a pipe carries, as told by the response of the control
+// which opened it.
+type PipeKind int
+
+// Valid values of type PipeKind
+const (
+	TTYPipe     PipeKind = iota // a terminal, or logs
+	BinaryPipe                  // binary data, such as a tar archive
+	ForwardPipe                 // the connections to a forwarded port
+)
+
+// PipeKindOf returns the kind of the pipe opened by a control, from its
+// response.
+func PipeKindOf(res xfer.Response) PipeKind {
+	switch {
+	case res.Forward != "":
+		return ForwardPipe
+	case res.BinaryPipe:
+		return BinaryPipe
+	}
+	return TTYPipe
+}
+
 // PipeRouter stores pipes and allows you to connect to either end of them.
+// Pipes are TTYPipes unless set otherwise, before the UI connects to them.
 type PipeRouter interface {
 	Exists(context.Context, string) (bool, error)
 	Get(context.Context, string, End) (xfer.Pipe, io.ReadWriter, error)
 	Release(context.Context, string, End) error
 	Delete(context.Context, string) error
+	SetKind(context.Context, string, PipeKind) error
+	Kind(context.Context, string) (PipeKind, error)
 	Stop()
 }
 
@@ -57,6 +83,7 @@ type localPipeRouter struct {
 type pipe struct {
 	xfer.Pipe
 
+	kind          PipeKind
 	tombstoneTime time.Time
 
 	ui, probe end
@@ -96,9 +123,9 @@ func (pr *localPipeRouter) Exists(_ context.Context, id string) (bool, error) {
 	return !p.Closed(), nil
 }
 
-func (pr *localPipeRouter) Get(_ context.Context, id string, e End) (xfer.Pipe, io.ReadWriter, error) {
-	pr.Lock()
-	defer pr.Unlock()
+// pipe returns the pipe with the given ID, creating it if need be. It must
+// be called with the lock held.
+func (pr *localPipeRouter) pipe(id string) *pipe {
 	p, ok := pr.pipes[id]
 	if !ok {
 		log.Debugf("Creating pipe id %s", id)
@@ -109,6 +136,13 @@ func (pr *localPipeRouter) Get(_ context.Context, id string, e End) (xfer.Pipe,
 		}
 		pr.pipes[id] = p
 	}
+	return p
+}
+
+func (pr *localPipeRouter) Get(_ context.Context, id string, e End) (xfer.Pipe, io.ReadWriter, error) {
+	pr.Lock()
+	defer pr.Unlock()
+	p := pr.pipe(id)
 	if p.Closed() {
 		return nil, nil, fmt.Errorf("Pipe %s closed", id)
 	}
@@ -139,18 +173,33 @@ func (pr *localPipeRouter) Release(_ context.Context, id string, e End) error {
 	return nil
 }
 
+// Delete closes the pipe. A pipe not connected to yet is tombstoned all the
+// same, so the probe end is refused when it connects.
 func (pr *localPipeRouter) Delete(_ context.Context, id string) error {
 	pr.Lock()
 	defer pr.Unlock()
-	p, ok := pr.pipes[id]
-	if !ok {
-		return nil
-	}
+	p := pr.pipe(id)
 	p.Close()
 	p.tombstoneTime = mtime.Now()
 	return nil
 }
 
+func (pr *localPipeRouter) SetKind(_ context.Context, id string, kind PipeKind) error {
+	pr.Lock()
+	defer pr.Unlock()
+	pr.pipe(id).kind = kind
+	return nil
+}
+
+func (pr *localPipeRouter) Kind(_ context.Context, id string) (PipeKind, error) {
+	pr.Lock()
+	defer pr.Unlock()
+	if p, ok := pr.pipes[id]; ok {
+		return p.kind, nil
+	}
+	return TTYPipe, nil
+}
+
 func (pr *localPipeRouter) Stop() {
 	close(pr.quit)
 	pr.wait.Wait()
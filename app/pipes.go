@@ -51,6 +51,14 @@ func checkPipe(pr PipeRouter) CtxHandlerFunc {
 func handlePipeWs(pr PipeRouter, end End) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["pipeID"]
+		kind := TTYPipe
+		if end == UIEnd {
+			var err error
+			if kind, err = pr.Kind(ctx, id); err != nil {
+				respondWith(ctx, w, http.StatusInternalServerError, err)
+				return
+			}
+		}
 		pipe, endIO, err := pr.Get(ctx, id, end)
 		if err != nil {
 			// this usually means the pipe has been closed
@@ -66,6 +74,10 @@ func handlePipeWs(pr PipeRouter, end End) CtxHandlerFunc {
 			return
 		}
 		defer conn.Close()
+		if kind != TTYPipe {
+			// Only the probe end is a binary pipe already
+			conn = xfer.BinaryWebsocket(conn)
+		}
 
 		if _, err := pipe.CopyToWebsocket(endIO, conn); err != nil {
 			if span := opentracing.SpanFromContext(ctx); span != nil {
@@ -141,3 +141,52 @@ func TestPipeClose(t *testing.T) {
 		return pipe.Closed()
 	})
 }
+
+func TestBinaryPipeRejectsText(t *testing.T) {
+	router := mux.NewRouter()
+	pr := NewLocalPipeRouter()
+	RegisterPipeRoutes(router, pr)
+	defer pr.Stop()
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	if err := pr.SetKind(ctx, "archive", BinaryPipe); err != nil {
+		t.Fatal(err)
+	}
+	_, probeEnd, err := pr.Get(ctx, "archive", ProbeEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Release(ctx, "archive", ProbeEnd)
+
+	pipeURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/pipe/archive"
+	conn, _, err := websocket.DefaultDialer.Dial(pipeURL, http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	msg := []byte("archive")
+	if err := conn.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	if n, err := probeEnd.Read(buf); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(msg, buf[:n]) {
+		t.Fatalf("%v != %v", buf[:n], msg)
+	}
+
+	// A text message closes the websocket
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("text")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("Expected the websocket to be closed")
+	} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		t.Fatalf("Expected the websocket to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// ProbeInfo describes a probe known to the app, and its health.
+type ProbeInfo struct {
+	ID                string                   `json:"id"`
+	Hostname          string                   `json:"hostname"`
+	Version           string                   `json:"version"`
+	LastSeen          time.Time                `json:"lastSeen"`
+	Stale             bool                     `json:"stale"`
+	ReportSize        int                      `json:"reportSize,omitempty"`
+	PublishLag        time.Duration            `json:"publishLag,omitempty"`
+	ReporterDurations map[string]time.Duration `json:"reporterDurations,omitempty"`
+	TaggerDurations   map[string]time.Duration `json:"taggerDurations,omitempty"`
+	Features          []string                 `json:"features,omitempty"`
+	Errors            map[string]string        `json:"errors,omitempty"`
+}
+
+// ProbeLister is something which can describe all the probes which
+// have reported to the app.
+type ProbeLister interface {
+	Probes(ctx context.Context, timestamp time.Time) ([]ProbeInfo, error)
+}
+
+type probeRecord struct {
+	ProbeInfo
+	hosts map[string]report.Node
+}
+
+// ProbeInventory is a Collector which keeps track of every probe which has
+// sent it a report, including probes which have since stopped reporting.
+type ProbeInventory struct {
+	Collector
+	staleAfter  time.Duration
+	forgetAfter time.Duration
+
+	mtx    sync.Mutex
+	probes map[string]*probeRecord
+}
+
+// NewProbeInventory wraps a collector so it keeps an inventory of probes.
+// Probes which haven't reported for staleAfter are considered stale, and
+// are forgotten after forgetAfter.
+func NewProbeInventory(collector Collector, staleAfter, forgetAfter time.Duration) *ProbeInventory {
+	return &ProbeInventory{
+		Collector:   collector,
+		staleAfter:  staleAfter,
+		forgetAfter: forgetAfter,
+		probes:      map[string]*probeRecord{},
+	}
+}
+
+// Add implements Adder
+func (i *ProbeInventory) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	i.record(rpt, len(buf), mtime.Now())
+	return i.Collector.Add(ctx, rpt, buf)
+}
+
+func (i *ProbeInventory) record(rpt report.Report, size int, now time.Time) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	lookup := func(id string) *probeRecord {
+		record, ok := i.probes[id]
+		if !ok {
+			record = &probeRecord{ProbeInfo: ProbeInfo{ID: id}}
+			i.probes[id] = record
+		}
+		record.LastSeen = now
+		record.ReportSize = size
+		return record
+	}
+
+	// Probes which don't publish a summary can still be identified from
+	// their host node.
+	hosts := map[string]map[string]report.Node{}
+	for id, n := range rpt.Host.Nodes {
+		probeID, ok := n.Latest.Lookup(report.ControlProbeID)
+		if !ok {
+			continue
+		}
+		if hosts[probeID] == nil {
+			hosts[probeID] = map[string]report.Node{}
+		}
+		hosts[probeID][id] = n
+		record := lookup(probeID)
+		if hostname, ok := n.Latest.Lookup(report.HostName); ok {
+			record.Hostname = hostname
+		}
+		if version, ok := n.Latest.Lookup(report.ScopeVersion); ok {
+			record.Version = version
+		}
+	}
+	for probeID, nodes := range hosts {
+		i.probes[probeID].hosts = nodes
+	}
+
+	for probeID, summary := range rpt.Probes {
+		record := lookup(probeID)
+		record.Hostname = summary.Hostname
+		record.Version = summary.Version
+		record.PublishLag = now.Sub(summary.Timestamp)
+		record.ReporterDurations = summary.ReporterDurations
+		record.TaggerDurations = summary.TaggerDurations
+		record.Features = summary.Features
+		record.Errors = summary.Errors
+	}
+
+	for id, record := range i.probes {
+		if now.Sub(record.LastSeen) > i.forgetAfter {
+			delete(i.probes, id)
+		}
+	}
+}
+
+// Probes implements ProbeLister
+func (i *ProbeInventory) Probes(_ context.Context, timestamp time.Time) ([]ProbeInfo, error) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	result := []ProbeInfo{}
+	for _, record := range i.probes {
+		if record.LastSeen.After(timestamp) {
+			continue
+		}
+		info := record.ProbeInfo
+		info.Stale = i.isStale(record, timestamp)
+		result = append(result, info)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].ID < result[b].ID })
+	return result, nil
+}
+
+func (i *ProbeInventory) isStale(record *probeRecord, timestamp time.Time) bool {
+	return timestamp.Sub(record.LastSeen) > i.staleAfter
+}
+
+// Report implements Reporter. Host nodes of stale probes are kept in the
+// report, annotated with the time their probe was last seen.
+func (i *ProbeInventory) Report(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	rpt, err := i.Collector.Report(ctx, timestamp)
+	if err != nil {
+		return rpt, err
+	}
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	for _, record := range i.probes {
+		if record.LastSeen.After(timestamp) || !i.isStale(record, timestamp) {
+			continue
+		}
+		lastSeen := record.LastSeen.UTC().Format(time.RFC3339Nano)
+		for id, host := range record.hosts {
+			node, ok := rpt.Host.Nodes[id]
+			if !ok {
+				// Drop the stale metrics, but keep the metadata
+				node = report.MakeNode(id).WithTopology(report.Host)
+				node.Latest = host.Latest
+				node.Sets = host.Sets
+			}
+			rpt.Host.Nodes[id] = node.WithLatest(report.ProbeLastSeen, record.LastSeen, lastSeen)
+		}
+	}
+	return rpt, nil
+}
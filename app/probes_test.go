@@ -0,0 +1,73 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestProbeInventory(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+	ctx := context.Background()
+
+	hostID := report.MakeHostNodeID("hostA")
+	rpt := report.MakeReport()
+	rpt.Host.AddNode(report.MakeNodeWith(hostID, map[string]string{
+		report.ControlProbeID: "probeA",
+		report.HostName:       "hostA",
+	}))
+	rpt.Probes = report.ProbeSummaries{
+		"probeA": {
+			ID:                "probeA",
+			Hostname:          "hostA",
+			Version:           "1.0",
+			Features:          []string{"Docker"},
+			ReporterDurations: map[string]time.Duration{"Docker": time.Millisecond},
+			Errors:            map[string]string{"Process": "failed"},
+			Timestamp:         now.Add(-time.Second),
+		},
+	}
+
+	inventory := app.NewProbeInventory(app.NewCollector(10*time.Second), 5*time.Second, time.Minute)
+	ok(t, inventory.Add(ctx, rpt, make([]byte, 100)))
+
+	probes, err := inventory.Probes(ctx, now)
+	ok(t, err)
+	equals(t, 1, len(probes))
+	equals(t, "probeA", probes[0].ID)
+	equals(t, "1.0", probes[0].Version)
+	equals(t, 100, probes[0].ReportSize)
+	equals(t, time.Second, probes[0].PublishLag)
+	equals(t, "failed", probes[0].Errors["Process"])
+	equals(t, false, probes[0].Stale)
+
+	// The probe stops reporting, and its report falls out of the window
+	later := now.Add(time.Minute - time.Second)
+	probes, err = inventory.Probes(ctx, later)
+	ok(t, err)
+	equals(t, true, probes[0].Stale)
+	equals(t, now, probes[0].LastSeen)
+
+	have, err := inventory.Report(ctx, later)
+	ok(t, err)
+	host, found := have.Host.Nodes[hostID]
+	assert(t, found, "Expected host of stale probe to be kept")
+	lastSeen, _ := host.Latest.Lookup(report.ProbeLastSeen)
+	equals(t, now.UTC().Format(time.RFC3339Nano), lastSeen)
+	hostname, _ := host.Latest.Lookup(report.HostName)
+	equals(t, "hostA", hostname)
+
+	// Once another report arrives, stale probes get forgotten
+	mtime.NowForce(now.Add(2 * time.Minute))
+	ok(t, inventory.Add(ctx, report.MakeReport(), nil))
+	probes, err = inventory.Probes(ctx, now.Add(2*time.Minute))
+	ok(t, err)
+	equals(t, 0, len(probes))
+}
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// ReplayConfig controls how recorded reports are replayed.
+type ReplayConfig struct {
+	// Speed multiplies the speed at which reports are replayed; 0 means 1.
+	Speed float64
+	// Once stops the replay at the last report, instead of looping round.
+	Once bool
+	// Offset is how far into the recording to start.
+	Offset time.Duration
+}
+
+// ParseReplayConfig parses the query of a file collector URL, e.g.
+// file:///tmp/recording?speed=2&once=true&offset=1m
+func ParseReplayConfig(query url.Values) (ReplayConfig, error) {
+	var (
+		config ReplayConfig
+		err    error
+	)
+	if s := query.Get("speed"); s != "" {
+		if config.Speed, err = strconv.ParseFloat(s, 64); err != nil || config.Speed <= 0 {
+			return config, fmt.Errorf("invalid replay speed %q", s)
+		}
+	}
+	if s := query.Get("once"); s != "" {
+		if config.Once, err = strconv.ParseBool(s); err != nil {
+			return config, fmt.Errorf("invalid replay once %q", s)
+		}
+	}
+	if s := query.Get("offset"); s != "" {
+		if config.Offset, err = time.ParseDuration(s); err != nil || config.Offset < 0 {
+			return config, fmt.Errorf("invalid replay offset %q", s)
+		}
+	}
+	return config, nil
+}
+
+// ReplayStatus describes the state of a replay, as returned by /api/replay.
+type ReplayStatus struct {
+	Paused   bool          `json:"paused"`
+	Finished bool          `json:"finished"`
+	Speed    float64       `json:"speed"`
+	Once     bool          `json:"once"`
+	Position time.Duration `json:"position"`
+	Duration time.Duration `json:"duration"`
+	Reports  int           `json:"reports"`
+}
+
+// Replayer adds recorded reports to an Adder at the pace they were recorded,
+// or faster or slower. It can be paused and seeked.
+type Replayer struct {
+	adder      Adder
+	timestamps []time.Time
+	reports    []report.Report
+	once       bool
+	wake       chan struct{}
+	quit       chan struct{}
+	done       sync.WaitGroup
+
+	mtx      sync.Mutex
+	next     int
+	speed    float64
+	paused   bool
+	finished bool
+}
+
+// NewReplayer starts replaying reports, which must be sorted by timestamp.
+func NewReplayer(a Adder, timestamps []time.Time, reports []report.Report, config ReplayConfig) *Replayer {
+	r := &Replayer{
+		adder:      a,
+		timestamps: timestamps,
+		reports:    reports,
+		once:       config.Once,
+		wake:       make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+		speed:      config.Speed,
+	}
+	if r.speed <= 0 {
+		r.speed = 1
+	}
+	r.next = r.indexAt(config.Offset)
+	r.done.Add(1)
+	go r.loop()
+	return r
+}
+
+// indexAt returns the index of the first report at or after offset into the
+// recording.
+func (r *Replayer) indexAt(offset time.Duration) int {
+	for i, t := range r.timestamps {
+		if t.Sub(r.timestamps[0]) >= offset {
+			return i
+		}
+	}
+	return len(r.timestamps) - 1
+}
+
+// delay is the time to wait, at normal speed, after replaying report i.
+func (r *Replayer) delay(i int) time.Duration {
+	if i+1 < len(r.timestamps) {
+		return r.timestamps[i+1].Sub(r.timestamps[i])
+	}
+	// We don't know how long to wait before looping round, so make a
+	// good guess.
+	l := len(r.timestamps)
+	return r.timestamps[l-1].Sub(r.timestamps[0]) / time.Duration(l)
+}
+
+func (r *Replayer) loop() {
+	defer r.done.Done()
+	for {
+		r.mtx.Lock()
+		if r.paused || r.finished {
+			r.mtx.Unlock()
+			select {
+			case <-r.wake:
+				continue
+			case <-r.quit:
+				return
+			}
+		}
+		i := r.next
+		r.next++
+		if r.next == len(r.reports) {
+			if r.once {
+				r.finished = true
+				r.next--
+			} else {
+				r.next = 0
+			}
+		}
+		delay := time.Duration(float64(r.delay(i)) / r.speed)
+		r.mtx.Unlock()
+
+		r.adder.Add(context.Background(), r.reports[i], nil)
+		select {
+		case <-time.After(delay):
+		case <-r.wake:
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *Replayer) poke() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pause stops replaying reports until Resume is called.
+func (r *Replayer) Pause() {
+	r.mtx.Lock()
+	r.paused = true
+	r.mtx.Unlock()
+	r.poke()
+}
+
+// Resume carries on replaying after Pause.
+func (r *Replayer) Resume() {
+	r.mtx.Lock()
+	r.paused = false
+	r.mtx.Unlock()
+	r.poke()
+}
+
+// Seek carries on replaying from offset into the recording, even if the
+// replay had finished.
+func (r *Replayer) Seek(offset time.Duration) {
+	r.mtx.Lock()
+	r.next = r.indexAt(offset)
+	r.finished = false
+	r.mtx.Unlock()
+	r.poke()
+}
+
+// SetSpeed changes the speed multiplier of the replay.
+func (r *Replayer) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("invalid replay speed %v", speed)
+	}
+	r.mtx.Lock()
+	r.speed = speed
+	r.mtx.Unlock()
+	return nil
+}
+
+// Status describes the state of the replay.
+func (r *Replayer) Status() ReplayStatus {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	first, last := r.timestamps[0], r.timestamps[len(r.timestamps)-1]
+	return ReplayStatus{
+		Paused:   r.paused,
+		Finished: r.finished,
+		Speed:    r.speed,
+		Once:     r.once,
+		Position: r.timestamps[r.next].Sub(first),
+		Duration: last.Sub(first),
+		Reports:  len(r.reports),
+	}
+}
+
+// Stop stops the replay for good.
+func (r *Replayer) Stop() {
+	close(r.quit)
+	r.done.Wait()
+}
+
+// ReplayingCollector is the Collector NewFileCollector returns for a
+// directory of recorded reports. Its Replayer controls the replay.
+type ReplayingCollector struct {
+	Collector
+	Replayer *Replayer
+}
+
+// Close implements Collector
+func (c *ReplayingCollector) Close() {
+	c.Replayer.Stop()
+	c.Collector.Close()
+}
+
+// RecordingCollector is a Collector which records every report added to it,
+// for replaying later.
+type RecordingCollector struct {
+	Collector
+	recorder *report.Recorder
+}
+
+// NewRecordingCollector records the reports added to collector with
+// recorder.
+func NewRecordingCollector(collector Collector, recorder *report.Recorder) *RecordingCollector {
+	return &RecordingCollector{Collector: collector, recorder: recorder}
+}
+
+// Add implements Adder
+func (c *RecordingCollector) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	if err := c.recorder.Record(rpt, mtime.Now()); err != nil {
+		log.Errorf("Error recording report: %v", err)
+	}
+	return c.Collector.Add(ctx, rpt, buf)
+}
+
+// RegisterReplayRoutes registers /api/replay, which reports the state of the
+// replay on GET, and changes it on POST with any of the parameters
+// action=pause|resume, seek=<offset> and speed=<multiplier>.
+func RegisterReplayRoutes(router *mux.Router, r *Replayer) {
+	router.Methods("GET").Path("/api/replay").Handler(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, _ *http.Request) {
+			respondWith(ctx, w, http.StatusOK, r.Status())
+		}))
+	router.Methods("POST").Path("/api/replay").Handler(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+			if err := req.ParseForm(); err != nil {
+				respondWith(ctx, w, http.StatusBadRequest, err)
+				return
+			}
+			action := req.Form.Get("action")
+			if action != "" && action != "pause" && action != "resume" {
+				respondWith(ctx, w, http.StatusBadRequest, fmt.Errorf("invalid action %q", action))
+				return
+			}
+			var seek *time.Duration
+			if s := req.Form.Get("seek"); s != "" {
+				offset, err := time.ParseDuration(s)
+				if err != nil || offset < 0 {
+					respondWith(ctx, w, http.StatusBadRequest, fmt.Errorf("invalid seek offset %q", s))
+					return
+				}
+				seek = &offset
+			}
+			if s := req.Form.Get("speed"); s != "" {
+				speed, err := strconv.ParseFloat(s, 64)
+				if err == nil {
+					err = r.SetSpeed(speed)
+				}
+				if err != nil {
+					respondWith(ctx, w, http.StatusBadRequest, fmt.Errorf("invalid speed %q", s))
+					return
+				}
+			}
+			if seek != nil {
+				r.Seek(*seek)
+			}
+			switch action {
+			case "pause":
+				r.Pause()
+			case "resume":
+				r.Resume()
+			}
+			respondWith(ctx, w, http.StatusOK, r.Status())
+		}))
+}
@@ -0,0 +1,234 @@
+package app_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+// replayAdder remembers the order in which reports were added, by their ID.
+type replayAdder struct {
+	sync.Mutex
+	ids []string
+}
+
+func (a *replayAdder) Add(_ context.Context, rpt report.Report, _ []byte) error {
+	a.Lock()
+	defer a.Unlock()
+	a.ids = append(a.ids, rpt.ID)
+	return nil
+}
+
+func (a *replayAdder) added() []string {
+	a.Lock()
+	defer a.Unlock()
+	return append([]string(nil), a.ids...)
+}
+
+func makeRecording(n int) ([]time.Time, []report.Report) {
+	var (
+		timestamps []time.Time
+		reports    []report.Report
+		start      = time.Unix(1500000000, 0)
+	)
+	for i := 0; i < n; i++ {
+		rpt := report.MakeReport()
+		rpt.ID = string(rune('a' + i))
+		timestamps = append(timestamps, start.Add(time.Duration(i)*time.Second))
+		reports = append(reports, rpt)
+	}
+	return timestamps, reports
+}
+
+func waitForReplay(t *testing.T, r *app.Replayer, done func(app.ReplayStatus) bool) app.ReplayStatus {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := r.Status()
+		if done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for replay, status %+v", status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReplayerOnceWithOffset(t *testing.T) {
+	timestamps, reports := makeRecording(4)
+	adder := &replayAdder{}
+	r := app.NewReplayer(adder, timestamps, reports, app.ReplayConfig{
+		Speed:  1000,
+		Once:   true,
+		Offset: 2 * time.Second,
+	})
+	defer r.Stop()
+
+	status := waitForReplay(t, r, func(s app.ReplayStatus) bool { return s.Finished })
+	equals(t, []string{"c", "d"}, adder.added())
+	equals(t, 3*time.Second, status.Position)
+	equals(t, 3*time.Second, status.Duration)
+
+	// Seeking restarts a finished replay
+	r.Seek(time.Second)
+	waitForReplay(t, r, func(s app.ReplayStatus) bool { return s.Finished && len(adder.added()) == 5 })
+	equals(t, []string{"c", "d", "b", "c", "d"}, adder.added())
+}
+
+func TestReplayerPause(t *testing.T) {
+	timestamps, reports := makeRecording(3)
+	adder := &replayAdder{}
+	// So slow only the first report is replayed before pausing.
+	r := app.NewReplayer(adder, timestamps, reports, app.ReplayConfig{Speed: 0.001})
+	defer r.Stop()
+
+	waitForReplay(t, r, func(app.ReplayStatus) bool { return len(adder.added()) == 1 })
+	r.Pause()
+	r.SetSpeed(1000)
+	time.Sleep(10 * time.Millisecond)
+	equals(t, []string{"a"}, adder.added())
+	equals(t, true, r.Status().Paused)
+
+	// Resuming loops round the recording
+	r.Resume()
+	waitForReplay(t, r, func(app.ReplayStatus) bool { return len(adder.added()) >= 4 })
+	equals(t, []string{"a", "b", "c", "a"}, adder.added()[:4])
+}
+
+func TestFileCollectorReplaysRecording(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scope-recording")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	recorder, err := report.NewRecorder(report.RecorderConfig{Dir: dir, MaxSegmentSize: 1})
+	ok(t, err)
+	timestamps, reports := makeRecording(3)
+	for i, rpt := range reports {
+		rpt.Host.AddNode(report.MakeNode(report.MakeHostNodeID(rpt.ID)))
+		ok(t, recorder.Record(rpt, timestamps[i]))
+	}
+
+	collector, err := app.NewFileCollector(dir, time.Minute, app.ReplayConfig{Speed: 1000, Once: true})
+	ok(t, err)
+	defer collector.Close()
+	replaying, isReplaying := collector.(*app.ReplayingCollector)
+	assert(t, isReplaying, "Expected a replaying collector")
+
+	waitForReplay(t, replaying.Replayer, func(s app.ReplayStatus) bool { return s.Finished })
+	rpt, err := collector.Report(context.Background(), time.Now())
+	ok(t, err)
+	equals(t, 3, len(rpt.Host.Nodes))
+}
+
+func TestRecordingCollectorListsProbes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scope-recording")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	// Build the collectors as the app does when recording
+	recorder, err := report.NewRecorder(report.RecorderConfig{Dir: dir})
+	ok(t, err)
+	var collector app.Collector = app.NewCollector(time.Minute)
+	collector = app.NewProbeInventory(collector, time.Minute, time.Hour)
+	collector = app.NewRecordingCollector(collector, recorder)
+	defer collector.Close()
+
+	rpt := report.MakeReport()
+	rpt.Host.AddNode(report.MakeNodeWith(report.MakeHostNodeID("hostA"), map[string]string{
+		report.ControlProbeID: "probeA",
+		report.HostName:       "hostA",
+	}))
+	rpt.Probes = report.ProbeSummaries{
+		"probeA": {ID: "probeA", Hostname: "hostA", Timestamp: time.Now()},
+	}
+	ok(t, collector.Add(context.Background(), rpt, make([]byte, 100)))
+
+	router := mux.NewRouter()
+	app.RegisterTopologyRoutes(router, collector, nil)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/probes")
+	ok(t, err)
+	defer resp.Body.Close()
+	var probes []app.ProbeInfo
+	ok(t, codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&probes))
+	equals(t, 1, len(probes))
+	equals(t, "probeA", probes[0].ID)
+	// Only the probe inventory knows the size of the reports
+	equals(t, 100, probes[0].ReportSize)
+
+	files, err := ioutil.ReadDir(dir)
+	ok(t, err)
+	assert(t, len(files) > 0, "Expected the report to be recorded")
+}
+
+func TestAPIReplay(t *testing.T) {
+	timestamps, reports := makeRecording(3)
+	r := app.NewReplayer(&replayAdder{}, timestamps, reports, app.ReplayConfig{Speed: 0.001})
+	defer r.Stop()
+	router := mux.NewRouter()
+	app.RegisterReplayRoutes(router, r)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(values url.Values) (int, app.ReplayStatus) {
+		resp, err := http.PostForm(ts.URL+"/api/replay", values)
+		ok(t, err)
+		defer resp.Body.Close()
+		var status app.ReplayStatus
+		if resp.StatusCode == http.StatusOK {
+			ok(t, codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&status))
+		}
+		return resp.StatusCode, status
+	}
+
+	code, status := post(url.Values{"action": {"pause"}, "seek": {"2s"}, "speed": {"4"}})
+	equals(t, http.StatusOK, code)
+	equals(t, true, status.Paused)
+	equals(t, 4.0, status.Speed)
+	equals(t, 2*time.Second, status.Position)
+
+	for _, bad := range []url.Values{
+		{"action": {"rewind"}},
+		{"seek": {"-1s"}},
+		{"speed": {"0"}},
+	} {
+		code, _ := post(bad)
+		equals(t, http.StatusBadRequest, code)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/replay")
+	ok(t, err)
+	defer resp.Body.Close()
+	ok(t, codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&status))
+	equals(t, true, status.Paused)
+	equals(t, 4.0, status.Speed)
+}
+
+func TestParseReplayConfig(t *testing.T) {
+	config, err := app.ParseReplayConfig(url.Values{"speed": {"2.5"}, "once": {"true"}, "offset": {"1m"}})
+	ok(t, err)
+	equals(t, app.ReplayConfig{Speed: 2.5, Once: true, Offset: time.Minute}, config)
+
+	for _, bad := range []url.Values{
+		{"speed": {"fast"}},
+		{"once": {"maybe"}},
+		{"offset": {"-1s"}},
+	} {
+		_, err := app.ParseReplayConfig(bad)
+		assert(t, err != nil, "Expected an error parsing %v", bad)
+	}
+}
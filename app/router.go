@@ -105,6 +105,10 @@ func RegisterTopologyRoutes(router *mux.Router, r Reporter, capabilities map[str
 	get.MatcherFunc(URLMatcher("/api/topology/{topology}/{id}")).Handler(
 		gzipHandler(requestContextDecorator(topologyRegistry.captureRenderer(r, handleNode)))).
 		Name("api_topology_topology_id")
+	// Not under /api/topology/{topology}/, where it would shadow a node
+	get.Handle("/api/compare/{topology}",
+		gzipHandler(requestContextDecorator(captureReporter(r, handleTopologyCompare)))).
+		Name("api_compare_topology")
 	get.Handle("/api/report",
 		gzipHandler(requestContextDecorator(makeRawReportHandler(r))))
 	get.Handle("/api/probes",
@@ -128,6 +132,10 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 		contentType := r.Header.Get("Content-Type")
 		var isMsgpack bool
 		switch {
+		case strings.HasPrefix(contentType, report.CompactContentType):
+			// MakeFromBinary recognises the compact encoding, wherever
+			// buf ends up being decoded.
+			isMsgpack = true
 		case strings.HasPrefix(contentType, "application/msgpack"):
 			isMsgpack = true
 		case strings.HasPrefix(contentType, "application/json"):
@@ -143,7 +151,7 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 			return
 		}
 
-		// a.Add(..., buf) assumes buf is gzip'd msgpack
+		// a.Add(..., buf) assumes buf is gzip'd msgpack, or compact
 		if !isMsgpack {
 			buf, _ = rpt.WriteBinary()
 		}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test/fixture"
 )
 
@@ -93,4 +94,35 @@ func TestReportPostHandler(t *testing.T) {
 		err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(v)
 		return buf.Bytes(), err
 	})
+	test(report.CompactContentType, func(v interface{}) ([]byte, error) {
+		buf := &bytes.Buffer{}
+		err := v.(report.Report).EncodeCompact(buf)
+		return buf.Bytes(), err
+	})
+}
+
+func TestAPIReportCompact(t *testing.T) {
+	ts := topologyServer()
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL+"/api/report", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", report.CompactContentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if have := resp.Header.Get("Content-Type"); have != report.CompactContentType {
+		t.Errorf("Content-Type %q, want %q", have, report.CompactContentType)
+	}
+	rpt, err := report.MakeFromBinary(context.Background(), resp.Body, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := len(fixture.Report.Container.Nodes), len(rpt.Container.Nodes); want != have {
+		t.Errorf("got %d containers, want %d", have, want)
+	}
 }
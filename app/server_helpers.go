@@ -41,6 +41,19 @@ func respondWith(ctx context.Context, w http.ResponseWriter, code int, response
 // Possibly we should do a complete parse of Accept, but for now just rudimentary check
 func respondWithReport(ctx context.Context, w http.ResponseWriter, req *http.Request, response report.Report) {
 	accept := req.Header.Get("Accept")
+	if strings.HasPrefix(accept, report.CompactContentType) {
+		buf := bytes.Buffer{}
+		if err := response.EncodeCompact(&buf); err != nil {
+			log.Errorf("Error encoding response: %v", err)
+		}
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			span.LogKV("encoded-size", len(buf.Bytes()))
+		}
+		w.Header().Set("Content-Type", report.CompactContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+		return
+	}
 	if strings.HasPrefix(accept, "application/msgpack") {
 		buf := bytes.Buffer{}
 		encoder := codec.NewEncoder(&buf, &codec.MsgpackHandle{})
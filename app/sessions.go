@@ -0,0 +1,469 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+const (
+	retentionInterval = time.Hour
+	// Terminal size assumed until the UI resizes the TTY
+	defaultTTYWidth  = 80
+	defaultTTYHeight = 24
+)
+
+var sessionIDRegexp = regexp.MustCompile(`^[0-9]+-[0-9a-f]+$`)
+
+// SessionRecorderConfig says where terminal sessions are recorded, and for
+// how long they are kept.
+type SessionRecorderConfig struct {
+	Dir string
+	// Retention is how long recordings are kept after they end; zero keeps
+	// them forever.
+	Retention time.Duration
+	// UserHeader is the HTTP header naming the user, e.g. as set by an
+	// authenticating proxy. Without it, the basic authentication user is
+	// recorded.
+	UserHeader string
+}
+
+// SessionInfo describes a recorded terminal session, as listed by
+// /api/sessions.
+type SessionInfo struct {
+	ID      string     `json:"id"`
+	PipeID  string     `json:"pipe_id"`
+	User    string     `json:"user,omitempty"`
+	ProbeID string     `json:"probe_id"`
+	NodeID  string     `json:"node_id"`
+	Control string     `json:"control"`
+	Started time.Time  `json:"started"`
+	Ended   *time.Time `json:"ended,omitempty"` // nil while the session is in progress
+}
+
+// SessionRecorder records the interactive terminal sessions of controls such
+// as docker exec and attach, or host exec: both directions of their pipes,
+// and TTY resizes, with timings. Each session is written in asciinema v2
+// format to <id>.cast, next to its SessionInfo in <id>.json.
+type SessionRecorder struct {
+	config SessionRecorderConfig
+	quit   chan struct{}
+	wait   sync.WaitGroup
+
+	mtx      sync.Mutex
+	sessions map[string]*session // by pipe ID
+	pipes    PipeRouter          // the PipeRouter wrapped, to close pipes not recorded
+}
+
+// NewSessionRecorder makes a SessionRecorder, creating its directory if need
+// be, and starts expiring old recordings.
+func NewSessionRecorder(config SessionRecorderConfig) (*SessionRecorder, error) {
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &SessionRecorder{
+		config:   config,
+		quit:     make(chan struct{}),
+		sessions: map[string]*session{},
+	}
+	if config.Retention > 0 {
+		s.wait.Add(1)
+		go s.retentionLoop()
+	}
+	return s, nil
+}
+
+// Stop ends the sessions in progress, and stops expiring recordings.
+func (s *SessionRecorder) Stop() {
+	close(s.quit)
+	s.wait.Wait()
+	s.mtx.Lock()
+	sessions := s.sessions
+	s.sessions = map[string]*session{}
+	s.mtx.Unlock()
+	for _, sess := range sessions {
+		sess.end()
+	}
+}
+
+func (s *SessionRecorder) retentionLoop() {
+	defer s.wait.Done()
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		s.expire()
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expire deletes the recordings of sessions which ended before the
+// retention period.
+func (s *SessionRecorder) expire() {
+	sessions, err := s.Sessions()
+	if err != nil {
+		log.Errorf("Error listing recorded sessions: %v", err)
+		return
+	}
+	cutoff := mtime.Now().Add(-s.config.Retention)
+	for _, info := range sessions {
+		if info.Ended == nil || info.Ended.After(cutoff) {
+			continue
+		}
+		for _, ext := range []string{".cast", ".json"} {
+			if err := os.Remove(filepath.Join(s.config.Dir, info.ID+ext)); err != nil && !os.IsNotExist(err) {
+				log.Errorf("Error expiring recorded session %s: %v", info.ID, err)
+			}
+		}
+	}
+}
+
+// Sessions lists the recorded sessions, the latest first.
+func (s *SessionRecorder) Sessions() ([]SessionInfo, error) {
+	filenames, err := filepath.Glob(filepath.Join(s.config.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []SessionInfo{}
+	for _, filename := range filenames {
+		buf, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		var info SessionInfo
+		if err := json.Unmarshal(buf, &info); err != nil {
+			log.Warnf("Ignoring recorded session %s: %v", filename, err)
+			continue
+		}
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.After(sessions[j].Started) })
+	return sessions, nil
+}
+
+// Open opens the asciinema recording of a session.
+func (s *SessionRecorder) Open(id string) (io.ReadCloser, error) {
+	if !sessionIDRegexp.MatchString(id) {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(s.config.Dir, id+".cast"))
+}
+
+func (s *SessionRecorder) user(ctx context.Context) string {
+	request, ok := ctx.Value(RequestCtxKey).(*http.Request)
+	if !ok || request == nil {
+		return ""
+	}
+	if s.config.UserHeader != "" {
+		return request.Header.Get(s.config.UserHeader)
+	}
+	user, _, _ := request.BasicAuth()
+	return user
+}
+
+// start records the session of the pipe returned by a control.
+func (s *SessionRecorder) start(info SessionInfo) error {
+	info.Started = mtime.Now()
+	info.ID = fmt.Sprintf("%d-%x", info.Started.Unix(), rand.Int63())
+	sess, err := newSession(s.config.Dir, info)
+	if err != nil {
+		log.Errorf("Error recording session of pipe %s: %v", info.PipeID, err)
+		return err
+	}
+	log.Infof("Recording session %s of %s on %s by %q", info.ID, info.Control, info.NodeID, info.User)
+	s.mtx.Lock()
+	s.sessions[info.PipeID] = sess
+	s.mtx.Unlock()
+	return nil
+}
+
+func (s *SessionRecorder) session(pipeID string) *session {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.sessions[pipeID]
+}
+
+func (s *SessionRecorder) end(pipeID string) {
+	s.mtx.Lock()
+	sess, ok := s.sessions[pipeID]
+	delete(s.sessions, pipeID)
+	s.mtx.Unlock()
+	if ok {
+		sess.end()
+	}
+}
+
+// ControlRouter wraps a ControlRouter to start recording the pipes of
+// controls returning raw TTYs, and to record their resizes. The controls
+// fail if their recording can't be started, and their pipes are closed.
+func (s *SessionRecorder) ControlRouter(cr ControlRouter) ControlRouter {
+	return recordingControlRouter{ControlRouter: cr, recorder: s}
+}
+
+// PipeRouter wraps a PipeRouter to record what goes through the UI end of
+// the pipes being recorded.
+func (s *SessionRecorder) PipeRouter(pr PipeRouter) PipeRouter {
+	s.mtx.Lock()
+	s.pipes = pr
+	s.mtx.Unlock()
+	return recordingPipeRouter{PipeRouter: pr, recorder: s}
+}
+
+// closePipe closes a pipe which isn't recorded, if the PipeRouter has been
+// wrapped; otherwise it times out, as the UI doesn't connect to it.
+func (s *SessionRecorder) closePipe(ctx context.Context, pipeID string) {
+	s.mtx.Lock()
+	pipes := s.pipes
+	s.mtx.Unlock()
+	if pipes == nil {
+		return
+	}
+	if err := pipes.Delete(ctx, pipeID); err != nil {
+		log.Errorf("Error closing pipe %s: %v", pipeID, err)
+	}
+}
+
+type recordingControlRouter struct {
+	ControlRouter
+	recorder *SessionRecorder
+}
+
+func (r recordingControlRouter) Handle(ctx context.Context, probeID string, req xfer.Request) (xfer.Response, error) {
+	res, err := r.ControlRouter.Handle(ctx, probeID, req)
+	if err != nil || res.Error != "" {
+		return res, err
+	}
+	if res.Pipe != "" && res.RawTTY {
+		if err := r.recorder.start(SessionInfo{
+			PipeID:  res.Pipe,
+			User:    r.recorder.user(ctx),
+			ProbeID: probeID,
+			NodeID:  req.NodeID,
+			Control: req.Control,
+		}); err != nil {
+			r.recorder.closePipe(ctx, res.Pipe)
+			return xfer.ResponseErrorf("Error recording the session: %v", err), nil
+		}
+	} else if pipeID, ok := req.ControlArgs["pipeID"]; ok {
+		// The arguments of xfer.ResizeTTYControlWrapper
+		if sess := r.recorder.session(pipeID); sess != nil {
+			width, _ := strconv.Atoi(req.ControlArgs["width"])
+			height, _ := strconv.Atoi(req.ControlArgs["height"])
+			if width > 0 && height > 0 {
+				sess.event("r", fmt.Sprintf("%dx%d", width, height))
+			}
+		}
+	}
+	return res, nil
+}
+
+type recordingPipeRouter struct {
+	PipeRouter
+	recorder *SessionRecorder
+}
+
+func (r recordingPipeRouter) Get(ctx context.Context, id string, e End) (xfer.Pipe, io.ReadWriter, error) {
+	pipe, endIO, err := r.PipeRouter.Get(ctx, id, e)
+	if err != nil || e != UIEnd {
+		return pipe, endIO, err
+	}
+	if sess := r.recorder.session(id); sess != nil {
+		endIO = &recordingEnd{ReadWriter: endIO, session: sess, end: func() { r.recorder.end(id) }}
+	}
+	return pipe, endIO, nil
+}
+
+func (r recordingPipeRouter) Delete(ctx context.Context, id string) error {
+	r.recorder.end(id)
+	return r.PipeRouter.Delete(ctx, id)
+}
+
+// recordingEnd records the UI end of a pipe: what is read from it is the
+// output of the session, and what is written to it the input.
+type recordingEnd struct {
+	io.ReadWriter
+	session *session
+	end     func()
+}
+
+func (r *recordingEnd) Read(p []byte) (int, error) {
+	n, err := r.ReadWriter.Read(p)
+	if n > 0 {
+		r.session.output(p[:n])
+	}
+	if err != nil {
+		r.end()
+	}
+	return n, err
+}
+
+func (r *recordingEnd) Write(p []byte) (int, error) {
+	r.session.input(p)
+	return r.ReadWriter.Write(p)
+}
+
+type session struct {
+	mtx     sync.Mutex
+	info    SessionInfo
+	dir     string
+	file    *os.File
+	enc     *json.Encoder
+	partial map[string][]byte // incomplete UTF-8 sequences, by event type
+	ended   bool
+	lastErr error
+}
+
+func newSession(dir string, info SessionInfo) (*session, error) {
+	file, err := os.OpenFile(filepath.Join(dir, info.ID+".cast"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	sess := &session{
+		info:    info,
+		dir:     dir,
+		file:    file,
+		enc:     json.NewEncoder(file),
+		partial: map[string][]byte{},
+	}
+	header := map[string]interface{}{
+		"version":   2,
+		"width":     defaultTTYWidth,
+		"height":    defaultTTYHeight,
+		"timestamp": info.Started.Unix(),
+		"title":     fmt.Sprintf("%s on %s", info.Control, info.NodeID),
+	}
+	if err := sess.enc.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := sess.writeInfo(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *session) writeInfo() error {
+	buf, err := json.Marshal(s.info)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.dir, s.info.ID+".json"), buf, 0600)
+}
+
+func (s *session) output(p []byte) { s.data("o", p) }
+func (s *session) input(p []byte)  { s.data("i", p) }
+
+// data records terminal data, holding back a UTF-8 sequence split across
+// reads until the rest of it arrives, as events must be valid strings.
+func (s *session) data(kind string, p []byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	buf := append(s.partial[kind], p...)
+	complete, rest := splitUTF8(buf)
+	s.partial[kind] = append([]byte(nil), rest...)
+	if len(complete) > 0 {
+		s.write(kind, string(complete))
+	}
+}
+
+func (s *session) event(kind, data string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.write(kind, data)
+}
+
+func (s *session) write(kind, data string) {
+	if s.ended {
+		return
+	}
+	elapsed := mtime.Now().Sub(s.info.Started).Seconds()
+	if err := s.enc.Encode([]interface{}{elapsed, kind, data}); err != nil && s.lastErr == nil {
+		s.lastErr = err
+		log.Errorf("Error recording session %s: %v", s.info.ID, err)
+	}
+}
+
+func (s *session) end() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	ended := mtime.Now()
+	s.info.Ended = &ended
+	if err := s.file.Close(); err != nil {
+		log.Errorf("Error closing recording of session %s: %v", s.info.ID, err)
+	}
+	if err := s.writeInfo(); err != nil {
+		log.Errorf("Error recording end of session %s: %v", s.info.ID, err)
+	}
+	log.Infof("Recorded session %s", s.info.ID)
+}
+
+// splitUTF8 splits off an incomplete UTF-8 sequence at the end of b.
+func splitUTF8(b []byte) ([]byte, []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], b[i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
+
+// RegisterSessionRoutes registers /api/sessions, which lists the recorded
+// sessions, and /api/sessions/{id}, which downloads one as an asciinema
+// cast.
+func RegisterSessionRoutes(router *mux.Router, s *SessionRecorder) {
+	router.Methods("GET").Path("/api/sessions").Handler(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, _ *http.Request) {
+			sessions, err := s.Sessions()
+			if err != nil {
+				respondWith(ctx, w, http.StatusInternalServerError, err)
+				return
+			}
+			respondWith(ctx, w, http.StatusOK, sessions)
+		}))
+	router.Methods("GET").Path("/api/sessions/{id}").Handler(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimSuffix(mux.Vars(r)["id"], ".cast")
+			f, err := s.Open(id)
+			if os.IsNotExist(err) {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				respondWith(ctx, w, http.StatusInternalServerError, err)
+				return
+			}
+			defer f.Close()
+			w.Header().Set("Content-Type", "application/x-asciicast")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".cast"))
+			io.Copy(w, f)
+		}))
+}
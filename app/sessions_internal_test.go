@@ -0,0 +1,229 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+func TestSessionRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Unix(1500000000, 0)
+	mtime.NowForce(start)
+	defer mtime.NowReset()
+
+	recorder, err := NewSessionRecorder(SessionRecorderConfig{Dir: dir, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Stop()
+	pr := recorder.PipeRouter(NewLocalPipeRouter())
+	defer pr.Stop()
+	cr := recorder.ControlRouter(NewLocalControlRouter())
+	ctx := context.Background()
+	if _, err := cr.Register(ctx, "probe", func(req xfer.Request) xfer.Response {
+		if req.Control == "exec" {
+			return xfer.Response{Pipe: "pipe", RawTTY: true}
+		}
+		return xfer.Response{}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	request, _ := http.NewRequest("POST", "/api/control/probe/node/exec", nil)
+	request.SetBasicAuth("alice", "secret")
+	ctx = context.WithValue(ctx, RequestCtxKey, request)
+	if _, err := cr.Handle(ctx, "probe", xfer.Request{NodeID: "node", Control: "exec"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ui, err := pr.Get(ctx, "pipe", UIEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, probe, err := pr.Get(ctx, "pipe", ProbeEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "é" is split across two writes, and recorded once it is complete
+	mtime.NowForce(start.Add(time.Second))
+	go probe.Write([]byte("caf\xc3"))
+	readFull(t, ui, 4)
+	go probe.Write([]byte("\xa9\n"))
+	readFull(t, ui, 2)
+	mtime.NowForce(start.Add(2 * time.Second))
+	go readFull(t, probe, 3)
+	if _, err := ui.Write([]byte("ls\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cr.Handle(ctx, "probe", xfer.Request{
+		NodeID:      "node",
+		Control:     "resize",
+		ControlArgs: map[string]string{"pipeID": "pipe", "width": "120", "height": "40"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mtime.NowForce(start.Add(3 * time.Second))
+	if err := pr.Delete(ctx, "pipe"); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := recorder.Sessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %v", sessions)
+	}
+	info := sessions[0]
+	if info.User != "alice" || info.NodeID != "node" || info.Control != "exec" || info.ProbeID != "probe" {
+		t.Errorf("Unexpected session %+v", info)
+	}
+	if !info.Started.Equal(start) || info.Ended == nil || !info.Ended.Equal(start.Add(3*time.Second)) {
+		t.Errorf("Unexpected session times %v - %v", info.Started, info.Ended)
+	}
+
+	router := mux.NewRouter()
+	RegisterSessionRoutes(router, recorder)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var listed []SessionInfo
+	getJSON(t, server.URL+"/api/sessions", &listed)
+	if len(listed) != 1 || listed[0].ID != info.ID {
+		t.Errorf("Unexpected sessions %v", listed)
+	}
+
+	resp, err := http.Get(server.URL + "/api/sessions/" + info.ID + ".cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status %d", resp.StatusCode)
+	}
+	lines := bufio.NewScanner(resp.Body)
+	var header map[string]interface{}
+	if !lines.Scan() {
+		t.Fatal("Expected a header")
+	}
+	if err := json.Unmarshal(lines.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header["version"] != 2.0 || header["width"] != 80.0 || header["height"] != 24.0 {
+		t.Errorf("Unexpected header %v", header)
+	}
+	var events [][]interface{}
+	for lines.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(lines.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	want := [][]interface{}{
+		{1.0, "o", "caf"},
+		{1.0, "o", "é\n"},
+		{2.0, "i", "ls\n"},
+		{2.0, "r", "120x40"},
+	}
+	if !reflect.DeepEqual(want, events) {
+		t.Errorf("Expected events %v, got %v", want, events)
+	}
+
+	for _, path := range []string{"/api/sessions/nonexistent", "/api/sessions/..%2Fsecret"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+
+	// Recordings are kept for the retention period after they end
+	mtime.NowForce(start.Add(time.Hour))
+	recorder.expire()
+	if sessions, _ := recorder.Sessions(); len(sessions) != 1 {
+		t.Errorf("Expected the session to be kept, got %v", sessions)
+	}
+	mtime.NowForce(start.Add(2 * time.Hour))
+	recorder.expire()
+	if sessions, _ := recorder.Sessions(); len(sessions) != 0 {
+		t.Errorf("Expected the session to be expired, got %v", sessions)
+	}
+	if _, err := os.Stat(dir + "/" + info.ID + ".cast"); !os.IsNotExist(err) {
+		t.Errorf("Expected the recording to be deleted, got %v", err)
+	}
+}
+
+func TestSessionRecorderFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := NewSessionRecorder(SessionRecorderConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Stop()
+	pr := recorder.PipeRouter(NewLocalPipeRouter())
+	defer pr.Stop()
+	cr := recorder.ControlRouter(NewLocalControlRouter())
+	ctx := context.Background()
+	if _, err := cr.Register(ctx, "probe", func(req xfer.Request) xfer.Response {
+		return xfer.Response{Pipe: "pipe", RawTTY: true}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sessions can't be recorded without their directory
+	os.RemoveAll(dir)
+	res, err := cr.Handle(ctx, "probe", xfer.Request{NodeID: "node", Control: "exec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error == "" {
+		t.Fatalf("Expected the control to fail, got %+v", res)
+	}
+	if _, _, err := pr.Get(ctx, "pipe", ProbeEnd); err == nil {
+		t.Error("Expected the pipe to be closed")
+	}
+}
+func readFull(t *testing.T, r io.Reader, n int) {
+	if _, err := io.ReadFull(r, make([]byte, n)); err != nil {
+		t.Error(err)
+	}
+}
+
+func getJSON(t *testing.T, url string, result interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(result); err != nil {
+		t.Fatal(err)
+	}
+}
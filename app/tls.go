@@ -0,0 +1,84 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+// ServerTLSConfig builds the TLS configuration for the app's HTTP server.
+// When a client CA is given, clients presenting a certificate must have it
+// signed by that CA. Certificates remain optional for the UI; probes are
+// made to present them by RequireProbeCertificates.
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+func loadCertPool(filename string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA certificates: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificates found in %s", filename)
+	}
+	return pool, nil
+}
+
+// ProbeIdentity returns the ID of the probe making the request. When the
+// probe presented a verified client certificate, the certificate's common
+// name is used, and the X-Scope-Probe-ID header is ignored.
+func ProbeIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	return r.Header.Get(xfer.ScopeProbeIDHeader)
+}
+
+// isProbeRequest tells whether the request is made by a probe rather than
+// by the UI: publishing reports, and the probe ends of controls and pipes.
+func isProbeRequest(r *http.Request) bool {
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/report":
+		return true
+	case r.URL.Path == "/api/control/ws":
+		return true
+	case strings.HasPrefix(r.URL.Path, "/api/pipe/") && strings.HasSuffix(r.URL.Path, "/probe"):
+		return true
+	}
+	return false
+}
+
+// RequireProbeCertificates rejects probe requests which don't come with a
+// verified client certificate. Requests from the UI are let through.
+func RequireProbeCertificates(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isProbeRequest(r) && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+			http.Error(w, "probe client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
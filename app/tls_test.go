@@ -0,0 +1,119 @@
+package app_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	der  []byte
+}
+
+func makeTestCert(t *testing.T, cn string, parent *testCert) testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	ok(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	ok(t, err)
+	cert, err := x509.ParseCertificate(der)
+	ok(t, err)
+	return testCert{cert: cert, key: key, der: der}
+}
+
+func (c testCert) write(t *testing.T, dir, name string) (string, string) {
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	ok(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600))
+	ok(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(c.key)}), 0600))
+	return certFile, keyFile
+}
+
+func TestProbeCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scope-tls")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := makeTestCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	serverCert, serverKey := makeTestCert(t, "app", &ca).write(t, dir, "app")
+	probe := makeTestCert(t, "probe-1", &ca)
+
+	config, err := app.ServerTLSConfig(serverCert, serverKey, caFile)
+	ok(t, err)
+
+	server := httptest.NewUnstartedServer(app.RequireProbeCertificates(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(app.ProbeIdentity(r)))
+	})))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      pool,
+			Certificates: certs,
+		}}}
+	}
+	do := func(client *http.Client, method, path string) (int, string) {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		ok(t, err)
+		req.Header.Set(xfer.ScopeProbeIDHeader, "spoofed")
+		resp, err := client.Do(req)
+		ok(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		ok(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// Probes are identified by their certificate, not the header
+	withCert := client(tls.Certificate{Certificate: [][]byte{probe.der}, PrivateKey: probe.key})
+	code, body := do(withCert, "POST", "/api/report")
+	equals(t, http.StatusOK, code)
+	equals(t, "probe-1", body)
+
+	// Probes without certificates are turned away, the UI isn't
+	withoutCert := client()
+	code, _ = do(withoutCert, "POST", "/api/report")
+	equals(t, http.StatusUnauthorized, code)
+	code, _ = do(withoutCert, "GET", "/api/pipe/foo/probe")
+	equals(t, http.StatusUnauthorized, code)
+	code, body = do(withoutCert, "GET", "/api/topology")
+	equals(t, http.StatusOK, code)
+	equals(t, "spoofed", body)
+}
@@ -313,9 +313,10 @@ export function receiveControlError(nodeId, err) {
   };
 }
 
-export function receiveControlSuccess(nodeId) {
+export function receiveControlSuccess(nodeId, notice = null) {
   return {
     nodeId,
+    notice,
     type: ActionTypes.DO_CONTROL_SUCCESS
   };
 }
@@ -9,6 +9,7 @@ and dispatch actions when handling request promises.
 */
 import debug from 'debug';
 import { fromJS } from 'immutable';
+import { isEmpty } from 'lodash';
 
 import ActionTypes from '../constants/action-types';
 import { RESOURCE_VIEW_MODE } from '../constants/naming';
@@ -16,6 +17,8 @@ import {
   API_REFRESH_INTERVAL,
   TOPOLOGY_REFRESH_INTERVAL,
 } from '../constants/timer';
+import { saveFile } from '../utils/file-utils';
+import { isCompleteTarArchive, makeLineReader, makeTarArchive } from '../utils/pipe-utils';
 import { updateRoute } from '../utils/router-utils';
 import { getCurrentTopologyUrl } from '../utils/topology-utils';
 import {
@@ -25,6 +28,7 @@ import {
   getNodesOnce,
   deletePipe,
   getNodeDetails,
+  getWebsocketUrl,
   getResourceViewNodesSnapshot,
   topologiesUrl,
   buildWebsocketUrl,
@@ -68,6 +72,10 @@ let continuePolling = true;
 let firstMessageOnWebsocketAt = null;
 let createWebsocketAt = null;
 let currentUrl = null;
+// The sockets of the upload pipes, by pipe ID
+const uploads = {};
+
+const UPLOAD_CHUNK_SIZE = 64 * 1024;
 
 function createWebsocket(websocketUrl, getState, dispatch) {
   if (socket) {
@@ -389,11 +397,124 @@ export function receiveControlPipe(pipeId, nodeId, rawTty, resizeTtyControl, con
   };
 }
 
-function doControlRequest(nodeId, control, dispatch) {
+// receiveDownload saves the archive received through the pipe of a download
+// control, once the pipe is closed at its end.
+function receiveDownload(nodeId, pipeId, filename, dispatch) {
+  const socket = new WebSocket(`${getWebsocketUrl()}/api/pipe/${pipeId}`);
+  socket.binaryType = 'arraybuffer';
+  const chunks = [];
+  let size = 0;
+  dispatch(receiveControlSuccess(nodeId, `Downloading ${filename}...`));
+  socket.onmessage = (event) => {
+    chunks.push(event.data);
+    size += event.data.byteLength;
+  };
+  socket.onclose = () => {
+    if (!isCompleteTarArchive(chunks)) {
+      dispatch(receiveControlError(nodeId, `Download of ${filename} failed after ${size} bytes`));
+      return;
+    }
+    saveFile(chunks, filename, 'application/x-tar');
+    dispatch(receiveControlSuccess(nodeId, `Downloaded ${filename} (${size} bytes)`));
+  };
+}
+
+// receiveUpload connects to the pipe of an upload control, keeping it open
+// while a file to upload is chosen, and reports the lines the probe sends
+// back until it closes the pipe.
+function receiveUpload(nodeId, pipeId, dispatch) {
+  const socket = new WebSocket(`${getWebsocketUrl()}/api/pipe/${pipeId}`);
+  socket.binaryType = 'arraybuffer';
+  const upload = { cancelled: false, socket, started: false };
+  uploads[pipeId] = upload;
+  let lastLine = '';
+  const readLines = makeLineReader((line) => {
+    lastLine = line;
+    dispatch(receiveControlSuccess(nodeId, line));
+  });
+  socket.onmessage = (event) => {
+    readLines(event.data);
+  };
+  socket.onclose = () => {
+    delete uploads[pipeId];
+    if (lastLine.startsWith('error: ')) {
+      dispatch(receiveControlError(nodeId, lastLine.substring('error: '.length)));
+    } else if (!upload.cancelled && !lastLine.startsWith('uploaded ')) {
+      dispatch(receiveControlError(nodeId, 'The upload was closed before it completed'));
+    }
+  };
+  dispatch({
+    nodeId,
+    pipeId,
+    type: ActionTypes.RECEIVE_CONTROL_UPLOAD
+  });
+}
+
+export function uploadFile(nodeId, pipeId, file) {
+  return (dispatch) => {
+    const upload = uploads[pipeId];
+    if (!upload || upload.started) {
+      return;
+    }
+    upload.started = true;
+    dispatch(receiveControlSuccess(nodeId, `Uploading ${file.name}...`));
+    const fail = (message) => {
+      upload.cancelled = true;
+      upload.socket.close();
+      deletePipe(pipeId, dispatch);
+      dispatch(receiveControlError(nodeId, message));
+    };
+    const reader = new FileReader();
+    reader.onerror = () => fail(`Error reading ${file.name}`);
+    reader.onload = () => {
+      let archive;
+      try {
+        archive = makeTarArchive(file.name, new Uint8Array(reader.result), file.lastModified);
+      } catch (err) {
+        fail(err.message);
+        return;
+      }
+      const send = () => {
+        for (let i = 0; i < archive.length; i += UPLOAD_CHUNK_SIZE) {
+          upload.socket.send(archive.subarray(i, i + UPLOAD_CHUNK_SIZE));
+        }
+      };
+      if (upload.socket.readyState === WebSocket.OPEN) {
+        send();
+      } else {
+        upload.socket.onopen = send;
+      }
+    };
+    reader.readAsArrayBuffer(file);
+  };
+}
+
+export function stopForward(nodeId, pipeId) {
+  return (dispatch) => {
+    deletePipe(pipeId, dispatch);
+    dispatch(receiveControlSuccess(nodeId));
+  };
+}
+
+export function cancelUpload(nodeId, pipeId) {
+  return (dispatch) => {
+    const upload = uploads[pipeId];
+    if (!upload || upload.started) {
+      return;
+    }
+    upload.cancelled = true;
+    upload.socket.close();
+    deletePipe(pipeId, dispatch);
+    dispatch(receiveControlSuccess(nodeId));
+  };
+}
+
+function doControlRequest(nodeId, control, args, dispatch) {
   clearTimeout(controlErrorTimer);
   const url = `${getApiPath()}/api/control/${encodeURIComponent(control.probeId)}/`
     + `${encodeURIComponent(control.nodeId)}/${control.id}`;
   doRequest({
+    data: isEmpty(args) ? undefined : JSON.stringify(args),
     error: (err) => {
       dispatch(receiveControlError(nodeId, err.response));
       controlErrorTimer = setTimeout(() => {
@@ -402,9 +523,24 @@ function doControlRequest(nodeId, control, dispatch) {
     },
     method: 'POST',
     success: (res) => {
-      dispatch(receiveControlSuccess(nodeId));
+      const notice = res && res.job ? `${control.human} is running, see Jobs below` : null;
+      dispatch(receiveControlSuccess(nodeId, notice));
       if (res) {
-        if (res.pipe) {
+        if (res.pipe && res.forward) {
+          // The app proxies to the UI end of the pipe, so it's left alone
+          dispatch({
+            address: res.forward,
+            nodeId,
+            pipeId: res.pipe,
+            type: ActionTypes.RECEIVE_CONTROL_FORWARD
+          });
+        } else if (res.pipe && res.binary_pipe) {
+          if (res.filename) {
+            receiveDownload(nodeId, res.pipe, res.filename, dispatch);
+          } else {
+            receiveUpload(nodeId, res.pipe, dispatch);
+          }
+        } else if (res.pipe) {
           dispatch(blurSearch());
           const resizeTtyControl = res.resize_tty_control
             && { id: res.resize_tty_control, nodeId: control.nodeId, probeId: control.probeId };
@@ -425,14 +561,33 @@ function doControlRequest(nodeId, control, dispatch) {
   });
 }
 
-export function doControl(nodeId, control) {
+export function doControl(nodeId, control, args) {
   return (dispatch) => {
     dispatch({
       control,
       nodeId,
       type: ActionTypes.DO_CONTROL
     });
-    doControlRequest(nodeId, control, dispatch);
+    doControlRequest(nodeId, control, args, dispatch);
+  };
+}
+
+export function cancelJob(nodeId, jobId) {
+  return (dispatch) => {
+    clearTimeout(controlErrorTimer);
+    doRequest({
+      error: (err) => {
+        dispatch(receiveControlError(nodeId, err.response));
+        controlErrorTimer = setTimeout(() => {
+          dispatch(clearControlError(nodeId));
+        }, 10000);
+      },
+      method: 'POST',
+      success: () => {
+        dispatch(receiveControlSuccess(nodeId, 'Cancelling the job...'));
+      },
+      url: `${getApiPath()}/api/jobs/${encodeURIComponent(jobId)}/cancel`
+    });
   };
 }
 
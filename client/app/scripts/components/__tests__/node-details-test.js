@@ -48,4 +48,31 @@ describe('NodeDetails', () => {
     const title = TestUtils.findRenderedDOMComponentWithClass(c, 'node-details-header-label');
     expect(title.title).toBe('Node 1');
   });
+
+  it('shows the jobs running on the node', () => {
+    nodes = nodes.set(nodeId, Immutable.fromJS({id: nodeId}));
+    details = {
+      controls: [{human: 'Drain', icon: 'fa fa-cloud-download-alt', id: 'kubernetes_drain_node'}],
+      jobs: [{
+        control: 'kubernetes_drain_node', id: 'j1', progress: 'evicted pod p1', state: 'running'
+      }],
+      label: 'Node 1'
+    };
+    const c = TestUtils.renderIntoDocument((
+      <Provider store={configureStore()}>
+        <NodeDetails
+          nodes={nodes}
+          topologyId="hosts"
+          nodeId={nodeId}
+          details={details}
+          />
+      </Provider>
+    ));
+
+    const control = TestUtils.findRenderedDOMComponentWithClass(c, 'node-details-jobs-job-control');
+    expect(control.textContent).toBe('Drain');
+    const progress = TestUtils.findRenderedDOMComponentWithClass(c, 'node-details-jobs-job-progress');
+    expect(progress.textContent).toBe('evicted pod p1');
+    expect(TestUtils.scryRenderedDOMComponentsWithClass(c, 'node-details-jobs-job-cancel').length).toBe(1);
+  });
 });
@@ -672,6 +672,83 @@ const GlobalStyle = createGlobalStyle`
             margin-right: 0.5em;
           }
         }
+
+        &-notice {
+          ${truncate};
+          float: right;
+          width: 55%;
+          padding-top: 6px;
+          text-align: left;
+          color: ${color('white')};
+        }
+
+        &-forward {
+          ${truncate};
+          float: right;
+          width: 55%;
+          padding-top: 6px;
+          color: ${color('white')};
+
+          a {
+            color: ${color('white')};
+            margin-left: 0.5em;
+          }
+
+          &-stop {
+            ${btnOpacity};
+            margin-left: 0.5em;
+            cursor: pointer;
+          }
+        }
+
+        &-upload {
+          float: right;
+          width: 55%;
+          color: ${color('white')};
+
+          input {
+            max-width: 85%;
+          }
+
+          &-cancel {
+            ${btnOpacity};
+            margin-left: 0.5em;
+            cursor: pointer;
+          }
+        }
+      }
+
+      &-jobs {
+        font-size: ${fontSize('small')};
+
+        &-job {
+          display: flex;
+          align-items: baseline;
+          padding: 2px 0;
+
+          &-control {
+            width: 30%;
+            color: ${scopeTheme('textColor')};
+          }
+
+          &-state {
+            width: 15%;
+            color: ${scopeTheme('textSecondaryColor')};
+          }
+
+          &-progress {
+            flex: 1;
+            min-width: 0;
+            color: ${scopeTheme('textSecondaryColor')};
+          }
+
+          &-cancel {
+            ${btnOpacity};
+            margin-left: 0.5em;
+            cursor: pointer;
+            text-decoration: underline;
+          }
+        }
       }
 
       &-content {
@@ -18,6 +18,7 @@ import NodeDetailsGenericTable from './node-details/node-details-generic-table';
 import NodeDetailsPropertyList from './node-details/node-details-property-list';
 import NodeDetailsHealth from './node-details/node-details-health';
 import NodeDetailsInfo from './node-details/node-details-info';
+import NodeDetailsJobs from './node-details/node-details-jobs';
 import NodeDetailsRelatives from './node-details/node-details-relatives';
 import NodeDetailsTable from './node-details/node-details-table';
 import Warning from './warning';
@@ -163,7 +164,9 @@ not found!
     } = this.props;
     const showControls = details.controls && details.controls.length > 0;
     const nodeColor = getNodeColorDark(details.rank, details.label, details.pseudo);
-    const {error, pending} = nodeControlStatus ? nodeControlStatus.toJS() : {};
+    const {
+      error, forwardAddress, forwardPipe, notice, pending, uploadPipe
+    } = nodeControlStatus ? nodeControlStatus.toJS() : {};
     const tools = this.renderTools();
     const styles = {
       controls: {
@@ -199,12 +202,27 @@ not found!
               nodeId={this.props.nodeId}
               controls={details.controls}
               pending={pending}
+              notice={notice}
+              uploadPipe={uploadPipe}
+              forwardPipe={forwardPipe}
+              forwardAddress={forwardAddress}
               error={error} />
           </div>
           )
         }
 
         <div className="node-details-content">
+          {details.jobs && details.jobs.length > 0
+            && (
+            <div className="node-details-content-section">
+              <div className="node-details-content-section-header">Jobs</div>
+              <NodeDetailsJobs
+                nodeId={this.props.nodeId}
+                jobs={details.jobs}
+                controls={details.controls} />
+            </div>
+            )
+          }
           {details.metrics
             && (
             <div className="node-details-content-section">
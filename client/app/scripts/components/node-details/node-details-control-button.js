@@ -4,6 +4,7 @@ import { isEmpty } from 'lodash';
 import classNames from 'classnames';
 
 import { trackAnalyticsEvent } from '../../utils/tracking-utils';
+import { promptControlArgs } from '../../utils/node-details-utils';
 import { doControl } from '../../actions/request-actions';
 
 class NodeDetailsControlButton extends React.Component {
@@ -26,10 +27,15 @@ class NodeDetailsControlButton extends React.Component {
 
   handleClick(ev) {
     ev.preventDefault();
-    const { id, human, confirmation } = this.props.control;
+    const {
+      id, human, confirmation, args
+    } = this.props.control;
     trackAnalyticsEvent('scope.node.control.click', { id, title: human });
     if (isEmpty(confirmation) || window.confirm(confirmation)) { // eslint-disable-line no-alert
-      this.props.dispatch(doControl(this.props.nodeId, this.props.control));
+      const controlArgs = promptControlArgs(args);
+      if (controlArgs) {
+        this.props.dispatch(doControl(this.props.nodeId, this.props.control, controlArgs));
+      }
     }
   }
 }
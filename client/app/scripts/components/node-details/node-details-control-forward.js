@@ -0,0 +1,31 @@
+import React from 'react';
+import { connect } from 'react-redux';
+
+import { stopForward } from '../../actions/request-actions';
+import { getApiPath } from '../../utils/web-api-utils';
+
+// NodeDetailsControlForward links to the port forwarded by a control, which
+// the app proxies through its pipe until it is stopped.
+class NodeDetailsControlForward extends React.Component {
+  handleClickStop = (ev) => {
+    ev.preventDefault();
+    this.props.stopForward(this.props.nodeId, this.props.pipeId);
+  }
+
+  render() {
+    const { address, pipeId } = this.props;
+    const url = `${getApiPath()}/api/forward/${encodeURIComponent(pipeId)}/`;
+    return (
+      <div className="node-details-controls-forward" title={`Forwarding to ${address}`}>
+        Forwarding to {address}
+        <a href={url} target="_blank" rel="noopener noreferrer">Open</a>
+        <i
+          className="node-details-controls-forward-stop fa fa-times"
+          title="Stop forwarding"
+          onClick={this.handleClickStop} />
+      </div>
+    );
+  }
+}
+
+export default connect(null, { stopForward })(NodeDetailsControlForward);
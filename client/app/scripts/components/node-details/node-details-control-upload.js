@@ -0,0 +1,39 @@
+import React from 'react';
+import { connect } from 'react-redux';
+
+import { cancelUpload, uploadFile } from '../../actions/request-actions';
+
+// NodeDetailsControlUpload asks for the file to send through the pipe of an
+// upload control. The upload is cancelled if no file is chosen before the
+// node details are closed.
+class NodeDetailsControlUpload extends React.Component {
+  handleChange = (ev) => {
+    const file = ev.target.files[0];
+    if (file) {
+      this.props.uploadFile(this.props.nodeId, this.props.pipeId, file);
+    }
+  }
+
+  handleClickCancel = (ev) => {
+    ev.preventDefault();
+    this.props.cancelUpload(this.props.nodeId, this.props.pipeId);
+  }
+
+  componentWillUnmount() {
+    this.props.cancelUpload(this.props.nodeId, this.props.pipeId);
+  }
+
+  render() {
+    return (
+      <div className="node-details-controls-upload">
+        <input type="file" title="Choose the file to upload" onChange={this.handleChange} />
+        <i
+          className="node-details-controls-upload-cancel fa fa-times"
+          title="Cancel the upload"
+          onClick={this.handleClickCancel} />
+      </div>
+    );
+  }
+}
+
+export default connect(null, { cancelUpload, uploadFile })(NodeDetailsControlUpload);
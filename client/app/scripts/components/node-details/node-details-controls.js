@@ -2,9 +2,11 @@ import React from 'react';
 import { sortBy } from 'lodash';
 
 import NodeDetailsControlButton from './node-details-control-button';
+import NodeDetailsControlForward from './node-details-control-forward';
+import NodeDetailsControlUpload from './node-details-control-upload';
 
 export default function NodeDetailsControls({
-  controls, error, nodeId, pending
+  controls, error, forwardAddress, forwardPipe, nodeId, notice, pending, uploadPipe
 }) {
   let spinnerClassName = 'fa fa-circle-notch fa-spin';
   if (pending) {
@@ -23,6 +25,24 @@ export default function NodeDetailsControls({
         </div>
         )
       }
+      {!error && uploadPipe
+        && <NodeDetailsControlUpload nodeId={nodeId} pipeId={uploadPipe} />
+      }
+      {!error && forwardPipe
+        && (
+        <NodeDetailsControlForward
+          nodeId={nodeId}
+          pipeId={forwardPipe}
+          address={forwardAddress} />
+        )
+      }
+      {!error && !uploadPipe && !forwardPipe && notice
+        && (
+        <div className="node-details-controls-notice" title={notice}>
+          {notice}
+        </div>
+        )
+      }
       <span className="node-details-controls-buttons">
         {sortBy(controls, 'rank').map(control => (
           <NodeDetailsControlButton
@@ -0,0 +1,47 @@
+import React from 'react';
+import { connect } from 'react-redux';
+import { find } from 'lodash';
+
+import { cancelJob } from '../../actions/request-actions';
+
+class NodeDetailsJob extends React.Component {
+  handleClickCancel = (ev) => {
+    ev.preventDefault();
+    this.props.cancelJob(this.props.nodeId, this.props.job.id);
+  }
+
+  render() {
+    const { controls, job } = this.props;
+    // Jobs name their control by ID, so show the name of the control
+    const control = find(controls, c => c.id === job.control);
+    return (
+      <div className="node-details-jobs-job">
+        <span className="node-details-jobs-job-control truncate">
+          {control ? control.human : job.control}
+        </span>
+        <span className="node-details-jobs-job-state">{job.state}</span>
+        <span className="node-details-jobs-job-progress truncate" title={job.progress}>
+          {job.progress}
+        </span>
+        <span
+          className="node-details-jobs-job-cancel"
+          title="Cancel this job"
+          onClick={this.handleClickCancel}>
+          Cancel
+        </span>
+      </div>
+    );
+  }
+}
+
+const ConnectedNodeDetailsJob = connect(null, { cancelJob })(NodeDetailsJob);
+
+export default function NodeDetailsJobs({ controls, jobs, nodeId }) {
+  return (
+    <div className="node-details-jobs">
+      {jobs.map(job => (
+        <ConnectedNodeDetailsJob key={job.id} nodeId={nodeId} job={job} controls={controls} />
+      ))}
+    </div>
+  );
+}
@@ -39,7 +39,9 @@ const ACTION_TYPES = [
   'RECEIVE_API_DETAILS',
   'RECEIVE_CONTROL_NODE_REMOVED',
   'RECEIVE_CONTROL_PIPE_STATUS',
+  'RECEIVE_CONTROL_FORWARD',
   'RECEIVE_CONTROL_PIPE',
+  'RECEIVE_CONTROL_UPLOAD',
   'RECEIVE_ERROR',
   'RECEIVE_NODE_DETAILS',
   'RECEIVE_NODES_DELTA',
@@ -499,6 +499,7 @@ export function rootReducer(state = initialState, action) {
     case ActionTypes.DO_CONTROL_SUCCESS: {
       return state.setIn(['controlStatus', action.nodeId], makeMap({
         error: null,
+        notice: action.notice,
         pending: false
       }));
     }
@@ -523,6 +524,17 @@ export function rootReducer(state = initialState, action) {
       }));
     }
 
+    case ActionTypes.RECEIVE_CONTROL_FORWARD: {
+      return state.mergeIn(['controlStatus', action.nodeId], makeMap({
+        forwardAddress: action.address,
+        forwardPipe: action.pipeId
+      }));
+    }
+
+    case ActionTypes.RECEIVE_CONTROL_UPLOAD: {
+      return state.setIn(['controlStatus', action.nodeId, 'uploadPipe'], action.pipeId);
+    }
+
     case ActionTypes.RECEIVE_CONTROL_PIPE_STATUS: {
       if (state.hasIn(['controlPipes', action.pipeId])) {
         state = state.setIn(['controlPipes', action.pipeId, 'status'], action.status);
@@ -0,0 +1,26 @@
+
+describe('NodeDetailsUtils', () => {
+  const NodeDetailsUtils = require('../node-details-utils');
+
+  describe('promptControlArgs', () => {
+    const f = NodeDetailsUtils.promptControlArgs;
+    const answers = values => () => values.shift();
+
+    it('it should return no values for controls without arguments', () => {
+      expect(f(undefined, answers([]))).toEqual({});
+    });
+    it('it should return the values given, leaving out the empty ones', () => {
+      const args = [
+        { human: 'Replicas', name: 'replicas', required: true },
+        { default: 'TERM', human: 'Signal', name: 'signal' },
+        { human: 'Container', name: 'container' },
+      ];
+      expect(f(args, answers(['3', 'KILL', '']))).toEqual({ replicas: '3', signal: 'KILL' });
+    });
+    it('it should return null when cancelled or missing a required value', () => {
+      const args = [{ human: 'Replicas', name: 'replicas', required: true }];
+      expect(f(args, answers([null]))).toBe(null);
+      expect(f(args, answers(['']))).toBe(null);
+    });
+  });
+});
@@ -0,0 +1,55 @@
+import { isCompleteTarArchive, makeLineReader, makeTarArchive } from '../pipe-utils';
+
+function field(header, offset, size) {
+  return String.fromCharCode.apply(null, header.subarray(offset, offset + size));
+}
+
+describe('PipeUtils', () => {
+  describe('makeTarArchive', () => {
+    const content = new Uint8Array([102, 111, 111]); // foo
+    const archive = makeTarArchive('config.yaml', content, 1500000000000);
+
+    it('archives the file in a header block, a content block and two zero blocks', () => {
+      expect(archive.length).toBe(4 * 512);
+      expect(field(archive, 0, 11)).toBe('config.yaml');
+      expect(field(archive, 124, 12)).toBe('00000000003\0');
+      expect(field(archive, 136, 12)).toBe('13132027400\0');
+      expect(field(archive, 156, 1)).toBe('0');
+      expect(field(archive, 257, 5)).toBe('ustar');
+      expect(field(archive, 512, 3)).toBe('foo');
+      expect(isCompleteTarArchive([archive.buffer])).toBe(true);
+    });
+
+    it('checksums the header', () => {
+      const header = archive.slice(0, 512);
+      const checksum = parseInt(field(header, 148, 6), 8);
+      header.fill(32, 148, 156);
+      expect(checksum).toBe(header.reduce((sum, b) => sum + b, 0));
+    });
+
+    it('refuses names too long for the header', () => {
+      expect(() => makeTarArchive('a'.repeat(101), content)).toThrow();
+    });
+  });
+
+  describe('isCompleteTarArchive', () => {
+    it('needs the two zero blocks', () => {
+      const archive = makeTarArchive('f', new Uint8Array([1]));
+      expect(isCompleteTarArchive([archive.slice(0, 1024).buffer, archive.slice(1024).buffer]))
+        .toBe(true);
+      expect(isCompleteTarArchive([archive.slice(0, 1536).buffer])).toBe(false);
+      expect(isCompleteTarArchive([])).toBe(false);
+    });
+  });
+
+  describe('makeLineReader', () => {
+    it('splits messages into lines', () => {
+      const lines = [];
+      const read = makeLineReader(line => lines.push(line));
+      read(new Uint8Array([97, 10, 98]).buffer);
+      read(new Uint8Array([195]).buffer);
+      read(new Uint8Array([169, 10]).buffer);
+      expect(lines).toEqual(['a', 'bé']);
+    });
+  });
+});
@@ -136,6 +136,26 @@ function cleanup() {
   svg.setAttribute('class', '');
 }
 
+/**
+ * Saves the parts, such as the chunks of a download, as a file.
+ */
+export function saveFile(parts, filename, type) {
+  window.URL = (window.URL || window.webkitURL);
+  const url = window.URL.createObjectURL(new Blob(parts, { type }));
+
+  const a = document.createElement('a');
+  document.body.appendChild(a);
+  a.setAttribute('download', filename);
+  a.setAttribute('href', url);
+  a.style.display = 'none';
+  a.click();
+  document.body.removeChild(a);
+
+  setTimeout(() => {
+    window.URL.revokeObjectURL(url);
+  }, 10);
+}
+
 export function saveGraph(filename) {
   window.URL = (window.URL || window.webkitURL);
 
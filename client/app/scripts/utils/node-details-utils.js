@@ -39,3 +39,24 @@ export function getTableColumnsStyles(headers) {
     width: NODE_DETAILS_TABLE_COLUMN_WIDTHS[header.id]
   }));
 }
+
+/**
+ * Asks for the arguments of a control, offering their defaults. Returns
+ * the values given, leaving out the empty ones, or null when cancelled or
+ * when a required argument is missing.
+ */
+export function promptControlArgs(args, prompt = window.prompt) {
+  const values = {};
+  for (let i = 0; i < (args || []).length; i += 1) {
+    const arg = args[i];
+    const choices = arg.choices ? ` (${arg.choices.join(', ')})` : '';
+    const value = prompt(`${arg.human}${choices}`, arg.default || '');
+    if (value === null || (arg.required && value === '')) {
+      return null;
+    }
+    if (value !== '') {
+      values[arg.name] = value;
+    }
+  }
+  return values;
+}
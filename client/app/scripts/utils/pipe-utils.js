@@ -0,0 +1,85 @@
+import { padStart } from 'lodash';
+
+// Helpers for the binary pipes of the file copy controls, which carry tar
+// archives, or text lines back from the probe.
+
+const TAR_BLOCK_SIZE = 512;
+const TAR_NAME_SIZE = 100;
+
+// http://stackoverflow.com/questions/17191945/conversion-between-utf-8-arraybuffer-and-string
+function utf8Encode(str) {
+  const encoded = unescape(encodeURIComponent(str));
+  const bytes = new Uint8Array(encoded.length);
+  for (let i = 0; i < encoded.length; i += 1) {
+    bytes[i] = encoded.charCodeAt(i);
+  }
+  return bytes;
+}
+
+function writeOctal(header, offset, size, value) {
+  // Zero-padded, leaving room for the terminating NUL
+  header.set(utf8Encode(padStart(value.toString(8), size - 1, '0')), offset);
+}
+
+/**
+ * Makes a tar archive of a single file, as the upload control takes.
+ */
+export function makeTarArchive(name, content, mtime = Date.now()) {
+  const nameBytes = utf8Encode(name);
+  if (nameBytes.length > TAR_NAME_SIZE) {
+    throw new Error(`File name too long: ${name}`);
+  }
+  const contentSize = Math.ceil(content.length / TAR_BLOCK_SIZE) * TAR_BLOCK_SIZE;
+  // A header block, the content and two zero blocks ending the archive
+  const archive = new Uint8Array(TAR_BLOCK_SIZE + contentSize + 2 * TAR_BLOCK_SIZE);
+  const header = archive.subarray(0, TAR_BLOCK_SIZE);
+  header.set(nameBytes, 0);
+  writeOctal(header, 100, 8, 0o644); // mode
+  writeOctal(header, 108, 8, 0); // uid
+  writeOctal(header, 116, 8, 0); // gid
+  writeOctal(header, 124, 12, content.length);
+  writeOctal(header, 136, 12, Math.floor(mtime / 1000));
+  header.fill(' '.charCodeAt(0), 148, 156); // checksum, counted as spaces
+  header[156] = '0'.charCodeAt(0); // regular file
+  header.set(utf8Encode('ustar'), 257);
+  header.set(utf8Encode('00'), 263);
+  const checksum = header.reduce((sum, b) => sum + b, 0);
+  writeOctal(header, 148, 7, checksum);
+  archive.set(content, TAR_BLOCK_SIZE);
+  return archive;
+}
+
+/**
+ * Tells whether the chunks received make up a whole tar archive, which
+ * ends with two zero blocks, rather than one cut short.
+ */
+export function isCompleteTarArchive(chunks) {
+  let zeros = 0;
+  for (let i = chunks.length - 1; i >= 0; i -= 1) {
+    const bytes = new Uint8Array(chunks[i]);
+    for (let j = bytes.length - 1; j >= 0; j -= 1) {
+      if (bytes[j] !== 0) {
+        return false;
+      }
+      zeros += 1;
+      if (zeros === 2 * TAR_BLOCK_SIZE) {
+        return true;
+      }
+    }
+  }
+  return false;
+}
+
+/**
+ * Returns a function splitting the binary messages it is given into UTF-8
+ * lines, passed to onLine, whichever messages they span.
+ */
+export function makeLineReader(onLine) {
+  let pending = '';
+  return (buf) => {
+    pending += String.fromCharCode.apply(null, new Uint8Array(buf));
+    const lines = pending.split('\n');
+    pending = lines.pop();
+    lines.forEach(line => onLine(decodeURIComponent(escape(line))));
+  };
+}
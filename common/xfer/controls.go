@@ -27,9 +27,20 @@ type Response struct {
 	Pipe             string `json:"pipe,omitempty"`
 	RawTTY           bool   `json:"raw_tty,omitempty"`
 	ResizeTTYControl string `json:"resize_tty_control,omitempty"`
+	// BinaryPipe is set when the pipe carries a tar archive: downloaded,
+	// to be saved as Filename, or else to be uploaded.
+	BinaryPipe bool   `json:"binary_pipe,omitempty"`
+	Filename   string `json:"filename,omitempty"`
+	// Forward is set to the address connections are forwarded to, when the
+	// pipe multiplexes them (see Mux).
+	Forward string `json:"forward,omitempty"`
 
 	// Remove specific fields
 	RemovedNode string `json:"removedNode,omitempty"` // Set if node was removed
+
+	// Job is set to the ID of the job running the control, when it runs
+	// asynchronously. Its progress is in the reports.
+	Job string `json:"job,omitempty"`
 }
 
 // Message is the unions of Request, Response and arbitrary Value.
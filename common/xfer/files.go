@@ -0,0 +1,42 @@
+package xfer
+
+import (
+	"fmt"
+	"path"
+)
+
+// FileCopyOptions are the ControlArgs understood by the controls copying
+// files as tar archives: path, the absolute path of the file or directory to
+// download, or of the directory to upload into, and for pods, container,
+// the container to copy from or to.
+type FileCopyOptions struct {
+	Path      string
+	Container string // the first container of the pod if blank
+}
+
+// ParseFileCopyOptions extracts the FileCopyOptions from the ControlArgs of
+// a request.
+func ParseFileCopyOptions(args map[string]string) (FileCopyOptions, error) {
+	p, ok := args["path"]
+	if !ok || !path.IsAbs(p) {
+		return FileCopyOptions{}, fmt.Errorf("Bad parameter: path (%q): must be an absolute path", p)
+	}
+	return FileCopyOptions{
+		Path:      path.Clean(p),
+		Container: args["container"],
+	}, nil
+}
+
+// Dir is the directory a download is archived from, as in tar -C <dir>.
+func (o FileCopyOptions) Dir() string { return path.Dir(o.Path) }
+
+// Base is the name of the file or directory downloaded, within Dir.
+func (o FileCopyOptions) Base() string { return path.Base(o.Path) }
+
+// Filename is the name a downloaded archive is saved as.
+func (o FileCopyOptions) Filename() string {
+	if o.Path == "/" {
+		return "root.tar"
+	}
+	return o.Base() + ".tar"
+}
@@ -0,0 +1,31 @@
+package xfer_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+func TestParseFileCopyOptions(t *testing.T) {
+	opts, err := xfer.ParseFileCopyOptions(map[string]string{"path": "/tmp//heap/dump.hprof/", "container": "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := xfer.FileCopyOptions{Path: "/tmp/heap/dump.hprof", Container: "app"}
+	if opts != want {
+		t.Errorf("Expected %+v, got %+v", want, opts)
+	}
+	if opts.Dir() != "/tmp/heap" || opts.Base() != "dump.hprof" || opts.Filename() != "dump.hprof.tar" {
+		t.Errorf("Unexpected %q, %q, %q", opts.Dir(), opts.Base(), opts.Filename())
+	}
+
+	for _, args := range []map[string]string{
+		nil,
+		{"path": ""},
+		{"path": "tmp/dump"},
+	} {
+		if _, err := xfer.ParseFileCopyOptions(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
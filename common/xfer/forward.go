@@ -0,0 +1,261 @@
+package xfer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ParseForwardPort extracts the port argument of the controls forwarding
+// ports from the ControlArgs of a request.
+func ParseForwardPort(args map[string]string) (int, error) {
+	s := args["port"]
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("Bad parameter: port (%q): must be a port number", s)
+	}
+	return port, nil
+}
+
+// Connections forwarded through a pipe are multiplexed over it in frames: a
+// header of the connection ID, the type of the frame and the length of the
+// data following it.
+const (
+	frameOpen byte = iota
+	frameData
+	frameClose
+
+	frameHeaderSize = 7
+	maxFrameData    = 32 * 1024
+)
+
+// ErrMuxClosed is returned using a Mux after its pipe has closed.
+var ErrMuxClosed = errors.New("forwarding closed")
+
+// Mux multiplexes connections over one end of a pipe: the app opens them,
+// and the probe accepts them, and forwards them to their destination. The
+// data of a connection is passed on as it is read, so a connection which
+// isn't read holds up the others.
+type Mux struct {
+	rw       io.ReadWriter
+	writeMtx sync.Mutex
+
+	mtx    sync.Mutex
+	conns  map[uint32]*muxConn
+	nextID uint32
+	accept chan *muxConn
+	done   chan struct{}
+	err    error
+}
+
+// NewMux starts multiplexing connections over rw.
+func NewMux(rw io.ReadWriter) *Mux {
+	m := &Mux{
+		rw:     rw,
+		conns:  map[uint32]*muxConn{},
+		accept: make(chan *muxConn),
+		done:   make(chan struct{}),
+	}
+	go m.readLoop()
+	return m
+}
+
+// Open opens a new connection, to be accepted at the other end.
+func (m *Mux) Open() (net.Conn, error) {
+	m.mtx.Lock()
+	if m.err != nil {
+		m.mtx.Unlock()
+		return nil, m.err
+	}
+	m.nextID++
+	c := newMuxConn(m, m.nextID)
+	m.conns[c.id] = c
+	m.mtx.Unlock()
+
+	if err := m.writeFrame(c.id, frameOpen, nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Accept waits for the next connection opened at the other end.
+func (m *Mux) Accept() (net.Conn, error) {
+	select {
+	case c := <-m.accept:
+		return c, nil
+	case <-m.done:
+		return nil, m.err
+	}
+}
+
+// Done is closed once the pipe of the Mux has closed.
+func (m *Mux) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *Mux) readLoop() {
+	header := make([]byte, frameHeaderSize)
+	for {
+		if _, err := io.ReadFull(m.rw, header); err != nil {
+			m.close()
+			return
+		}
+		id := binary.BigEndian.Uint32(header)
+		data := make([]byte, binary.BigEndian.Uint16(header[5:]))
+		if _, err := io.ReadFull(m.rw, data); err != nil {
+			m.close()
+			return
+		}
+
+		m.mtx.Lock()
+		c, ok := m.conns[id]
+		switch header[4] {
+		case frameOpen:
+			if !ok {
+				c = newMuxConn(m, id)
+				m.conns[id] = c
+			}
+			m.mtx.Unlock()
+			select {
+			case m.accept <- c:
+			case <-m.done:
+			}
+		case frameData:
+			m.mtx.Unlock()
+			if ok {
+				// Fails once the connection is closed at this end
+				c.pw.Write(data)
+			}
+		case frameClose:
+			delete(m.conns, id)
+			m.mtx.Unlock()
+			if ok {
+				c.closeLocal()
+			}
+		default:
+			m.mtx.Unlock()
+		}
+	}
+}
+
+func (m *Mux) close() {
+	m.mtx.Lock()
+	if m.err != nil {
+		m.mtx.Unlock()
+		return
+	}
+	m.err = ErrMuxClosed
+	conns := m.conns
+	m.conns = map[uint32]*muxConn{}
+	close(m.done)
+	m.mtx.Unlock()
+	for _, c := range conns {
+		c.closeLocal()
+	}
+}
+
+func (m *Mux) writeFrame(id uint32, frameType byte, data []byte) error {
+	buf := make([]byte, frameHeaderSize+len(data))
+	binary.BigEndian.PutUint32(buf, id)
+	buf[4] = frameType
+	binary.BigEndian.PutUint16(buf[5:], uint16(len(data)))
+	copy(buf[frameHeaderSize:], data)
+
+	m.writeMtx.Lock()
+	defer m.writeMtx.Unlock()
+	select {
+	case <-m.done:
+		return ErrMuxClosed
+	default:
+	}
+	_, err := m.rw.Write(buf)
+	return err
+}
+
+// muxConn is a connection multiplexed by a Mux. Closing it at either end
+// closes it at both.
+type muxConn struct {
+	mux    *Mux
+	id     uint32
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	mtx    sync.Mutex
+	closed bool
+}
+
+func newMuxConn(m *Mux, id uint32) *muxConn {
+	pr, pw := io.Pipe()
+	return &muxConn{mux: m, id: id, pr: pr, pw: pw}
+}
+
+func (c *muxConn) Read(p []byte) (int, error) {
+	return c.pr.Read(p)
+}
+
+func (c *muxConn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if c.isClosed() {
+			return written, io.ErrClosedPipe
+		}
+		n := len(p)
+		if n > maxFrameData {
+			n = maxFrameData
+		}
+		if err := c.mux.writeFrame(c.id, frameData, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (c *muxConn) isClosed() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.closed
+}
+
+// closeLocal closes the connection at this end only.
+func (c *muxConn) closeLocal() bool {
+	c.mtx.Lock()
+	wasClosed := c.closed
+	c.closed = true
+	c.mtx.Unlock()
+	c.pw.Close()
+	return !wasClosed
+}
+
+func (c *muxConn) Close() error {
+	if !c.closeLocal() {
+		return nil
+	}
+	c.pr.Close()
+	c.mux.mtx.Lock()
+	delete(c.mux.conns, c.id)
+	c.mux.mtx.Unlock()
+	if err := c.mux.writeFrame(c.id, frameClose, nil); err != nil && err != ErrMuxClosed {
+		return err
+	}
+	return nil
+}
+
+func (c *muxConn) LocalAddr() net.Addr  { return muxAddr(c.id) }
+func (c *muxConn) RemoteAddr() net.Addr { return muxAddr(c.id) }
+
+// Deadlines aren't supported; connections are closed with their pipe.
+func (c *muxConn) SetDeadline(time.Time) error      { return nil }
+func (c *muxConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *muxConn) SetWriteDeadline(time.Time) error { return nil }
+
+type muxAddr uint32
+
+func (a muxAddr) Network() string { return "pipe" }
+func (a muxAddr) String() string  { return fmt.Sprintf("pipe:%d", a) }
@@ -0,0 +1,87 @@
+package xfer_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+func TestParseForwardPort(t *testing.T) {
+	if port, err := xfer.ParseForwardPort(map[string]string{"port": "8080"}); err != nil || port != 8080 {
+		t.Errorf("Expected 8080, got %d, %v", port, err)
+	}
+	for _, port := range []string{"", "http", "0", "65536"} {
+		if _, err := xfer.ParseForwardPort(map[string]string{"port": port}); err == nil {
+			t.Errorf("%q: expected an error", port)
+		}
+	}
+}
+
+func TestMux(t *testing.T) {
+	pipe := xfer.NewBinaryPipe()
+	local, remote := pipe.Ends()
+	app, probe := xfer.NewMux(remote), xfer.NewMux(local)
+
+	// Larger than a frame, on two connections at once
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			conn, err := probe.Accept()
+			if err != nil {
+				if err != xfer.ErrMuxClosed {
+					t.Errorf("Expected %v, got %v", xfer.ErrMuxClosed, err)
+				}
+				return
+			}
+			go func() {
+				// Echo back, then close once the other side has closed
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	results := make(chan []byte, 2)
+	for i := 0; i < 2; i++ {
+		conn, err := app.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			buf := make([]byte, len(payload))
+			_, err := io.ReadFull(conn, buf)
+			if err != nil {
+				t.Error(err)
+			}
+			conn.Close()
+			results <- buf
+		}()
+		go conn.Write(payload)
+	}
+	for i := 0; i < 2; i++ {
+		if buf := <-results; !bytes.Equal(buf, payload) {
+			t.Errorf("Unexpected payload of %d bytes", len(buf))
+		}
+	}
+
+	// Closing the pipe closes the muxes and their connections
+	conn, err := app.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe.Close()
+	<-app.Done()
+	<-probe.Done()
+	<-done
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+	if _, err := app.Open(); err != xfer.ErrMuxClosed {
+		t.Errorf("Expected %v, got %v", xfer.ErrMuxClosed, err)
+	}
+}
@@ -0,0 +1,46 @@
+package xfer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// LogOptions are the ControlArgs understood by the controls streaming logs:
+// follow and previous are booleans, since a duration such as "10m", and
+// tail a number of lines.
+type LogOptions struct {
+	Follow   bool          // keep streaming new lines; defaults to true
+	Previous bool          // the logs of the previous, crashed, container
+	Since    time.Duration // only lines newer than this; zero for all
+	Tail     int64         // only this many lines from the end; -1 for all
+}
+
+// ParseLogOptions extracts the LogOptions from the ControlArgs of a request.
+func ParseLogOptions(args map[string]string) (LogOptions, error) {
+	var (
+		opts = LogOptions{Follow: true, Tail: -1}
+		err  error
+	)
+	if s, ok := args["follow"]; ok {
+		if opts.Follow, err = strconv.ParseBool(s); err != nil {
+			return opts, fmt.Errorf("Bad parameter: follow (%q): %v", s, err)
+		}
+	}
+	if s, ok := args["previous"]; ok {
+		if opts.Previous, err = strconv.ParseBool(s); err != nil {
+			return opts, fmt.Errorf("Bad parameter: previous (%q): %v", s, err)
+		}
+	}
+	if s, ok := args["since"]; ok {
+		if opts.Since, err = time.ParseDuration(s); err != nil || opts.Since < 0 {
+			return opts, fmt.Errorf("Bad parameter: since (%q): must be a positive duration", s)
+		}
+	}
+	if s, ok := args["tail"]; ok {
+		if opts.Tail, err = strconv.ParseInt(s, 10, 64); err != nil || opts.Tail < 0 {
+			return opts, fmt.Errorf("Bad parameter: tail (%q): must be a number of lines", s)
+		}
+	}
+	return opts, nil
+}
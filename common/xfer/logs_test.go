@@ -0,0 +1,41 @@
+package xfer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+func TestParseLogOptions(t *testing.T) {
+	for _, c := range []struct {
+		args map[string]string
+		want xfer.LogOptions
+	}{
+		{nil, xfer.LogOptions{Follow: true, Tail: -1}},
+		{
+			map[string]string{"follow": "false", "previous": "true", "since": "10m", "tail": "100"},
+			xfer.LogOptions{Follow: false, Previous: true, Since: 10 * time.Minute, Tail: 100},
+		},
+		{map[string]string{"tail": "0"}, xfer.LogOptions{Follow: true, Tail: 0}},
+	} {
+		have, err := xfer.ParseLogOptions(c.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have != c.want {
+			t.Errorf("%v: expected %+v, got %+v", c.args, c.want, have)
+		}
+	}
+
+	for _, bad := range []map[string]string{
+		{"follow": "maybe"},
+		{"since": "yesterday"},
+		{"since": "-1h"},
+		{"tail": "-5"},
+	} {
+		if _, err := xfer.ParseLogOptions(bad); err == nil {
+			t.Errorf("Expected an error parsing %v", bad)
+		}
+	}
+}
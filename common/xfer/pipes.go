@@ -1,6 +1,7 @@
 package xfer
 
 import (
+	"fmt"
 	"io"
 	"sync"
 
@@ -26,8 +27,16 @@ type pipe struct {
 	quit            chan struct{}
 	closed          bool
 	onClose         func()
+	binary          bool
 }
 
+const (
+	// Terminals are copied to the websocket a keystroke or a screenful at a
+	// time, archives in larger chunks.
+	ttyBufferSize    = 1024
+	binaryBufferSize = 32 * 1024
+)
+
 // NewPipeFromEnds makes a new pipe specifying its ends
 func NewPipeFromEnds(local io.ReadWriter, remote io.ReadWriter) Pipe {
 	return &pipe{
@@ -39,6 +48,16 @@ func NewPipeFromEnds(local io.ReadWriter, remote io.ReadWriter) Pipe {
 
 // NewPipe makes a new pipe
 func NewPipe() Pipe {
+	return newPipe(false)
+}
+
+// NewBinaryPipe makes a new pipe for binary data, such as a tar archive,
+// rather than a terminal: only binary websocket messages are accepted.
+func NewBinaryPipe() Pipe {
+	return newPipe(true)
+}
+
+func newPipe(binary bool) Pipe {
 	r1, w1 := io.Pipe()
 	r2, w2 := io.Pipe()
 	return &pipe{
@@ -57,7 +76,8 @@ func NewPipe() Pipe {
 		closers: []io.Closer{
 			r1, r2, w1, w2,
 		},
-		quit: make(chan struct{}),
+		quit:   make(chan struct{}),
+		binary: binary,
 	}
 }
 
@@ -110,18 +130,20 @@ func (p *pipe) CopyToWebsocket(end io.ReadWriter, conn Websocket) (bool, error)
 	p.mtx.Unlock()
 	defer p.wg.Done()
 
+	if p.binary {
+		conn = BinaryWebsocket(conn)
+	}
 	endError := make(chan error, 1)
 	connError := make(chan error, 1)
 
 	// Read-from-UI loop
 	go func() {
 		for {
-			_, buf, err := conn.ReadMessage() // TODO type should be binary message
+			_, buf, err := conn.ReadMessage()
 			if err != nil {
 				connError <- err
 				return
 			}
-
 			if p.Closed() {
 				return
 			}
@@ -135,7 +157,11 @@ func (p *pipe) CopyToWebsocket(end io.ReadWriter, conn Websocket) (bool, error)
 
 	// Write-to-UI loop
 	go func() {
-		buf := make([]byte, 1024)
+		size := ttyBufferSize
+		if p.binary {
+			size = binaryBufferSize
+		}
+		buf := make([]byte, size)
 		for {
 			n, err := end.Read(buf)
 			if err != nil {
@@ -168,3 +194,21 @@ func (p *pipe) CopyToWebsocket(end io.ReadWriter, conn Websocket) (bool, error)
 		return true, nil
 	}
 }
+
+// BinaryWebsocket wraps conn to fail reading any message but binary ones, as
+// on the pipes of binary data.
+func BinaryWebsocket(conn Websocket) Websocket {
+	return binaryWebsocket{conn}
+}
+
+type binaryWebsocket struct {
+	Websocket
+}
+
+func (b binaryWebsocket) ReadMessage() (int, []byte, error) {
+	messageType, buf, err := b.Websocket.ReadMessage()
+	if err == nil && messageType != websocket.BinaryMessage {
+		return messageType, nil, fmt.Errorf("unexpected message type %d on binary pipe", messageType)
+	}
+	return messageType, buf, err
+}
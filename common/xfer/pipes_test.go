@@ -0,0 +1,67 @@
+package xfer_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+// messageWebsocket reads a single message, and then blocks.
+type messageWebsocket struct {
+	xfer.Websocket
+	messageType int
+	data        []byte
+	read        bool
+	closed      chan struct{}
+}
+
+func (m *messageWebsocket) ReadMessage() (int, []byte, error) {
+	if !m.read {
+		m.read = true
+		return m.messageType, m.data, nil
+	}
+	<-m.closed
+	return 0, nil, io.EOF
+}
+
+func (m *messageWebsocket) WriteMessage(int, []byte) error { return nil }
+
+func TestBinaryPipe(t *testing.T) {
+	for _, c := range []struct {
+		pipe        xfer.Pipe
+		messageType int
+		wantErr     string
+	}{
+		{xfer.NewPipe(), websocket.TextMessage, ""},
+		{xfer.NewBinaryPipe(), websocket.BinaryMessage, ""},
+		{xfer.NewBinaryPipe(), websocket.TextMessage, "unexpected message type"},
+	} {
+		local, remote := c.pipe.Ends()
+		conn := &messageWebsocket{messageType: c.messageType, data: []byte("data"), closed: make(chan struct{})}
+		received := make(chan string, 1)
+		go func() {
+			buf := make([]byte, 4)
+			n, _ := io.ReadFull(local, buf)
+			received <- string(buf[:n])
+		}()
+		errs := make(chan error, 1)
+		go func() {
+			_, err := c.pipe.CopyToWebsocket(remote, conn)
+			errs <- err
+		}()
+
+		if c.wantErr == "" {
+			if data := <-received; data != "data" {
+				t.Errorf("Expected the message to go through the pipe, got %q", data)
+			}
+			close(conn.closed)
+		} else if err := <-errs; err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("Expected error %q, got %v", c.wantErr, err)
+		}
+		c.pipe.Close()
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config describes the simulated cluster, how it evolves, and what to
+// measure.
+type Config struct {
+	// App is the URL of the app to load, e.g. http://localhost:4040
+	App string `yaml:"app" json:"app"`
+	// Token to publish with, for multitenant apps
+	Token string `yaml:"token" json:"-"`
+	// Compact makes the probes publish in the compact encoding
+	Compact bool `yaml:"compact" json:"compact"`
+
+	// Hosts is the number of simulated hosts, each with its own probe ID.
+	Hosts int `yaml:"hosts" json:"hosts"`
+	// ContainersPerHost is the number of containers on each host, with
+	// one process each.
+	ContainersPerHost int `yaml:"containersPerHost" json:"containersPerHost"`
+	// ContainersPerPod is the number of containers grouped in each pod.
+	ContainersPerPod int `yaml:"containersPerPod" json:"containersPerPod"`
+	// Services is the number of services the pods are spread over.
+	Services int `yaml:"services" json:"services"`
+	// Namespaces is the number of namespaces the pods are spread over.
+	Namespaces int `yaml:"namespaces" json:"namespaces"`
+	// Images is the number of distinct container images.
+	Images int `yaml:"images" json:"images"`
+	// Churn is the fraction of pods replaced, with new containers, on
+	// every publish.
+	Churn float64 `yaml:"churn" json:"churn"`
+	// FanOut is the number of connections each container makes to
+	// other containers.
+	FanOut int `yaml:"fanOut" json:"fanOut"`
+	// Metrics adds CPU and memory metrics to hosts, containers and
+	// processes.
+	Metrics bool `yaml:"metrics" json:"metrics"`
+	// Seed for the random generator, so runs can be compared.
+	Seed int64 `yaml:"seed" json:"seed"`
+
+	// PublishInterval is how often every probe publishes a report.
+	PublishInterval time.Duration `yaml:"publishInterval" json:"publishInterval"`
+	// Duration of the run.
+	Duration time.Duration `yaml:"duration" json:"duration"`
+	// Warmup is the time during which nothing is measured, while the app
+	// fills its window.
+	Warmup time.Duration `yaml:"warmup" json:"warmup"`
+	// SampleInterval is how often the app is measured.
+	SampleInterval time.Duration `yaml:"sampleInterval" json:"sampleInterval"`
+	// Topologies to render, and to watch over websockets.
+	Topologies []string `yaml:"topologies" json:"topologies"`
+}
+
+var defaultConfig = Config{
+	App:               "http://localhost:4040",
+	Compact:           true,
+	Hosts:             10,
+	ContainersPerHost: 20,
+	ContainersPerPod:  2,
+	Services:          10,
+	Namespaces:        3,
+	Images:            10,
+	Churn:             0.02,
+	FanOut:            3,
+	Metrics:           true,
+	Seed:              1,
+	PublishInterval:   3 * time.Second,
+	Duration:          5 * time.Minute,
+	Warmup:            30 * time.Second,
+	SampleInterval:    5 * time.Second,
+	Topologies:        []string{"processes", "containers", "pods", "services", "hosts"},
+}
+
+func loadConfig(filename string) (Config, error) {
+	config := defaultConfig
+	if filename != "" {
+		buf, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return config, err
+		}
+		if err := yaml.UnmarshalStrict(buf, &config); err != nil {
+			return config, fmt.Errorf("parsing %s: %v", filename, err)
+		}
+	}
+	switch {
+	case config.Hosts < 1:
+		return config, fmt.Errorf("hosts must be at least 1")
+	case config.ContainersPerHost < 1 || config.ContainersPerPod < 1:
+		return config, fmt.Errorf("containersPerHost and containersPerPod must be at least 1")
+	case config.Services < 1 || config.Namespaces < 1 || config.Images < 1:
+		return config, fmt.Errorf("services, namespaces and images must be at least 1")
+	case config.Churn < 0 || config.Churn > 1:
+		return config, fmt.Errorf("churn must be between 0 and 1")
+	case config.PublishInterval <= 0 || config.SampleInterval <= 0:
+		return config, fmt.Errorf("publishInterval and sampleInterval must be positive")
+	}
+	return config, nil
+}
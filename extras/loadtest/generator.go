@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	serverPort  = "80"
+	memoryTotal = 16 << 30
+)
+
+type simHost struct {
+	name    string
+	nodeID  string
+	probeID string
+	cpu     float64
+	memory  float64
+	pods    []*simPod
+	nextPID int
+}
+
+type simPod struct {
+	uid        string
+	name       string
+	namespace  string
+	service    int
+	ip         string
+	containers []*simContainer
+}
+
+type simContainer struct {
+	id     string
+	name   string
+	image  int
+	pid    string
+	cpu    float64
+	memory float64
+	// peers are the IPs of the pods this container connects to
+	peers []string
+}
+
+// cluster is the simulated state all the reports are generated from. It's
+// changed between publishes by tick, and only read while reports are
+// generated, so generating reports for different hosts can happen
+// concurrently.
+type cluster struct {
+	config Config
+	rand   *rand.Rand
+	hosts  []*simHost
+	pods   []*simPod
+	nextID int
+}
+
+func newCluster(config Config) *cluster {
+	c := &cluster{
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+	}
+	for i := 0; i < config.Hosts; i++ {
+		name := fmt.Sprintf("loadtest-host-%04d", i)
+		h := &simHost{
+			name:    name,
+			nodeID:  report.MakeHostNodeID(name),
+			probeID: fmt.Sprintf("loadtest-probe-%04d", i),
+			cpu:     c.rand.Float64() * 100,
+			memory:  c.rand.Float64() * memoryTotal,
+			nextPID: 1000,
+		}
+		c.hosts = append(c.hosts, h)
+		for n := 0; n < config.ContainersPerHost; n += config.ContainersPerPod {
+			containers := config.ContainersPerPod
+			if n+containers > config.ContainersPerHost {
+				containers = config.ContainersPerHost - n
+			}
+			h.pods = append(h.pods, c.newPod(h, containers))
+		}
+	}
+	for _, h := range c.hosts {
+		c.pods = append(c.pods, h.pods...)
+	}
+	for _, p := range c.pods {
+		for _, container := range p.containers {
+			c.connect(container)
+		}
+	}
+	return c
+}
+
+func (c *cluster) id() int {
+	c.nextID++
+	return c.nextID
+}
+
+// ip returns a distinct address in 10.0.0.0/8 for every id.
+func (c *cluster) ip(id int) string {
+	return fmt.Sprintf("10.%d.%d.%d", (id>>16)&0xff, (id>>8)&0xff, id&0xff)
+}
+
+func (c *cluster) newPod(h *simHost, containers int) *simPod {
+	id := c.id()
+	service := c.rand.Intn(c.config.Services)
+	p := &simPod{
+		uid:       fmt.Sprintf("00000000-0000-0000-0000-%012d", id),
+		name:      fmt.Sprintf("service-%d-%d", service, id),
+		namespace: fmt.Sprintf("namespace-%d", service%c.config.Namespaces),
+		service:   service,
+		ip:        c.ip(id),
+	}
+	for i := 0; i < containers; i++ {
+		h.nextPID++
+		p.containers = append(p.containers, &simContainer{
+			id:     fmt.Sprintf("%064x", c.id()),
+			name:   fmt.Sprintf("k8s_container-%d_%s_%s_%s_0", i, p.name, p.namespace, p.uid),
+			image:  c.rand.Intn(c.config.Images),
+			pid:    strconv.Itoa(h.nextPID),
+			cpu:    c.rand.Float64() * 100,
+			memory: c.rand.Float64() * memoryTotal / float64(c.config.ContainersPerHost),
+		})
+	}
+	return p
+}
+
+func (c *cluster) connect(container *simContainer) {
+	container.peers = nil
+	for i := 0; i < c.config.FanOut && len(c.pods) > 1; i++ {
+		container.peers = append(container.peers, c.pods[c.rand.Intn(len(c.pods))].ip)
+	}
+}
+
+// tick moves the cluster on by one publish interval: some pods are replaced
+// and all the metrics wander.
+func (c *cluster) tick() {
+	for _, h := range c.hosts {
+		for i, p := range h.pods {
+			if c.rand.Float64() >= c.config.Churn {
+				continue
+			}
+			h.pods[i] = c.newPod(h, len(p.containers))
+			for _, container := range h.pods[i].containers {
+				c.connect(container)
+			}
+		}
+		h.cpu = c.walk(h.cpu, 100)
+		h.memory = c.walk(h.memory, memoryTotal)
+		for _, p := range h.pods {
+			for _, container := range p.containers {
+				container.cpu = c.walk(container.cpu, 100)
+				container.memory = c.walk(container.memory, memoryTotal/float64(c.config.ContainersPerHost))
+			}
+		}
+	}
+	c.pods = c.pods[:0]
+	for _, h := range c.hosts {
+		c.pods = append(c.pods, h.pods...)
+	}
+}
+
+// walk moves value randomly by up to 5% of max, keeping it within [0, max].
+func (c *cluster) walk(value, max float64) float64 {
+	value += (c.rand.Float64() - 0.5) * max / 10
+	if value < 0 {
+		return 0
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func (c *cluster) metrics(now time.Time, cpuKey string, cpu float64, memoryKey string, memory float64) report.Metrics {
+	if !c.config.Metrics {
+		return nil
+	}
+	return report.Metrics{
+		cpuKey:    report.MakeSingletonMetric(now, cpu).WithMax(100),
+		memoryKey: report.MakeSingletonMetric(now, memory).WithMax(memoryTotal),
+	}
+}
+
+// report generates the report the probe on host i would publish. The probe
+// on the first host also reports the services, as the probe doing the
+// cluster-wide kubernetes reporting would.
+func (c *cluster) report(i int, now time.Time) report.Report {
+	h := c.hosts[i]
+	rpt := report.MakeReport()
+	rpt.TS = now
+	rpt.Window = c.config.PublishInterval
+	rpt.Probes = report.ProbeSummaries{h.probeID: {
+		ID:        h.probeID,
+		Hostname:  h.name,
+		Version:   "loadtest",
+		Timestamp: now,
+	}}
+
+	rpt.Host.AddNode(report.MakeNodeWith(h.nodeID, map[string]string{
+		report.HostName:       h.name,
+		report.OS:             "linux",
+		report.HostNodeID:     h.nodeID,
+		report.ControlProbeID: h.probeID,
+		report.ScopeVersion:   "loadtest",
+	}).
+		WithTopology(report.Host).
+		WithSets(report.MakeSets().Add(report.HostLocalNetworks, report.MakeStringSet("10.0.0.0/8"))).
+		WithMetrics(c.metrics(now, report.HostCPUUsage, h.cpu, report.HostMemoryUsage, h.memory)))
+
+	for _, p := range h.pods {
+		podNodeID := report.MakePodNodeID(p.uid)
+		rpt.Pod.AddNode(report.MakeNodeWith(podNodeID, map[string]string{
+			report.KubernetesName:      p.name,
+			report.KubernetesNamespace: p.namespace,
+			report.KubernetesState:     "Running",
+			report.KubernetesIP:        p.ip,
+			report.HostNodeID:          h.nodeID,
+			report.ControlProbeID:      h.probeID,
+		}).
+			WithTopology(report.Pod).
+			WithParent(report.Service, c.serviceNodeID(p.service)))
+
+		serverEndpointID := report.MakeEndpointNodeID(h.name, "", p.ip, serverPort)
+		for j, container := range p.containers {
+			c.addContainer(&rpt, h, p, container, now)
+			if j == 0 {
+				rpt.Endpoint.AddNode(report.MakeNodeWith(serverEndpointID, map[string]string{
+					process.PID:       container.pid,
+					report.HostNodeID: h.nodeID,
+				}).WithTopology(report.Endpoint))
+			}
+			for k, peer := range container.peers {
+				remoteID := report.MakeEndpointNodeID(h.name, "", peer, serverPort)
+				localID := report.MakeEndpointNodeID(h.name, "", p.ip, strconv.Itoa(32768+j*c.config.FanOut+k))
+				rpt.Endpoint.AddNode(report.MakeNodeWith(localID, map[string]string{
+					process.PID:       container.pid,
+					report.HostNodeID: h.nodeID,
+				}).WithTopology(report.Endpoint).WithAdjacent(remoteID))
+				rpt.Endpoint.AddNode(report.MakeNode(remoteID).WithTopology(report.Endpoint))
+			}
+		}
+	}
+
+	if i == 0 {
+		for s := 0; s < c.config.Services; s++ {
+			rpt.Service.AddNode(report.MakeNodeWith(c.serviceNodeID(s), map[string]string{
+				report.KubernetesName:      fmt.Sprintf("service-%d", s),
+				report.KubernetesNamespace: fmt.Sprintf("namespace-%d", s%c.config.Namespaces),
+				report.ControlProbeID:      h.probeID,
+			}).WithTopology(report.Service))
+		}
+	}
+	return rpt
+}
+
+func (c *cluster) serviceNodeID(service int) string {
+	return report.MakeServiceNodeID(fmt.Sprintf("service-%d", service))
+}
+
+func (c *cluster) addContainer(rpt *report.Report, h *simHost, p *simPod, container *simContainer, now time.Time) {
+	imageID := fmt.Sprintf("sha256:%064x", container.image)
+	imageNodeID := report.MakeContainerImageNodeID(imageID)
+	containerNodeID := report.MakeContainerNodeID(container.id)
+
+	rpt.ContainerImage.AddNode(report.MakeNodeWith(imageNodeID, map[string]string{
+		report.DockerImageID:   imageID,
+		report.DockerImageName: fmt.Sprintf("loadtest/image-%d:latest", container.image),
+		report.HostNodeID:      h.nodeID,
+	}).
+		WithTopology(report.ContainerImage).
+		WithParent(report.Host, h.nodeID))
+
+	rpt.Container.AddNode(report.MakeNodeWith(containerNodeID, map[string]string{
+		report.DockerContainerID:                                 container.id,
+		report.DockerContainerName:                               container.name,
+		report.DockerContainerHostname:                           p.name,
+		report.DockerImageID:                                     imageID,
+		report.DockerContainerState:                              report.StateRunning,
+		report.DockerContainerStateHuman:                         report.StateRunning,
+		report.DockerLabelPrefix + "io.kubernetes.pod.uid":       p.uid,
+		report.DockerLabelPrefix + "io.kubernetes.pod.name":      p.name,
+		report.DockerLabelPrefix + "io.kubernetes.pod.namespace": p.namespace,
+		report.KubernetesNamespace:                               p.namespace,
+		report.HostNodeID:                                        h.nodeID,
+		report.ControlProbeID:                                    h.probeID,
+	}).
+		WithTopology(report.Container).
+		WithParent(report.Host, h.nodeID).
+		WithParent(report.ContainerImage, imageNodeID).
+		WithParent(report.Pod, report.MakePodNodeID(p.uid)).
+		WithMetrics(c.metrics(now, docker.CPUTotalUsage, container.cpu, docker.MemoryUsage, container.memory)))
+
+	processNodeID := report.MakeProcessNodeID(h.name, container.pid)
+	rpt.Process.AddNode(report.MakeNodeWith(processNodeID, map[string]string{
+		process.PID:        container.pid,
+		process.Name:       fmt.Sprintf("image-%d", container.image),
+		process.Cmdline:    fmt.Sprintf("/bin/image-%d --listen=:%s", container.image, serverPort),
+		docker.ContainerID: container.id,
+		report.HostNodeID:  h.nodeID,
+	}).
+		WithTopology(report.Process).
+		WithParent(report.Host, h.nodeID).
+		WithParent(report.Container, containerNodeID).
+		WithMetrics(c.metrics(now, process.CPUUsage, container.cpu, process.MemoryUsage, container.memory)))
+}
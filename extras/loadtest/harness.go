@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render/detailed"
+)
+
+// Stats summarises a set of samples. Durations are in milliseconds.
+type Stats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+func makeStats(samples []float64) Stats {
+	if len(samples) == 0 {
+		return Stats{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, s := range sorted {
+		sum += s
+	}
+	percentile := func(p float64) float64 {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return Stats{
+		Count: len(sorted),
+		Mean:  sum / float64(len(sorted)),
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+		P99:   percentile(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// TopologyResults are the measurements of a single topology.
+type TopologyResults struct {
+	RenderMillis Stats `json:"renderMillis"`
+	Nodes        Stats `json:"nodes"`
+	DiffBytes    Stats `json:"diffBytes"`
+	DiffAdds     Stats `json:"diffAdds"`
+	DiffUpdates  Stats `json:"diffUpdates"`
+	DiffRemoves  Stats `json:"diffRemoves"`
+	Errors       int   `json:"errors"`
+}
+
+// Results is what's written to the results file at the end of a run.
+type Results struct {
+	Config        Config                     `json:"config"`
+	AppVersion    string                     `json:"appVersion"`
+	Start         time.Time                  `json:"start"`
+	Duration      string                     `json:"duration"`
+	Published     int64                      `json:"published"`
+	PublishErrors int64                      `json:"publishErrors"`
+	IngestMillis  Stats                      `json:"ingestMillis"`
+	ReportBytes   Stats                      `json:"reportBytes"`
+	HeapInUse     Stats                      `json:"heapInUseBytes"`
+	Resident      Stats                      `json:"residentBytes"`
+	Topologies    map[string]TopologyResults `json:"topologies"`
+}
+
+type topologySamples struct {
+	render, nodes                     []float64
+	diffBytes, adds, updates, removes []float64
+	errors                            int
+}
+
+// harness measures the app while the probes are publishing to it.
+type harness struct {
+	config Config
+	client *http.Client
+
+	mtx        sync.Mutex
+	measuring  bool
+	ingest     []float64
+	sizes      []float64
+	heap       []float64
+	resident   []float64
+	topologies map[string]*topologySamples
+}
+
+func newHarness(config Config) *harness {
+	h := &harness{
+		config:     config,
+		client:     &http.Client{Timeout: time.Minute},
+		topologies: map[string]*topologySamples{},
+	}
+	for _, topology := range config.Topologies {
+		h.topologies[topology] = &topologySamples{}
+	}
+	return h
+}
+
+func (h *harness) get(path string, result interface{}) error {
+	resp, err := h.client.Get(h.config.App + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(result)
+}
+
+func (h *harness) appVersion() string {
+	var details xfer.Details
+	if err := h.get("/api", &details); err != nil {
+		log.Warnf("Cannot get the app version: %v", err)
+	}
+	return details.Version
+}
+
+// startMeasuring ends the warmup; samples are only recorded after it.
+func (h *harness) startMeasuring() {
+	h.mtx.Lock()
+	h.measuring = true
+	h.mtx.Unlock()
+}
+
+func (h *harness) record(f func()) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.measuring {
+		f()
+	}
+}
+
+// sample measures the app once: the ingest latency and report size of
+// every probe, its memory, and how long each topology takes to render.
+func (h *harness) sample() {
+	var probes []app.ProbeInfo
+	if err := h.get("/api/probes", &probes); err != nil {
+		log.Warnf("Cannot get probes: %v", err)
+	}
+	h.record(func() {
+		for _, p := range probes {
+			if p.Stale || p.PublishLag == 0 {
+				continue
+			}
+			h.ingest = append(h.ingest, float64(p.PublishLag)/float64(time.Millisecond))
+			h.sizes = append(h.sizes, float64(p.ReportSize))
+		}
+	})
+
+	if heap, resident, err := h.memory(); err != nil {
+		log.Warnf("Cannot get app metrics: %v", err)
+	} else {
+		h.record(func() {
+			h.heap = append(h.heap, heap)
+			h.resident = append(h.resident, resident)
+		})
+	}
+
+	for _, topology := range h.config.Topologies {
+		var result app.APITopology
+		start := time.Now()
+		err := h.get("/api/topology/"+url.PathEscape(topology), &result)
+		took := time.Since(start)
+		samples := h.topologies[topology]
+		h.record(func() {
+			if err != nil {
+				log.Warnf("Cannot render %s: %v", topology, err)
+				samples.errors++
+				return
+			}
+			samples.render = append(samples.render, float64(took)/float64(time.Millisecond))
+			samples.nodes = append(samples.nodes, float64(len(result.Nodes)))
+		})
+	}
+}
+
+// memory reads the app's heap and resident set sizes from its prometheus
+// metrics.
+func (h *harness) memory() (heap, resident float64, err error) {
+	resp, err := h.client.Get(h.config.App + "/metrics")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("GET /metrics: %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go_memstats_heap_inuse_bytes":
+			heap, _ = strconv.ParseFloat(fields[1], 64)
+		case "process_resident_memory_bytes":
+			resident, _ = strconv.ParseFloat(fields[1], 64)
+		}
+	}
+	return heap, resident, scanner.Err()
+}
+
+// watch follows the websocket of a topology until quit is closed, recording
+// the size of every diff.
+func (h *harness) watch(topology string, quit <-chan struct{}) {
+	u, err := url.Parse(h.config.App)
+	if err != nil {
+		log.Errorf("Bad app URL: %v", err)
+		return
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	u.Path = "/api/topology/" + topology + "/ws"
+	u.RawQuery = url.Values{"t": {h.config.PublishInterval.String()}}.Encode()
+
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		log.Errorf("Cannot watch %s: %v", topology, err)
+		return
+	}
+	go func() {
+		<-quit
+		conn.Close()
+	}()
+
+	samples := h.topologies[topology]
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-quit:
+			default:
+				log.Errorf("Watching %s: %v", topology, err)
+			}
+			return
+		}
+		var diff detailed.Diff
+		if err := codec.NewDecoderBytes(message, &codec.JsonHandle{}).Decode(&diff); err != nil {
+			log.Errorf("Bad diff for %s: %v", topology, err)
+			continue
+		}
+		h.record(func() {
+			samples.diffBytes = append(samples.diffBytes, float64(len(message)))
+			samples.adds = append(samples.adds, float64(len(diff.Add)))
+			samples.updates = append(samples.updates, float64(len(diff.Update)))
+			samples.removes = append(samples.removes, float64(len(diff.Remove)))
+		})
+	}
+}
+
+func (h *harness) results() Results {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	results := Results{
+		Config:       h.config,
+		IngestMillis: makeStats(h.ingest),
+		ReportBytes:  makeStats(h.sizes),
+		HeapInUse:    makeStats(h.heap),
+		Resident:     makeStats(h.resident),
+		Topologies:   map[string]TopologyResults{},
+	}
+	for topology, samples := range h.topologies {
+		results.Topologies[topology] = TopologyResults{
+			RenderMillis: makeStats(samples.render),
+			Nodes:        makeStats(samples.nodes),
+			DiffBytes:    makeStats(samples.diffBytes),
+			DiffAdds:     makeStats(samples.adds),
+			DiffUpdates:  makeStats(samples.updates),
+			DiffRemoves:  makeStats(samples.removes),
+			Errors:       samples.errors,
+		}
+	}
+	return results
+}
@@ -0,0 +1,172 @@
+// Load an app with synthetic reports from many probes, and measure how it
+// copes.
+//
+// The simulated cluster (hosts, containers, pods, services, churn and
+// connections) is described in a YAML config file; see example.yaml. Every
+// host publishes its own report, under its own probe ID, through the same
+// client the probes use. Meanwhile the app's ingest latency, render latency
+// per topology, memory and websocket diffs are sampled, and summarised in a
+// JSON results file at the end of the run.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/probe/appclient"
+	"github.com/weaveworks/scope/report"
+)
+
+// publisher publishes the reports of all the simulated hosts.
+type publisher struct {
+	cluster   *cluster
+	clients   []appclient.AppClient
+	published int64
+	errors    int64
+}
+
+func newPublisher(config Config, c *cluster) (*publisher, error) {
+	target, err := url.Parse(config.App)
+	if err != nil {
+		return nil, err
+	}
+	p := &publisher{cluster: c}
+	for _, h := range c.hosts {
+		client, err := appclient.NewAppClient(appclient.ProbeConfig{
+			Token:          config.Token,
+			ProbeID:        h.probeID,
+			ProbeVersion:   "loadtest",
+			CompactReports: config.Compact,
+		}, target.Host, *target, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.clients = append(p.clients, client)
+	}
+	return p, nil
+}
+
+// publish generates and publishes the report of every host, concurrently.
+func (p *publisher) publish(now time.Time) {
+	var wg sync.WaitGroup
+	for i, client := range p.clients {
+		wg.Add(1)
+		go func(i int, client appclient.AppClient) {
+			defer wg.Done()
+			rpt := p.cluster.report(i, now)
+			var (
+				buf *bytes.Buffer
+				err error
+			)
+			contentType := client.ReportContentType()
+			if contentType == report.CompactContentType {
+				buf, err = rpt.WriteCompact()
+			} else {
+				buf, err = rpt.WriteBinary()
+			}
+			if err == nil {
+				err = client.Publish(buf, contentType, false)
+			}
+			if err != nil {
+				log.Errorf("Publishing for %s: %v", p.cluster.hosts[i].name, err)
+				atomic.AddInt64(&p.errors, 1)
+				return
+			}
+			atomic.AddInt64(&p.published, 1)
+		}(i, client)
+	}
+	wg.Wait()
+}
+
+func (p *publisher) stop() {
+	for _, client := range p.clients {
+		client.Stop()
+	}
+}
+
+func main() {
+	var (
+		configFile  = flag.String("config", "", "YAML file describing the load; defaults are used if empty")
+		resultsFile = flag.String("results", "loadtest-results.json", "file to write the results to")
+	)
+	flag.Parse()
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := newCluster(config)
+	pub, err := newPublisher(config, c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := newHarness(config)
+	results := Results{AppVersion: h.appVersion(), Start: time.Now()}
+	log.Infof("Loading %s (version %q) with %d probes", config.App, results.AppVersion, config.Hosts)
+
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, topology := range config.Topologies {
+		wg.Add(1)
+		go func(topology string) {
+			defer wg.Done()
+			h.watch(topology, quit)
+		}(topology)
+	}
+
+	publishTicker := time.NewTicker(config.PublishInterval)
+	defer publishTicker.Stop()
+	sampleTicker := time.NewTicker(config.SampleInterval)
+	defer sampleTicker.Stop()
+	warmup := time.After(config.Warmup)
+	done := time.After(config.Warmup + config.Duration)
+
+	pub.publish(time.Now())
+loop:
+	for {
+		select {
+		case <-publishTicker.C:
+			c.tick()
+			pub.publish(time.Now())
+		case <-sampleTicker.C:
+			// Sampling takes a while under load; don't hold up publishing.
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.sample()
+			}()
+		case <-warmup:
+			log.Info("Warmup done, measuring")
+			h.startMeasuring()
+		case <-done:
+			break loop
+		}
+	}
+	close(quit)
+	pub.stop()
+	wg.Wait()
+
+	measured := h.results()
+	measured.AppVersion = results.AppVersion
+	measured.Start = results.Start
+	measured.Duration = time.Since(results.Start).String()
+	measured.Published = atomic.LoadInt64(&pub.published)
+	measured.PublishErrors = atomic.LoadInt64(&pub.errors)
+	buf, err := json.MarshalIndent(measured, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*resultsFile, buf, 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("Results written to %s", *resultsFile)
+}
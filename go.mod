@@ -90,6 +90,7 @@ require (
 	golang.org/x/tools v0.0.0-20190424220101-1e8e1cfdf96b
 	google.golang.org/grpc v1.19.0
 	gopkg.in/inf.v0 v0.9.0 // indirect
+	gopkg.in/yaml.v2 v2.2.5
 	k8s.io/api v0.0.0-20181204000039-89a74a8d264d
 	k8s.io/apimachinery v0.0.0-20181127025237-2b1284ed4c93
 	k8s.io/cli-runtime v0.0.0-20181204004549-a04da5c88c07 // indirect
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"net/rpc"
 	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -18,6 +20,7 @@ import (
 	"github.com/ugorji/go/codec"
 
 	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
 )
 
 const (
@@ -32,7 +35,8 @@ type AppClient interface {
 	ControlConnection()
 	PipeConnection(string, xfer.Pipe)
 	PipeClose(string) error
-	Publish(io.Reader, bool) error
+	Publish(r io.Reader, contentType string, shortcut bool) error
+	ReportContentType() string
 	Target() url.URL
 	ReTarget(url.URL)
 	Stop()
@@ -58,7 +62,8 @@ type appClient struct {
 
 	// For publish
 	publishLoop sync.Once
-	readers     chan io.Reader
+	readers     chan publication
+	compact     int32 // atomic; whether the app may accept compact reports
 
 	// For controls
 	control xfer.ControlHandler
@@ -66,7 +71,10 @@ type appClient struct {
 
 // NewAppClient makes a new appClient.
 func NewAppClient(pc ProbeConfig, hostname string, target url.URL, control xfer.ControlHandler) (AppClient, error) {
-	httpTransport := pc.getHTTPTransport(hostname)
+	httpTransport, err := pc.getHTTPTransport(hostname)
+	if err != nil {
+		return nil, err
+	}
 	httpClient := cleanhttp.DefaultClient()
 	httpClient.Transport = httpTransport
 	httpClient.Timeout = httpClientTimeout
@@ -82,11 +90,25 @@ func NewAppClient(pc ProbeConfig, hostname string, target url.URL, control xfer.
 			HandshakeTimeout: httpClientTimeout,
 		},
 		conns:   map[string]xfer.Websocket{},
-		readers: make(chan io.Reader, 2),
+		readers: make(chan publication, 2),
+		compact: boolToInt32(pc.CompactReports),
 		control: control,
 	}, nil
 }
 
+// publication is a report waiting to be published, with its encoding.
+type publication struct {
+	io.Reader
+	contentType string
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func (c *appClient) url(path string) string {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
@@ -284,14 +306,14 @@ func (c *appClient) ControlConnection() {
 	}()
 }
 
-func (c *appClient) publish(r io.Reader) error {
+func (c *appClient) publish(p publication) error {
 	url := c.url("/api/report")
-	req, err := c.ProbeConfig.authorizedRequest("POST", url, r)
+	req, err := c.ProbeConfig.authorizedRequest("POST", url, p)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Content-Type", p.contentType)
 	// req.Header.Set("Content-Type", "application/binary") // TODO: we should use http.DetectContentType(..) on the gob'ed
 
 	// Make sure this request is cancelled when we stop the client
@@ -309,32 +331,53 @@ func (c *appClient) publish(r io.Reader) error {
 	})
 	if resp.StatusCode != http.StatusOK {
 		text, _ := ioutil.ReadAll(resp.Body)
+		if p.contentType == report.CompactContentType && unsupportedContentType(resp.StatusCode, string(text)) {
+			// Older apps only know about msgpack and JSON. This report is
+			// lost, the next ones will be fine.
+			log.Infof("App %s doesn't support compact reports, falling back to msgpack", c.hostname)
+			atomic.StoreInt32(&c.compact, 0)
+		}
 		return fmt.Errorf(resp.Status + ": " + string(text))
 	}
 	return nil
 }
 
+func unsupportedContentType(status int, text string) bool {
+	return status == http.StatusUnsupportedMediaType ||
+		(status == http.StatusBadRequest && strings.Contains(text, "Unsupported Content-Type"))
+}
+
+// ReportContentType returns the encoding in which reports should be
+// published to the app.
+func (c *appClient) ReportContentType() string {
+	if atomic.LoadInt32(&c.compact) == 1 {
+		return report.CompactContentType
+	}
+	return "application/msgpack"
+}
+
 func (c *appClient) startPublishing() {
 	go func() {
 		log.Infof("Publish loop for %s starting", c.hostname)
 		defer log.Infof("Publish loop for %s exiting", c.hostname)
 		c.doWithBackoff("publish", func() (bool, error) {
-			r := <-c.readers
-			if r == nil {
+			p, ok := <-c.readers
+			if !ok {
 				return true, nil
 			}
-			return false, c.publish(r)
+			return false, c.publish(p)
 		})
 	}()
 }
 
 // Publish implements Publisher
-func (c *appClient) Publish(r io.Reader, shortcut bool) error {
+func (c *appClient) Publish(r io.Reader, contentType string, shortcut bool) error {
 	// Lazily start the background publishing loop.
 	c.publishLoop.Do(c.startPublishing)
+	p := publication{Reader: r, contentType: contentType}
 	// enqueue report
 	select {
-	case c.readers <- r:
+	case c.readers <- p:
 	default:
 		log.Warnf("Dropping report to %s", c.hostname)
 		if shortcut {
@@ -347,7 +390,7 @@ func (c *appClient) Publish(r io.Reader, shortcut bool) error {
 		case <-c.readers:
 		default:
 		}
-		c.readers <- r
+		c.readers <- p
 	}
 	return nil
 }
@@ -102,7 +102,7 @@ func TestAppClientPublish(t *testing.T) {
 	// First few reports might be dropped as the client is spinning up.
 	for i := 0; i < 10; i++ {
 		buf, _ := rpt.WriteBinary()
-		if err := p.Publish(buf, false); err != nil {
+		if err := p.Publish(buf, "application/msgpack", false); err != nil {
 			t.Error(err)
 		}
 		time.Sleep(10 * time.Millisecond)
@@ -115,6 +115,53 @@ func TestAppClientPublish(t *testing.T) {
 	}
 }
 
+func TestAppClientCompactFallback(t *testing.T) {
+	received := make(chan string, 10)
+	// Like older apps, which only know about msgpack and JSON.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		received <- contentType
+		if contentType != "application/msgpack" {
+			http.Error(w, "Unsupported Content-Type: "+contentType, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAppClient(ProbeConfig{CompactReports: true}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	rpt := report.MakeReport()
+	if have := p.ReportContentType(); have != report.CompactContentType {
+		t.Fatalf("content type %q, want %q", have, report.CompactContentType)
+	}
+	buf, _ := rpt.WriteCompact()
+	if err := p.Publish(buf, p.ReportContentType(), false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	deadline := time.Now().Add(time.Second)
+	for p.ReportContentType() != "application/msgpack" {
+		if time.Now().After(deadline) {
+			t.Fatalf("client didn't fall back to msgpack")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestAppClientDetails(t *testing.T) {
 	var (
 		id      = "foobarbaz"
@@ -207,7 +254,7 @@ func TestStop(t *testing.T) {
 			done = true
 		default:
 			buf, _ := rpt.WriteBinary()
-			if err := p.Publish(buf, false); err != nil {
+			if err := p.Publish(buf, "application/msgpack", false); err != nil {
 				t.Error(err)
 			}
 			time.Sleep(10 * time.Millisecond)
@@ -220,3 +267,24 @@ func TestStop(t *testing.T) {
 	// Let the server go so that the test can end
 	close(stopHanging)
 }
+
+func TestHTTPTransportTLSFiles(t *testing.T) {
+	for _, pc := range []ProbeConfig{
+		{TLSCertFile: "/does/not/exist.crt", TLSKeyFile: "/does/not/exist.key"},
+		{TLSCAFile: "/does/not/exist.crt"},
+	} {
+		if _, err := pc.getHTTPTransport("localhost"); err == nil {
+			t.Errorf("Expected error for %+v", pc)
+		}
+	}
+	if _, err := (ProbeConfig{TLSCertFile: "/does/not/exist.crt"}).CertificateIdentity(); err == nil {
+		t.Error("Expected error reading missing certificate")
+	}
+	transport, err := ProbeConfig{}.getHTTPTransport("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := transport.TLSClientConfig.ServerName; have != "localhost" {
+		t.Errorf("want %q, have %q", "localhost", have)
+	}
+}
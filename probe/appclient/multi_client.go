@@ -160,14 +160,25 @@ func (c *multiClient) Publish(r report.Report) error {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 
-	buf, err := r.WriteBinary()
-	if err != nil {
-		return err
-	}
-
+	// Apps may accept different encodings; encode the report once for each.
+	encoded := map[string]*bytes.Buffer{}
 	errs := []string{}
 	for _, c := range c.clients {
-		if err := c.Publish(bytes.NewReader(buf.Bytes()), r.Shortcut); err != nil {
+		contentType := c.ReportContentType()
+		buf, ok := encoded[contentType]
+		if !ok {
+			var err error
+			if contentType == report.CompactContentType {
+				buf, err = r.WriteCompact()
+			} else {
+				buf, err = r.WriteBinary()
+			}
+			if err != nil {
+				return err
+			}
+			encoded[contentType] = buf
+		}
+		if err := c.Publish(bytes.NewReader(buf.Bytes()), contentType, r.Shortcut); err != nil {
 			errs = append(errs, err.Error())
 		}
 	}
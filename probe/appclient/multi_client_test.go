@@ -37,11 +37,15 @@ func (c *mockClient) Stop() {
 	c.stopped++
 }
 
-func (c *mockClient) Publish(io.Reader, bool) error {
+func (c *mockClient) Publish(io.Reader, string, bool) error {
 	c.publish++
 	return nil
 }
 
+func (c *mockClient) ReportContentType() string {
+	return "application/msgpack"
+}
+
 func (c *mockClient) PipeConnection(_ string, _ xfer.Pipe) {}
 func (c *mockClient) PipeClose(_ string) error             { return nil }
 
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
@@ -36,6 +37,17 @@ type ProbeConfig struct {
 	ProbeVersion string
 	ProbeID      string
 	Insecure     bool
+
+	// TLSCertFile and TLSKeyFile hold the client certificate presented to
+	// the app, for mutual TLS. TLSCAFile holds the CA certificates the app's
+	// certificate is verified against, instead of the well-known ones.
+	TLSCertFile string
+	TLSKeyFile  string
+	TLSCAFile   string
+
+	// CompactReports makes the probe publish reports in the compact
+	// encoding, falling back to msgpack for apps which don't support it.
+	CompactReports bool
 }
 
 func (pc ProbeConfig) authorizeHeaders(headers http.Header) {
@@ -58,7 +70,7 @@ func (pc ProbeConfig) authorizedRequest(method string, urlStr string, body io.Re
 	return req, err
 }
 
-func (pc ProbeConfig) getHTTPTransport(hostname string) *http.Transport {
+func (pc ProbeConfig) getHTTPTransport(hostname string) (*http.Transport, error) {
 	transport := cleanhttp.DefaultTransport()
 	transport.DialContext = (&net.Dialer{
 		Timeout:   dialTimeout,
@@ -67,10 +79,42 @@ func (pc ProbeConfig) getHTTPTransport(hostname string) *http.Transport {
 	if pc.Insecure {
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	} else {
+		rootCAs := certPool
+		if pc.TLSCAFile != "" {
+			pem, err := ioutil.ReadFile(pc.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA certificates: %v", err)
+			}
+			rootCAs = x509.NewCertPool()
+			if !rootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no CA certificates found in %s", pc.TLSCAFile)
+			}
+		}
 		transport.TLSClientConfig = &tls.Config{
-			RootCAs:    certPool,
+			RootCAs:    rootCAs,
 			ServerName: hostname,
 		}
 	}
-	return transport
+	if pc.TLSCertFile != "" || pc.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(pc.TLSCertFile, pc.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	return transport, nil
+}
+
+// CertificateIdentity returns the common name of the client certificate,
+// which the app uses as the probe's ID when it verifies client certificates.
+func (pc ProbeConfig) CertificateIdentity() (string, error) {
+	cert, err := tls.LoadX509KeyPair(pc.TLSCertFile, pc.TLSKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("loading client certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return "", fmt.Errorf("parsing client certificate: %v", err)
+	}
+	return leaf.Subject.CommonName, nil
 }
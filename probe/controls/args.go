@@ -0,0 +1,37 @@
+package controls
+
+import (
+	"github.com/weaveworks/scope/report"
+)
+
+// Arguments of the controls built on this package, and on the parsers of
+// common/xfer, for the report.Control of each.
+var (
+	// FileCopyArgs are parsed by xfer.ParseFileCopyOptions
+	FileCopyArgs = []report.ControlArg{
+		{Name: "path", Human: "Path", Type: report.StringArg, Required: true},
+	}
+
+	// PortForwardArgs are parsed by xfer.ParseForwardPort
+	PortForwardArgs = []report.ControlArg{
+		{Name: "port", Human: "Port", Type: report.IntArg, Min: report.ArgLimit(1), Max: report.ArgLimit(65535), Required: true},
+	}
+
+	// LogArgs are parsed by xfer.ParseLogOptions, but for previous, which
+	// only some controls support
+	LogArgs = []report.ControlArg{
+		{Name: "follow", Human: "Follow", Type: report.BoolArg, Default: "true"},
+		{Name: "since", Human: "Since (e.g. 10m)", Type: report.StringArg},
+		{Name: "tail", Human: "Lines from the end", Type: report.IntArg, Min: report.ArgLimit(0)},
+	}
+	PreviousLogArg = report.ControlArg{Name: "previous", Human: "Previous container", Type: report.BoolArg}
+)
+
+// Args joins arguments into a new slice, to declare those of a control.
+func Args(args ...[]report.ControlArg) []report.ControlArg {
+	var result []report.ControlArg
+	for _, a := range args {
+		result = append(result, a...)
+	}
+	return result
+}
@@ -0,0 +1,289 @@
+package controls
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+// MaxFileCopySize is the most the file copy controls copy, in bytes: the
+// size of a downloaded archive, or of the files in an uploaded one.
+var MaxFileCopySize int64 = 100 << 20
+
+// DownloadProgressInterval is how often the progress of downloads is
+// updated. Exported for testing.
+var DownloadProgressInterval = time.Second
+
+// Download streams the tar archive written by download through a new binary
+// pipe. The control fails if download fails before writing anything, e.g.
+// as the path doesn't exist; later errors close the pipe early. The pipe
+// carries nothing but the archive, so the download is reported as a job of
+// jobs, if not nil, whose progress is the bytes sent so far, and whose
+// outcome tells whether the archive is complete. The context of download is
+// cancelled when the pipe is closed, the job is cancelled, or the archive
+// exceeds MaxFileCopySize.
+func Download(c PipeClient, jobs *Jobs, req xfer.Request, opts xfer.FileCopyOptions, download func(ctx context.Context, w io.Writer) error) xfer.Response {
+	id, pipe, err := NewBinaryPipe(c, req.AppID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	local, _ := pipe.Ends()
+	ctx, cancel := context.WithCancel(context.Background())
+	pipe.OnClose(cancel)
+
+	w := &limitedWriter{w: local, limit: MaxFileCopySize, cancel: cancel, started: make(chan struct{})}
+	var (
+		downloadErr error
+		done        = make(chan struct{})
+	)
+	go func() {
+		err := download(ctx, w)
+		if w.err != nil {
+			err = w.err
+		} else if err == nil && ctx.Err() != nil {
+			err = fmt.Errorf("pipe closed after %d bytes", w.written())
+		}
+		if err == nil {
+			log.Infof("Downloaded %s from %s (%d bytes)", opts.Path, req.NodeID, w.written())
+		} else if ctx.Err() == nil || w.err != nil {
+			log.Errorf("Error downloading %s from %s: %v", opts.Path, req.NodeID, err)
+		}
+		downloadErr = err
+		close(done)
+		pipe.Close()
+	}()
+
+	select {
+	case <-w.started:
+	case <-done:
+		if downloadErr != nil {
+			return xfer.ResponseError(downloadErr)
+		}
+	}
+	res := xfer.Response{
+		Pipe:       id,
+		BinaryPipe: true,
+		Filename:   opts.Filename(),
+	}
+	if jobs != nil {
+		res.Job = jobs.Start(req, func(ctx context.Context, progress func(string, ...interface{})) error {
+			ticker := time.NewTicker(DownloadProgressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					progress("%d bytes sent", w.written())
+				case <-ctx.Done():
+					pipe.Close()
+					<-done
+					return ctx.Err()
+				case <-done:
+					progress("%d bytes sent", w.written())
+					return downloadErr
+				}
+			}
+		}).Job
+	}
+	return res
+}
+
+// limitedWriter fails writes beyond its limit, cancelling the download.
+type limitedWriter struct {
+	w       io.Writer
+	n       int64
+	limit   int64
+	err     error
+	cancel  func()
+	once    sync.Once
+	started chan struct{}
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	l.once.Do(func() { close(l.started) })
+	if l.written()+int64(len(p)) > l.limit {
+		l.err = fmt.Errorf("archive larger than the limit of %d bytes", l.limit)
+		l.cancel()
+		return 0, l.err
+	}
+	n, err := l.w.Write(p)
+	atomic.AddInt64(&l.n, int64(n))
+	return n, err
+}
+
+// written is the number of bytes written so far.
+func (l *limitedWriter) written() int64 {
+	return atomic.LoadInt64(&l.n)
+}
+
+// Upload receives a tar archive through a new binary pipe and passes it to
+// upload, reporting progress back through the pipe, a line per file. The
+// archive is checked and re-encoded on the way: names, and the targets of
+// links, must stay within the directory uploaded to without going through
+// the archive's symbolic links, the files must not exceed MaxFileCopySize in
+// total, and the archive upload reads ends where the uploaded one does. The
+// context of upload is cancelled when the pipe is closed.
+func Upload(c PipeClient, req xfer.Request, opts xfer.FileCopyOptions, upload func(ctx context.Context, r io.Reader) error) xfer.Response {
+	id, pipe, err := NewBinaryPipe(c, req.AppID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	local, _ := pipe.Ends()
+	ctx, cancel := context.WithCancel(context.Background())
+	pipe.OnClose(cancel)
+
+	go func() {
+		defer pipe.Close()
+		printf := func(format string, args ...interface{}) {
+			fmt.Fprintf(local, format+"\n", args...)
+		}
+
+		r, w := io.Pipe()
+		var (
+			files  int
+			size   int64
+			copied = make(chan error, 1)
+		)
+		go func() {
+			var err error
+			files, size, err = copyArchive(w, local, printf)
+			w.CloseWithError(err)
+			copied <- err
+		}()
+		err := upload(ctx, r)
+		r.Close()
+		if err == nil {
+			// upload read the archive to its end, so the copy is done
+			err = <-copied
+		}
+
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("Error uploading to %s on %s: %v", opts.Path, req.NodeID, err)
+				printf("error: %v", err)
+			}
+			return
+		}
+		log.Infof("Uploaded %d files (%d bytes) to %s on %s", files, size, opts.Path, req.NodeID)
+		printf("uploaded %d files (%d bytes) to %s", files, size, opts.Path)
+	}()
+
+	return xfer.Response{
+		Pipe:       id,
+		BinaryPipe: true,
+	}
+}
+
+// DownloadCommand is the command writing an archive of the path to download
+// to its standard output, where files are copied by running tar. tar
+// archives what it can find, so the path is checked first for a missing one
+// to fail the control rather than download an empty archive.
+func DownloadCommand(opts xfer.FileCopyOptions) []string {
+	const script = `cd "$1" || exit 1; [ -e "$2" ] || { echo "$1/$2: No such file or directory" >&2; exit 1; }; exec tar cf - "$2"`
+	return []string{"/bin/sh", "-c", script, "sh", opts.Dir(), opts.Base()}
+}
+
+// UploadCommand is the command extracting the archive on its standard input
+// into the directory uploaded to.
+func UploadCommand(opts xfer.FileCopyOptions) []string {
+	return []string{"tar", "xf", "-", "-C", opts.Path}
+}
+
+// copyArchive copies the tar archive from src to dst, up to its end.
+func copyArchive(dst io.Writer, src io.Reader, printf func(string, ...interface{})) (int, int64, error) {
+	var (
+		tr    = tar.NewReader(src)
+		tw    = tar.NewWriter(dst)
+		links = archiveLinks{}
+		files int
+		size  int64
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return files, size, err
+		}
+		if !links.add(hdr) {
+			return files, size, fmt.Errorf("%s: outside the directory uploaded to", hdr.Name)
+		}
+		if size += hdr.Size; size > MaxFileCopySize {
+			return files, size, fmt.Errorf("files larger than the limit of %d bytes", MaxFileCopySize)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return files, size, err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return files, size, err
+		}
+		files++
+		printf("%s (%d bytes)", hdr.Name, hdr.Size)
+	}
+	return files, size, tw.Close()
+}
+
+// archiveLinks are the symbolic links of an archive, by name. Once
+// extracted, a link can lead anywhere, so the names in the archive must not
+// go through any of them.
+type archiveLinks map[string]bool
+
+// add checks that the entry stays within the directory uploaded to: its
+// name, the file it is a hard link to, or the target of a symbolic link,
+// relative to the link's directory. It records symbolic links.
+func (l archiveLinks) add(hdr *tar.Header) bool {
+	name, ok := l.resolve(hdr.Name)
+	if !ok || l[name] {
+		return false
+	}
+	switch hdr.Typeflag {
+	case tar.TypeLink:
+		target, ok := l.resolve(hdr.Linkname)
+		if !ok || l[target] {
+			return false
+		}
+	case tar.TypeSymlink:
+		if name == "" || path.IsAbs(hdr.Linkname) {
+			return false
+		}
+		if _, ok := l.resolve(path.Dir(name) + "/" + hdr.Linkname); !ok {
+			return false
+		}
+		l[name] = true
+	}
+	return true
+}
+
+// resolve cleans the relative name, unless it leads outside the directory
+// uploaded to, or through a symbolic link.
+func (l archiveLinks) resolve(name string) (string, bool) {
+	if path.IsAbs(name) {
+		return "", false
+	}
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		if len(parts) > 0 && l[strings.Join(parts, "/")] {
+			return "", false
+		}
+		if part != ".." {
+			parts = append(parts, part)
+		} else if len(parts) > 0 {
+			parts = parts[:len(parts)-1]
+		} else {
+			return "", false
+		}
+	}
+	return strings.Join(parts, "/"), true
+}
@@ -0,0 +1,321 @@
+package controls_test
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+// capturePipes makes the file copy controls use a pipe the test can read.
+func capturePipes(t *testing.T) (io.ReadWriter, func()) {
+	pipe := xfer.NewBinaryPipe()
+	_, remote := pipe.Ends()
+	old := controls.NewBinaryPipe
+	controls.NewBinaryPipe = func(controls.PipeClient, string) (string, xfer.Pipe, error) {
+		return "pipe", pipe, nil
+	}
+	return remote, func() { controls.NewBinaryPipe = old }
+}
+
+func TestDownload(t *testing.T) {
+	remote, restore := capturePipes(t)
+	defer restore()
+
+	opts := xfer.FileCopyOptions{Path: "/tmp/dump"}
+	res := controls.Download(controls.DummyPipeClient{}, nil, xfer.Request{}, opts, func(_ context.Context, w io.Writer) error {
+		_, err := w.Write([]byte("archive"))
+		return err
+	})
+	want := xfer.Response{Pipe: "pipe", BinaryPipe: true, Filename: "dump.tar"}
+	if res != want {
+		t.Fatalf("Expected %+v, got %+v", want, res)
+	}
+	if buf, _ := ioutil.ReadAll(remote); string(buf) != "archive" {
+		t.Errorf("Unexpected download %q", buf)
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	_, restore := capturePipes(t)
+	defer restore()
+
+	res := controls.Download(controls.DummyPipeClient{}, nil, xfer.Request{}, xfer.FileCopyOptions{Path: "/nonexistent"}, func(context.Context, io.Writer) error {
+		return fmt.Errorf("no such file")
+	})
+	if res.Error != "no such file" {
+		t.Errorf("Expected an error, got %+v", res)
+	}
+
+	remote, restore := capturePipes(t)
+	defer restore()
+	defer func(old int64) { controls.MaxFileCopySize = old }(controls.MaxFileCopySize)
+	controls.MaxFileCopySize = 10
+	cancelled := make(chan bool, 1)
+	controls.Download(controls.DummyPipeClient{}, nil, xfer.Request{}, xfer.FileCopyOptions{Path: "/big"}, func(ctx context.Context, w io.Writer) error {
+		var err error
+		for err == nil {
+			_, err = w.Write([]byte("0123456"))
+		}
+		cancelled <- ctx.Err() != nil
+		return err
+	})
+	if buf, _ := ioutil.ReadAll(remote); string(buf) != "0123456" {
+		t.Errorf("Expected the download to stop at the limit, got %q", buf)
+	}
+	if !<-cancelled {
+		t.Errorf("Expected the download to be cancelled")
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	remote, restore := capturePipes(t)
+	defer restore()
+	defer func(old time.Duration) { controls.DownloadProgressInterval = old }(controls.DownloadProgressInterval)
+	controls.DownloadProgressInterval = 10 * time.Millisecond
+
+	var (
+		registry = controls.NewDefaultHandlerRegistry()
+		jobs     = controls.NewJobs("probe", registry, nil)
+		next     = make(chan struct{})
+	)
+	defer jobs.Stop()
+	job := func(id string) report.ControlJob {
+		rpt, _ := jobs.Report()
+		return rpt.ControlJobs[id]
+	}
+	waitFor := func(id, progress string) report.ControlJob {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if j := job(id); j.Progress == progress {
+				return j
+			}
+		}
+		t.Fatalf("Timed out waiting for progress %q, got %+v", progress, job(id))
+		return report.ControlJob{}
+	}
+
+	req := xfer.Request{NodeID: "node", Control: "download"}
+	res := controls.Download(controls.DummyPipeClient{}, jobs, req, xfer.FileCopyOptions{Path: "/tmp/dump"}, func(_ context.Context, w io.Writer) error {
+		w.Write([]byte("abc"))
+		<-next
+		_, err := w.Write([]byte("de"))
+		return err
+	})
+	if res.Pipe != "pipe" || res.Job == "" {
+		t.Fatalf("Expected a pipe and a job, got %+v", res)
+	}
+	go ioutil.ReadAll(remote)
+	waitFor(res.Job, "3 bytes sent")
+	close(next)
+	for deadline := time.Now().Add(5 * time.Second); !job(res.Job).Done() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if j := job(res.Job); j.State != report.JobSucceeded || j.Progress != "5 bytes sent" {
+		t.Errorf("Expected the download to succeed with 5 bytes sent, got %+v", j)
+	}
+
+	// Cancelling the job stops the download
+	remote, restore = capturePipes(t)
+	defer restore()
+	cancelled := make(chan bool, 1)
+	res = controls.Download(controls.DummyPipeClient{}, jobs, req, xfer.FileCopyOptions{Path: "/tmp/dump"}, func(ctx context.Context, w io.Writer) error {
+		w.Write([]byte("abc"))
+		<-ctx.Done()
+		cancelled <- true
+		return ctx.Err()
+	})
+	go ioutil.ReadAll(remote)
+	if resp := registry.HandleControlRequest(xfer.Request{NodeID: res.Job, Control: report.CancelJob}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the download to be cancelled")
+	}
+	for deadline := time.Now().Add(5 * time.Second); !job(res.Job).Done() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if j := job(res.Job); j.State != report.JobCancelled {
+		t.Errorf("Expected the job to be cancelled, got %+v", j)
+	}
+}
+
+func makeArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func upload(t *testing.T, archive []byte) (map[string]string, []string) {
+	remote, restore := capturePipes(t)
+	defer restore()
+
+	uploaded := map[string]string{}
+	res := controls.Upload(controls.DummyPipeClient{}, xfer.Request{}, xfer.FileCopyOptions{Path: "/tmp"}, func(_ context.Context, r io.Reader) error {
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			content, _ := ioutil.ReadAll(tr)
+			uploaded[hdr.Name] = string(content)
+		}
+		// Only returns once the archive ends
+		_, err := ioutil.ReadAll(r)
+		return err
+	})
+	if want := (xfer.Response{Pipe: "pipe", BinaryPipe: true}); res != want {
+		t.Fatalf("Expected %+v, got %+v", want, res)
+	}
+
+	// The archive isn't followed by EOF, as the pipe stays open
+	go remote.Write(archive)
+	var progress []string
+	for lines := bufio.NewScanner(remote); lines.Scan(); {
+		progress = append(progress, lines.Text())
+	}
+	return uploaded, progress
+}
+
+func TestUpload(t *testing.T) {
+	uploaded, progress := upload(t, makeArchive(t, map[string]string{"config.yaml": "foo: bar\n"}))
+	if uploaded["config.yaml"] != "foo: bar\n" {
+		t.Errorf("Unexpected upload %v", uploaded)
+	}
+	want := []string{"config.yaml (9 bytes)", "uploaded 1 files (9 bytes) to /tmp"}
+	if strings.Join(progress, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected progress %q, got %q", want, progress)
+	}
+
+	_, progress = upload(t, makeArchive(t, map[string]string{"../etc/passwd": "root::0:0::/:/bin/sh\n"}))
+	if len(progress) != 1 || !strings.HasPrefix(progress[0], "error: ../etc/passwd: outside") {
+		t.Errorf("Expected the upload to be refused, got %q", progress)
+	}
+}
+
+func TestUploadLinks(t *testing.T) {
+	for _, tc := range []struct {
+		headers []tar.Header
+		refused string
+	}{
+		{
+			headers: []tar.Header{
+				{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+			},
+			refused: "etc",
+		},
+		{
+			headers: []tar.Header{
+				{Name: "a/etc", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+			},
+			refused: "a/etc",
+		},
+		{
+			// A link within the directory, but the file goes through it
+			headers: []tar.Header{
+				{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "."},
+				{Name: "d/passwd", Typeflag: tar.TypeReg},
+			},
+			refused: "d/passwd",
+		},
+		{
+			headers: []tar.Header{
+				{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "."},
+				{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "d/d/../.."},
+			},
+			refused: "up",
+		},
+		{
+			headers: []tar.Header{
+				{Name: "a/up", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				{Name: "up", Typeflag: tar.TypeLink, Linkname: "a/up"},
+			},
+			refused: "up",
+		},
+		{
+			headers: []tar.Header{
+				{Name: "config", Typeflag: tar.TypeDir},
+				{Name: "current", Typeflag: tar.TypeSymlink, Linkname: "config/../config"},
+				{Name: "config/app.yaml", Typeflag: tar.TypeReg},
+			},
+		},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range tc.headers {
+			hdr.Mode = 0644
+			if err := tw.WriteHeader(&hdr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tw.Close()
+
+		_, progress := upload(t, buf.Bytes())
+		last := progress[len(progress)-1]
+		if tc.refused == "" {
+			if !strings.HasPrefix(last, "uploaded") {
+				t.Errorf("Expected %v to be uploaded, got %q", tc.headers, progress)
+			}
+		} else if want := "error: " + tc.refused + ": outside"; !strings.HasPrefix(last, want) {
+			t.Errorf("Expected %q, got %q", want, progress)
+		}
+	}
+}
+
+func TestDownloadCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "dump"), []byte("heap"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := controls.DownloadCommand(xfer.FileCopyOptions{Path: filepath.Join(dir, "dump")})
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	tr := tar.NewReader(&stdout)
+	if hdr, err := tr.Next(); err != nil || hdr.Name != "dump" {
+		t.Errorf("Expected an archive of dump, got %v, %v", hdr, err)
+	}
+
+	// Missing paths fail without any output
+	stdout.Reset()
+	command = controls.DownloadCommand(xfer.FileCopyOptions{Path: filepath.Join(dir, "nonexistent")})
+	cmd = exec.Command(command[0], command[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err == nil || stdout.Len() != 0 {
+		t.Errorf("Expected an error and no output, got %v and %d bytes", err, stdout.Len())
+	}
+}
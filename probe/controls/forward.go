@@ -0,0 +1,66 @@
+package controls
+
+import (
+	"io"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+// Forward forwards the connections opened through a new binary pipe to
+// target, dialled with dial. The control fails if target can't be dialled to
+// begin with. Connections are forwarded until the pipe is closed.
+func Forward(c PipeClient, req xfer.Request, target string, dial func() (net.Conn, error)) xfer.Response {
+	conn, err := dial()
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	conn.Close()
+
+	id, pipe, err := NewBinaryPipe(c, req.AppID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	local, _ := pipe.Ends()
+	mux := xfer.NewMux(local)
+
+	go func() {
+		defer pipe.Close()
+		log.Infof("Forwarding connections to %s of %s", target, req.NodeID)
+		for {
+			conn, err := mux.Accept()
+			if err != nil {
+				log.Infof("Stopped forwarding connections to %s of %s", target, req.NodeID)
+				return
+			}
+			go forwardConn(conn, target, dial)
+		}
+	}()
+	return xfer.Response{
+		Pipe:       id,
+		BinaryPipe: true,
+		Forward:    target,
+	}
+}
+
+func forwardConn(conn net.Conn, target string, dial func() (net.Conn, error)) {
+	defer conn.Close()
+	dst, err := dial()
+	if err != nil {
+		log.Warningf("Error forwarding connection to %s: %v", target, err)
+		return
+	}
+	defer dst.Close()
+
+	done := make(chan struct{}, 2)
+	copyConn := func(w io.Writer, r io.Reader) {
+		io.Copy(w, r)
+		done <- struct{}{}
+	}
+	go copyConn(dst, conn)
+	go copyConn(conn, dst)
+	// Either side finishing closes both
+	<-done
+}
@@ -0,0 +1,66 @@
+package controls_test
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+)
+
+func TestForward(t *testing.T) {
+	remote, restore := capturePipes(t)
+	defer restore()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	target := listener.Addr().String()
+	dial := func() (net.Conn, error) { return net.Dial("tcp", target) }
+
+	res := controls.Forward(controls.DummyPipeClient{}, xfer.Request{}, target, dial)
+	want := xfer.Response{Pipe: "pipe", BinaryPipe: true, Forward: target}
+	if res != want {
+		t.Fatalf("Expected %+v, got %+v", want, res)
+	}
+
+	mux := xfer.NewMux(remote)
+	for _, msg := range []string{"hello", "world"} {
+		conn, err := mux.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != msg {
+			t.Errorf("Expected %q, got %q", msg, buf)
+		}
+		conn.Close()
+	}
+
+	// The control fails if the target can't be dialled
+	listener.Close()
+	if res := controls.Forward(controls.DummyPipeClient{}, xfer.Request{}, target, dial); res.Error == "" {
+		t.Errorf("Expected an error, got %+v", res)
+	}
+}
@@ -0,0 +1,181 @@
+package controls
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+// JobRetention is how long finished jobs are reported for.
+var JobRetention = 5 * time.Minute
+
+// JobFunc runs a job until it is done or ctx is cancelled, reporting its
+// progress with progress.
+type JobFunc func(ctx context.Context, progress func(format string, args ...interface{})) error
+
+// Publisher publishes reports as soon as possible, such as the probe.
+type Publisher interface {
+	Publish(report.Report)
+}
+
+type job struct {
+	report.ControlJob
+	cancel context.CancelFunc
+}
+
+// Jobs runs long-running controls asynchronously, reports their progress
+// and handles requests to cancel them.
+type Jobs struct {
+	probeID   string
+	registry  *HandlerRegistry
+	publisher Publisher
+
+	mtx  sync.Mutex
+	jobs map[string]*job
+}
+
+// NewJobs makes Jobs reporting as probe probeID, and registers the control
+// to cancel jobs with registry. Changes to the state of jobs are published
+// with publisher, if not nil.
+func NewJobs(probeID string, registry *HandlerRegistry, publisher Publisher) *Jobs {
+	j := &Jobs{
+		probeID:   probeID,
+		registry:  registry,
+		publisher: publisher,
+		jobs:      map[string]*job{},
+	}
+	registry.Register(report.CancelJob, j.cancel)
+	return j
+}
+
+// Name of this reporter, for metrics gathering
+func (*Jobs) Name() string { return "Jobs" }
+
+// Stop cancels all running jobs and deregisters the cancel control.
+func (j *Jobs) Stop() {
+	j.registry.Rm(report.CancelJob)
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	for _, job := range j.jobs {
+		job.cancel()
+	}
+}
+
+// Start runs f as a job for the control request req, and responds with the
+// ID of the job. On nil Jobs, f is run synchronously instead.
+func (j *Jobs) Start(req xfer.Request, f JobFunc) xfer.Response {
+	if j == nil {
+		if err := f(context.Background(), func(string, ...interface{}) {}); err != nil {
+			return xfer.ResponseError(err)
+		}
+		return xfer.Response{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	now := mtime.Now()
+	id := strconv.FormatInt(rand.Int63(), 16)
+	j.mtx.Lock()
+	j.jobs[id] = &job{
+		ControlJob: report.ControlJob{
+			ID:      id,
+			ProbeID: j.probeID,
+			NodeID:  req.NodeID,
+			Control: req.Control,
+			State:   report.JobRunning,
+			Started: now,
+			Updated: now,
+		},
+		cancel: cancel,
+	}
+	j.mtx.Unlock()
+	j.publish()
+
+	go func() {
+		defer cancel()
+		err := f(ctx, func(format string, args ...interface{}) {
+			j.update(id, func(job *report.ControlJob) {
+				job.Progress = fmt.Sprintf(format, args...)
+			})
+		})
+		j.update(id, func(job *report.ControlJob) {
+			switch {
+			case ctx.Err() != nil:
+				job.State = report.JobCancelled
+			case err != nil:
+				job.State = report.JobFailed
+				job.Error = err.Error()
+			default:
+				job.State = report.JobSucceeded
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("Error running %s on %s: %v", req.Control, req.NodeID, err)
+		}
+		j.publish()
+	}()
+	return xfer.Response{
+		Job: id,
+	}
+}
+
+func (j *Jobs) update(id string, f func(*report.ControlJob)) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	if job, ok := j.jobs[id]; ok {
+		f(&job.ControlJob)
+		job.Updated = mtime.Now()
+	}
+}
+
+func (j *Jobs) cancel(req xfer.Request) xfer.Response {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	job, ok := j.jobs[req.NodeID]
+	if !ok {
+		return xfer.ResponseErrorf("Job not found: %s", req.NodeID)
+	}
+	if job.Done() {
+		return xfer.ResponseErrorf("Job %s is already %s", req.NodeID, job.State)
+	}
+	job.cancel()
+	return xfer.Response{}
+}
+
+func (j *Jobs) publish() {
+	if j.publisher == nil {
+		return
+	}
+	rpt := j.report()
+	rpt.Shortcut = true
+	j.publisher.Publish(rpt)
+}
+
+func (j *Jobs) report() report.Report {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	rpt := report.MakeReport()
+	rpt.ControlJobs = make(report.ControlJobs, len(j.jobs))
+	for id, job := range j.jobs {
+		if job.Done() && mtime.Now().Sub(job.Updated) > JobRetention {
+			delete(j.jobs, id)
+			continue
+		}
+		rpt.ControlJobs[id] = job.ControlJob
+	}
+	return rpt
+}
+
+// Report reports the running jobs, and those finished in the last
+// JobRetention.
+func (j *Jobs) Report() (report.Report, error) {
+	return j.report(), nil
+}
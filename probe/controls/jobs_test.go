@@ -0,0 +1,87 @@
+package controls_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+type mockPublisher chan report.Report
+
+func (p mockPublisher) Publish(rpt report.Report) {
+	p <- rpt
+}
+
+func TestJobs(t *testing.T) {
+	var (
+		registry  = controls.NewDefaultHandlerRegistry()
+		publisher = make(mockPublisher, 10)
+		jobs      = controls.NewJobs("probe", registry, publisher)
+		progress  = make(chan struct{})
+	)
+	defer jobs.Stop()
+
+	// Wait for the published report of the given job in the given state
+	waitFor := func(id, state string) report.ControlJob {
+		for {
+			select {
+			case rpt := <-publisher:
+				if job := rpt.ControlJobs[id]; job.State == state {
+					return job
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for job %s to be %s", id, state)
+			}
+		}
+	}
+
+	resp := jobs.Start(xfer.Request{NodeID: "node", Control: "slow"}, func(ctx context.Context, p func(string, ...interface{})) error {
+		p("step %d", 1)
+		close(progress)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if resp.Job == "" || resp.Error != "" {
+		t.Fatalf("Expected a job, got %+v", resp)
+	}
+	job := waitFor(resp.Job, report.JobRunning)
+	if job.ProbeID != "probe" || job.NodeID != "node" || job.Control != "slow" {
+		t.Errorf("Unexpected job %+v", job)
+	}
+	<-progress
+	rpt, _ := jobs.Report()
+	if job := rpt.ControlJobs[resp.Job]; job.Progress != "step 1" {
+		t.Errorf("Unexpected progress %q", job.Progress)
+	}
+
+	if resp := registry.HandleControlRequest(xfer.Request{NodeID: resp.Job, Control: report.CancelJob}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	waitFor(resp.Job, report.JobCancelled)
+	if resp := registry.HandleControlRequest(xfer.Request{NodeID: resp.Job, Control: report.CancelJob}); resp.Error == "" {
+		t.Errorf("Expected an error cancelling a cancelled job")
+	}
+	if resp := registry.HandleControlRequest(xfer.Request{NodeID: "unknown", Control: report.CancelJob}); resp.Error == "" {
+		t.Errorf("Expected an error cancelling an unknown job")
+	}
+
+	resp = jobs.Start(xfer.Request{NodeID: "node", Control: "failing"}, func(context.Context, func(string, ...interface{})) error {
+		return fmt.Errorf("boom")
+	})
+	if job := waitFor(resp.Job, report.JobFailed); job.Error != "boom" {
+		t.Errorf("Unexpected error %q", job.Error)
+	}
+
+	// Without Jobs, jobs run synchronously
+	var none *controls.Jobs
+	if resp := none.Start(xfer.Request{}, func(context.Context, func(string, ...interface{})) error {
+		return fmt.Errorf("boom")
+	}); resp.Error != "boom" || resp.Job != "" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
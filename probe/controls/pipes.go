@@ -41,6 +41,12 @@ var NewPipe = func(c PipeClient, appID string) (string, xfer.Pipe, error) {
 	return newPipe(xfer.NewPipe(), c, appID)
 }
 
+// NewBinaryPipe creates a new pipe for binary data, such as a tar archive,
+// and connects it to the app.
+var NewBinaryPipe = func(c PipeClient, appID string) (string, xfer.Pipe, error) {
+	return newPipe(xfer.NewBinaryPipe(), c, appID)
+}
+
 // NewPipeFromEnds creates a new pipe from its ends and connects it to the app.
 func NewPipeFromEnds(local, remote io.ReadWriter, c PipeClient, appID string) (string, xfer.Pipe, error) {
 	return newPipe(xfer.NewPipeFromEnds(local, remote), c, appID)
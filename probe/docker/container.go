@@ -31,14 +31,34 @@ const (
 	ContainerUptime        = report.DockerContainerUptime
 	ContainerRestartCount  = report.DockerContainerRestartCount
 	ContainerNetworkMode   = report.DockerContainerNetworkMode
-
-	MemoryUsage   = "docker_memory_usage"
-	CPUTotalUsage = "docker_cpu_total_usage"
+	ContainerNewerImage    = report.DockerContainerNewerImage
+	ContainerHealth        = report.DockerContainerHealth
+	ContainerHealthOutput  = report.DockerContainerHealthOutput
+	ContainerExitCode      = report.DockerContainerExitCode
+	ContainerOOMKilled     = report.DockerContainerOOMKilled
+	ContainerLastRestart   = report.DockerContainerLastRestart
+	ContainerRecentExits   = report.DockerContainerRecentExits
+	ContainerRecentOOMs    = report.DockerContainerRecentOOMs
+
+	MemoryUsage       = "docker_memory_usage"
+	CPUTotalUsage     = "docker_cpu_total_usage"
+	NetworkRxBytes    = report.DockerNetworkRxBytes
+	NetworkTxBytes    = report.DockerNetworkTxBytes
+	NetworkRxPackets  = report.DockerNetworkRxPackets
+	NetworkTxPackets  = report.DockerNetworkTxPackets
+	NetworkRxDropped  = report.DockerNetworkRxDropped
+	NetworkTxDropped  = report.DockerNetworkTxDropped
+	BlockIOReadBytes  = report.DockerBlockIOReadBytes
+	BlockIOWriteBytes = report.DockerBlockIOWriteBytes
 
 	LabelPrefix = report.DockerLabelPrefix
 	EnvPrefix   = report.DockerEnvPrefix
 )
 
+// EventWindow is how long the exits and OOM kills of containers are counted
+// for.
+var EventWindow = 10 * time.Minute
+
 // StatsGatherer gathers container stats
 type StatsGatherer interface {
 	Stats(docker.StatsOptions) error
@@ -61,6 +81,7 @@ type Container interface {
 	StopGatheringStats()
 	NetworkMode() (string, bool)
 	NetworkInfo([]net.IP) report.Sets
+	RecordEvent(status string, t time.Time)
 }
 
 type container struct {
@@ -74,6 +95,12 @@ type container struct {
 	baseNode               report.Node
 	noCommandLineArguments bool
 	noEnvironmentVariables bool
+	events                 []containerEvent // exits and OOM kills in the last EventWindow
+}
+
+type containerEvent struct {
+	status string
+	time   time.Time
 }
 
 // NewContainer creates a new Container
@@ -94,6 +121,33 @@ func (c *container) UpdateState(container *docker.Container) {
 	c.container = container
 }
 
+// RecordEvent records that the container exited or was OOM killed at t,
+// forgetting the events older than EventWindow.
+func (c *container) RecordEvent(status string, t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	cutoff := mtime.Now().Add(-EventWindow)
+	recent := c.events[:0]
+	for _, event := range c.events {
+		if !event.time.Before(cutoff) {
+			recent = append(recent, event)
+		}
+	}
+	c.events = append(recent, containerEvent{status: status, time: t})
+}
+
+// recentEvents counts the events of each status in the last EventWindow.
+func (c *container) recentEvents() map[string]int {
+	cutoff := mtime.Now().Add(-EventWindow)
+	counts := map[string]int{}
+	for _, event := range c.events {
+		if !event.time.Before(cutoff) {
+			counts[event.status]++
+		}
+	}
+	return counts
+}
+
 func (c *container) ID() string {
 	return c.container.ID
 }
@@ -338,14 +392,90 @@ func (c *container) cpuPercentMetric(stats []docker.Stats) report.Metric {
 	return report.MakeMetric(samples).WithMax(100.0)
 }
 
+// rateMetric makes a metric of the rate per second of the counter of the
+// stats. Counters going down, when the container restarts, have a zero
+// rate.
+func rateMetric(stats []docker.Stats, counter func(docker.Stats) uint64) report.Metric {
+	if len(stats) < 2 {
+		return report.MakeMetric(nil)
+	}
+
+	samples := make([]report.Sample, 0, len(stats)-1)
+	previous := stats[0]
+	for _, s := range stats[1:] {
+		seconds := s.Read.Sub(previous.Read).Seconds()
+		if seconds <= 0 {
+			continue
+		}
+		rate := 0.0
+		if current, last := counter(s), counter(previous); current >= last {
+			rate = float64(current-last) / seconds
+		}
+		samples = append(samples, report.Sample{Timestamp: s.Read, Value: rate})
+		previous = s
+	}
+	return report.MakeMetric(samples)
+}
+
+// counterMetric makes a metric of the counter of the stats.
+func counterMetric(stats []docker.Stats, counter func(docker.Stats) uint64) report.Metric {
+	samples := make([]report.Sample, 0, len(stats))
+	for i, s := range stats {
+		if i > 0 && !s.Read.After(stats[i-1].Read) {
+			continue
+		}
+		samples = append(samples, report.Sample{Timestamp: s.Read, Value: float64(counter(s))})
+	}
+	return report.MakeMetric(samples)
+}
+
+// networkCounter sums a counter of the network stats over the interfaces
+// of the container.
+func networkCounter(f func(docker.NetworkStats) uint64) func(docker.Stats) uint64 {
+	return func(s docker.Stats) uint64 {
+		var total uint64
+		for _, network := range s.Networks {
+			total += f(network)
+		}
+		return total
+	}
+}
+
+// blockIOCounter sums the bytes read or written over the block devices of
+// the container.
+func blockIOCounter(op string) func(docker.Stats) uint64 {
+	return func(s docker.Stats) uint64 {
+		var total uint64
+		for _, entry := range s.BlkioStats.IOServiceBytesRecursive {
+			// cgroups v2 report the operations in lower case
+			if strings.EqualFold(entry.Op, op) {
+				total += entry.Value
+			}
+		}
+		return total
+	}
+}
+
 func (c *container) metrics() report.Metrics {
 	if c.numPending == 0 {
 		return report.Metrics{}
 	}
 	pendingStats := c.pendingStats[:c.numPending]
 	result := report.Metrics{
-		MemoryUsage:   c.memoryUsageMetric(pendingStats),
-		CPUTotalUsage: c.cpuPercentMetric(pendingStats),
+		MemoryUsage:       c.memoryUsageMetric(pendingStats),
+		CPUTotalUsage:     c.cpuPercentMetric(pendingStats),
+		BlockIOReadBytes:  rateMetric(pendingStats, blockIOCounter("Read")),
+		BlockIOWriteBytes: rateMetric(pendingStats, blockIOCounter("Write")),
+	}
+	// Containers in the network namespace of the host or of another
+	// container have no network stats
+	if len(pendingStats[len(pendingStats)-1].Networks) > 0 {
+		result[NetworkRxBytes] = rateMetric(pendingStats, networkCounter(func(n docker.NetworkStats) uint64 { return n.RxBytes }))
+		result[NetworkTxBytes] = rateMetric(pendingStats, networkCounter(func(n docker.NetworkStats) uint64 { return n.TxBytes }))
+		result[NetworkRxPackets] = rateMetric(pendingStats, networkCounter(func(n docker.NetworkStats) uint64 { return n.RxPackets }))
+		result[NetworkTxPackets] = rateMetric(pendingStats, networkCounter(func(n docker.NetworkStats) uint64 { return n.TxPackets }))
+		result[NetworkRxDropped] = counterMetric(pendingStats, networkCounter(func(n docker.NetworkStats) uint64 { return n.RxDropped }))
+		result[NetworkTxDropped] = counterMetric(pendingStats, networkCounter(func(n docker.NetworkStats) uint64 { return n.TxDropped }))
 	}
 
 	// leave one stat to help with relative metrics
@@ -395,9 +525,9 @@ func (c *container) controls() []string {
 	case c.container.State.Paused:
 		return []string{UnpauseContainer}
 	case c.container.State.Running:
-		return []string{RestartContainer, StopContainer, PauseContainer, AttachContainer, ExecContainer}
+		return []string{RestartContainer, StopContainer, PauseContainer, AttachContainer, ExecContainer, GetLogs, DownloadFile, UploadFile, PortForward}
 	default:
-		return []string{StartContainer, RemoveContainer}
+		return []string{StartContainer, RemoveContainer, GetLogs, DownloadFile, UploadFile}
 	}
 }
 
@@ -405,9 +535,13 @@ func (c *container) GetNode() report.Node {
 	c.RLock()
 	defer c.RUnlock()
 	latest := map[string]string{
-		ContainerName:       strings.TrimPrefix(c.container.Name, "/"),
-		ContainerState:      c.StateString(),
-		ContainerStateHuman: c.State(),
+		ContainerName:         strings.TrimPrefix(c.container.Name, "/"),
+		ContainerState:        c.StateString(),
+		ContainerStateHuman:   c.State(),
+		ContainerRestartCount: strconv.Itoa(c.container.RestartCount),
+	}
+	for key, value := range c.health() {
+		latest[key] = value
 	}
 
 	if !c.container.State.Paused && c.container.State.Running {
@@ -417,7 +551,6 @@ func (c *container) GetNode() report.Node {
 			networkMode = c.container.HostConfig.NetworkMode
 		}
 		latest[ContainerUptime] = strconv.Itoa(uptimeSeconds)
-		latest[ContainerRestartCount] = strconv.Itoa(c.container.RestartCount)
 		latest[ContainerNetworkMode] = networkMode
 	}
 
@@ -427,6 +560,32 @@ func (c *container) GetNode() report.Node {
 	return result
 }
 
+// health returns the metadata about the health of the container: its
+// healthcheck status, how it last exited and how often it recently did.
+func (c *container) health() map[string]string {
+	state := c.container.State
+	latest := map[string]string{}
+	if state.Health.Status != "" && state.Health.Status != "none" {
+		latest[ContainerHealth] = state.Health.Status
+		if n := len(state.Health.Log); n > 0 {
+			latest[ContainerHealthOutput] = strings.TrimSpace(state.Health.Log[n-1].Output)
+		}
+	}
+	if !state.FinishedAt.IsZero() {
+		latest[ContainerExitCode] = strconv.Itoa(state.ExitCode)
+	}
+	if state.OOMKilled {
+		latest[ContainerOOMKilled] = "true"
+	}
+	if c.container.RestartCount > 0 && !state.StartedAt.IsZero() {
+		latest[ContainerLastRestart] = state.StartedAt.Format(time.RFC3339Nano)
+	}
+	events := c.recentEvents()
+	latest[ContainerRecentExits] = strconv.Itoa(events[DieEvent])
+	latest[ContainerRecentOOMs] = strconv.Itoa(events[OOMEvent])
+	return latest
+}
+
 // ExtractContainerIPs returns the list of container IPs given a Node from the Container topology.
 func ExtractContainerIPs(nmd report.Node) []string {
 	v, _ := nmd.Sets.Lookup(ContainerIPs)
@@ -10,6 +10,7 @@ import (
 	client "github.com/fsouza/go-dockerclient"
 
 	"github.com/weaveworks/common/mtime"
+	commonTest "github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test"
@@ -66,6 +67,10 @@ func TestContainer(t *testing.T) {
 			docker.PauseContainer,
 			docker.AttachContainer,
 			docker.ExecContainer,
+			docker.GetLogs,
+			docker.DownloadFile,
+			docker.UploadFile,
+			docker.PortForward,
 		}
 		want := report.MakeNodeWith("ping;<container>", map[string]string{
 			"docker_container_command":     "ping foo.bar.local",
@@ -82,8 +87,10 @@ func TestContainer(t *testing.T) {
 		}).WithLatestActiveControls(
 			controls...,
 		).WithMetrics(report.Metrics{
-			"docker_cpu_total_usage": report.MakeMetric(nil),
-			"docker_memory_usage":    report.MakeSingletonMetric(now, 12345).WithMax(45678),
+			"docker_cpu_total_usage":   report.MakeMetric(nil),
+			"docker_memory_usage":      report.MakeSingletonMetric(now, 12345).WithMax(45678),
+			"docker_blkio_read_bytes":  report.MakeMetric(nil),
+			"docker_blkio_write_bytes": report.MakeMetric(nil),
 		}).WithParents(report.MakeSets().
 			Add(report.ContainerImage, report.MakeStringSet(report.MakeContainerImageNodeID("baz"))),
 		)
@@ -128,6 +135,55 @@ func TestContainer(t *testing.T) {
 	}
 }
 
+func TestContainerIOMetrics(t *testing.T) {
+	c := docker.NewContainer(container1, "scope", false, false)
+	s := newMockStatsGatherer()
+	if err := c.StartGatheringStats(s); err != nil {
+		t.Fatal(err)
+	}
+	defer c.StopGatheringStats()
+
+	start := time.Unix(12345, 0).UTC()
+	send := func(seconds int, rxBytes, rxDropped, read uint64) {
+		stats := &client.Stats{}
+		stats.Read = start.Add(time.Duration(seconds) * time.Second)
+		stats.Networks = map[string]client.NetworkStats{
+			"eth0": {RxBytes: rxBytes, TxBytes: 100, RxDropped: rxDropped},
+			"eth1": {RxBytes: rxBytes},
+		}
+		stats.BlkioStats.IOServiceBytesRecursive = []client.BlkioStatsEntry{
+			{Major: 8, Op: "Read", Value: read},
+			{Major: 8, Op: "write", Value: 4096},
+			{Major: 8, Op: "Total", Value: read + 4096},
+		}
+		s.Send(stats)
+	}
+	send(0, 1000, 1, 0)
+	send(2, 3000, 3, 8192)
+	// the counters are reset when the container restarts
+	send(4, 500, 0, 0)
+	// sending the same stats again waits for the others to be gathered
+	send(4, 500, 0, 0)
+
+	second, fourth := start.Add(2*time.Second), start.Add(4*time.Second)
+	want := report.Metrics{
+		"docker_network_rx_bytes":   report.MakeMetric([]report.Sample{{Timestamp: second, Value: 2000}, {Timestamp: fourth, Value: 0}}),
+		"docker_network_tx_bytes":   report.MakeMetric([]report.Sample{{Timestamp: second, Value: 0}, {Timestamp: fourth, Value: 0}}),
+		"docker_network_rx_dropped": report.MakeMetric([]report.Sample{{Timestamp: start, Value: 1}, {Timestamp: second, Value: 3}, {Timestamp: fourth, Value: 0}}),
+		"docker_blkio_read_bytes":   report.MakeMetric([]report.Sample{{Timestamp: second, Value: 4096}, {Timestamp: fourth, Value: 0}}),
+		"docker_blkio_write_bytes":  report.MakeMetric([]report.Sample{{Timestamp: second, Value: 0}, {Timestamp: fourth, Value: 0}}),
+	}
+	have := report.Metrics{}
+	for key, metric := range c.GetNode().Metrics {
+		if _, ok := want[key]; ok {
+			have[key] = metric
+		}
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(commonTest.Diff(want, have))
+	}
+}
+
 func TestContainerHidingArgs(t *testing.T) {
 	const hostID = "scope"
 	c := docker.NewContainer(container1, hostID, true, false)
@@ -163,3 +219,57 @@ func TestContainerHidingBoth(t *testing.T) {
 		}
 	})
 }
+
+func TestContainerHealth(t *testing.T) {
+	now := time.Unix(12345, 67890).UTC()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	unhealthy := *container1
+	unhealthy.RestartCount = 4
+	unhealthy.State = client.State{
+		Pid:        2,
+		Running:    true,
+		StartedAt:  now.Add(-time.Minute),
+		FinishedAt: now.Add(-2 * time.Minute),
+		ExitCode:   137,
+		OOMKilled:  true,
+		Health: client.Health{
+			Status: report.HealthUnhealthy,
+			Log: []client.HealthCheck{
+				{ExitCode: 0, Output: "ok\n"},
+				{ExitCode: 1, Output: "connection refused\n"},
+			},
+		},
+	}
+	c := docker.NewContainer(&unhealthy, "scope", false, false)
+	// Only the events in the last EventWindow are counted
+	c.RecordEvent(docker.DieEvent, now.Add(-docker.EventWindow-time.Second))
+	c.RecordEvent(docker.DieEvent, now.Add(-2*time.Minute))
+	c.RecordEvent(docker.OOMEvent, now.Add(-2*time.Minute))
+	c.RecordEvent(docker.DieEvent, now.Add(-time.Minute))
+
+	node := c.GetNode()
+	for k, want := range map[string]string{
+		docker.ContainerHealth:       report.HealthUnhealthy,
+		docker.ContainerHealthOutput: "connection refused",
+		docker.ContainerExitCode:     "137",
+		docker.ContainerOOMKilled:    "true",
+		docker.ContainerRestartCount: "4",
+		docker.ContainerLastRestart:  now.Add(-time.Minute).Format(time.RFC3339Nano),
+		docker.ContainerRecentExits:  "2",
+		docker.ContainerRecentOOMs:   "1",
+	} {
+		if have, ok := node.Latest.Lookup(k); !ok || have != want {
+			t.Errorf("Expected %s: %q, got %q", k, want, have)
+		}
+	}
+
+	// Containers without a healthcheck which never exited have no health
+	node = docker.NewContainer(container2, "scope", false, false).GetNode()
+	for _, k := range []string{docker.ContainerHealth, docker.ContainerExitCode, docker.ContainerOOMKilled, docker.ContainerLastRestart} {
+		if have, ok := node.Latest.Lookup(k); ok {
+			t.Errorf("Expected no %s, got %q", k, have)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package docker
 
 import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+
 	docker_client "github.com/fsouza/go-dockerclient"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks/common/mtime"
 
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/controls"
@@ -21,6 +27,10 @@ const (
 	AttachContainer  = report.DockerAttachContainer
 	ExecContainer    = report.DockerExecContainer
 	ResizeExecTTY    = "docker_resize_exec_tty"
+	GetLogs          = report.DockerGetLogs
+	DownloadFile     = report.DockerDownloadFile
+	UploadFile       = report.DockerUploadFile
+	PortForward      = report.DockerPortForward
 
 	waitTime = 10
 )
@@ -164,6 +174,105 @@ func (r *registry) execContainer(containerID string, req xfer.Request) xfer.Resp
 	}
 }
 
+func (r *registry) getLogs(containerID string, req xfer.Request) xfer.Response {
+	c, ok := r.GetContainer(containerID)
+	if !ok {
+		return xfer.ResponseErrorf("Not found: %s", containerID)
+	}
+	opts, err := xfer.ParseLogOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	logsOptions := docker_client.LogsOptions{
+		Container:  containerID,
+		Tail:       "all",
+		Follow:     opts.Follow,
+		Stdout:     true,
+		Stderr:     true,
+		Timestamps: true,
+		// The logs of a container with a TTY aren't multiplexed
+		RawTerminal: c.HasTTY(),
+	}
+	if opts.Since > 0 {
+		logsOptions.Since = mtime.Now().Add(-opts.Since).Unix()
+	}
+	if opts.Tail >= 0 {
+		logsOptions.Tail = strconv.FormatInt(opts.Tail, 10)
+	}
+
+	id, pipe, err := controls.NewPipe(r.pipes, req.AppID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	local, _ := pipe.Ends()
+	ctx, cancel := context.WithCancel(context.Background())
+	logsOptions.Context = ctx
+	logsOptions.OutputStream = local
+	logsOptions.ErrorStream = local
+	pipe.OnClose(cancel)
+	go func() {
+		if err := r.client.Logs(logsOptions); err != nil && ctx.Err() == nil {
+			log.Errorf("Error getting logs of container %s: %v", containerID, err)
+		}
+		pipe.Close()
+	}()
+	return xfer.Response{
+		Pipe: id,
+	}
+}
+
+func (r *registry) downloadFile(containerID string, req xfer.Request) xfer.Response {
+	opts, err := xfer.ParseFileCopyOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	log.Infof("Downloading %s from container %s", opts.Path, containerID)
+	return controls.Download(r.pipes, r.jobs, req, opts, func(ctx context.Context, w io.Writer) error {
+		return r.client.DownloadFromContainer(containerID, docker_client.DownloadFromContainerOptions{
+			Path:         opts.Path,
+			OutputStream: w,
+			Context:      ctx,
+		})
+	})
+}
+
+func (r *registry) uploadFile(containerID string, req xfer.Request) xfer.Response {
+	opts, err := xfer.ParseFileCopyOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	log.Infof("Uploading to %s in container %s", opts.Path, containerID)
+	return controls.Upload(r.pipes, req, opts, func(ctx context.Context, archive io.Reader) error {
+		return r.client.UploadToContainer(containerID, docker_client.UploadToContainerOptions{
+			Path:        opts.Path,
+			InputStream: archive,
+			Context:     ctx,
+		})
+	})
+}
+
+func (r *registry) portForward(containerID string, req xfer.Request) xfer.Response {
+	port, err := xfer.ParseForwardPort(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	c, ok := r.GetContainer(containerID)
+	if !ok {
+		return xfer.ResponseErrorf("Unknown container: %s", containerID)
+	}
+	pid := c.PID()
+	if pid <= 1 {
+		return xfer.ResponseErrorf("Container %s is not running", containerID)
+	}
+	// Ports published only on the loopback interface of the container are
+	// reachable too
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	log.Infof("Forwarding port %d of container %s", port, containerID)
+	return controls.Forward(r.pipes, req, address, func() (net.Conn, error) {
+		return dialInNetNS(pid, address)
+	})
+}
+
 func (r *registry) resizeExecTTY(pipeID string, height, width uint) xfer.Response {
 	r.Lock()
 	execID, ok := r.pipeIDToexecID[pipeID]
@@ -202,7 +311,14 @@ func (r *registry) registerControls() {
 		RemoveContainer:  captureContainerID(r.removeContainer),
 		AttachContainer:  captureContainerID(r.attachContainer),
 		ExecContainer:    captureContainerID(r.execContainer),
+		GetLogs:          captureContainerID(r.getLogs),
+		DownloadFile:     captureContainerID(r.downloadFile),
+		UploadFile:       captureContainerID(r.uploadFile),
+		PortForward:      captureContainerID(r.portForward),
 		ResizeExecTTY:    xfer.ResizeTTYControlWrapper(r.resizeExecTTY),
+		PullImage:        r.pullImage,
+		RemoveImage:      r.removeImage,
+		PruneImages:      r.pruneImages,
 	}
 	r.handlerRegistry.Batch(nil, controls)
 }
@@ -217,7 +333,14 @@ func (r *registry) deregisterControls() {
 		RemoveContainer,
 		AttachContainer,
 		ExecContainer,
+		GetLogs,
+		DownloadFile,
+		UploadFile,
+		PortForward,
 		ResizeExecTTY,
+		PullImage,
+		RemoveImage,
+		PruneImages,
 	}
 	r.handlerRegistry.Batch(controls, nil)
 }
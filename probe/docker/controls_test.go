@@ -2,10 +2,13 @@ package docker_test
 
 import (
 	"io"
+	"io/ioutil"
 	"reflect"
 	"testing"
 	"time"
 
+	client "github.com/fsouza/go-dockerclient"
+
 	commonTest "github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/controls"
@@ -58,6 +61,14 @@ func TestPipes(t *testing.T) {
 	controls.NewPipe = func(_ controls.PipeClient, _ string) (string, xfer.Pipe, error) {
 		return "pipeid", mockPipe{}, nil
 	}
+	oldNewBinaryPipe := controls.NewBinaryPipe
+	defer func() { controls.NewBinaryPipe = oldNewBinaryPipe }()
+	controls.NewBinaryPipe = func(_ controls.PipeClient, _ string) (string, xfer.Pipe, error) {
+		pipe := xfer.NewBinaryPipe()
+		_, remote := pipe.Ends()
+		go io.Copy(ioutil.Discard, remote)
+		return "pipeid", pipe, nil
+	}
 
 	mdc := newMockClient()
 	setupStubs(mdc, func() {
@@ -85,6 +96,30 @@ func TestPipes(t *testing.T) {
 				},
 			},
 
+			{
+				control: docker.GetLogs,
+				response: xfer.Response{
+					Pipe: "pipeid",
+				},
+			},
+
+			{
+				control: docker.DownloadFile,
+				response: xfer.Response{
+					Pipe:       "pipeid",
+					BinaryPipe: true,
+					Filename:   "dump.hprof.tar",
+				},
+			},
+
+			{
+				control: docker.UploadFile,
+				response: xfer.Response{
+					Pipe:       "pipeid",
+					BinaryPipe: true,
+				},
+			},
+
 			{
 				control: docker.ExecContainer,
 				response: xfer.Response{
@@ -95,8 +130,9 @@ func TestPipes(t *testing.T) {
 			},
 		} {
 			result := hr.HandleControlRequest(xfer.Request{
-				Control: want.control,
-				NodeID:  report.MakeContainerNodeID("ping"),
+				Control:     want.control,
+				NodeID:      report.MakeContainerNodeID("ping"),
+				ControlArgs: map[string]string{"path": "/tmp/dump.hprof"},
 			})
 			if !reflect.DeepEqual(result, want.response) {
 				t.Errorf("diff %s: %s", want.control, commonTest.Diff(want, result))
@@ -119,3 +155,74 @@ func TestDockerImageName(t *testing.T) {
 		}
 	}
 }
+
+func TestImageControls(t *testing.T) {
+	mdc := newMockClient()
+	mdc.apiImages = append(mdc.apiImages,
+		client.APIImages{ID: "sha256:unused", RepoTags: []string{"registry:5000/team/old:1.0"}},
+		client.APIImages{ID: "sha256:stopped"},
+	)
+	mdc.apiContainers = append(mdc.apiContainers, client.APIContainers{ID: "exited"})
+	mdc.containers["exited"] = &client.Container{ID: "exited", Image: "stopped", Config: &client.Config{}}
+	setupStubs(mdc, func() {
+		hr := controls.NewDefaultHandlerRegistry()
+		registry, _ := docker.NewRegistry(docker.RegistryOptions{
+			Interval:        10 * time.Second,
+			HostID:          "host1",
+			HandlerRegistry: hr,
+		})
+		defer registry.Stop()
+		test.Poll(t, 100*time.Millisecond, true, func() interface{} {
+			_, ok := registry.GetContainerImage("unused")
+			return ok && len(allContainers(registry)) == 2
+		})
+
+		// Image nodes of the containers by image view are named after the image
+		if resp := hr.HandleControlRequest(xfer.Request{
+			Control: docker.PullImage,
+			NodeID:  report.MakeContainerImageNodeID("team/old"),
+		}); resp.Error != "" {
+			t.Fatal(resp.Error)
+		}
+		if want := []string{"registry:5000/team/old:1.0"}; !reflect.DeepEqual(mdc.pulled, want) {
+			t.Errorf("Expected %v to be pulled, got %v", want, mdc.pulled)
+		}
+
+		if resp := hr.HandleControlRequest(xfer.Request{
+			Control: docker.RemoveImage,
+			NodeID:  report.MakeContainerImageNodeID("baz"),
+		}); resp.Error == "" {
+			t.Errorf("Expected an error removing an image in use")
+		}
+		// Stopped containers are not removed with their image
+		if resp := hr.HandleControlRequest(xfer.Request{
+			Control: docker.RemoveImage,
+			NodeID:  report.MakeContainerImageNodeID("stopped"),
+		}); resp.Error == "" {
+			t.Errorf("Expected an error removing an image used by a stopped container")
+		}
+		nodeID := report.MakeContainerImageNodeID("unused")
+		if resp := hr.HandleControlRequest(xfer.Request{
+			Control: docker.RemoveImage,
+			NodeID:  nodeID,
+		}); !reflect.DeepEqual(resp, xfer.Response{RemovedNode: nodeID}) {
+			t.Errorf("Unexpected response %+v", resp)
+		}
+		if want := []string{"unused"}; !reflect.DeepEqual(mdc.removed, want) {
+			t.Errorf("Expected %v to be removed, got %v", want, mdc.removed)
+		}
+
+		if resp := hr.HandleControlRequest(xfer.Request{
+			Control: docker.PruneImages,
+			NodeID:  report.MakeHostNodeID("host1"),
+		}); resp.Value != "Removed 0 image(s), reclaiming 2.0 kB" {
+			t.Errorf("Unexpected response %+v", resp)
+		}
+		if resp := hr.HandleControlRequest(xfer.Request{
+			Control: docker.PruneImages,
+			NodeID:  report.MakeHostNodeID("host2"),
+		}); resp.Error == "" {
+			t.Errorf("Expected an error pruning the images of another host")
+		}
+	})
+}
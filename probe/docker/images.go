@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	docker_client "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+// Control IDs of the image controls.
+const (
+	PullImage   = report.DockerPullImage
+	RemoveImage = report.DockerRemoveImage
+	PruneImages = report.DockerPruneImages
+)
+
+// imageTagReference returns the tagged reference an image was named by,
+// adding the implicit latest tag. Images named by digest have no tag to
+// follow.
+func imageTagReference(image string) (string, bool) {
+	if image == "" || strings.Contains(image, "@") || strings.HasPrefix(image, "sha256:") {
+		return "", false
+	}
+	if strings.LastIndex(image, ":") <= strings.LastIndex(image, "/") {
+		image += ":latest"
+	}
+	return image, true
+}
+
+// splitImageTagReference splits a tagged reference into the repository,
+// including its registry, and the tag.
+func splitImageTagReference(reference string) (string, string) {
+	i := strings.LastIndex(reference, ":")
+	if i <= strings.LastIndex(reference, "/") {
+		return reference, ""
+	}
+	return reference[:i], reference[i+1:]
+}
+
+// imageIDs returns the IDs of the images an image node stands for: the
+// image with the ID of the node, or all the images with its name, for
+// the nodes of the containers by image view.
+func (r *registry) imageIDs(nodeID string) ([]string, error) {
+	id, ok := report.ParseContainerImageNodeID(nodeID)
+	if !ok {
+		return nil, fmt.Errorf("Invalid ID: %s", nodeID)
+	}
+	r.RLock()
+	defer r.RUnlock()
+	if _, ok := r.images[id]; ok {
+		return []string{id}, nil
+	}
+	ids := []string{}
+	for imageID, image := range r.images {
+		for _, tag := range image.RepoTags {
+			if ImageNameWithoutTag(tag) == id {
+				ids = append(ids, imageID)
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("Image not found: %s", id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (r *registry) pullImage(req xfer.Request) xfer.Response {
+	ids, err := r.imageIDs(req.NodeID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	tags := report.MakeStringSet()
+	for _, id := range ids {
+		image, _ := r.GetContainerImage(id)
+		for _, tag := range image.RepoTags {
+			if tag != "<none>:<none>" {
+				tags = tags.Add(tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return xfer.ResponseErrorf("Image %s has no tag to pull", ids[0])
+	}
+	return r.jobs.Start(req, func(ctx context.Context, progress func(string, ...interface{})) error {
+		for i, tag := range tags {
+			log.Infof("Pulling image %s", tag)
+			progress("Pulling %s (%d of %d)", tag, i+1, len(tags))
+			repository, tag := splitImageTagReference(tag)
+			if err := r.client.PullImage(docker_client.PullImageOptions{
+				Repository:   repository,
+				Tag:          tag,
+				OutputStream: ioutil.Discard,
+				Context:      ctx,
+			}, docker_client.AuthConfiguration{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removeImage removes images no container, running or stopped, uses.
+func (r *registry) removeImage(req xfer.Request) xfer.Response {
+	ids, err := r.imageIDs(req.NodeID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	images := report.MakeStringSet(ids...)
+	var users []string
+	r.WalkContainers(func(c Container) {
+		if images.Contains(c.Image()) {
+			users = append(users, c.ID())
+		}
+	})
+	if len(users) > 0 {
+		return xfer.ResponseErrorf("Image is in use by %d container(s)", len(users))
+	}
+	for _, id := range ids {
+		log.Infof("Removing image %s", id)
+		if err := r.client.RemoveImageExtended(id, docker_client.RemoveImageOptions{}); err != nil {
+			return xfer.ResponseError(err)
+		}
+	}
+	return xfer.Response{
+		RemovedNode: req.NodeID,
+	}
+}
+
+func (r *registry) pruneImages(req xfer.Request) xfer.Response {
+	if hostID, ok := report.ParseHostNodeID(req.NodeID); !ok || hostID != r.hostID {
+		return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+	}
+	log.Infof("Pruning dangling images")
+	result, err := r.client.PruneImages(docker_client.PruneImagesOptions{
+		Filters: map[string][]string{"dangling": {"true"}},
+	})
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{
+		Value: fmt.Sprintf("Removed %d image(s), reclaiming %s",
+			len(result.ImagesDeleted), humanize.Bytes(uint64(result.SpaceReclaimed))),
+	}
+}
@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"time"
 
 	"github.com/vishvananda/netlink"
 	"github.com/vishvananda/netns"
 	"golang.org/x/sys/unix"
 )
 
+const dialTimeout = 5 * time.Second
+
 // Code adapted from github.com/weaveworks/weave/net/netdev.go
 
 // Return any non-local IP addresses for processID if in a non-root namespace
@@ -59,6 +62,23 @@ func allNonLocalAddresses() ([]*net.IPNet, error) {
 	return cidrs, nil
 }
 
+// dialInNetNS connects to address from the network namespace of processID.
+// The connection stays in that namespace once made.
+func dialInNetNS(processID int, address string) (net.Conn, error) {
+	ns, err := netns.GetFromPid(processID)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+
+	var conn net.Conn
+	err = withNetNS(ns, func() error {
+		conn, err = net.DialTimeout("tcp", address, dialTimeout)
+		return err
+	})
+	return conn, err
+}
+
 // Run the 'work' function in a different network namespace
 func withNetNS(ns netns.NsHandle, work func() error) error {
 	runtime.LockOSThread()
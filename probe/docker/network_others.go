@@ -11,3 +11,7 @@ import (
 func namespaceIPAddresses(processID int) ([]*net.IPNet, error) {
 	return nil, errors.New("namespaceIPAddresses not implemented on this platform")
 }
+
+func dialInNetNS(processID int, address string) (net.Conn, error) {
+	return nil, errors.New("dialInNetNS not implemented on this platform")
+}
@@ -1,13 +1,16 @@
 package docker
 
 import (
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/armon/go-radix"
+	docker_registry "github.com/docker/docker/api/types/registry"
 	docker_client "github.com/fsouza/go-dockerclient"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/probe/controls"
 	"github.com/weaveworks/scope/report"
 )
@@ -19,6 +22,8 @@ const (
 	RenameEvent            = "rename"
 	StartEvent             = "start"
 	DieEvent               = "die"
+	OOMEvent               = "oom"
+	HealthStatusEvent      = "health_status"
 	PauseEvent             = "pause"
 	UnpauseEvent           = "unpause"
 	NetworkConnectEvent    = "network:connect"
@@ -42,6 +47,8 @@ type Registry interface {
 	GetContainer(string) (Container, bool)
 	GetContainerByPrefix(string) (Container, bool)
 	GetContainerImage(string) (docker_client.APIImages, bool)
+	GetImageDetails(string) (*docker_client.Image, bool)
+	GetNewerImage(Container) (string, bool)
 }
 
 // ContainerUpdateWatcher is the type of functions that get called when containers are updated.
@@ -58,11 +65,16 @@ type registry struct {
 	handlerRegistry        *controls.HandlerRegistry
 	noCommandLineArguments bool
 	noEnvironmentVariables bool
+	jobs                   *controls.Jobs
+	registryCheckInterval  time.Duration
+	stopRegistryChecks     chan struct{}
 
 	watchers        []ContainerUpdateWatcher
 	containers      *radix.Tree
 	containersByPID map[int]Container
 	images          map[string]docker_client.APIImages
+	imageDetails    map[string]*docker_client.Image
+	registryDigests map[string]string
 	networks        []docker_client.Network
 	pipeIDToexecID  map[string]string
 }
@@ -72,6 +84,8 @@ type Client interface {
 	ListContainers(docker_client.ListContainersOptions) ([]docker_client.APIContainers, error)
 	InspectContainer(string) (*docker_client.Container, error)
 	ListImages(docker_client.ListImagesOptions) ([]docker_client.APIImages, error)
+	InspectImage(string) (*docker_client.Image, error)
+	InspectDistribution(string) (*docker_registry.DistributionInspect, error)
 	ListNetworks() ([]docker_client.Network, error)
 	AddEventListener(chan<- *docker_client.APIEvents) error
 	RemoveEventListener(chan *docker_client.APIEvents) error
@@ -85,8 +99,14 @@ type Client interface {
 	AttachToContainerNonBlocking(docker_client.AttachToContainerOptions) (docker_client.CloseWaiter, error)
 	CreateExec(docker_client.CreateExecOptions) (*docker_client.Exec, error)
 	StartExecNonBlocking(string, docker_client.StartExecOptions) (docker_client.CloseWaiter, error)
+	Logs(docker_client.LogsOptions) error
+	DownloadFromContainer(string, docker_client.DownloadFromContainerOptions) error
+	UploadToContainer(string, docker_client.UploadToContainerOptions) error
 	Stats(docker_client.StatsOptions) error
 	ResizeExecTTY(id string, height, width int) error
+	PullImage(docker_client.PullImageOptions, docker_client.AuthConfiguration) error
+	RemoveImageExtended(string, docker_client.RemoveImageOptions) error
+	PruneImages(docker_client.PruneImagesOptions) (*docker_client.PruneImagesResults, error)
 }
 
 func newDockerClient(endpoint string) (Client, error) {
@@ -106,6 +126,12 @@ type RegistryOptions struct {
 	DockerEndpoint         string
 	NoCommandLineArguments bool
 	NoEnvironmentVariables bool
+	// Jobs runs the image pulls, and reports downloads, if not nil.
+	Jobs *controls.Jobs
+	// RegistryCheckInterval is how often to resolve the tags of the images
+	// in use with their registry, to tell containers running outdated
+	// images. Zero disables the checks.
+	RegistryCheckInterval time.Duration
 }
 
 // NewRegistry returns a usable Registry. Don't forget to Stop it.
@@ -119,6 +145,8 @@ func NewRegistry(options RegistryOptions) (Registry, error) {
 		containers:      radix.New(),
 		containersByPID: map[int]Container{},
 		images:          map[string]docker_client.APIImages{},
+		imageDetails:    map[string]*docker_client.Image{},
+		registryDigests: map[string]string{},
 		pipeIDToexecID:  map[string]string{},
 
 		client:                 client,
@@ -130,16 +158,23 @@ func NewRegistry(options RegistryOptions) (Registry, error) {
 		quit:                   make(chan chan struct{}),
 		noCommandLineArguments: options.NoCommandLineArguments,
 		noEnvironmentVariables: options.NoEnvironmentVariables,
+		jobs:                   options.Jobs,
+		registryCheckInterval:  options.RegistryCheckInterval,
+		stopRegistryChecks:     make(chan struct{}),
 	}
 
 	r.registerControls()
 	go r.loop()
+	if r.registryCheckInterval > 0 {
+		go r.registryCheckLoop()
+	}
 	return r, nil
 }
 
 // Stop stops the Docker registry's event subscriber.
 func (r *registry) Stop() {
 	r.deregisterControls()
+	close(r.stopRegistryChecks)
 	ch := make(chan struct{})
 	r.quit <- ch
 	<-ch
@@ -254,6 +289,7 @@ func (r *registry) reset() {
 	r.containers = radix.New()
 	r.containersByPID = map[int]Container{}
 	r.images = map[string]docker_client.APIImages{}
+	r.imageDetails = map[string]*docker_client.Image{}
 	r.networks = r.networks[:0]
 }
 
@@ -276,16 +312,82 @@ func (r *registry) updateImages() error {
 		return err
 	}
 
+	// Only inspect the images we haven't seen yet; their details don't
+	// change.
+	r.RLock()
+	details := make(map[string]*docker_client.Image, len(images))
+	for _, image := range images {
+		id := trimImageID(image.ID)
+		if detail, ok := r.imageDetails[id]; ok {
+			details[id] = detail
+		}
+	}
+	r.RUnlock()
+	for _, image := range images {
+		id := trimImageID(image.ID)
+		if _, ok := details[id]; ok {
+			continue
+		}
+		detail, err := r.client.InspectImage(image.ID)
+		if err != nil {
+			log.Warnf("docker registry: unable to inspect image %s: %v", id, err)
+			continue
+		}
+		details[id] = detail
+	}
+
 	r.Lock()
 	defer r.Unlock()
 
+	r.images = make(map[string]docker_client.APIImages, len(images))
 	for _, image := range images {
 		r.images[trimImageID(image.ID)] = image
 	}
+	r.imageDetails = details
 
 	return nil
 }
 
+func (r *registry) registryCheckLoop() {
+	ticker := time.NewTicker(r.registryCheckInterval)
+	defer ticker.Stop()
+	for {
+		r.checkRegistry()
+		select {
+		case <-ticker.C:
+		case <-r.stopRegistryChecks:
+			return
+		}
+	}
+}
+
+// checkRegistry resolves the tags of the images of the containers with
+// their registry.
+func (r *registry) checkRegistry() {
+	tags := map[string]struct{}{}
+	r.WalkContainers(func(c Container) {
+		config := c.Container().Config
+		if config == nil {
+			return
+		}
+		if tag, ok := imageTagReference(config.Image); ok {
+			tags[tag] = struct{}{}
+		}
+	})
+	digests := make(map[string]string, len(tags))
+	for tag := range tags {
+		distribution, err := r.client.InspectDistribution(tag)
+		if err != nil {
+			log.Debugf("docker registry: unable to resolve %s: %v", tag, err)
+			continue
+		}
+		digests[tag] = distribution.Descriptor.Digest.String()
+	}
+	r.Lock()
+	r.registryDigests = digests
+	r.Unlock()
+}
+
 func (r *registry) updateNetworks() error {
 	networks, err := r.client.ListNetworks()
 	if err != nil {
@@ -302,16 +404,38 @@ func (r *registry) updateNetworks() error {
 func (r *registry) handleEvent(event *docker_client.APIEvents) {
 	// TODO: Send shortcut reports on networks being created/destroyed?
 	switch event.Status {
-	case CreateEvent, RenameEvent, StartEvent, DieEvent, PauseEvent, UnpauseEvent, NetworkConnectEvent, NetworkDisconnectEvent:
+	case DieEvent, OOMEvent:
+		r.recordEvent(event)
+		r.updateContainerState(event.ID)
+	case CreateEvent, RenameEvent, StartEvent, PauseEvent, UnpauseEvent, NetworkConnectEvent, NetworkDisconnectEvent:
 		r.updateContainerState(event.ID)
 	case DestroyEvent:
 		r.Lock()
 		r.deleteContainer(event.ID)
 		r.Unlock()
 		r.sendDeletedUpdate(event.ID)
+	default:
+		// The status of health events is "health_status: <health>"
+		if strings.HasPrefix(event.Status, HealthStatusEvent) {
+			r.updateContainerState(event.ID)
+		}
 	}
 }
 
+// recordEvent records the exits and OOM kills of containers, to tell those
+// in a restart loop.
+func (r *registry) recordEvent(event *docker_client.APIEvents) {
+	c, ok := r.GetContainer(event.ID)
+	if !ok {
+		return
+	}
+	t := mtime.Now()
+	if event.TimeNano != 0 {
+		t = time.Unix(0, event.TimeNano)
+	}
+	c.RecordEvent(event.Status, t)
+}
+
 func (r *registry) updateContainerState(containerID string) {
 	r.Lock()
 	defer r.Unlock()
@@ -445,6 +569,56 @@ func (r *registry) GetContainerImage(id string) (docker_client.APIImages, bool)
 	return image, ok
 }
 
+// GetImageDetails returns the inspected details of the image with the given ID.
+func (r *registry) GetImageDetails(id string) (*docker_client.Image, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	image, ok := r.imageDetails[id]
+	return image, ok
+}
+
+// GetNewerImage tells whether the tag the container was started from now
+// refers to a different image, either locally or in its registry, and
+// returns that image's ID or digest.
+func (r *registry) GetNewerImage(c Container) (string, bool) {
+	config := c.Container().Config
+	if config == nil {
+		return "", false
+	}
+	tag, ok := imageTagReference(config.Image)
+	if !ok {
+		return "", false
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+	for id, image := range r.images {
+		if id == c.Image() {
+			continue
+		}
+		for _, repoTag := range image.RepoTags {
+			if repoTag == tag {
+				return id, true
+			}
+		}
+	}
+	digest, ok := r.registryDigests[tag]
+	if !ok {
+		return "", false
+	}
+	// Images built locally have no repo digest to compare with
+	running, ok := r.imageDetails[c.Image()]
+	if !ok || len(running.RepoDigests) == 0 {
+		return "", false
+	}
+	for _, repoDigest := range running.RepoDigests {
+		if strings.HasSuffix(repoDigest, "@"+digest) {
+			return "", false
+		}
+	}
+	return digest, true
+}
+
 // WalkImages runs f on every image of running containers the registry
 // knows of.  f may be run on the same image more than once.
 func (r *registry) WalkImages(f func(docker_client.APIImages)) {
@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
+	docker_registry "github.com/docker/docker/api/types/registry"
 	client "github.com/fsouza/go-dockerclient"
+	"github.com/opencontainers/go-digest"
 
 	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/probe/controls"
@@ -55,6 +57,9 @@ func (c *mockContainer) State() string {
 }
 
 func (c *mockContainer) StateString() string {
+	if !c.c.State.Running {
+		return report.StateExited
+	}
 	return report.StateRunning
 }
 
@@ -87,13 +92,19 @@ func (c *mockContainer) Container() *client.Container {
 
 func (c *mockContainer) HasTTY() bool { return true }
 
+func (c *mockContainer) RecordEvent(string, time.Time) {}
+
 type mockDockerClient struct {
 	sync.RWMutex
 	apiContainers []client.APIContainers
 	containers    map[string]*client.Container
 	apiImages     []client.APIImages
+	images        map[string]*client.Image
+	distributions map[string]string
 	networks      []client.Network
 	events        []chan<- *client.APIEvents
+	pulled        []string
+	removed       []string
 }
 
 func (m *mockDockerClient) ListContainers(client.ListContainersOptions) ([]client.APIContainers, error) {
@@ -118,6 +129,46 @@ func (m *mockDockerClient) ListImages(client.ListImagesOptions) ([]client.APIIma
 	return m.apiImages, nil
 }
 
+func (m *mockDockerClient) InspectImage(id string) (*client.Image, error) {
+	m.RLock()
+	defer m.RUnlock()
+	image, ok := m.images[id]
+	if !ok {
+		return nil, client.ErrNoSuchImage
+	}
+	return image, nil
+}
+
+func (m *mockDockerClient) InspectDistribution(name string) (*docker_registry.DistributionInspect, error) {
+	m.RLock()
+	defer m.RUnlock()
+	d, ok := m.distributions[name]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", name)
+	}
+	result := &docker_registry.DistributionInspect{}
+	result.Descriptor.Digest = digest.Digest(d)
+	return result, nil
+}
+
+func (m *mockDockerClient) PullImage(opts client.PullImageOptions, _ client.AuthConfiguration) error {
+	m.Lock()
+	defer m.Unlock()
+	m.pulled = append(m.pulled, opts.Repository+":"+opts.Tag)
+	return nil
+}
+
+func (m *mockDockerClient) RemoveImageExtended(id string, _ client.RemoveImageOptions) error {
+	m.Lock()
+	defer m.Unlock()
+	m.removed = append(m.removed, id)
+	return nil
+}
+
+func (m *mockDockerClient) PruneImages(client.PruneImagesOptions) (*client.PruneImagesResults, error) {
+	return &client.PruneImagesResults{SpaceReclaimed: 2048}, nil
+}
+
 func (m *mockDockerClient) ListNetworks() ([]client.Network, error) {
 	m.RLock()
 	defer m.RUnlock()
@@ -174,6 +225,19 @@ func (m *mockDockerClient) ResizeExecTTY(id string, height, width int) error {
 	return fmt.Errorf("resizeExecTTY")
 }
 
+func (m *mockDockerClient) Logs(client.LogsOptions) error {
+	return nil
+}
+
+func (m *mockDockerClient) DownloadFromContainer(_ string, opts client.DownloadFromContainerOptions) error {
+	_, err := opts.OutputStream.Write([]byte("archive"))
+	return err
+}
+
+func (m *mockDockerClient) UploadToContainer(string, client.UploadToContainerOptions) error {
+	return nil
+}
+
 type mockCloseWaiter struct{}
 
 func (mockCloseWaiter) Close() error { return nil }
@@ -516,3 +580,100 @@ func TestRegistryDelete(t *testing.T) {
 		}
 	})
 }
+
+func TestRegistryNewerImage(t *testing.T) {
+	running := &client.Container{
+		ID:     "ping",
+		Image:  "baz",
+		State:  client.State{Pid: 2, Running: true},
+		Config: &client.Config{Image: "bang"},
+	}
+	newMDC := func() *mockDockerClient {
+		mdc := newMockClient()
+		mdc.containers = map[string]*client.Container{"ping": running}
+		mdc.images = map[string]*client.Image{
+			"baz": {ID: "baz", RepoDigests: []string{"bang@sha256:0123"}},
+		}
+		return mdc
+	}
+	newerImage := func(registry docker.Registry) interface{} {
+		c, ok := registry.GetContainer("ping")
+		if !ok {
+			return ""
+		}
+		newer, _ := registry.GetNewerImage(c)
+		return newer
+	}
+
+	// The image was inspected
+	mdc := newMDC()
+	setupStubs(mdc, func() {
+		registry := testRegistry()
+		defer registry.Stop()
+		test.Poll(t, 100*time.Millisecond, []string{"bang@sha256:0123"}, func() interface{} {
+			image, ok := registry.GetImageDetails("baz")
+			if !ok {
+				return nil
+			}
+			return image.RepoDigests
+		})
+		if newer, ok := registry.GetNewerImage(&mockContainer{running}); ok {
+			t.Errorf("Expected the image to be up to date, got %s", newer)
+		}
+	})
+
+	// The tag now refers to another local image
+	mdc = newMDC()
+	mdc.apiImages = append(mdc.apiImages, client.APIImages{ID: "sha256:qux", RepoTags: []string{"bang:latest"}})
+	setupStubs(mdc, func() {
+		registry := testRegistry()
+		defer registry.Stop()
+		test.Poll(t, 100*time.Millisecond, "qux", func() interface{} {
+			return newerImage(registry)
+		})
+	})
+
+	// The tag now refers to another image in the registry, and containers
+	// which are only partly inspected are skipped
+	mdc = newMDC()
+	mdc.distributions = map[string]string{"bang:latest": "sha256:4567"}
+	mdc.apiContainers = append(mdc.apiContainers, client.APIContainers{ID: "partial"})
+	mdc.containers["partial"] = &client.Container{ID: "partial", Image: "baz", State: client.State{Pid: 3, Running: true}}
+	setupStubs(mdc, func() {
+		registry, _ := docker.NewRegistry(docker.RegistryOptions{
+			Interval:              10 * time.Second,
+			HandlerRegistry:       controls.NewDefaultHandlerRegistry(),
+			RegistryCheckInterval: 10 * time.Millisecond,
+		})
+		defer registry.Stop()
+		test.Poll(t, 100*time.Millisecond, "sha256:4567", func() interface{} {
+			return newerImage(registry)
+		})
+	})
+}
+
+func TestRegistryRecordsExits(t *testing.T) {
+	mdc := newMockClient()
+	setupStubs(mdc, func() {
+		docker.NewContainerStub = docker.NewContainer
+		registry := testRegistry()
+		defer registry.Stop()
+		test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+			return len(allContainers(registry))
+		})
+
+		for _, status := range []string{docker.DieEvent, docker.OOMEvent, docker.DieEvent, "health_status: unhealthy"} {
+			mdc.send(&client.APIEvents{Status: status, ID: "ping", TimeNano: time.Now().UnixNano()})
+		}
+		for k, want := range map[string]string{
+			docker.ContainerRecentExits: "2",
+			docker.ContainerRecentOOMs:  "1",
+		} {
+			test.Poll(t, 100*time.Millisecond, want, func() interface{} {
+				c, _ := registry.GetContainer("ping")
+				have, _ := c.GetNode().Latest.Lookup(k)
+				return have
+			})
+		}
+	})
+}
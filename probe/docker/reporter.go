@@ -2,12 +2,16 @@ package docker
 
 import (
 	"net"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	humanize "github.com/dustin/go-humanize"
 	docker_client "github.com/fsouza/go-dockerclient"
 
 	"github.com/weaveworks/scope/probe"
+	"github.com/weaveworks/scope/probe/controls"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -18,6 +22,11 @@ const (
 	ImageTag         = report.DockerImageTag
 	ImageSize        = report.DockerImageSize
 	ImageVirtualSize = report.DockerImageVirtualSize
+	ImageCreated     = report.DockerImageCreated
+	ImageLayers      = report.DockerImageLayers
+	ImagePorts       = report.DockerImagePorts
+	ImageEntrypoint  = report.DockerImageEntrypoint
+	ImageDigest      = report.DockerImageDigest
 	IsInHostNetwork  = report.DockerIsInHostNetwork
 	ImageLabelPrefix = report.DockerImageLabelPrefix
 	ImageTableID     = "image_table"
@@ -40,15 +49,55 @@ var (
 		ContainerPorts:        {ID: ContainerPorts, Label: "Ports", From: report.FromSets, Priority: 9},
 		ContainerCreated:      {ID: ContainerCreated, Label: "Created", From: report.FromLatest, Datatype: report.DateTime, Priority: 10},
 		ContainerID:           {ID: ContainerID, Label: "ID", From: report.FromLatest, Truncate: 12, Priority: 11},
+		ContainerNewerImage:   {ID: ContainerNewerImage, Label: "Newer image", From: report.FromLatest, Priority: 12},
+		ContainerHealth:       {ID: ContainerHealth, Label: "Health", From: report.FromLatest, Priority: 13},
+		ContainerHealthOutput: {ID: ContainerHealthOutput, Label: "Last health check", From: report.FromLatest, Priority: 14},
+		ContainerExitCode:     {ID: ContainerExitCode, Label: "Last exit code", From: report.FromLatest, Priority: 15},
+		ContainerOOMKilled:    {ID: ContainerOOMKilled, Label: "OOM killed", From: report.FromLatest, Priority: 16},
+		ContainerLastRestart:  {ID: ContainerLastRestart, Label: "Last restart", From: report.FromLatest, Datatype: report.DateTime, Priority: 17},
+		ContainerRecentExits:  {ID: ContainerRecentExits, Label: "Recent exits", From: report.FromLatest, Datatype: report.Number, Priority: 18},
+		ContainerRecentOOMs:   {ID: ContainerRecentOOMs, Label: "Recent OOM kills", From: report.FromLatest, Datatype: report.Number, Priority: 19},
 	}
 
 	ContainerMetricTemplates = report.MetricTemplates{
 		CPUTotalUsage: {ID: CPUTotalUsage, Label: "CPU", Format: report.PercentFormat, Priority: 1},
 		MemoryUsage:   {ID: MemoryUsage, Label: "Memory", Format: report.FilesizeFormat, Priority: 2},
+	}.Merge(ContainerIOMetricTemplates)
+
+	// ContainerIOMetricTemplates are the network and block I/O metrics of
+	// containers, which are summed up to the images, pods and hosts of the
+	// containers when rendering.
+	ContainerIOMetricTemplates = report.MetricTemplates{
+		NetworkRxBytes:    {ID: NetworkRxBytes, Label: "RX bytes/s", Format: report.FilesizeFormat, Priority: 3},
+		NetworkTxBytes:    {ID: NetworkTxBytes, Label: "TX bytes/s", Format: report.FilesizeFormat, Priority: 4},
+		NetworkRxPackets:  {ID: NetworkRxPackets, Label: "RX packets/s", Format: report.DefaultFormat, Priority: 5},
+		NetworkTxPackets:  {ID: NetworkTxPackets, Label: "TX packets/s", Format: report.DefaultFormat, Priority: 6},
+		NetworkRxDropped:  {ID: NetworkRxDropped, Label: "RX dropped", Format: report.IntegerFormat, Priority: 7},
+		NetworkTxDropped:  {ID: NetworkTxDropped, Label: "TX dropped", Format: report.IntegerFormat, Priority: 8},
+		BlockIOReadBytes:  {ID: BlockIOReadBytes, Label: "Disk read/s", Format: report.FilesizeFormat, Priority: 9},
+		BlockIOWriteBytes: {ID: BlockIOWriteBytes, Label: "Disk write/s", Format: report.FilesizeFormat, Priority: 10},
+	}
+
+	// HostMetricTemplates are the container network and block I/O metrics
+	// summed up to the hosts, after the metrics of the host reporter.
+	HostMetricTemplates = report.MetricTemplates{
+		NetworkRxBytes:    {ID: NetworkRxBytes, Label: "Containers RX bytes/s", Format: report.FilesizeFormat, Priority: 12},
+		NetworkTxBytes:    {ID: NetworkTxBytes, Label: "Containers TX bytes/s", Format: report.FilesizeFormat, Priority: 13},
+		NetworkRxPackets:  {ID: NetworkRxPackets, Label: "Containers RX packets/s", Format: report.DefaultFormat, Priority: 14},
+		NetworkTxPackets:  {ID: NetworkTxPackets, Label: "Containers TX packets/s", Format: report.DefaultFormat, Priority: 15},
+		NetworkRxDropped:  {ID: NetworkRxDropped, Label: "Containers RX dropped", Format: report.IntegerFormat, Priority: 16},
+		NetworkTxDropped:  {ID: NetworkTxDropped, Label: "Containers TX dropped", Format: report.IntegerFormat, Priority: 17},
+		BlockIOReadBytes:  {ID: BlockIOReadBytes, Label: "Containers disk read/s", Format: report.FilesizeFormat, Priority: 18},
+		BlockIOWriteBytes: {ID: BlockIOWriteBytes, Label: "Containers disk write/s", Format: report.FilesizeFormat, Priority: 19},
 	}
 
 	ContainerImageMetadataTemplates = report.MetadataTemplates{
 		report.Container: {ID: report.Container, Label: "# Containers", From: report.FromCounters, Datatype: report.Number, Priority: 2},
+		ImageCreated:     {ID: ImageCreated, Label: "Created", From: report.FromLatest, Datatype: report.DateTime, Priority: 3},
+		ImageLayers:      {ID: ImageLayers, Label: "# Layers", From: report.FromLatest, Datatype: report.Number, Priority: 4},
+		ImagePorts:       {ID: ImagePorts, Label: "Exposed ports", From: report.FromSets, Priority: 5},
+		ImageEntrypoint:  {ID: ImageEntrypoint, Label: "Entrypoint", From: report.FromLatest, Priority: 6},
+		ImageDigest:      {ID: ImageDigest, Label: "Digest", From: report.FromLatest, Priority: 7},
 	}
 
 	ContainerTableTemplates = report.TableTemplates{
@@ -89,6 +138,13 @@ var (
 	}
 
 	ContainerControls = []report.Control{
+		{
+			ID:    GetLogs,
+			Human: "Get logs",
+			Icon:  "fa fa-file-alt",
+			Rank:  0,
+			Args:  controls.LogArgs,
+		},
 		{
 			ID:    AttachContainer,
 			Human: "Attach",
@@ -137,6 +193,53 @@ var (
 			Icon:  "far fa-trash-alt",
 			Rank:  8,
 		},
+		{
+			ID:    DownloadFile,
+			Human: "Download file",
+			Icon:  "fa fa-download",
+			Rank:  9,
+			Args:  controls.FileCopyArgs,
+		},
+		{
+			ID:    UploadFile,
+			Human: "Upload file",
+			Icon:  "fa fa-upload",
+			Rank:  10,
+			Args:  controls.FileCopyArgs,
+		},
+		{
+			ID:    PortForward,
+			Human: "Forward port",
+			Icon:  "fa fa-exchange-alt",
+			Rank:  11,
+			Args:  controls.PortForwardArgs,
+		},
+	}
+
+	ContainerImageControls = []report.Control{
+		{
+			ID:    PullImage,
+			Human: "Pull latest",
+			Icon:  "fa fa-cloud-download-alt",
+			Rank:  0,
+		},
+		{
+			ID:           RemoveImage,
+			Human:        "Remove",
+			Icon:         "far fa-trash-alt",
+			Rank:         1,
+			Confirmation: "Are you sure you want to remove this image?",
+		},
+	}
+
+	HostControls = []report.Control{
+		{
+			ID:           PruneImages,
+			Human:        "Prune dangling images",
+			Icon:         "fa fa-broom",
+			Rank:         20,
+			Confirmation: "Are you sure you want to remove the untagged images no container uses?",
+		},
 	}
 
 	SwarmServiceMetadataTemplates = report.MetadataTemplates{
@@ -189,6 +292,7 @@ func (r *Reporter) Report() (report.Report, error) {
 	result.ContainerImage = result.ContainerImage.Merge(r.containerImageTopology())
 	result.Overlay = result.Overlay.Merge(r.overlayTopology())
 	result.SwarmService = result.SwarmService.Merge(r.swarmServiceTopology())
+	result.Host = result.Host.Merge(r.hostTopology())
 	return result, nil
 }
 
@@ -216,9 +320,16 @@ func (r *Reporter) containerTopology(localAddrs []net.IP) report.Topology {
 
 	metadata := map[string]string{report.ControlProbeID: r.probeID}
 	nodes := []report.Node{}
+	containers := []Container{}
 	r.registry.WalkContainers(func(c Container) {
 		nodes = append(nodes, c.GetNode().WithLatests(metadata))
+		containers = append(containers, c)
 	})
+	for i, c := range containers {
+		if newer, ok := r.registry.GetNewerImage(c); ok {
+			nodes[i] = nodes[i].WithLatests(map[string]string{ContainerNewerImage: newer})
+		}
+	}
 
 	// Copy the IP addresses from other containers where they share network
 	// namespaces & deal with containers in the host net namespace.  This
@@ -274,29 +385,76 @@ func (r *Reporter) containerTopology(localAddrs []net.IP) report.Topology {
 func (r *Reporter) containerImageTopology() report.Topology {
 	result := report.MakeTopology().
 		WithMetadataTemplates(ContainerImageMetadataTemplates).
+		WithMetricTemplates(ContainerIOMetricTemplates).
 		WithTableTemplates(ContainerImageTableTemplates)
+	result.Controls.AddControls(ContainerImageControls)
+
+	// Images can only be removed when no container, even stopped, uses them
+	inUse := map[string]bool{}
+	r.registry.WalkContainers(func(c Container) {
+		inUse[c.Image()] = true
+	})
 
 	r.registry.WalkImages(func(image docker_client.APIImages) {
 		imageID := trimImageID(image.ID)
 		latests := map[string]string{
-			ImageID:          imageID,
-			ImageSize:        humanize.Bytes(uint64(image.Size)),
-			ImageVirtualSize: humanize.Bytes(uint64(image.VirtualSize)),
+			ImageID:               imageID,
+			ImageSize:             humanize.Bytes(uint64(image.Size)),
+			ImageVirtualSize:      humanize.Bytes(uint64(image.VirtualSize)),
+			report.ControlProbeID: r.probeID,
 		}
-		if len(image.RepoTags) > 0 {
+		controls := []string{}
+		if len(image.RepoTags) > 0 && image.RepoTags[0] != "<none>:<none>" {
 			imageFullName := image.RepoTags[0]
 			latests[ImageName] = ImageNameWithoutTag(imageFullName)
 			latests[ImageTag] = ImageNameTag(imageFullName)
+			controls = append(controls, PullImage)
+		}
+		if !inUse[imageID] {
+			controls = append(controls, RemoveImage)
 		}
 		nodeID := report.MakeContainerImageNodeID(imageID)
 		node := report.MakeNodeWith(nodeID, latests)
 		node = node.AddPrefixPropertyList(ImageLabelPrefix, image.Labels)
-		result.AddNode(node)
+		if details, ok := r.registry.GetImageDetails(imageID); ok {
+			node = withImageDetails(node, details)
+		}
+		result.AddNode(node.WithLatestActiveControls(controls...))
 	})
 
 	return result
 }
 
+// withImageDetails adds the details of an inspected image to its node.
+func withImageDetails(node report.Node, image *docker_client.Image) report.Node {
+	latests := map[string]string{}
+	if !image.Created.IsZero() {
+		latests[ImageCreated] = image.Created.Format(time.RFC3339Nano)
+	}
+	if image.RootFS != nil {
+		latests[ImageLayers] = strconv.Itoa(len(image.RootFS.Layers))
+	}
+	if len(image.RepoDigests) > 0 {
+		if i := strings.Index(image.RepoDigests[0], "@"); i >= 0 {
+			latests[ImageDigest] = image.RepoDigests[0][i+1:]
+		}
+	}
+	if image.Config != nil {
+		if len(image.Config.Entrypoint) > 0 {
+			latests[ImageEntrypoint] = strings.Join(image.Config.Entrypoint, " ")
+		}
+		if len(image.Config.ExposedPorts) > 0 {
+			ports := make([]string, 0, len(image.Config.ExposedPorts))
+			for port := range image.Config.ExposedPorts {
+				ports = append(ports, string(port))
+			}
+			sort.Strings(ports)
+			node = node.WithSet(ImagePorts, report.MakeStringSet(ports...))
+		}
+	}
+	return node.WithLatests(latests)
+}
+
 func (r *Reporter) overlayTopology() report.Topology {
 	subnets := []string{}
 	r.registry.WalkNetworks(func(network docker_client.Network) {
@@ -314,6 +472,15 @@ func (r *Reporter) overlayTopology() report.Topology {
 	return t
 }
 
+// hostTopology adds the image controls of the host, to the node of the
+// host reported by the host reporter.
+func (r *Reporter) hostTopology() report.Topology {
+	result := report.MakeTopology().WithMetricTemplates(HostMetricTemplates)
+	result.Controls.AddControls(HostControls)
+	result.AddNode(report.MakeNode(report.MakeHostNodeID(r.hostID)).WithLatestActiveControls(PruneImages))
+	return result
+}
+
 func (r *Reporter) swarmServiceTopology() report.Topology {
 	return report.MakeTopology().WithMetadataTemplates(SwarmServiceMetadataTemplates)
 }
@@ -2,6 +2,7 @@ package docker_test
 
 import (
 	"testing"
+	"time"
 
 	client "github.com/fsouza/go-dockerclient"
 
@@ -12,6 +13,8 @@ import (
 type mockRegistry struct {
 	containersByPID map[int]docker.Container
 	images          map[string]client.APIImages
+	imageDetails    map[string]*client.Image
+	newerImages     map[string]string
 	networks        []client.Network
 }
 
@@ -52,6 +55,16 @@ func (r *mockRegistry) GetContainerImage(id string) (client.APIImages, bool) {
 	return image, ok
 }
 
+func (r *mockRegistry) GetImageDetails(id string) (*client.Image, bool) {
+	image, ok := r.imageDetails[id]
+	return image, ok
+}
+
+func (r *mockRegistry) GetNewerImage(c docker.Container) (string, bool) {
+	image, ok := r.newerImages[c.ID()]
+	return image, ok
+}
+
 var (
 	imageID              = "baz"
 	mockRegistryInstance = &mockRegistry{
@@ -61,7 +74,19 @@ var (
 		images: map[string]client.APIImages{
 			imageID: apiImage1,
 		},
-		networks: []client.Network{network1},
+		imageDetails: map[string]*client.Image{
+			imageID: {
+				Created:     time.Date(2018, time.March, 1, 12, 0, 0, 0, time.UTC),
+				RepoDigests: []string{"bang@sha256:0123"},
+				RootFS:      &client.RootFS{Layers: []string{"a", "b", "c"}},
+				Config: &client.Config{
+					Entrypoint:   []string{"/bin/ping", "-c"},
+					ExposedPorts: map[client.Port]struct{}{"80/tcp": {}, "53/udp": {}},
+				},
+			},
+		},
+		newerImages: map[string]string{"ping": "sha256:4567"},
+		networks:    []client.Network{network1},
 	}
 )
 
@@ -86,10 +111,11 @@ func TestReporter(t *testing.T) {
 		}
 
 		for k, want := range map[string]string{
-			docker.ContainerID:    "ping",
-			docker.ContainerName:  "pong",
-			docker.ImageID:        imageID,
-			report.ControlProbeID: controlProbeID,
+			docker.ContainerID:         "ping",
+			docker.ContainerName:       "pong",
+			docker.ImageID:             imageID,
+			docker.ContainerNewerImage: "sha256:4567",
+			report.ControlProbeID:      controlProbeID,
 		} {
 			if have, ok := node.Latest.Lookup(k); !ok || have != want {
 				t.Errorf("Expected container %s latest %q: %q, got %q", containerNodeID, k, want, have)
@@ -119,15 +145,34 @@ func TestReporter(t *testing.T) {
 			docker.ImageName:                    "bang",
 			docker.ImageLabelPrefix + "imgfoo1": "bar1",
 			docker.ImageLabelPrefix + "imgfoo2": "bar2",
+			docker.ImageCreated:                 "2018-03-01T12:00:00Z",
+			docker.ImageLayers:                  "3",
+			docker.ImageEntrypoint:              "/bin/ping -c",
+			docker.ImageDigest:                  "sha256:0123",
+			report.ControlProbeID:               controlProbeID,
 		} {
 			if have, ok := node.Latest.Lookup(k); !ok || have != want {
 				t.Errorf("Expected container image %s latest %q: %q, got %q", containerImageNodeID, k, want, have)
 			}
 		}
+		if have, _ := node.Sets.Lookup(docker.ImagePorts); !have.Equal(report.MakeStringSet("53/udp", "80/tcp")) {
+			t.Errorf("Unexpected exposed ports %v", have)
+		}
+
+		// the image can be pulled, but not removed while a container runs it
+		if have := node.ActiveControls(); len(have) != 1 || have[0] != docker.PullImage {
+			t.Errorf("Expected only the pull control to be active, got %v", have)
+		}
+	}
 
-		// container image should have no controls
-		if len(rpt.ContainerImage.Controls) != 0 {
-			t.Errorf("Container images should not have any controls")
+	// Reporter should add the prune control to the host
+	{
+		node, ok := rpt.Host.Nodes[report.MakeHostNodeID(hostID)]
+		if !ok {
+			t.Fatalf("Expected report to have host %q, but not found", hostID)
+		}
+		if have := node.ActiveControls(); len(have) != 1 || have[0] != docker.PruneImages {
+			t.Errorf("Expected the prune control to be active, got %v", have)
 		}
 	}
 
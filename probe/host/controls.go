@@ -1,7 +1,13 @@
 package host
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os/exec"
+	"strings"
 
 	"github.com/docker/docker/pkg/term"
 	"github.com/kr/pty"
@@ -15,16 +21,22 @@ import (
 const (
 	ExecHost      = "host_exec"
 	ResizeExecTTY = "host_resize_exec_tty"
+	DownloadFile  = "host_download_file"
+	UploadFile    = "host_upload_file"
 )
 
 func (r *Reporter) registerControls() {
 	r.handlerRegistry.Register(ExecHost, r.execHost)
 	r.handlerRegistry.Register(ResizeExecTTY, xfer.ResizeTTYControlWrapper(r.resizeExecTTY))
+	r.handlerRegistry.Register(DownloadFile, r.downloadFile)
+	r.handlerRegistry.Register(UploadFile, r.uploadFile)
 }
 
 func (r *Reporter) deregisterControls() {
 	r.handlerRegistry.Rm(ExecHost)
 	r.handlerRegistry.Rm(ResizeExecTTY)
+	r.handlerRegistry.Rm(DownloadFile)
+	r.handlerRegistry.Rm(UploadFile)
 }
 
 func (r *Reporter) execHost(req xfer.Request) xfer.Response {
@@ -93,3 +105,41 @@ func (r *Reporter) resizeExecTTY(pipeID string, height, width uint) xfer.Respons
 	return xfer.Response{}
 
 }
+
+func (r *Reporter) downloadFile(req xfer.Request) xfer.Response {
+	opts, err := xfer.ParseFileCopyOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	log.Infof("Downloading %s from the host", opts.Path)
+	return controls.Download(r.pipes, r.jobs, req, opts, func(ctx context.Context, w io.Writer) error {
+		return r.runOnHost(ctx, controls.DownloadCommand(opts), nil, w)
+	})
+}
+
+func (r *Reporter) uploadFile(req xfer.Request) xfer.Response {
+	opts, err := xfer.ParseFileCopyOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	log.Infof("Uploading to %s on the host", opts.Path)
+	return controls.Upload(r.pipes, req, opts, func(ctx context.Context, archive io.Reader) error {
+		return r.runOnHost(ctx, controls.UploadCommand(opts), archive, ioutil.Discard)
+	})
+}
+
+// runOnHost runs a command in the host's mount namespace, failing with what
+// it printed to stderr.
+func (r *Reporter) runOnHost(ctx context.Context, command []string, stdin io.Reader, stdout io.Writer) error {
+	argv := append(append([]string{}, r.hostCmdPrefix...), command...)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -3,3 +3,7 @@ package host
 func getHostShellCmd() []string {
 	return []string{"/bin/bash", "-l"}
 }
+
+func getHostCmdPrefix() []string {
+	return nil
+}
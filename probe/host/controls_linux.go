@@ -31,6 +31,15 @@ func getHostShellCmd() []string {
 	return []string{shell, "-l"}
 }
 
+// getHostCmdPrefix returns the prefix of commands run on the host, rather
+// than in the probe's container, such as to copy files.
+func getHostCmdPrefix() []string {
+	if isProbeContainerized() {
+		return []string{"/usr/bin/nsenter", "-t1", "-m", "--no-fork"}
+	}
+	return nil
+}
+
 func getRootUserDetails(readPasswdCmd []string) (uid, gid, shell string) {
 	uid = "0"
 	gid = "0"
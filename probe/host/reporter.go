@@ -24,6 +24,7 @@ const (
 	CPUUsage      = report.HostCPUUsage
 	MemoryUsage   = report.HostMemoryUsage
 	ScopeVersion  = report.ScopeVersion
+	ProbeLastSeen = report.ProbeLastSeen
 )
 
 // Exposed for testing.
@@ -43,6 +44,7 @@ var (
 		OS:            {ID: OS, Label: "OS", From: report.FromLatest, Priority: 12},
 		LocalNetworks: {ID: LocalNetworks, Label: "Local networks", From: report.FromSets, Priority: 13},
 		ScopeVersion:  {ID: ScopeVersion, Label: "Scope version", From: report.FromLatest, Priority: 14},
+		ProbeLastSeen: {ID: ProbeLastSeen, Label: "Probe last seen", From: report.FromLatest, Datatype: report.DateTime, Priority: 15},
 	}
 
 	MetricTemplates = report.MetricTemplates{
@@ -61,13 +63,15 @@ type Reporter struct {
 	version         string
 	pipes           controls.PipeClient
 	hostShellCmd    []string
+	hostCmdPrefix   []string
 	handlerRegistry *controls.HandlerRegistry
+	jobs            *controls.Jobs
 	pipeIDToTTY     map[string]uintptr
 }
 
 // NewReporter returns a Reporter which produces a report containing host
-// topology for this host.
-func NewReporter(hostID, hostName, probeID, version string, pipes controls.PipeClient, handlerRegistry *controls.HandlerRegistry) *Reporter {
+// topology for this host. Downloads are reported as jobs, if jobs is not nil.
+func NewReporter(hostID, hostName, probeID, version string, pipes controls.PipeClient, handlerRegistry *controls.HandlerRegistry, jobs *controls.Jobs) *Reporter {
 	r := &Reporter{
 		hostID:          hostID,
 		hostName:        hostName,
@@ -75,7 +79,9 @@ func NewReporter(hostID, hostName, probeID, version string, pipes controls.PipeC
 		pipes:           pipes,
 		version:         version,
 		hostShellCmd:    getHostShellCmd(),
+		hostCmdPrefix:   getHostCmdPrefix(),
 		handlerRegistry: handlerRegistry,
+		jobs:            jobs,
 		pipeIDToTTY:     map[string]uintptr{},
 	}
 	r.registerControls()
@@ -138,7 +144,7 @@ func (r *Reporter) Report() (report.Report, error) {
 				Add(LocalNetworks, report.MakeStringSet(localCIDRs...)),
 			).
 			WithMetrics(metrics).
-			WithLatestActiveControls(ExecHost),
+			WithLatestActiveControls(ExecHost, DownloadFile, UploadFile),
 	)
 
 	rep.Host.Controls.AddControl(report.Control{
@@ -146,6 +152,20 @@ func (r *Reporter) Report() (report.Report, error) {
 		Human: "Exec shell",
 		Icon:  "fa fa-terminal",
 	})
+	rep.Host.Controls.AddControl(report.Control{
+		ID:    DownloadFile,
+		Human: "Download file",
+		Icon:  "fa fa-download",
+		Rank:  1,
+		Args:  controls.FileCopyArgs,
+	})
+	rep.Host.Controls.AddControl(report.Control{
+		ID:    UploadFile,
+		Human: "Upload file",
+		Icon:  "fa fa-upload",
+		Rank:  2,
+		Args:  controls.FileCopyArgs,
+	})
 
 	return rep, nil
 }
@@ -57,7 +57,7 @@ func TestReporter(t *testing.T) {
 	host.GetLocalNetworks = func() ([]*net.IPNet, error) { return []*net.IPNet{ipnet}, nil }
 
 	hr := controls.NewDefaultHandlerRegistry()
-	rpt, err := host.NewReporter(hostID, hostname, "probe-id", "", nil, hr).Report()
+	rpt, err := host.NewReporter(hostID, hostname, "probe-id", "", nil, hr, nil).Report()
 	if err != nil {
 		t.Fatal(err)
 	}
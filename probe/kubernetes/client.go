@@ -2,15 +2,20 @@ package kubernetes
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/weaveworks/common/backoff"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
 
 	snapshotv1 "github.com/openebs/k8s-snapshot-client/snapshot/pkg/apis/volumesnapshot/v1"
 	snapshot "github.com/openebs/k8s-snapshot-client/snapshot/pkg/client/clientset/versioned"
@@ -21,14 +26,19 @@ import (
 	apibatchv1 "k8s.io/api/batch/v1"
 	apibatchv1beta1 "k8s.io/api/batch/v1beta1"
 	apiv1 "k8s.io/api/core/v1"
+	apipolicyv1beta1 "k8s.io/api/policy/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -44,6 +54,7 @@ type Client interface {
 	WalkPods(f func(Pod) error) error
 	WalkServices(f func(Service) error) error
 	WalkDeployments(f func(Deployment) error) error
+	WalkReplicaSets(f func(ReplicaSet) error) error
 	WalkDaemonSets(f func(DaemonSet) error) error
 	WalkStatefulSets(f func(StatefulSet) error) error
 	WalkCronJobs(f func(CronJob) error) error
@@ -54,17 +65,32 @@ type Client interface {
 	WalkVolumeSnapshots(f func(VolumeSnapshot) error) error
 	WalkVolumeSnapshotData(f func(VolumeSnapshotData) error) error
 	WalkJobs(f func(Job) error) error
+	WalkCustomResources(f func(CustomResource) error) error
+	CustomResourceConfigs() []CustomResourceConfig
 
 	WatchPods(f func(Event, Pod))
 
-	CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) error
+	CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) (string, error)
 	CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capacity string) error
-	GetLogs(namespaceID, podID string, containerNames []string) (io.ReadCloser, error)
+	GetLogs(namespaceID, podID string, containerNames []string, opts xfer.LogOptions) (io.ReadCloser, error)
+	// GetPodsLogs merges the logs of the containers of several pods,
+	// labelling the lines with their pod and container. Containers whose
+	// logs can't be opened are skipped, and at most maxLogStreams merged.
+	GetPodsLogs(namespaceID string, podContainers map[string][]string, opts xfer.LogOptions) (io.ReadCloser, error)
 	Describe(namespaceID, resourceID string, groupKind schema.GroupKind, restMapping apimeta.RESTMapping) (io.ReadCloser, error)
 	DeletePod(namespaceID, podID string) error
+	// Evict a pod through the Eviction API, which respects PodDisruptionBudgets.
+	EvictPod(namespaceID, podID string) error
+	// Exec runs a command in a container of a pod, feeding it stdin if not
+	// nil, and returns once it has exited.
+	Exec(ctx context.Context, namespaceID, podID, container string, command []string, stdin io.Reader, stdout io.Writer) error
 	DeleteVolumeSnapshot(namespaceID, volumeSnapshotID string) error
 	ScaleUp(namespaceID, id string) error
 	ScaleDown(namespaceID, id string) error
+	Scale(namespaceID, id string, replicas int32) error
+	// RestartWorkload restarts the pods of a deployment, daemon set or
+	// stateful set by rolling them out again, as kubectl rollout restart.
+	RestartWorkload(kind, namespaceID, id string, at time.Time) error
 	// Cordon or Uncordon a node based on whether `desired` is true or false respectively.
 	CordonNode(name string, desired bool) error
 	// Returns a list of kubernetes nodes.
@@ -76,6 +102,7 @@ var ResourceMap = map[string]schema.GroupKind{
 	"Pod":                   {Group: apiv1.GroupName, Kind: "Pod"},
 	"Service":               {Group: apiv1.GroupName, Kind: "Service"},
 	"Deployment":            {Group: apiappsv1.GroupName, Kind: "Deployment"},
+	"ReplicaSet":            {Group: apiappsv1.GroupName, Kind: "ReplicaSet"},
 	"DaemonSet":             {Group: apiappsv1.GroupName, Kind: "DaemonSet"},
 	"StatefulSet":           {Group: apiappsv1.GroupName, Kind: "StatefulSet"},
 	"Job":                   {Group: apibatchv1.GroupName, Kind: "Job"},
@@ -89,10 +116,12 @@ var ResourceMap = map[string]schema.GroupKind{
 type client struct {
 	quit                       chan struct{}
 	client                     *kubernetes.Clientset
+	restConfig                 *rest.Config
 	snapshotClient             *snapshot.Clientset
 	podStore                   cache.Store
 	serviceStore               cache.Store
 	deploymentStore            cache.Store
+	replicaSetStore            cache.Store
 	daemonSetStore             cache.Store
 	statefulSetStore           cache.Store
 	jobStore                   cache.Store
@@ -105,6 +134,10 @@ type client struct {
 	volumeSnapshotStore        cache.Store
 	volumeSnapshotDataStore    cache.Store
 
+	dynamicClient        dynamic.Interface
+	customResources      []CustomResourceConfig
+	customResourceStores []cache.Store
+
 	podWatchesMutex sync.Mutex
 	podWatches      []func(Event, Pod)
 }
@@ -123,6 +156,9 @@ type ClientConfig struct {
 	Token                string
 	User                 string
 	Username             string
+
+	// CustomResources are the kinds of custom resources to report.
+	CustomResources []CustomResourceConfig
 }
 
 // NewClient returns a usable Client. Don't forget to Stop it.
@@ -177,10 +213,18 @@ func NewClient(config ClientConfig) (Client, error) {
 		return nil, err
 	}
 
+	dc, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	result := &client{
-		quit:           make(chan struct{}),
-		client:         c,
-		snapshotClient: sc,
+		quit:            make(chan struct{}),
+		client:          c,
+		restConfig:      restConfig,
+		snapshotClient:  sc,
+		dynamicClient:   dc,
+		customResources: config.CustomResources,
 	}
 
 	result.podStore = NewEventStore(result.triggerPodWatches, cache.MetaNamespaceKeyFunc)
@@ -190,6 +234,7 @@ func NewClient(config ClientConfig) (Client, error) {
 	result.nodeStore = result.setupStore("nodes")
 	result.namespaceStore = result.setupStore("namespaces")
 	result.deploymentStore = result.setupStore("deployments")
+	result.replicaSetStore = result.setupStore("replicasets")
 	result.daemonSetStore = result.setupStore("daemonsets")
 	result.jobStore = result.setupStore("jobs")
 	result.statefulSetStore = result.setupStore("statefulsets")
@@ -200,6 +245,12 @@ func NewClient(config ClientConfig) (Client, error) {
 	result.volumeSnapshotStore = result.setupStore("volumesnapshots")
 	result.volumeSnapshotDataStore = result.setupStore("volumesnapshotdatas")
 
+	for _, crd := range result.customResources {
+		store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		result.runCustomResourceReflectorUntil(crd.GroupVersionResource(), store)
+		result.customResourceStores = append(result.customResourceStores, store)
+	}
+
 	return result, nil
 }
 
@@ -245,6 +296,8 @@ func (c *client) clientAndType(resource string) (rest.Interface, interface{}, er
 		return c.client.StorageV1().RESTClient(), &storagev1.StorageClass{}, nil
 	case "deployments":
 		return c.client.AppsV1().RESTClient(), &apiappsv1.Deployment{}, nil
+	case "replicasets":
+		return c.client.AppsV1().RESTClient(), &apiappsv1.ReplicaSet{}, nil
 	case "daemonsets":
 		return c.client.AppsV1().RESTClient(), &apiappsv1.DaemonSet{}, nil
 	case "jobs":
@@ -264,14 +317,42 @@ func (c *client) clientAndType(resource string) (rest.Interface, interface{}, er
 // runReflectorUntil runs cache.Reflector#ListAndWatch in an endless loop, after checking that the resource is supported by kubernetes.
 // Errors are logged and retried with exponential backoff.
 func (c *client) runReflectorUntil(resource string, store cache.Store) {
+	c.runListerWatcherUntil(resource, store, func() (schema.GroupVersion, cache.ListerWatcher, interface{}, error) {
+		kclient, itemType, err := c.clientAndType(resource)
+		if err != nil {
+			return schema.GroupVersion{}, nil, nil, err
+		}
+		lw := cache.NewListWatchFromClient(kclient, resource, metav1.NamespaceAll, fields.Everything())
+		return kclient.APIVersion(), lw, itemType, nil
+	})
+}
+
+// runCustomResourceReflectorUntil is runReflectorUntil for custom resources,
+// which are listed and watched with the dynamic client.
+func (c *client) runCustomResourceReflectorUntil(gvr schema.GroupVersionResource, store cache.Store) {
+	resource := c.dynamicClient.Resource(gvr)
+	c.runListerWatcherUntil(gvr.Resource, store, func() (schema.GroupVersion, cache.ListerWatcher, interface{}, error) {
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return resource.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return resource.Watch(options)
+			},
+		}
+		return gvr.GroupVersion(), lw, &unstructured.Unstructured{}, nil
+	})
+}
+
+func (c *client) runListerWatcherUntil(resource string, store cache.Store, listerWatcher func() (schema.GroupVersion, cache.ListerWatcher, interface{}, error)) {
 	var r *cache.Reflector
 	listAndWatch := func() (bool, error) {
 		if r == nil {
-			kclient, itemType, err := c.clientAndType(resource)
+			groupVersion, lw, itemType, err := listerWatcher()
 			if err != nil {
 				return false, err
 			}
-			ok, err := c.isResourceSupported(kclient.APIVersion(), resource)
+			ok, err := c.isResourceSupported(groupVersion, resource)
 			if err != nil {
 				return false, err
 			}
@@ -279,7 +360,6 @@ func (c *client) runReflectorUntil(resource string, store cache.Store) {
 				log.Infof("%v are not supported by this Kubernetes version", resource)
 				return true, nil
 			}
-			lw := cache.NewListWatchFromClient(kclient, resource, metav1.NamespaceAll, fields.Everything())
 			r = cache.NewReflector(lw, itemType, store, 0)
 		}
 
@@ -373,6 +453,17 @@ func (c *client) WalkDeployments(f func(Deployment) error) error {
 	return nil
 }
 
+// WalkReplicaSets calls f for each replicaset
+func (c *client) WalkReplicaSets(f func(ReplicaSet) error) error {
+	for _, m := range c.replicaSetStore.List() {
+		rs := m.(*apiappsv1.ReplicaSet)
+		if err := f(NewReplicaSet(rs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WalkDaemonSets calls f for each daemonset
 func (c *client) WalkDaemonSets(f func(DaemonSet) error) error {
 	if c.daemonSetStore == nil {
@@ -461,14 +552,31 @@ func (c *client) WalkJobs(f func(Job) error) error {
 	return nil
 }
 
-func (c *client) CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) error {
+// WalkCustomResources calls f for each custom resource, of every configured kind
+func (c *client) WalkCustomResources(f func(CustomResource) error) error {
+	for i, store := range c.customResourceStores {
+		for _, m := range store.List() {
+			u := m.(*unstructured.Unstructured)
+			if err := f(NewCustomResource(&c.customResources[i], u)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *client) CustomResourceConfigs() []CustomResourceConfig {
+	return c.customResources
+}
+
+func (c *client) CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) (string, error) {
 	var scName string
 	var claimSize string
 	UID := strings.Split(uuid.New(), "-")
 	scProvisionerName := "volumesnapshot.external-storage.k8s.io/snapshot-promoter"
 	scList, err := c.client.StorageV1().StorageClasses().List(metav1.ListOptions{})
 	if err != nil {
-		return err
+		return "", err
 	}
 	// Retrieve the first snapshot-promoter storage class
 	for _, sc := range scList.Items {
@@ -478,7 +586,7 @@ func (c *client) CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVo
 		}
 	}
 	if scName == "" {
-		return errors.New("snapshot-promoter storage class is not present")
+		return "", errors.New("snapshot-promoter storage class is not present")
 	}
 	volumeSnapshot, _ := c.snapshotClient.VolumesnapshotV1().VolumeSnapshots(namespaceID).Get(volumeSnapshotID, metav1.GetOptions{})
 	if volumeSnapshot.Spec.PersistentVolumeClaimName != "" {
@@ -518,9 +626,9 @@ func (c *client) CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVo
 	}
 	_, err = c.client.CoreV1().PersistentVolumeClaims(namespaceID).Create(persistentVolumeClaim)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	return persistentVolumeClaim.Name, nil
 }
 
 func (c *client) CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capacity string) error {
@@ -544,28 +652,100 @@ func (c *client) CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capa
 	return nil
 }
 
-func (c *client) GetLogs(namespaceID, podID string, containerNames []string) (io.ReadCloser, error) {
+func (c *client) GetLogs(namespaceID, podID string, containerNames []string, opts xfer.LogOptions) (io.ReadCloser, error) {
+	return c.getLogs(namespaceID, map[string][]string{podID: containerNames}, opts, false, func(_, container string) string {
+		return container
+	})
+}
+
+func (c *client) GetPodsLogs(namespaceID string, podContainers map[string][]string, opts xfer.LogOptions) (io.ReadCloser, error) {
+	return c.getLogs(namespaceID, podContainers, opts, true, func(pod, container string) string {
+		return pod + "/" + container
+	})
+}
+
+func (c *client) getLogs(namespaceID string, podContainers map[string][]string, opts xfer.LogOptions, skipFailed bool, label func(pod, container string) string) (io.ReadCloser, error) {
+	logOptions := apiv1.PodLogOptions{
+		Follow:     opts.Follow,
+		Previous:   opts.Previous,
+		Timestamps: true,
+	}
+	if opts.Since > 0 {
+		sinceSeconds := int64(opts.Since / time.Second)
+		logOptions.SinceSeconds = &sinceSeconds
+	}
+	if opts.Tail >= 0 {
+		logOptions.TailLines = &opts.Tail
+	}
+
+	readClosersWithLabel, err := openLogStreams(podContainers, skipFailed, label, func(podID, container string) (io.ReadCloser, error) {
+		containerOptions := logOptions
+		containerOptions.Container = container
+		return c.client.CoreV1().Pods(namespaceID).GetLogs(podID, &containerOptions).Stream()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewLogReadCloser(readClosersWithLabel), nil
+}
+
+// maxLogStreams is the most containers whose logs are merged: each is a
+// request to the API server, streaming for as long as the logs are followed.
+var maxLogStreams = 20
+
+// openLogStreams opens the logs of the containers of the pods, labelled, up
+// to maxLogStreams of them. With skipFailed, the containers whose logs can't
+// be opened, e.g. as they have no previous instance, are skipped with a note
+// in their place, unless none can be opened.
+func openLogStreams(podContainers map[string][]string, skipFailed bool, label func(pod, container string) string, open func(pod, container string) (io.ReadCloser, error)) (map[io.ReadCloser]string, error) {
+	type stream struct{ pod, container string }
+	var streams []stream
+	for pod, containers := range podContainers {
+		for _, container := range containers {
+			streams = append(streams, stream{pod, container})
+		}
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		if streams[i].pod != streams[j].pod {
+			return streams[i].pod < streams[j].pod
+		}
+		return streams[i].container < streams[j].container
+	})
+
+	note := func(format string, args ...interface{}) io.ReadCloser {
+		return ioutil.NopCloser(strings.NewReader(fmt.Sprintf(format+"\n", args...)))
+	}
 	readClosersWithLabel := map[io.ReadCloser]string{}
-	for _, container := range containerNames {
-		req := c.client.CoreV1().Pods(namespaceID).GetLogs(
-			podID,
-			&apiv1.PodLogOptions{
-				Follow:     true,
-				Timestamps: true,
-				Container:  container,
-			},
-		)
-		readCloser, err := req.Stream()
-		if err != nil {
+	if len(streams) > maxLogStreams {
+		readClosersWithLabel[note("not showing the logs of %d more containers", len(streams)-maxLogStreams)] = "scope"
+		streams = streams[:maxLogStreams]
+	}
+	var (
+		opened   int
+		firstErr error
+	)
+	for _, s := range streams {
+		readCloser, err := open(s.pod, s.container)
+		if err == nil {
+			readClosersWithLabel[readCloser] = label(s.pod, s.container)
+			opened++
+			continue
+		}
+		if !skipFailed {
 			for rc := range readClosersWithLabel {
 				rc.Close()
 			}
 			return nil, err
 		}
-		readClosersWithLabel[readCloser] = container
+		if firstErr == nil {
+			firstErr = err
+		}
+		readClosersWithLabel[note("skipped: %v", err)] = label(s.pod, s.container)
 	}
-
-	return NewLogReadCloser(readClosersWithLabel), nil
+	if opened == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return readClosersWithLabel, nil
 }
 
 func (c *client) Describe(namespaceID, resourceID string, groupKind schema.GroupKind, restMapping apimeta.RESTMapping) (io.ReadCloser, error) {
@@ -598,6 +778,12 @@ func (c *client) DeletePod(namespaceID, podID string) error {
 	return c.client.CoreV1().Pods(namespaceID).Delete(podID, &metav1.DeleteOptions{})
 }
 
+func (c *client) EvictPod(namespaceID, podID string) error {
+	return c.client.CoreV1().Pods(namespaceID).Evict(&apipolicyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceID, Name: podID},
+	})
+}
+
 func (c *client) DeleteVolumeSnapshot(namespaceID, volumeSnapshotID string) error {
 	return c.snapshotClient.VolumesnapshotV1().VolumeSnapshots(namespaceID).Delete(volumeSnapshotID, &metav1.DeleteOptions{})
 }
@@ -614,6 +800,12 @@ func (c *client) ScaleDown(namespaceID, id string) error {
 	})
 }
 
+func (c *client) Scale(namespaceID, id string, replicas int32) error {
+	return c.modifyScale(namespaceID, id, func(scale *autoscalingv1.Scale) {
+		scale.Spec.Replicas = replicas
+	})
+}
+
 func (c *client) modifyScale(namespaceID, id string, f func(*autoscalingv1.Scale)) error {
 	scaler := c.client.AppsV1().Deployments(namespaceID)
 	scale, err := scaler.GetScale(id, metav1.GetOptions{})
@@ -625,6 +817,39 @@ func (c *client) modifyScale(namespaceID, id string, f func(*autoscalingv1.Scale
 	return err
 }
 
+// restartedAtAnnotation is the pod template annotation kubectl rollout
+// restart sets, which changes the template and so rolls the pods out.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+func (c *client) RestartWorkload(kind, namespaceID, id string, at time.Time) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: at.Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	apps := c.client.AppsV1()
+	switch kind {
+	case report.Deployment:
+		_, err = apps.Deployments(namespaceID).Patch(id, types.StrategicMergePatchType, patch)
+	case report.DaemonSet:
+		_, err = apps.DaemonSets(namespaceID).Patch(id, types.StrategicMergePatchType, patch)
+	case report.StatefulSet:
+		_, err = apps.StatefulSets(namespaceID).Patch(id, types.StrategicMergePatchType, patch)
+	default:
+		err = fmt.Errorf("Cannot restart a %s", kind)
+	}
+	return err
+}
+
 func (c *client) Stop() {
 	close(c.quit)
 }
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOpenLogStreams(t *testing.T) {
+	oldMaxLogStreams := maxLogStreams
+	defer func() { maxLogStreams = oldMaxLogStreams }()
+	maxLogStreams = 3
+
+	podContainers := map[string][]string{
+		"pod1": {"app", "sidecar"},
+		"pod2": {"app", "sidecar"},
+	}
+	label := func(pod, container string) string { return pod + "/" + container }
+	open := func(pod, container string) (io.ReadCloser, error) {
+		if pod == "pod2" {
+			return nil, fmt.Errorf("previous terminated container %q not found", container)
+		}
+		return ioutil.NopCloser(strings.NewReader("logs\n")), nil
+	}
+	contents := func(readClosers map[io.ReadCloser]string) []string {
+		var result []string
+		for rc, label := range readClosers {
+			buf, _ := ioutil.ReadAll(rc)
+			result = append(result, label+": "+strings.TrimSpace(string(buf)))
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	readClosers, err := openLogStreams(podContainers, true, label, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"pod1/app: logs",
+		"pod1/sidecar: logs",
+		`pod2/app: skipped: previous terminated container "app" not found`,
+		"scope: not showing the logs of 1 more containers",
+	}
+	if got := contents(readClosers); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := openLogStreams(podContainers, false, label, open); err == nil {
+		t.Error("Expected an error without skipping")
+	}
+	if _, err := openLogStreams(map[string][]string{"pod2": {"app"}}, true, label, open); err == nil {
+		t.Error("Expected an error when no logs can be opened")
+	}
+}
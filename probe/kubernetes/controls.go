@@ -1,13 +1,23 @@
 package kubernetes
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/controls"
 	"github.com/weaveworks/scope/report"
+	apiv1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -21,8 +31,21 @@ const (
 	DeleteVolumeSnapshot = report.KubernetesDeleteVolumeSnapshot
 	ScaleUp              = report.KubernetesScaleUp
 	ScaleDown            = report.KubernetesScaleDown
+	Scale                = report.KubernetesScale
+	RestartWorkload      = report.KubernetesRestartWorkload
 	CordonNode           = report.KubernetesCordonNode
 	UncordonNode         = report.KubernetesUncordonNode
+	DrainNode            = report.KubernetesDrainNode
+	GetWorkloadLogs      = report.KubernetesGetWorkloadLogs
+	DownloadFile         = report.KubernetesDownloadFile
+	UploadFile           = report.KubernetesUploadFile
+	PortForward          = report.KubernetesPortForward
+)
+
+const (
+	portForwardDialTimeout = 5 * time.Second
+	rolloutPollInterval    = time.Second
+	rolloutTimeout         = 10 * time.Minute
 )
 
 // GroupName and version used by CRDs
@@ -33,11 +56,93 @@ const (
 
 // GetLogs is the control to get the logs for a kubernetes pod
 func (r *Reporter) GetLogs(req xfer.Request, namespaceID, podID string, containerNames []string) xfer.Response {
-	readCloser, err := r.client.GetLogs(namespaceID, podID, containerNames)
+	opts, err := xfer.ParseLogOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	readCloser, err := r.client.GetLogs(namespaceID, podID, containerNames, opts)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return r.pipeFrom(req, readCloser)
+}
+
+// GetWorkloadLogs is the control to get the merged logs of all the pods of a
+// kubernetes workload, e.g. a deployment or a service
+func (r *Reporter) GetWorkloadLogs(req xfer.Request) xfer.Response {
+	opts, err := xfer.ParseLogOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	namespaceID, podContainers, err := r.workloadPods(req.NodeID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	if len(podContainers) == 0 {
+		return xfer.ResponseErrorf("No pods found: %s", req.NodeID)
+	}
+	readCloser, err := r.client.GetPodsLogs(namespaceID, podContainers, opts)
 	if err != nil {
 		return xfer.ResponseError(err)
 	}
+	return r.pipeFrom(req, readCloser)
+}
 
+// workloadPods finds the containers of the pods of a workload, by name: the
+// pods selected by a service, or owned by a controller.
+func (r *Reporter) workloadPods(nodeID string) (string, map[string][]string, error) {
+	_, tag, ok := report.ParseNodeID(nodeID)
+	if !ok {
+		return "", nil, fmt.Errorf("Invalid ID: %s", nodeID)
+	}
+	var (
+		namespaceID   string
+		podContainers = map[string][]string{}
+		matches       func(Pod) bool
+	)
+	if tag == "<service>" {
+		uid, _ := report.ParseServiceNodeID(nodeID)
+		var service Service
+		r.client.WalkServices(func(s Service) error {
+			if s.UID() == uid {
+				service = s
+			}
+			return nil
+		})
+		if service == nil {
+			return "", nil, fmt.Errorf("Service not found: %s", uid)
+		}
+		namespaceID = service.Namespace()
+		matches = func(p Pod) bool {
+			return p.Namespace() == service.Namespace() && service.Selector().Matches(labels.Set(p.Labels()))
+		}
+	} else {
+		owners, err := r.ownership()
+		if err != nil {
+			return "", nil, err
+		}
+		matches = func(p Pod) bool {
+			parents := owners.parents(p)
+			for _, topology := range parents.Keys() {
+				if ids, _ := parents.Lookup(topology); ids.Contains(nodeID) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	r.client.WalkPods(func(p Pod) error {
+		if matches(p) {
+			namespaceID = p.Namespace()
+			podContainers[p.Name()] = p.ContainerNames()
+		}
+		return nil
+	})
+	return namespaceID, podContainers, nil
+}
+
+// pipeFrom streams the output of readCloser to the UI through a pipe.
+func (r *Reporter) pipeFrom(req xfer.Request, readCloser io.ReadCloser) xfer.Response {
 	readWriter := struct {
 		io.Reader
 		io.Writer
@@ -57,6 +162,94 @@ func (r *Reporter) GetLogs(req xfer.Request, namespaceID, podID string, containe
 	}
 }
 
+// downloadFile is the control to download a file or directory from a
+// container of a pod, as a tar archive made by tar in the container.
+func (r *Reporter) downloadFile(req xfer.Request, namespaceID, podID string, containerNames []string) xfer.Response {
+	opts, container, err := fileCopyOptions(req, containerNames)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return controls.Download(r.pipes, r.jobs, req, opts, func(ctx context.Context, w io.Writer) error {
+		return r.client.Exec(ctx, namespaceID, podID, container, controls.DownloadCommand(opts), nil, w)
+	})
+}
+
+// uploadFile is the control to upload a tar archive into a directory of a
+// container of a pod, extracted by tar in the container.
+func (r *Reporter) uploadFile(req xfer.Request, namespaceID, podID string, containerNames []string) xfer.Response {
+	opts, container, err := fileCopyOptions(req, containerNames)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return controls.Upload(r.pipes, req, opts, func(ctx context.Context, archive io.Reader) error {
+		return r.client.Exec(ctx, namespaceID, podID, container, controls.UploadCommand(opts), archive, ioutil.Discard)
+	})
+}
+
+// PortForward is the control to forward connections to a port of a pod, or
+// of the cluster IP of a service.
+func (r *Reporter) PortForward(req xfer.Request) xfer.Response {
+	port, err := xfer.ParseForwardPort(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	var ip string
+	if uid, ok := report.ParsePodNodeID(req.NodeID); ok {
+		r.client.WalkPods(func(p Pod) error {
+			if p.UID() == uid {
+				ip = p.PodIP()
+				if ip == "" {
+					err = fmt.Errorf("Pod %s has no IP", p.Name())
+				}
+			}
+			return nil
+		})
+	} else if uid, ok := report.ParseServiceNodeID(req.NodeID); ok {
+		r.client.WalkServices(func(s Service) error {
+			if s.UID() == uid {
+				ip = s.ClusterIP()
+				if ip == "" || ip == apiv1.ClusterIPNone {
+					err = fmt.Errorf("Service %s has no cluster IP", s.Name())
+				}
+			}
+			return nil
+		})
+	} else {
+		return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+	}
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	if ip == "" {
+		return xfer.ResponseErrorf("Node not found: %s", req.NodeID)
+	}
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	return controls.Forward(r.pipes, req, address, func() (net.Conn, error) {
+		return net.DialTimeout("tcp", address, portForwardDialTimeout)
+	})
+}
+
+// fileCopyOptions parses the arguments of the file copy controls, defaulting
+// to the first container of the pod.
+func fileCopyOptions(req xfer.Request, containerNames []string) (xfer.FileCopyOptions, string, error) {
+	opts, err := xfer.ParseFileCopyOptions(req.ControlArgs)
+	if err != nil {
+		return opts, "", err
+	}
+	if opts.Container == "" {
+		if len(containerNames) == 0 {
+			return opts, "", fmt.Errorf("Pod has no containers")
+		}
+		return opts, containerNames[0], nil
+	}
+	for _, name := range containerNames {
+		if name == opts.Container {
+			return opts, name, nil
+		}
+	}
+	return opts, "", fmt.Errorf("Container not found: %s", opts.Container)
+}
+
 func (r *Reporter) describePod(req xfer.Request, namespaceID, podID string, _ []string) xfer.Response {
 	return r.describe(req, namespaceID, podID, ResourceMap["Pod"], apimeta.RESTMapping{})
 }
@@ -69,6 +262,10 @@ func (r *Reporter) describeDeployment(req xfer.Request, namespaceID, deploymentI
 	return r.describe(req, namespaceID, deploymentID, ResourceMap["Deployment"], apimeta.RESTMapping{})
 }
 
+func (r *Reporter) describeReplicaSet(req xfer.Request, namespaceID, replicaSetID string) xfer.Response {
+	return r.describe(req, namespaceID, replicaSetID, ResourceMap["ReplicaSet"], apimeta.RESTMapping{})
+}
+
 func (r *Reporter) describeService(req xfer.Request, namespaceID, serviceID string) xfer.Response {
 	return r.describe(req, namespaceID, serviceID, ResourceMap["Service"], apimeta.RESTMapping{})
 }
@@ -125,35 +322,62 @@ func (r *Reporter) describe(req xfer.Request, namespaceID, resourceID string, gr
 	if err != nil {
 		return xfer.ResponseError(err)
 	}
+	return r.pipeFrom(req, readCloser)
+}
 
-	readWriter := struct {
-		io.Reader
-		io.Writer
-	}{
-		readCloser,
-		ioutil.Discard,
-	}
-	id, pipe, err := controls.NewPipeFromEnds(nil, readWriter, r.pipes, req.AppID)
+func (r *Reporter) cloneVolumeSnapshot(req xfer.Request, namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) xfer.Response {
+	name, err := r.client.CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity)
 	if err != nil {
 		return xfer.ResponseError(err)
 	}
-	pipe.OnClose(func() {
-		readCloser.Close()
+	return r.jobs.Start(req, func(ctx context.Context, progress func(string, ...interface{})) error {
+		return r.waitForClaim(ctx, namespaceID, name, progress)
 	})
-	return xfer.Response{
-		Pipe: id,
-	}
 }
 
-func (r *Reporter) cloneVolumeSnapshot(req xfer.Request, namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) xfer.Response {
-	err := r.client.CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity)
-	if err != nil {
-		return xfer.ResponseError(err)
+// waitForClaim waits for the persistent volume claim with the given name to
+// be bound to a volume.
+func (r *Reporter) waitForClaim(ctx context.Context, namespaceID, name string, progress func(string, ...interface{})) error {
+	ctx, cancel := context.WithTimeout(ctx, rolloutTimeout)
+	defer cancel()
+	for {
+		phase := ""
+		r.client.WalkPersistentVolumeClaims(func(p PersistentVolumeClaim) error {
+			if p.Namespace() == namespaceID && p.Name() == name {
+				phase, _ = p.GetNode(r.probeID).Latest.Lookup(Status)
+			}
+			return nil
+		})
+		switch phase {
+		case string(apiv1.ClaimBound):
+			progress("Claim %s is bound", name)
+			return nil
+		case string(apiv1.ClaimLost):
+			return fmt.Errorf("Claim %s lost its volume", name)
+		case "":
+			progress("Waiting for claim %s", name)
+		default:
+			progress("Claim %s is %s", name, strings.ToLower(phase))
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("Timed out waiting for claim %s", name)
+			}
+			return ctx.Err()
+		case <-time.After(rolloutPollInterval):
+		}
 	}
-	return xfer.Response{}
 }
 
 func (r *Reporter) createVolumeSnapshot(req xfer.Request, namespaceID, persistentVolumeClaimID, capacity string) xfer.Response {
+	if s := req.ControlArgs["capacity"]; s != "" {
+		quantity, err := resource.ParseQuantity(s)
+		if err != nil {
+			return xfer.ResponseErrorf("Bad parameter: capacity (%q): must be a quantity such as 10Gi", s)
+		}
+		capacity = quantity.String()
+	}
 	err := r.client.CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capacity)
 	if err != nil {
 		return xfer.ResponseError(err)
@@ -196,6 +420,8 @@ func (r *Reporter) Describe() func(xfer.Request) xfer.Response {
 			f = r.CaptureCronJob(r.describeCronJob)
 		case "<deployment>":
 			f = r.CaptureDeployment(r.describeDeployment)
+		case "<replica_set>":
+			f = r.CaptureReplicaSet(r.describeReplicaSet)
 		case "<daemonset>":
 			f = r.CaptureDaemonSet(r.describeDaemonSet)
 		case "<persistent_volume>":
@@ -327,6 +553,27 @@ func (r *Reporter) CaptureService(f func(xfer.Request, string, string) xfer.Resp
 	}
 }
 
+// CaptureReplicaSet is exported for testing
+func (r *Reporter) CaptureReplicaSet(f func(xfer.Request, string, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		uid, ok := report.ParseReplicaSetNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		var replicaSet ReplicaSet
+		r.client.WalkReplicaSets(func(rs ReplicaSet) error {
+			if rs.UID() == uid {
+				replicaSet = rs
+			}
+			return nil
+		})
+		if replicaSet == nil {
+			return xfer.ResponseErrorf("Replica Set not found: %s", uid)
+		}
+		return f(req, replicaSet.Namespace(), replicaSet.Name())
+	}
+}
+
 // CaptureDaemonSet is exported for testing
 func (r *Reporter) CaptureDaemonSet(f func(xfer.Request, string, string) xfer.Response) func(xfer.Request) xfer.Response {
 	return func(req xfer.Request) xfer.Response {
@@ -497,6 +744,107 @@ func (r *Reporter) ScaleDown(req xfer.Request, namespace, id string) xfer.Respon
 	return xfer.ResponseError(r.client.ScaleDown(namespace, id))
 }
 
+// Scale is the control to scale a deployment to a number of replicas. It
+// runs as a job, until the deployment has rolled out.
+func (r *Reporter) Scale(req xfer.Request, namespace, id string) xfer.Response {
+	replicas, err := strconv.ParseInt(req.ControlArgs["replicas"], 10, 32)
+	if err != nil || replicas < 0 {
+		return xfer.ResponseErrorf("Bad parameter: replicas (%q): must be a number of replicas", req.ControlArgs["replicas"])
+	}
+	if err := r.client.Scale(namespace, id, int32(replicas)); err != nil {
+		return xfer.ResponseError(err)
+	}
+	return r.jobs.Start(req, func(ctx context.Context, progress func(string, ...interface{})) error {
+		return r.waitForRollout(ctx, req.NodeID, int(replicas), progress)
+	})
+}
+
+// RestartWorkload is the control to restart the pods of a deployment, daemon
+// set or stateful set. Like kubectl rollout restart, it changes the pod
+// template, so the pods are replaced following the update strategy of the
+// workload rather than all at once.
+func (r *Reporter) RestartWorkload(req xfer.Request) xfer.Response {
+	kind, workload, err := r.restartableWorkload(req.NodeID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.ResponseError(r.client.RestartWorkload(kind, workload.Namespace(), workload.Name(), mtime.Now()))
+}
+
+// restartableWorkload finds the deployment, daemon set or stateful set with
+// the given node ID, and its topology.
+func (r *Reporter) restartableWorkload(nodeID string) (string, Meta, error) {
+	var workload Meta
+	find := func(uid string, m Meta) error {
+		if m.UID() == uid {
+			workload = m
+		}
+		return nil
+	}
+	var kind string
+	if uid, ok := report.ParseDeploymentNodeID(nodeID); ok {
+		kind = report.Deployment
+		r.client.WalkDeployments(func(d Deployment) error { return find(uid, d) })
+	} else if uid, ok := report.ParseDaemonSetNodeID(nodeID); ok {
+		kind = report.DaemonSet
+		r.client.WalkDaemonSets(func(d DaemonSet) error { return find(uid, d) })
+	} else if uid, ok := report.ParseStatefulSetNodeID(nodeID); ok {
+		kind = report.StatefulSet
+		r.client.WalkStatefulSets(func(s StatefulSet) error { return find(uid, s) })
+	} else {
+		return "", nil, fmt.Errorf("Invalid ID: %s", nodeID)
+	}
+	if workload == nil {
+		return "", nil, fmt.Errorf("Workload not found: %s", nodeID)
+	}
+	return kind, workload, nil
+}
+
+// waitForRollout waits for the deployment with the given node ID to have
+// the given number of up-to-date, available replicas.
+func (r *Reporter) waitForRollout(ctx context.Context, nodeID string, replicas int, progress func(string, ...interface{})) error {
+	uid, ok := report.ParseDeploymentNodeID(nodeID)
+	if !ok {
+		return fmt.Errorf("Invalid ID: %s", nodeID)
+	}
+	ctx, cancel := context.WithTimeout(ctx, rolloutTimeout)
+	defer cancel()
+	for {
+		var (
+			node  report.Node
+			found bool
+		)
+		r.client.WalkDeployments(func(d Deployment) error {
+			if d.UID() == uid {
+				node, found = d.GetNode(r.probeID), true
+			}
+			return nil
+		})
+		if !found {
+			return fmt.Errorf("Deployment not found: %s", uid)
+		}
+		count := func(key string) int {
+			value, _ := node.Latest.Lookup(key)
+			n, _ := strconv.Atoi(value)
+			return n
+		}
+		current, updated, ready := count(Replicas), count(UpdatedReplicas), count(AvailableReplicas)
+		if current == replicas && updated == replicas && ready == replicas {
+			progress("%d of %d replicas available", ready, replicas)
+			return nil
+		}
+		progress("%d of %d replicas updated, %d available", updated, replicas, ready)
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("Timed out waiting for %d replicas", replicas)
+			}
+			return ctx.Err()
+		case <-time.After(rolloutPollInterval):
+		}
+	}
+}
+
 // CordonNode is the control to cordon a node.
 func (r *Reporter) CordonNode(req xfer.Request, name string) xfer.Response {
 	return xfer.ResponseError(r.client.CordonNode(name, true))
@@ -507,6 +855,23 @@ func (r *Reporter) UncordonNode(req xfer.Request, name string) xfer.Response {
 	return xfer.ResponseError(r.client.CordonNode(name, false))
 }
 
+// DrainNode is the control to drain a node: it cordons the node, then evicts
+// its pods in a job, reporting each step as the job's progress. Cancelling
+// the job stops the drain.
+func (r *Reporter) DrainNode(req xfer.Request, name string) xfer.Response {
+	return r.jobs.Start(req, func(ctx context.Context, progress func(string, ...interface{})) error {
+		return newDrainer(r.client, name, progressWriter(progress), ctx.Done()).drain()
+	})
+}
+
+// progressWriter reports every line written to it as the progress of a job.
+type progressWriter func(string, ...interface{})
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	w("%s", strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
 func (r *Reporter) registerControls() {
 	controls := map[string]xfer.ControlHandlerFunc{
 		CloneVolumeSnapshot:  r.CaptureVolumeSnapshot(r.cloneVolumeSnapshot),
@@ -517,8 +882,15 @@ func (r *Reporter) registerControls() {
 		DeleteVolumeSnapshot: r.CaptureVolumeSnapshot(r.deleteVolumeSnapshot),
 		ScaleUp:              r.CaptureDeployment(r.ScaleUp),
 		ScaleDown:            r.CaptureDeployment(r.ScaleDown),
+		Scale:                r.CaptureDeployment(r.Scale),
+		RestartWorkload:      r.RestartWorkload,
 		CordonNode:           r.CaptureNode(r.CordonNode),
 		UncordonNode:         r.CaptureNode(r.UncordonNode),
+		DrainNode:            r.CaptureNode(r.DrainNode),
+		GetWorkloadLogs:      r.GetWorkloadLogs,
+		DownloadFile:         r.CapturePod(r.downloadFile),
+		UploadFile:           r.CapturePod(r.uploadFile),
+		PortForward:          r.PortForward,
 	}
 	r.handlerRegistry.Batch(nil, controls)
 }
@@ -533,8 +905,15 @@ func (r *Reporter) deregisterControls() {
 		DeleteVolumeSnapshot,
 		ScaleUp,
 		ScaleDown,
+		Scale,
+		RestartWorkload,
 		CordonNode,
 		UncordonNode,
+		DrainNode,
+		GetWorkloadLogs,
+		DownloadFile,
+		UploadFile,
+		PortForward,
 	}
 	r.handlerRegistry.Batch(controls, nil)
 }
@@ -6,8 +6,6 @@ import (
 
 	batchv1 "k8s.io/api/batch/v1"
 	apibatchv1beta1 "k8s.io/api/batch/v1beta1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/weaveworks/scope/report"
@@ -24,7 +22,6 @@ const (
 // CronJob represents a Kubernetes cron job
 type CronJob interface {
 	Meta
-	Selectors() ([]labels.Selector, error)
 	GetNode(probeID string) report.Node
 }
 
@@ -50,18 +47,6 @@ func NewCronJob(cj *apibatchv1beta1.CronJob, jobs map[types.UID]*batchv1.Job) Cr
 	}
 }
 
-func (cj *cronJob) Selectors() ([]labels.Selector, error) {
-	selectors := []labels.Selector{}
-	for _, j := range cj.jobs {
-		selector, err := metav1.LabelSelectorAsSelector(j.Spec.Selector)
-		if err != nil {
-			return nil, err
-		}
-		selectors = append(selectors, selector)
-	}
-	return selectors, nil
-}
-
 func (cj *cronJob) GetNode(probeID string) report.Node {
 	latest := map[string]string{
 		NodeType:              "CronJob",
@@ -75,5 +60,5 @@ func (cj *cronJob) GetNode(probeID string) report.Node {
 	}
 	return cj.MetaNode(report.MakeCronJobNodeID(cj.UID())).
 		WithLatests(latest).
-		WithLatestActiveControls(Describe)
+		WithLatestActiveControls(GetWorkloadLogs, Describe)
 }
@@ -0,0 +1,257 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// CustomResourceConfig describes a kind of custom resource to report, e.g.
+// group argoproj.io, version v1alpha1 and resource rollouts for Argo Rollouts.
+type CustomResourceConfig struct {
+	Group    string `yaml:"group"`
+	Version  string `yaml:"version"`
+	Resource string `yaml:"resource"`
+	// Kind is the type shown for the resources; defaults to Resource.
+	Kind string `yaml:"kind"`
+	// Selector is the JSONPath of the label selector of the pods the
+	// resources manage. It can be a LabelSelector, a map of labels or a
+	// selector string. Pods owned by a resource are its children whether
+	// or not it has a selector.
+	Selector string `yaml:"selector"`
+	// Status is the JSONPath of the status of the resources.
+	Status   string                `yaml:"status"`
+	Metadata []CustomResourceField `yaml:"metadata"`
+
+	selector *jsonpath.JSONPath
+	status   *jsonpath.JSONPath
+}
+
+// CustomResourceField is a field of a custom resource shown in its details.
+type CustomResourceField struct {
+	Label string `yaml:"label"`
+	Path  string `yaml:"path"`
+
+	path *jsonpath.JSONPath
+}
+
+// LoadCustomResourceConfigs reads a YAML list of CustomResourceConfigs.
+func LoadCustomResourceConfigs(filename string) ([]CustomResourceConfig, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCustomResourceConfigs(buf)
+}
+
+// ParseCustomResourceConfigs parses and validates a YAML list of
+// CustomResourceConfigs.
+func ParseCustomResourceConfigs(buf []byte) ([]CustomResourceConfig, error) {
+	var configs []CustomResourceConfig
+	if err := yaml.UnmarshalStrict(buf, &configs); err != nil {
+		return nil, err
+	}
+	kinds := map[string]struct{}{}
+	for i := range configs {
+		c := &configs[i]
+		if c.Version == "" || c.Resource == "" {
+			return nil, fmt.Errorf("custom resource %d: version and resource are required", i)
+		}
+		if c.Kind == "" {
+			c.Kind = c.Resource
+		}
+		if _, ok := kinds[c.Kind]; ok {
+			return nil, fmt.Errorf("custom resource %s: kind configured twice", c.Kind)
+		}
+		kinds[c.Kind] = struct{}{}
+		var err error
+		if c.selector, err = parseJSONPath(c.Selector); err != nil {
+			return nil, fmt.Errorf("custom resource %s: selector: %v", c.Kind, err)
+		}
+		if c.status, err = parseJSONPath(c.Status); err != nil {
+			return nil, fmt.Errorf("custom resource %s: status: %v", c.Kind, err)
+		}
+		for j := range c.Metadata {
+			f := &c.Metadata[j]
+			if f.Label == "" {
+				return nil, fmt.Errorf("custom resource %s: metadata field %d has no label", c.Kind, j)
+			}
+			if f.path, err = parseJSONPath(f.Path); err != nil {
+				return nil, fmt.Errorf("custom resource %s: %s: %v", c.Kind, f.Label, err)
+			}
+			if f.path == nil {
+				return nil, fmt.Errorf("custom resource %s: %s has no path", c.Kind, f.Label)
+			}
+		}
+	}
+	return configs, nil
+}
+
+func parseJSONPath(path string) (*jsonpath.JSONPath, error) {
+	if path == "" {
+		return nil, nil
+	}
+	j := jsonpath.New("").AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// GroupVersionResource is the API resource of the custom resources.
+func (c CustomResourceConfig) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: c.Group, Version: c.Version, Resource: c.Resource}
+}
+
+// key is the node metadata key of a field of the custom resources.
+func (c CustomResourceConfig) key(f CustomResourceField) string {
+	return report.KubernetesCustomPrefix + slug(c.Kind) + "_" + slug(f.Label)
+}
+
+func slug(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, s)
+}
+
+// CustomResourceMetadataTemplates are the metadata templates of the custom
+// resource topology: the common ones, followed by the fields of each config.
+func CustomResourceMetadataTemplates(configs []CustomResourceConfig) report.MetadataTemplates {
+	templates := report.MetadataTemplates{
+		NodeType:   {ID: NodeType, Label: "Type", From: report.FromLatest, Priority: 1},
+		Namespace:  {ID: Namespace, Label: "Namespace", From: report.FromLatest, Priority: 2},
+		Created:    {ID: Created, Label: "Created", From: report.FromLatest, Datatype: report.DateTime, Priority: 3},
+		Status:     {ID: Status, Label: "Status", From: report.FromLatest, Priority: 4},
+		report.Pod: {ID: report.Pod, Label: "# Pods", From: report.FromCounters, Datatype: report.Number, Priority: 5},
+	}
+	priority := 6.0
+	for _, c := range configs {
+		for _, f := range c.Metadata {
+			key := c.key(f)
+			templates[key] = report.MetadataTemplate{ID: key, Label: f.Label, From: report.FromLatest, Priority: priority}
+			priority++
+		}
+	}
+	return templates
+}
+
+// CustomResource represents a Kubernetes custom resource
+type CustomResource interface {
+	Meta
+	Kind() string
+	Selector() (labels.Selector, error)
+	GetNode(probeID string) report.Node
+}
+
+type customResource struct {
+	*unstructured.Unstructured
+	Meta
+	config *CustomResourceConfig
+}
+
+// NewCustomResource creates a new CustomResource of the kind configured by
+// config.
+func NewCustomResource(config *CustomResourceConfig, u *unstructured.Unstructured) CustomResource {
+	return &customResource{
+		Unstructured: u,
+		Meta: meta{metav1.ObjectMeta{
+			Name:              u.GetName(),
+			Namespace:         u.GetNamespace(),
+			UID:               u.GetUID(),
+			CreationTimestamp: u.GetCreationTimestamp(),
+			Labels:            u.GetLabels(),
+			OwnerReferences:   u.GetOwnerReferences(),
+		}},
+		config: config,
+	}
+}
+
+func (c *customResource) Kind() string {
+	return c.config.Kind
+}
+
+// Selector returns the selector of the pods the resource manages, or nil if
+// it has none.
+func (c *customResource) Selector() (labels.Selector, error) {
+	if c.config.selector == nil {
+		return nil, nil
+	}
+	results, err := c.config.selector.FindResults(c.Object)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 || len(results[0]) == 0 {
+		return nil, nil
+	}
+	switch selector := results[0][0].Interface().(type) {
+	case map[string]interface{}:
+		_, hasLabels := selector["matchLabels"]
+		_, hasExpressions := selector["matchExpressions"]
+		if hasLabels || hasExpressions {
+			var labelSelector metav1.LabelSelector
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selector, &labelSelector); err != nil {
+				return nil, err
+			}
+			return metav1.LabelSelectorAsSelector(&labelSelector)
+		}
+		if len(selector) == 0 {
+			return nil, nil
+		}
+		set := labels.Set{}
+		for k, v := range selector {
+			set[k] = fmt.Sprint(v)
+		}
+		return labels.SelectorFromSet(set), nil
+	case string:
+		if selector == "" {
+			return nil, nil
+		}
+		return labels.Parse(selector)
+	default:
+		return nil, fmt.Errorf("%s %s/%s: unsupported selector %v", c.Kind(), c.Namespace(), c.Name(), selector)
+	}
+}
+
+// text formats the values at path, as kubectl does for custom columns.
+func (c *customResource) text(path *jsonpath.JSONPath) string {
+	var buf bytes.Buffer
+	if err := path.Execute(&buf, c.Object); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func (c *customResource) GetNode(probeID string) report.Node {
+	latests := map[string]string{
+		NodeType:              c.Kind(),
+		report.ControlProbeID: probeID,
+	}
+	if c.config.status != nil {
+		if status := c.text(c.config.status); status != "" {
+			latests[Status] = status
+		}
+	}
+	for _, f := range c.config.Metadata {
+		if value := c.text(f.path); value != "" {
+			latests[c.config.key(f)] = value
+		}
+	}
+	return c.MetaNode(report.MakeCustomResourceNodeID(c.UID())).WithLatests(latests)
+}
@@ -0,0 +1,176 @@
+package kubernetes_test
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+const customResourceConfig = `
+- group: argoproj.io
+  version: v1alpha1
+  resource: rollouts
+  kind: Rollout
+  selector: '{.spec.selector}'
+  status: '{.status.phase}'
+  metadata:
+  - label: Desired replicas
+    path: '{.spec.replicas}'
+- group: example.com
+  version: v1
+  resource: widgets
+`
+
+func makeCustomResource(config *kubernetes.CustomResourceConfig, uid string, object map[string]interface{}) kubernetes.CustomResource {
+	u := &unstructured.Unstructured{Object: object}
+	u.SetName("pong-" + uid)
+	u.SetNamespace("ping")
+	u.SetUID(types.UID(uid))
+	return kubernetes.NewCustomResource(config, u)
+}
+
+func TestParseCustomResourceConfigs(t *testing.T) {
+	configs, err := kubernetes.ParseCustomResourceConfigs([]byte(customResourceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 configs, got %d", len(configs))
+	}
+	if gvr := configs[0].GroupVersionResource(); gvr.String() != "argoproj.io/v1alpha1, Resource=rollouts" {
+		t.Errorf("Unexpected resource %v", gvr)
+	}
+	if configs[1].Kind != "widgets" {
+		t.Errorf("Expected kind to default to the resource, got %q", configs[1].Kind)
+	}
+
+	for _, bad := range []string{
+		"- {group: example.com, resource: widgets}",
+		"- {version: v1, resource: widgets, colour: blue}",
+		"- {version: v1, resource: widgets, selector: '{.spec'}",
+		"- {version: v1, resource: widgets, metadata: [{label: Size}]}",
+		"- {version: v1, resource: widgets}\n- {version: v2, resource: widgets}",
+	} {
+		if _, err := kubernetes.ParseCustomResourceConfigs([]byte(bad)); err == nil {
+			t.Errorf("Expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestCustomResource(t *testing.T) {
+	configs, err := kubernetes.ParseCustomResourceConfigs([]byte(customResourceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollout := makeCustomResource(&configs[0], "rollout1", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"ponger": "true"},
+			},
+		},
+		"status": map[string]interface{}{"phase": "Healthy"},
+	})
+
+	node := rollout.GetNode("probe-id")
+	if node.ID != report.MakeCustomResourceNodeID("rollout1") {
+		t.Errorf("Unexpected node ID %q", node.ID)
+	}
+	fieldKey := report.KubernetesCustomPrefix + "rollout_desired_replicas"
+	for k, want := range map[string]string{
+		kubernetes.Name:       "pong-rollout1",
+		kubernetes.Namespace:  "ping",
+		kubernetes.NodeType:   "Rollout",
+		kubernetes.Status:     "Healthy",
+		fieldKey:              "3",
+		report.ControlProbeID: "probe-id",
+	} {
+		if have, ok := node.Latest.Lookup(k); !ok || have != want {
+			t.Errorf("Expected latest %q: %q, got %q", k, want, have)
+		}
+	}
+	if _, ok := kubernetes.CustomResourceMetadataTemplates(configs)[fieldKey]; !ok {
+		t.Errorf("Expected a metadata template for %q", fieldKey)
+	}
+
+	// Selectors can be label selectors, maps of labels or strings
+	for _, selector := range []interface{}{
+		map[string]interface{}{"matchLabels": map[string]interface{}{"ponger": "true"}},
+		map[string]interface{}{"ponger": "true"},
+		"ponger=true",
+	} {
+		c := makeCustomResource(&configs[0], "rollout2", map[string]interface{}{
+			"spec": map[string]interface{}{"selector": selector},
+		})
+		s, err := c.Selector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s == nil || !s.Matches(labels.Set{"ponger": "true"}) || s.Matches(labels.Set{"pinger": "true"}) {
+			t.Errorf("Unexpected selector %v from %v", s, selector)
+		}
+	}
+
+	widget := makeCustomResource(&configs[1], "widget1", map[string]interface{}{})
+	if s, err := widget.Selector(); err != nil || s != nil {
+		t.Errorf("Expected no selector, got %v, %v", s, err)
+	}
+}
+
+func TestReporterCustomResources(t *testing.T) {
+	configs, err := kubernetes.ParseCustomResourceConfigs([]byte(customResourceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollout := makeCustomResource(&configs[0], "rollout1", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"ponger": "true"},
+		},
+	})
+	widget := makeCustomResource(&configs[1], "widget1", map[string]interface{}{})
+	// A selector which can't be parsed must not fail the whole report
+	broken := makeCustomResource(&configs[0], "broken1", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchExpressions": []interface{}{
+					map[string]interface{}{"key": "ponger", "operator": "Bogus"},
+				},
+			},
+		},
+	})
+	owned := apiPod2
+	owned.ObjectMeta.UID = "owned"
+	owned.ObjectMeta.Labels = nil
+	owned.ObjectMeta.OwnerReferences = []metav1.OwnerReference{{Kind: "widgets", Name: "pong-widget1", UID: "widget1"}}
+
+	client := newMockClient()
+	client.pods = []kubernetes.Pod{kubernetes.NewPod(&apiPod1), kubernetes.NewPod(&owned)}
+	client.customResources = []kubernetes.CustomResource{rollout, widget, broken}
+	rpt, err := kubernetes.NewReporter(client, nil, "probe-id", "foo", nil, controls.NewDefaultHandlerRegistry(), nil, nodeName).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rpt.CustomResource.Nodes) != 3 {
+		t.Errorf("Expected 3 custom resources, got %d", len(rpt.CustomResource.Nodes))
+	}
+	if len(rpt.Pod.Nodes) != 2 {
+		t.Errorf("Expected 2 pods, got %d", len(rpt.Pod.Nodes))
+	}
+	for podUID, want := range map[string]string{
+		pod1UID: report.MakeCustomResourceNodeID("rollout1"),
+		"owned": report.MakeCustomResourceNodeID("widget1"),
+	} {
+		node := rpt.Pod.Nodes[report.MakePodNodeID(podUID)]
+		if parents, ok := node.Parents.Lookup(report.CustomResource); !ok || len(parents) != 1 || !parents.Contains(want) {
+			t.Errorf("Expected pod %s to have parent %q, got %v", podUID, want, parents)
+		}
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"fmt"
 
 	apiv1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/weaveworks/scope/report"
 )
@@ -18,7 +16,6 @@ const (
 // DaemonSet represents a Kubernetes daemonset
 type DaemonSet interface {
 	Meta
-	Selector() (labels.Selector, error)
 	GetNode(probeID string) report.Node
 }
 
@@ -35,14 +32,6 @@ func NewDaemonSet(d *apiv1.DaemonSet) DaemonSet {
 	}
 }
 
-func (d *daemonSet) Selector() (labels.Selector, error) {
-	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
-	if err != nil {
-		return nil, err
-	}
-	return selector, nil
-}
-
 func (d *daemonSet) GetNode(probeID string) report.Node {
 	return d.MetaNode(report.MakeDaemonSetNodeID(d.UID())).WithLatests(map[string]string{
 		DesiredReplicas:       fmt.Sprint(d.Status.DesiredNumberScheduled),
@@ -50,5 +39,5 @@ func (d *daemonSet) GetNode(probeID string) report.Node {
 		MisscheduledReplicas:  fmt.Sprint(d.Status.NumberMisscheduled),
 		NodeType:              "DaemonSet",
 		report.ControlProbeID: probeID,
-	}).WithLatestActiveControls(Describe)
+	}).WithLatestActiveControls(GetWorkloadLogs, Describe, RestartWorkload)
 }
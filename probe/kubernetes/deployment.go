@@ -7,8 +7,6 @@ import (
 
 	apiappsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 )
 
 // These constants are keys used in node metadata
@@ -22,7 +20,6 @@ const (
 // Deployment represents a Kubernetes deployment
 type Deployment interface {
 	Meta
-	Selector() (labels.Selector, error)
 	GetNode(probeID string) report.Node
 }
 
@@ -37,14 +34,6 @@ func NewDeployment(d *apiappsv1.Deployment) Deployment {
 	return &deployment{Deployment: d, Meta: meta{d.ObjectMeta}}
 }
 
-func (d *deployment) Selector() (labels.Selector, error) {
-	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
-	if err != nil {
-		return nil, err
-	}
-	return selector, nil
-}
-
 func (d *deployment) GetNode(probeID string) report.Node {
 	// Spec.Replicas can be omitted, and the pointer will be nil. It defaults to 1.
 	desiredReplicas := 1
@@ -61,5 +50,5 @@ func (d *deployment) GetNode(probeID string) report.Node {
 		Strategy:              string(d.Spec.Strategy.Type),
 		report.ControlProbeID: probeID,
 		NodeType:              "Deployment",
-	}).WithLatestActiveControls(ScaleUp, ScaleDown, Describe)
+	}).WithLatestActiveControls(ScaleUp, ScaleDown, Scale, GetWorkloadLogs, Describe, RestartWorkload)
 }
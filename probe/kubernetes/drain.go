@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// How long to wait before retrying an eviction refused by a
+	// PodDisruptionBudget, and between checks for evicted pods going away.
+	evictionRetryInterval = 5 * time.Second
+	deletionPollInterval  = time.Second
+	// How long evicted pods get to go away beyond their grace period.
+	deletionTimeoutMargin = 30 * time.Second
+)
+
+// drainer cordons a node, then evicts its pods, as `kubectl drain` does.
+// Progress is written to out, a line per event.
+type drainer struct {
+	client Client
+	node   string
+	quit   <-chan struct{}
+
+	retryInterval time.Duration
+	pollInterval  time.Duration
+
+	mtx sync.Mutex
+	out io.Writer
+}
+
+func newDrainer(client Client, node string, out io.Writer, quit <-chan struct{}) *drainer {
+	return &drainer{
+		client:        client,
+		node:          node,
+		quit:          quit,
+		retryInterval: evictionRetryInterval,
+		pollInterval:  deletionPollInterval,
+		out:           out,
+	}
+}
+
+func (d *drainer) printf(format string, args ...interface{}) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	fmt.Fprintf(d.out, format+"\n", args...)
+}
+
+// wait returns false if the drain was cancelled before the interval passed.
+func (d *drainer) wait(interval time.Duration) bool {
+	select {
+	case <-d.quit:
+		return false
+	case <-time.After(interval):
+		return true
+	}
+}
+
+// drain evicts all the pods of the node in parallel, so that a pod held back
+// by its disruption budget doesn't hold back the others.
+func (d *drainer) drain() error {
+	if err := d.client.CordonNode(d.node, true); err != nil {
+		return fmt.Errorf("cordoning node %s: %v", d.node, err)
+	}
+	d.printf("node %s cordoned", d.node)
+
+	var pods []Pod
+	d.client.WalkPods(func(p Pod) error {
+		if p.NodeName() != d.node || p.Finished() {
+			return nil
+		}
+		if p.IsMirror() {
+			d.printf("skipping mirror pod %s/%s", p.Namespace(), p.Name())
+		} else if isDaemonSetPod(p) {
+			d.printf("skipping DaemonSet pod %s/%s", p.Namespace(), p.Name())
+		} else {
+			pods = append(pods, p)
+		}
+		return nil
+	})
+
+	var (
+		wg     sync.WaitGroup
+		failed = make(chan error, len(pods))
+	)
+	for _, p := range pods {
+		wg.Add(1)
+		go func(p Pod) {
+			defer wg.Done()
+			if err := d.evict(p); err != nil {
+				d.printf("pod %s/%s: %v", p.Namespace(), p.Name(), err)
+				failed <- err
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(failed)
+
+	if n := len(failed); n > 0 {
+		return fmt.Errorf("failed to evict %d of %d pods", n, len(pods))
+	}
+	d.printf("node %s drained", d.node)
+	return nil
+}
+
+func (d *drainer) evict(p Pod) error {
+	for {
+		err := d.client.EvictPod(p.Namespace(), p.Name())
+		if err == nil {
+			break
+		} else if apierrors.IsNotFound(err) {
+			d.printf("pod %s/%s evicted", p.Namespace(), p.Name())
+			return nil
+		} else if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+		d.printf("pod %s/%s: waiting on PodDisruptionBudget", p.Namespace(), p.Name())
+		if !d.wait(d.retryInterval) {
+			return fmt.Errorf("cancelled")
+		}
+	}
+
+	// Wait for the pod to terminate, giving it its grace period
+	timeout := time.After(p.GracePeriod() + deletionTimeoutMargin)
+	for d.exists(p) {
+		select {
+		case <-d.quit:
+			return fmt.Errorf("cancelled")
+		case <-timeout:
+			return fmt.Errorf("not terminated after its grace period")
+		case <-time.After(d.pollInterval):
+		}
+	}
+	d.printf("pod %s/%s evicted", p.Namespace(), p.Name())
+	return nil
+}
+
+func (d *drainer) exists(p Pod) bool {
+	found := false
+	d.client.WalkPods(func(other Pod) error {
+		if other.UID() == p.UID() {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+func isDaemonSetPod(p Pod) bool {
+	for _, ref := range p.OwnerReferences() {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
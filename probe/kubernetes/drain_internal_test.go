@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// drainClient evicts pods by removing them, after refusing as many times as
+// their disruption budget says.
+type drainClient struct {
+	Client
+	sync.Mutex
+	cordoned bool
+	pods     []Pod
+	refusals map[string]int
+}
+
+func (c *drainClient) CordonNode(name string, desired bool) error {
+	c.cordoned = desired
+	return nil
+}
+
+func (c *drainClient) WalkPods(f func(Pod) error) error {
+	c.Lock()
+	pods := c.pods
+	c.Unlock()
+	for _, p := range pods {
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *drainClient) EvictPod(namespaceID, podID string) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.refusals[podID] > 0 {
+		c.refusals[podID]--
+		return apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	}
+	for i, p := range c.pods {
+		if p.Name() == podID {
+			c.pods = append(c.pods[:i:i], c.pods[i+1:]...)
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, podID)
+}
+
+func makeDrainPod(name, nodeName string, owner string) Pod {
+	p := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ping", UID: types.UID(name)},
+		Spec:       apiv1.PodSpec{NodeName: nodeName},
+	}
+	if owner != "" {
+		controller := true
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+	}
+	return NewPod(p)
+}
+
+func TestDrain(t *testing.T) {
+	client := &drainClient{
+		pods: []Pod{
+			makeDrainPod("app", "node1", "ReplicaSet"),
+			makeDrainPod("budgeted", "node1", "StatefulSet"),
+			makeDrainPod("agent", "node1", "DaemonSet"),
+			makeDrainPod("elsewhere", "node2", "ReplicaSet"),
+		},
+		refusals: map[string]int{"budgeted": 2},
+	}
+	var out bytes.Buffer
+	d := newDrainer(client, "node1", &out, make(chan struct{}))
+	d.retryInterval = time.Millisecond
+	d.pollInterval = time.Millisecond
+	if err := d.drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !client.cordoned {
+		t.Error("Expected the node to be cordoned")
+	}
+	if len(client.pods) != 2 {
+		t.Errorf("Expected the DaemonSet pod and the pod of the other node to remain, got %v", client.pods)
+	}
+	for _, want := range []string{
+		"node node1 cordoned",
+		"skipping DaemonSet pod ping/agent",
+		"pod ping/app evicted",
+		"pod ping/budgeted: waiting on PodDisruptionBudget",
+		"pod ping/budgeted evicted",
+		"node node1 drained",
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("Expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDrainCancelled(t *testing.T) {
+	client := &drainClient{
+		pods:     []Pod{makeDrainPod("budgeted", "node1", "")},
+		refusals: map[string]int{"budgeted": 1},
+	}
+	quit := make(chan struct{})
+	close(quit)
+	if err := newDrainer(client, "node1", &bytes.Buffer{}, quit).drain(); err == nil {
+		t.Error("Expected a cancelled drain to fail")
+	}
+	if len(client.pods) != 1 {
+		t.Error("Expected the pod not to be evicted")
+	}
+}
@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// Commands are run in pods over a websocket, with the v4 channel protocol of
+// the API server: each message is prefixed with the stream it belongs to,
+// and the error stream reports how the command exited.
+const (
+	execProtocol         = "v4.channel.k8s.io"
+	execHandshakeTimeout = 30 * time.Second
+	maxExecStderr        = 4096
+
+	stdinChannel  = 0
+	stdoutChannel = 1
+	stderrChannel = 2
+	errorChannel  = 3
+)
+
+func (c *client) Exec(ctx context.Context, namespaceID, podID, container string, command []string, stdin io.Reader, stdout io.Writer) error {
+	u := c.client.CoreV1().RESTClient().Post().
+		Namespace(namespaceID).
+		Resource("pods").
+		Name(podID).
+		SubResource("exec").
+		VersionedParams(&apiv1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec).
+		URL()
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(c.restConfig)
+	if err != nil {
+		return err
+	}
+	header, err := authHeader(c.restConfig)
+	if err != nil {
+		return err
+	}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		TLSClientConfig:  tlsConfig,
+		HandshakeTimeout: execHandshakeTimeout,
+		Subprotocols:     []string{execProtocol},
+	}
+	conn, resp, err := dialer.Dial(u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("exec in %s/%s: %s", namespaceID, podID, resp.Status)
+		}
+		return err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if stdin != nil {
+		go func() {
+			buf := make([]byte, 32*1024)
+			for {
+				n, err := stdin.Read(buf[1:])
+				if n > 0 {
+					buf[0] = stdinChannel
+					if conn.WriteMessage(websocket.BinaryMessage, buf[:n+1]) != nil {
+						return
+					}
+				}
+				if err != nil {
+					// There is no closing stdin in this version of the
+					// protocol: commands must see the end of their input.
+					return
+				}
+			}
+		}()
+	}
+
+	var stderr strings.Builder
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if len(msg) < 2 {
+			continue
+		}
+		switch msg[0] {
+		case stdoutChannel:
+			if _, err := stdout.Write(msg[1:]); err != nil {
+				return err
+			}
+		case stderrChannel:
+			if stderr.Len() < maxExecStderr {
+				stderr.Write(msg[1:])
+			}
+		case errorChannel:
+			var status metav1.Status
+			if err := json.Unmarshal(msg[1:], &status); err != nil {
+				return fmt.Errorf("exec in %s/%s: %v", namespaceID, podID, err)
+			}
+			if status.Status == metav1.StatusSuccess {
+				return nil
+			}
+			if s := strings.TrimSpace(stderr.String()); s != "" {
+				return fmt.Errorf("%s", s)
+			}
+			return fmt.Errorf("%s", status.Message)
+		}
+	}
+}
+
+// authHeader returns the headers authenticating requests to the API server,
+// for the websocket dialer which doesn't use the REST client's transport.
+func authHeader(config *rest.Config) (http.Header, error) {
+	var capture headerCapture
+	rt, err := rest.HTTPWrappersForConfig(config, &capture)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", config.Host, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rt.RoundTrip(req); err != nil {
+		return nil, err
+	}
+	return capture.header, nil
+}
+
+// headerCapture is a http.RoundTripper recording the headers of a request
+// instead of sending it.
+type headerCapture struct {
+	header http.Header
+}
+
+func (h *headerCapture) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.header = req.Header
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
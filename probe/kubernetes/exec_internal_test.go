@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// execServer runs commands in pods as the API server does, over the v4
+// channel protocol: cat echoes its input, and anything else fails.
+func execServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{Subprotocols: []string{execProtocol}}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/ping/pods/pong/exec" || r.URL.Query().Get("container") != "app" {
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("Unexpected authorization %q", auth)
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		send := func(channel byte, data string) {
+			conn.WriteMessage(websocket.BinaryMessage, append([]byte{channel}, data...))
+		}
+		// The API server opens each stream with an empty message
+		send(stdoutChannel, "")
+
+		if command := r.URL.Query()["command"]; !reflect.DeepEqual(command, []string{"cat"}) {
+			send(stderrChannel, strings.Join(command, " ")+": not found\n")
+			send(errorChannel, `{"status":"Failure","message":"command terminated with non-zero exit code"}`)
+			return
+		}
+		_, msg, err := conn.ReadMessage()
+		if err != nil || msg[0] != stdinChannel {
+			t.Errorf("Expected input, got %q, %v", msg, err)
+			return
+		}
+		send(stdoutChannel, string(msg[1:]))
+		send(errorChannel, `{"status":"Success"}`)
+	}))
+}
+
+func TestExec(t *testing.T) {
+	server := execServer(t)
+	defer server.Close()
+	config := &rest.Config{Host: server.URL, BearerToken: "token"}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{client: clientset, restConfig: config}
+
+	var stdout bytes.Buffer
+	if err := c.Exec(context.Background(), "ping", "pong", "app", []string{"cat"}, strings.NewReader("hello"), &stdout); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "hello" {
+		t.Errorf("Unexpected output %q", stdout.String())
+	}
+
+	err = c.Exec(context.Background(), "ping", "pong", "app", []string{"tar", "cf", "-"}, nil, &stdout)
+	if err == nil || err.Error() != "tar cf -: not found" {
+		t.Errorf("Expected the error of the command, got %v", err)
+	}
+
+	if err := c.Exec(context.Background(), "ping", "pong", "db", []string{"cat"}, nil, &stdout); err == nil {
+		t.Errorf("Expected an error execing in an unknown container")
+	}
+}
@@ -2,8 +2,6 @@ package kubernetes
 
 import (
 	batchv1 "k8s.io/api/batch/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/weaveworks/scope/report"
 )
@@ -11,7 +9,6 @@ import (
 //Job represents a Kubernetes job
 type Job interface {
 	Meta
-	Selector() (labels.Selector, error)
 	GetNode(probeID string) report.Node
 }
 
@@ -28,14 +25,6 @@ func NewJob(j *batchv1.Job) Job {
 	}
 }
 
-func (j *job) Selector() (labels.Selector, error) {
-	selector, err := metav1.LabelSelectorAsSelector(j.Spec.Selector)
-	if err != nil {
-		return nil, err
-	}
-	return selector, nil
-}
-
 func (j *job) GetNode(probeID string) report.Node {
 	latests := map[string]string{
 		NodeType:              "Job",
@@ -43,5 +32,5 @@ func (j *job) GetNode(probeID string) report.Node {
 	}
 	return j.MetaNode(report.MakeJobNodeID(j.UID())).
 		WithLatests(latests).
-		WithLatestActiveControls(Describe)
+		WithLatestActiveControls(GetWorkloadLogs, Describe)
 }
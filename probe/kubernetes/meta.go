@@ -13,7 +13,7 @@ const (
 	Name            = report.KubernetesName
 	Namespace       = report.KubernetesNamespace
 	Created         = report.KubernetesCreated
-	LabelPrefix     = "kubernetes_labels_"
+	LabelPrefix     = report.KubernetesLabelPrefix
 	VolumeClaimName = report.KubernetesVolumeClaim
 )
 
@@ -24,6 +24,7 @@ type Meta interface {
 	Namespace() string
 	Created() string
 	Labels() map[string]string
+	OwnerReferences() []metav1.OwnerReference
 	MetaNode(id string) report.Node
 }
 
@@ -51,6 +52,10 @@ func (m meta) Labels() map[string]string {
 	return m.ObjectMeta.Labels
 }
 
+func (m meta) OwnerReferences() []metav1.OwnerReference {
+	return m.ObjectMeta.OwnerReferences
+}
+
 // MetaNode gets the node metadata
 func (m meta) MetaNode(id string) report.Node {
 	return report.MakeNodeWith(id, map[string]string{
@@ -84,6 +89,10 @@ func (m namespaceMeta) Labels() map[string]string {
 	return m.ObjectMeta.Labels
 }
 
+func (m namespaceMeta) OwnerReferences() []metav1.OwnerReference {
+	return m.ObjectMeta.OwnerReferences
+}
+
 // MetaNode gets the node metadata
 // For namespaces, ObjectMeta.Namespace is not set
 func (m namespaceMeta) MetaNode(id string) report.Node {
@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/weaveworks/scope/report"
 
@@ -14,6 +15,20 @@ const (
 	State           = report.KubernetesState
 	IsInHostNetwork = report.KubernetesIsInHostNetwork
 	RestartCount    = report.KubernetesRestartCount
+	QoSClass        = report.KubernetesQoSClass
+	PriorityClass   = report.KubernetesPriorityClass
+	CPURequest      = report.KubernetesCPURequest
+	CPULimit        = report.KubernetesCPULimit
+	MemoryRequest   = report.KubernetesMemoryRequest
+	MemoryLimit     = report.KubernetesMemoryLimit
+
+	ContainerResources = report.KubernetesContainerResources
+	ContainerName      = report.KubernetesContainerName
+)
+
+var (
+	requestKeys = map[apiv1.ResourceName]string{apiv1.ResourceCPU: CPURequest, apiv1.ResourceMemory: MemoryRequest}
+	limitKeys   = map[apiv1.ResourceName]string{apiv1.ResourceCPU: CPULimit, apiv1.ResourceMemory: MemoryLimit}
 )
 
 // Pod represents a Kubernetes pod
@@ -21,10 +36,16 @@ type Pod interface {
 	Meta
 	AddParent(topology, id string)
 	NodeName() string
+	PodIP() string
 	GetNode(probeID string) report.Node
 	RestartCount() uint
 	ContainerNames() []string
 	VolumeClaimNames() []string
+	Finished() bool
+	IsMirror() bool
+	GracePeriod() time.Duration
+	Requests() apiv1.ResourceList
+	Limits() apiv1.ResourceList
 }
 
 type pod struct {
@@ -63,10 +84,35 @@ func (p *pod) State() string {
 	return string(p.Status.Phase)
 }
 
+// Finished is true once all the containers of the pod have terminated, and
+// it no longer uses the resources it requested.
+func (p *pod) Finished() bool {
+	return p.Status.Phase == apiv1.PodSucceeded || p.Status.Phase == apiv1.PodFailed
+}
+
+// IsMirror is true for the mirrors of static pods, which the kubelet
+// manages, not the API server.
+func (p *pod) IsMirror() bool {
+	_, ok := p.ObjectMeta.Annotations[apiv1.MirrorPodAnnotationKey]
+	return ok
+}
+
+// GracePeriod is how long the pod is given to terminate.
+func (p *pod) GracePeriod() time.Duration {
+	if p.Spec.TerminationGracePeriodSeconds == nil {
+		return apiv1.DefaultTerminationGracePeriodSeconds * time.Second
+	}
+	return time.Duration(*p.Spec.TerminationGracePeriodSeconds) * time.Second
+}
+
 func (p *pod) NodeName() string {
 	return p.Spec.NodeName
 }
 
+func (p *pod) PodIP() string {
+	return p.Status.PodIP
+}
+
 func (p *pod) RestartCount() uint {
 	count := uint(0)
 	for _, cs := range p.Status.ContainerStatuses {
@@ -85,6 +131,70 @@ func (p *pod) VolumeClaimNames() []string {
 	return claimNames
 }
 
+// Requests are the resources requested by the pod as the scheduler counts
+// them: the sum of its containers, or the largest of its init containers if
+// that is more.
+func (p *pod) Requests() apiv1.ResourceList {
+	return p.effective(func(c apiv1.Container) apiv1.ResourceList { return c.Resources.Requests })
+}
+
+// Limits are the limits of the pod, computed as its requests are. Resources
+// without a limit in one of the containers are unbounded, so left out.
+func (p *pod) Limits() apiv1.ResourceList {
+	limits := p.effective(func(c apiv1.Container) apiv1.ResourceList { return c.Resources.Limits })
+	for name := range limits {
+		for _, c := range p.Spec.Containers {
+			if _, ok := c.Resources.Limits[name]; !ok {
+				delete(limits, name)
+				break
+			}
+		}
+	}
+	return limits
+}
+
+func (p *pod) effective(resources func(apiv1.Container) apiv1.ResourceList) apiv1.ResourceList {
+	result := apiv1.ResourceList{}
+	for _, c := range p.Spec.Containers {
+		for name, quantity := range resources(c) {
+			if total, ok := result[name]; ok {
+				total.Add(quantity)
+				result[name] = total
+			} else {
+				result[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	for _, c := range p.Spec.InitContainers {
+		for name, quantity := range resources(c) {
+			if total, ok := result[name]; !ok || quantity.Cmp(total) > 0 {
+				result[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return result
+}
+
+func addQuantities(latests map[string]string, resources apiv1.ResourceList, keys map[apiv1.ResourceName]string) {
+	for name, key := range keys {
+		if quantity, ok := resources[name]; ok {
+			latests[key] = quantity.String()
+		}
+	}
+}
+
+// containerResources is a row per container of its requests and limits.
+func (p *pod) containerResources() []report.Row {
+	rows := make([]report.Row, 0, len(p.Spec.Containers))
+	for _, c := range p.Spec.Containers {
+		entries := map[string]string{ContainerName: c.Name}
+		addQuantities(entries, c.Resources.Requests, requestKeys)
+		addQuantities(entries, c.Resources.Limits, limitKeys)
+		rows = append(rows, report.Row{ID: c.Name, Entries: entries})
+	}
+	return rows
+}
+
 func (p *pod) GetNode(probeID string) report.Node {
 	latests := map[string]string{
 		State:                 p.State(),
@@ -103,9 +213,28 @@ func (p *pod) GetNode(probeID string) report.Node {
 		latests[IsInHostNetwork] = "true"
 	}
 
+	if p.Status.QOSClass != "" {
+		latests[QoSClass] = string(p.Status.QOSClass)
+	}
+	if p.Spec.PriorityClassName != "" {
+		latests[PriorityClass] = p.Spec.PriorityClassName
+	}
+	addQuantities(latests, p.Requests(), requestKeys)
+	addQuantities(latests, p.Limits(), limitKeys)
+
+	controls := []string{GetLogs, DeletePod, Describe}
+	if p.Status.Phase == apiv1.PodRunning {
+		// Files are copied by running tar in the containers
+		controls = append(controls, DownloadFile, UploadFile)
+		if p.Status.PodIP != "" {
+			controls = append(controls, PortForward)
+		}
+	}
+
 	return p.MetaNode(report.MakePodNodeID(p.UID())).WithLatests(latests).
+		AddPrefixMulticolumnTable(ContainerResources, p.containerResources()).
 		WithParents(p.parents).
-		WithLatestActiveControls(GetLogs, DeletePod, Describe)
+		WithLatestActiveControls(controls...)
 }
 
 func (p *pod) ContainerNames() []string {
@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	apiappsv1 "k8s.io/api/apps/v1"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// These constants are keys used in node metadata
+const (
+	Revision = report.KubernetesRevision
+)
+
+// ReplicaSet represents a Kubernetes replica set
+type ReplicaSet interface {
+	Meta
+	GetNode(probeID string) report.Node
+}
+
+type replicaSet struct {
+	*apiappsv1.ReplicaSet
+	Meta
+}
+
+// NewReplicaSet creates a new ReplicaSet
+func NewReplicaSet(r *apiappsv1.ReplicaSet) ReplicaSet {
+	return &replicaSet{ReplicaSet: r, Meta: meta{r.ObjectMeta}}
+}
+
+func (r *replicaSet) GetNode(probeID string) report.Node {
+	// Spec.Replicas can be omitted, and the pointer will be nil. It defaults to 1.
+	desiredReplicas := 1
+	if r.Spec.Replicas != nil {
+		desiredReplicas = int(*r.Spec.Replicas)
+	}
+	latests := map[string]string{
+		ObservedGeneration:    fmt.Sprint(r.Status.ObservedGeneration),
+		DesiredReplicas:       fmt.Sprint(desiredReplicas),
+		Replicas:              fmt.Sprint(r.Status.Replicas),
+		AvailableReplicas:     fmt.Sprint(r.Status.AvailableReplicas),
+		report.ControlProbeID: probeID,
+		NodeType:              "ReplicaSet",
+	}
+	// The revision of the deployment this replica set was rolled out for
+	if revision, ok := r.Annotations["deployment.kubernetes.io/revision"]; ok {
+		latests[Revision] = revision
+	}
+	return r.MetaNode(report.MakeReplicaSetNodeID(r.UID())).
+		WithLatests(latests).
+		WithLatestActiveControls(GetWorkloadLogs, Describe)
+}
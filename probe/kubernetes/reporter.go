@@ -1,7 +1,11 @@
 package kubernetes
 
 import (
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/probe"
@@ -31,6 +35,10 @@ const (
 	VolumeSnapshotName = report.KubernetesVolumeSnapshotName
 	SnapshotData       = report.KubernetesSnapshotData
 	VolumeCapacity     = report.KubernetesVolumeCapacity
+	AllocatableCPU     = report.KubernetesAllocatableCPU
+	AllocatableMemory  = report.KubernetesAllocatableMemory
+	CPURequested       = report.KubernetesCPURequested
+	MemoryRequested    = report.KubernetesMemoryRequested
 )
 
 // Exposed for testing
@@ -42,6 +50,12 @@ var (
 		Namespace:        {ID: Namespace, Label: "Namespace", From: report.FromLatest, Priority: 5},
 		Created:          {ID: Created, Label: "Created", From: report.FromLatest, Datatype: report.DateTime, Priority: 6},
 		RestartCount:     {ID: RestartCount, Label: "Restart #", From: report.FromLatest, Priority: 7},
+		QoSClass:         {ID: QoSClass, Label: "QoS class", From: report.FromLatest, Priority: 8},
+		PriorityClass:    {ID: PriorityClass, Label: "Priority class", From: report.FromLatest, Priority: 9},
+		CPURequest:       {ID: CPURequest, Label: "CPU request", From: report.FromLatest, Priority: 10},
+		CPULimit:         {ID: CPULimit, Label: "CPU limit", From: report.FromLatest, Priority: 11},
+		MemoryRequest:    {ID: MemoryRequest, Label: "Memory request", From: report.FromLatest, Priority: 12},
+		MemoryLimit:      {ID: MemoryLimit, Label: "Memory limit", From: report.FromLatest, Priority: 13},
 	}
 
 	PodMetricTemplates = docker.ContainerMetricTemplates
@@ -70,6 +84,18 @@ var (
 
 	DeploymentMetricTemplates = PodMetricTemplates
 
+	ReplicaSetMetadataTemplates = report.MetadataTemplates{
+		NodeType:           {ID: NodeType, Label: "Type", From: report.FromLatest, Priority: 1},
+		Namespace:          {ID: Namespace, Label: "Namespace", From: report.FromLatest, Priority: 2},
+		Created:            {ID: Created, Label: "Created", From: report.FromLatest, Datatype: report.DateTime, Priority: 3},
+		Revision:           {ID: Revision, Label: "Revision", From: report.FromLatest, Datatype: report.Number, Priority: 4},
+		ObservedGeneration: {ID: ObservedGeneration, Label: "Observed gen.", From: report.FromLatest, Datatype: report.Number, Priority: 5},
+		DesiredReplicas:    {ID: DesiredReplicas, Label: "Desired replicas", From: report.FromLatest, Datatype: report.Number, Priority: 6},
+		report.Pod:         {ID: report.Pod, Label: "# Pods", From: report.FromCounters, Datatype: report.Number, Priority: 7},
+	}
+
+	ReplicaSetMetricTemplates = PodMetricTemplates
+
 	DaemonSetMetadataTemplates = report.MetadataTemplates{
 		NodeType:        {ID: NodeType, Label: "Type", From: report.FromLatest, Priority: 1},
 		Namespace:       {ID: Namespace, Label: "Namespace", From: report.FromLatest, Priority: 2},
@@ -160,6 +186,40 @@ var (
 		},
 	}
 
+	PodTableTemplates = report.TableTemplates{
+		LabelPrefix: TableTemplates[LabelPrefix],
+		ContainerResources: {
+			ID:     ContainerResources,
+			Label:  "Container resources",
+			Type:   report.MulticolumnTableType,
+			Prefix: ContainerResources,
+			Columns: []report.Column{
+				{ID: ContainerName, Label: "Container"},
+				{ID: CPURequest, Label: "CPU request"},
+				{ID: CPULimit, Label: "CPU limit"},
+				{ID: MemoryRequest, Label: "Memory request"},
+				{ID: MemoryLimit, Label: "Memory limit"},
+			},
+		},
+	}
+
+	HostMetadataTemplates = report.MetadataTemplates{
+		AllocatableCPU:    {ID: AllocatableCPU, Label: "Allocatable CPU", From: report.FromLatest, Priority: 3},
+		AllocatableMemory: {ID: AllocatableMemory, Label: "Allocatable memory", From: report.FromLatest, Priority: 4},
+	}
+
+	HostMetricTemplates = report.MetricTemplates{
+		CPURequested:    {ID: CPURequested, Label: "CPU requested", Format: report.PercentFormat, Priority: 3},
+		MemoryRequested: {ID: MemoryRequested, Label: "Memory requested", Format: report.FilesizeFormat, Priority: 4},
+	}
+
+	// The logs of pods and workloads can be those of the previous, crashed,
+	// containers, and files are copied to and from a container of a pod
+	podLogArgs      = controls.Args(controls.LogArgs, []report.ControlArg{controls.PreviousLogArg})
+	podFileCopyArgs = controls.Args(controls.FileCopyArgs, []report.ControlArg{
+		{Name: "container", Human: "Container (defaults to the first)", Type: report.StringArg},
+	})
+
 	ScalingControls = []report.Control{
 		{
 			ID:    ScaleDown,
@@ -173,6 +233,15 @@ var (
 			Icon:  "fa fa-plus",
 			Rank:  1,
 		},
+		{
+			ID:    Scale,
+			Human: "Scale",
+			Icon:  "fa fa-arrows-alt-v",
+			Rank:  3,
+			Args: []report.ControlArg{
+				{Name: "replicas", Human: "Replicas", Type: report.IntArg, Min: report.ArgLimit(0), Required: true},
+			},
+		},
 	}
 
 	DescribeControl = report.Control{
@@ -182,6 +251,30 @@ var (
 		Rank:  2,
 	}
 
+	RestartWorkloadControl = report.Control{
+		ID:           RestartWorkload,
+		Human:        "Restart",
+		Icon:         "fa fa-redo",
+		Confirmation: "Are you sure you want to restart all the pods? They are replaced following the update strategy, as in a rollout.",
+		Rank:         4,
+	}
+
+	WorkloadLogsControl = report.Control{
+		ID:    GetWorkloadLogs,
+		Human: "Get logs",
+		Icon:  "fa fa-desktop",
+		Rank:  0,
+		Args:  podLogArgs,
+	}
+
+	PortForwardControl = report.Control{
+		ID:    PortForward,
+		Human: "Forward port",
+		Icon:  "fa fa-exchange-alt",
+		Rank:  6,
+		Args:  controls.PortForwardArgs,
+	}
+
 	CordonControl = []report.Control{
 		{
 			ID:    CordonNode,
@@ -195,6 +288,13 @@ var (
 			Icon:  "fa fa-toggle-on",
 			Rank:  0,
 		},
+		{
+			ID:           DrainNode,
+			Human:        "Drain",
+			Icon:         "fa fa-eject",
+			Confirmation: "Are you sure you want to evict all the pods of this node?",
+			Rank:         2,
+		},
 	}
 )
 
@@ -206,11 +306,13 @@ type Reporter struct {
 	probe           *probe.Probe
 	hostID          string
 	handlerRegistry *controls.HandlerRegistry
+	jobs            *controls.Jobs
 	nodeName        string
 }
 
-// NewReporter makes a new Reporter
-func NewReporter(client Client, pipes controls.PipeClient, probeID string, hostID string, probe *probe.Probe, handlerRegistry *controls.HandlerRegistry, nodeName string) *Reporter {
+// NewReporter makes a new Reporter. Long-running controls run as jobs, or
+// synchronously if jobs is nil.
+func NewReporter(client Client, pipes controls.PipeClient, probeID string, hostID string, probe *probe.Probe, handlerRegistry *controls.HandlerRegistry, jobs *controls.Jobs, nodeName string) *Reporter {
 	reporter := &Reporter{
 		client:          client,
 		pipes:           pipes,
@@ -218,6 +320,7 @@ func NewReporter(client Client, pipes controls.PipeClient, probeID string, hostI
 		probe:           probe,
 		hostID:          hostID,
 		handlerRegistry: handlerRegistry,
+		jobs:            jobs,
 		nodeName:        nodeName,
 	}
 	reporter.registerControls()
@@ -308,31 +411,43 @@ func (r *Tagger) Tag(rpt report.Report) (report.Report, error) {
 // Report generates a Report containing Container and ContainerImage topologies
 func (r *Reporter) Report() (report.Report, error) {
 	result := report.MakeReport()
+	owners, err := r.ownership()
+	if err != nil {
+		return result, err
+	}
 	serviceTopology, services, err := r.serviceTopology()
 	if err != nil {
 		return result, err
 	}
-	daemonSetTopology, daemonSets, err := r.daemonSetTopology()
+	daemonSetTopology, _, err := r.daemonSetTopology()
 	if err != nil {
 		return result, err
 	}
-	statefulSetTopology, statefulSets, err := r.statefulSetTopology()
+	statefulSetTopology, _, err := r.statefulSetTopology()
 	if err != nil {
 		return result, err
 	}
-	cronJobTopology, cronJobs, err := r.cronJobTopology()
+	cronJobTopology, _, err := r.cronJobTopology()
 	if err != nil {
 		return result, err
 	}
-	deploymentTopology, deployments, err := r.deploymentTopology()
+	deploymentTopology, _, err := r.deploymentTopology()
 	if err != nil {
 		return result, err
 	}
-	jobTopology, jobs, err := r.jobTopology()
+	replicaSetTopology, err := r.replicaSetTopology(owners)
 	if err != nil {
 		return result, err
 	}
-	podTopology, err := r.podTopology(services, deployments, daemonSets, statefulSets, cronJobs, jobs)
+	jobTopology, _, err := r.jobTopology(owners)
+	if err != nil {
+		return result, err
+	}
+	customResourceTopology, customResources, err := r.customResourceTopology(owners)
+	if err != nil {
+		return result, err
+	}
+	podTopology, err := r.podTopology(services, customResources, owners)
 	if err != nil {
 		return result, err
 	}
@@ -371,6 +486,7 @@ func (r *Reporter) Report() (report.Report, error) {
 	result.StatefulSet = result.StatefulSet.Merge(statefulSetTopology)
 	result.CronJob = result.CronJob.Merge(cronJobTopology)
 	result.Deployment = result.Deployment.Merge(deploymentTopology)
+	result.ReplicaSet = result.ReplicaSet.Merge(replicaSetTopology)
 	result.Namespace = result.Namespace.Merge(namespaceTopology)
 	result.PersistentVolume = result.PersistentVolume.Merge(persistentVolumeTopology)
 	result.PersistentVolumeClaim = result.PersistentVolumeClaim.Merge(persistentVolumeClaimTopology)
@@ -378,6 +494,7 @@ func (r *Reporter) Report() (report.Report, error) {
 	result.VolumeSnapshot = result.VolumeSnapshot.Merge(volumeSnapshotTopology)
 	result.VolumeSnapshotData = result.VolumeSnapshotData.Merge(volumeSnapshotDataTopology)
 	result.Job = result.Job.Merge(jobTopology)
+	result.CustomResource = result.CustomResource.Merge(customResourceTopology)
 	result.Host = result.Host.Merge(hostTopology)
 
 	return result, nil
@@ -392,6 +509,8 @@ func (r *Reporter) serviceTopology() (report.Topology, []Service, error) {
 		services = []Service{}
 	)
 	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
+	result.Controls.AddControl(PortForwardControl)
 	err := r.client.WalkServices(func(s Service) error {
 		result.AddNode(s.GetNode(r.probeID))
 		services = append(services, s)
@@ -410,6 +529,8 @@ func (r *Reporter) deploymentTopology() (report.Topology, []Deployment, error) {
 	)
 	result.Controls.AddControls(ScalingControls)
 	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
+	result.Controls.AddControl(RestartWorkloadControl)
 
 	err := r.client.WalkDeployments(func(d Deployment) error {
 		result.AddNode(d.GetNode(r.probeID))
@@ -419,6 +540,20 @@ func (r *Reporter) deploymentTopology() (report.Topology, []Deployment, error) {
 	return result, deployments, err
 }
 
+func (r *Reporter) replicaSetTopology(owners ownership) (report.Topology, error) {
+	result := report.MakeTopology().
+		WithMetadataTemplates(ReplicaSetMetadataTemplates).
+		WithMetricTemplates(ReplicaSetMetricTemplates).
+		WithTableTemplates(TableTemplates)
+	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
+	err := r.client.WalkReplicaSets(func(rs ReplicaSet) error {
+		result.AddNode(rs.GetNode(r.probeID).WithParents(owners.parents(rs)))
+		return nil
+	})
+	return result, err
+}
+
 func (r *Reporter) daemonSetTopology() (report.Topology, []DaemonSet, error) {
 	daemonSets := []DaemonSet{}
 	result := report.MakeTopology().
@@ -426,6 +561,8 @@ func (r *Reporter) daemonSetTopology() (report.Topology, []DaemonSet, error) {
 		WithMetricTemplates(DaemonSetMetricTemplates).
 		WithTableTemplates(TableTemplates)
 	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
+	result.Controls.AddControl(RestartWorkloadControl)
 	err := r.client.WalkDaemonSets(func(d DaemonSet) error {
 		result.AddNode(d.GetNode(r.probeID))
 		daemonSets = append(daemonSets, d)
@@ -441,6 +578,8 @@ func (r *Reporter) statefulSetTopology() (report.Topology, []StatefulSet, error)
 		WithMetricTemplates(StatefulSetMetricTemplates).
 		WithTableTemplates(TableTemplates)
 	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
+	result.Controls.AddControl(RestartWorkloadControl)
 	err := r.client.WalkStatefulSets(func(s StatefulSet) error {
 		result.AddNode(s.GetNode(r.probeID))
 		statefulSets = append(statefulSets, s)
@@ -456,6 +595,7 @@ func (r *Reporter) cronJobTopology() (report.Topology, []CronJob, error) {
 		WithMetricTemplates(CronJobMetricTemplates).
 		WithTableTemplates(TableTemplates)
 	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
 	err := r.client.WalkCronJobs(func(c CronJob) error {
 		result.AddNode(c.GetNode(r.probeID))
 		cronJobs = append(cronJobs, c)
@@ -488,6 +628,9 @@ func (r *Reporter) persistentVolumeClaimTopology() (report.Topology, []Persisten
 		Human: "Create snapshot",
 		Icon:  "fa fa-camera",
 		Rank:  0,
+		Args: []report.ControlArg{
+			{Name: "capacity", Human: "Capacity (defaults to that of the claim)", Type: report.StringArg},
+		},
 	})
 	result.Controls.AddControl(DescribeControl)
 	err := r.client.WalkPersistentVolumeClaims(func(p PersistentVolumeClaim) error {
@@ -552,21 +695,36 @@ func (r *Reporter) volumeSnapshotDataTopology() (report.Topology, []VolumeSnapsh
 	return result, volumeSnapshotData, err
 }
 
-func (r *Reporter) jobTopology() (report.Topology, []Job, error) {
+func (r *Reporter) jobTopology(owners ownership) (report.Topology, []Job, error) {
 	jobs := []Job{}
 	result := report.MakeTopology().
 		WithMetadataTemplates(JobMetadataTemplates).
 		WithMetricTemplates(JobMetricTemplates).
 		WithTableTemplates(TableTemplates)
 	result.Controls.AddControl(DescribeControl)
+	result.Controls.AddControl(WorkloadLogsControl)
 	err := r.client.WalkJobs(func(c Job) error {
-		result.AddNode(c.GetNode(r.probeID))
+		result.AddNode(c.GetNode(r.probeID).WithParents(owners.parents(c)))
 		jobs = append(jobs, c)
 		return nil
 	})
 	return result, jobs, err
 }
 
+func (r *Reporter) customResourceTopology(owners ownership) (report.Topology, []CustomResource, error) {
+	customResources := []CustomResource{}
+	result := report.MakeTopology().
+		WithMetadataTemplates(CustomResourceMetadataTemplates(r.client.CustomResourceConfigs())).
+		WithMetricTemplates(PodMetricTemplates).
+		WithTableTemplates(TableTemplates)
+	err := r.client.WalkCustomResources(func(c CustomResource) error {
+		result.AddNode(c.GetNode(r.probeID).WithParents(owners.parents(c)))
+		customResources = append(customResources, c)
+		return nil
+	})
+	return result, customResources, err
+}
+
 type labelledChild interface {
 	Labels() map[string]string
 	AddParent(string, string)
@@ -582,12 +740,103 @@ func match(namespace string, selector labels.Selector, topology, id string) func
 	}
 }
 
-func (r *Reporter) podTopology(services []Service, deployments []Deployment, daemonSets []DaemonSet, statefulSets []StatefulSet, cronJobs []CronJob, jobs []Job) (report.Topology, error) {
+// owner is an object which can own others, as found in the report.
+type owner struct {
+	topology string
+	id       string
+	owners   []metav1.OwnerReference
+}
+
+// ownership indexes the objects which can own others by UID, to walk the
+// ownerReferences of an object up to its topmost owner.
+type ownership map[types.UID]owner
+
+func (o ownership) add(topology, id string, m Meta) {
+	o[types.UID(m.UID())] = owner{topology: topology, id: id, owners: m.OwnerReferences()}
+}
+
+// parents returns all the owners of m, direct or not, e.g. the ReplicaSet and
+// Deployment of a Pod, or the Job and CronJob.
+func (o ownership) parents(m Meta) report.Sets {
+	var (
+		parents = report.MakeSets()
+		refs    = m.OwnerReferences()
+		seen    = map[types.UID]struct{}{}
+	)
+	for len(refs) > 0 {
+		ref := refs[0]
+		refs = refs[1:]
+		if _, ok := seen[ref.UID]; ok {
+			continue
+		}
+		seen[ref.UID] = struct{}{}
+		if owner, ok := o[ref.UID]; ok {
+			parents = parents.AddString(owner.topology, owner.id)
+			refs = append(refs, owner.owners...)
+		}
+	}
+	return parents
+}
+
+// ownership walks all the objects which can own pods, or own objects which
+// own pods.
+func (r *Reporter) ownership() (ownership, error) {
+	owners := ownership{}
+	if err := r.client.WalkDeployments(func(d Deployment) error {
+		owners.add(report.Deployment, report.MakeDeploymentNodeID(d.UID()), d)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.client.WalkReplicaSets(func(rs ReplicaSet) error {
+		owners.add(report.ReplicaSet, report.MakeReplicaSetNodeID(rs.UID()), rs)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.client.WalkDaemonSets(func(d DaemonSet) error {
+		owners.add(report.DaemonSet, report.MakeDaemonSetNodeID(d.UID()), d)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.client.WalkStatefulSets(func(s StatefulSet) error {
+		owners.add(report.StatefulSet, report.MakeStatefulSetNodeID(s.UID()), s)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.client.WalkCronJobs(func(c CronJob) error {
+		owners.add(report.CronJob, report.MakeCronJobNodeID(c.UID()), c)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.client.WalkJobs(func(j Job) error {
+		owners.add(report.Job, report.MakeJobNodeID(j.UID()), j)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.client.WalkCustomResources(func(c CustomResource) error {
+		owners.add(report.CustomResource, report.MakeCustomResourceNodeID(c.UID()), c)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// podTopology reports pods, with their owners and the services selecting
+// them as parents. Controllers are found through ownerReferences rather than
+// selectors, which can overlap; only custom resources configured with a
+// selector path are matched by selector too.
+func (r *Reporter) podTopology(services []Service, customResources []CustomResource, owners ownership) (report.Topology, error) {
 	var (
 		pods = report.MakeTopology().
 			WithMetadataTemplates(PodMetadataTemplates).
 			WithMetricTemplates(PodMetricTemplates).
-			WithTableTemplates(TableTemplates)
+			WithTableTemplates(PodTableTemplates)
 		selectors = []func(labelledChild){}
 	)
 	pods.Controls.AddControl(report.Control{
@@ -595,6 +844,7 @@ func (r *Reporter) podTopology(services []Service, deployments []Deployment, dae
 		Human: "Get logs",
 		Icon:  "fa fa-desktop",
 		Rank:  0,
+		Args:  podLogArgs,
 	})
 	pods.Controls.AddControl(report.Control{
 		ID:           DeletePod,
@@ -604,6 +854,21 @@ func (r *Reporter) podTopology(services []Service, deployments []Deployment, dae
 		Rank:         3,
 	})
 	pods.Controls.AddControl(DescribeControl)
+	pods.Controls.AddControl(report.Control{
+		ID:    DownloadFile,
+		Human: "Download file",
+		Icon:  "fa fa-download",
+		Rank:  4,
+		Args:  podFileCopyArgs,
+	})
+	pods.Controls.AddControl(report.Control{
+		ID:    UploadFile,
+		Human: "Upload file",
+		Icon:  "fa fa-upload",
+		Rank:  5,
+		Args:  podFileCopyArgs,
+	})
+	pods.Controls.AddControl(PortForwardControl)
 	for _, service := range services {
 		selectors = append(selectors, match(
 			service.Namespace(),
@@ -612,65 +877,18 @@ func (r *Reporter) podTopology(services []Service, deployments []Deployment, dae
 			report.MakeServiceNodeID(service.UID()),
 		))
 	}
-	for _, deployment := range deployments {
-		selector, err := deployment.Selector()
-		if err != nil {
-			return pods, err
-		}
-		selectors = append(selectors, match(
-			deployment.Namespace(),
-			selector,
-			report.Deployment,
-			report.MakeDeploymentNodeID(deployment.UID()),
-		))
-	}
-	for _, daemonSet := range daemonSets {
-		selector, err := daemonSet.Selector()
-		if err != nil {
-			return pods, err
-		}
-		selectors = append(selectors, match(
-			daemonSet.Namespace(),
-			selector,
-			report.DaemonSet,
-			report.MakeDaemonSetNodeID(daemonSet.UID()),
-		))
-	}
-	for _, statefulSet := range statefulSets {
-		selector, err := statefulSet.Selector()
+	for _, customResource := range customResources {
+		selector, err := customResource.Selector()
 		if err != nil {
-			return pods, err
-		}
-		selectors = append(selectors, match(
-			statefulSet.Namespace(),
-			selector,
-			report.StatefulSet,
-			report.MakeStatefulSetNodeID(statefulSet.UID()),
-		))
-	}
-	for _, cronJob := range cronJobs {
-		cronJobSelectors, err := cronJob.Selectors()
-		if err != nil {
-			return pods, err
-		}
-		for _, selector := range cronJobSelectors {
-			selectors = append(selectors, match(
-				cronJob.Namespace(),
-				selector,
-				report.CronJob,
-				report.MakeCronJobNodeID(cronJob.UID()),
-			))
+			log.Warnf("Ignoring the selector of %s %s/%s: %v", customResource.Kind(), customResource.Namespace(), customResource.Name(), err)
+			continue
 		}
-		for _, job := range jobs {
-			selector, err := job.Selector()
-			if err != nil {
-				return pods, err
-			}
+		if selector != nil {
 			selectors = append(selectors, match(
-				job.Namespace(),
+				customResource.Namespace(),
 				selector,
-				report.Job,
-				report.MakeJobNodeID(job.UID()),
+				report.CustomResource,
+				report.MakeCustomResourceNodeID(customResource.UID()),
 			))
 		}
 	}
@@ -685,7 +903,7 @@ func (r *Reporter) podTopology(services []Service, deployments []Deployment, dae
 		for _, selector := range selectors {
 			selector(p)
 		}
-		pods.AddNode(p.GetNode(r.probeID))
+		pods.AddNode(p.GetNode(r.probeID).WithParents(owners.parents(p)))
 		return nil
 	})
 	return pods, err
@@ -701,7 +919,9 @@ func (r *Reporter) namespaceTopology() (report.Topology, error) {
 }
 
 func (r *Reporter) hostTopology() (report.Topology, error) {
-	result := report.MakeTopology()
+	result := report.MakeTopology().
+		WithMetadataTemplates(HostMetadataTemplates).
+		WithMetricTemplates(HostMetricTemplates)
 	// Add buttons for Host view, with the ID of the Kubernetes probe
 	for _, control := range CordonControl {
 		control.ProbeID = r.probeID
@@ -713,6 +933,28 @@ func (r *Reporter) hostTopology() (report.Topology, error) {
 		return result, err
 	}
 
+	// Sum what the pods scheduled on each node request, as the scheduler does
+	requested := map[string]apiv1.ResourceList{}
+	if err := r.client.WalkPods(func(p Pod) error {
+		if p.NodeName() == "" || p.Finished() {
+			return nil
+		}
+		total, ok := requested[p.NodeName()]
+		if !ok {
+			total = apiv1.ResourceList{}
+			requested[p.NodeName()] = total
+		}
+		for name, quantity := range p.Requests() {
+			sum := total[name]
+			sum.Add(quantity)
+			total[name] = sum
+		}
+		return nil
+	}); err != nil {
+		return result, err
+	}
+
+	now := mtime.Now()
 	for _, n := range nodes {
 		var activeControl string
 		if n.Spec.Unschedulable {
@@ -720,10 +962,26 @@ func (r *Reporter) hostTopology() (report.Topology, error) {
 		} else {
 			activeControl = CordonNode
 		}
+		latests := map[string]string{}
+		metrics := report.Metrics{}
+		if cpu, ok := n.Status.Allocatable[apiv1.ResourceCPU]; ok {
+			latests[AllocatableCPU] = cpu.String()
+			if allocatable := cpu.MilliValue(); allocatable > 0 {
+				cpuRequested := requested[n.Name][apiv1.ResourceCPU]
+				metrics[CPURequested] = report.MakeSingletonMetric(now, 100*float64(cpuRequested.MilliValue())/float64(allocatable)).WithMax(100)
+			}
+		}
+		if memory, ok := n.Status.Allocatable[apiv1.ResourceMemory]; ok {
+			latests[AllocatableMemory] = memory.String()
+			memoryRequested := requested[n.Name][apiv1.ResourceMemory]
+			metrics[MemoryRequested] = report.MakeSingletonMetric(now, float64(memoryRequested.Value())).WithMax(float64(memory.Value()))
+		}
 		result.AddNode(
 			report.MakeNode(report.MakeHostNodeID(n.Name)).
 				WithTopology(report.Host).
-				WithLatestActiveControls(activeControl),
+				WithLatests(latests).
+				WithMetrics(metrics).
+				WithLatestActiveControls(activeControl, DrainNode),
 		)
 	}
 	return result, nil
@@ -1,19 +1,27 @@
 package kubernetes_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"strings"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 
+	snapshotv1 "github.com/openebs/k8s-snapshot-client/snapshot/pkg/apis/volumesnapshot/v1"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/controls"
 	"github.com/weaveworks/scope/probe/docker"
@@ -114,10 +122,22 @@ func newMockClient() *mockClient {
 }
 
 type mockClient struct {
-	pods        []kubernetes.Pod
-	services    []kubernetes.Service
-	deployments []kubernetes.Deployment
-	logs        map[string]io.ReadCloser
+	pods            []kubernetes.Pod
+	services        []kubernetes.Service
+	deployments     []kubernetes.Deployment
+	replicaSets     []kubernetes.ReplicaSet
+	jobs            []kubernetes.Job
+	nodes           []apiv1.Node
+	customResources []kubernetes.CustomResource
+	logs            map[string]io.ReadCloser
+	logOptions      xfer.LogOptions
+	logPods         map[string][]string
+	execs           []string
+	claims          []kubernetes.PersistentVolumeClaim
+	volumeSnapshots []kubernetes.VolumeSnapshot
+	scaled          []string
+	restarted       []string
+	snapshots       []string
 }
 
 func (c *mockClient) Stop() {}
@@ -154,6 +174,14 @@ func (c *mockClient) WalkDeployments(f func(kubernetes.Deployment) error) error
 	}
 	return nil
 }
+func (c *mockClient) WalkReplicaSets(f func(kubernetes.ReplicaSet) error) error {
+	for _, replicaSet := range c.replicaSets {
+		if err := f(replicaSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 func (c *mockClient) WalkNamespaces(f func(kubernetes.NamespaceResource) error) error {
 	return nil
 }
@@ -161,41 +189,113 @@ func (c *mockClient) WalkPersistentVolumes(f func(kubernetes.PersistentVolume) e
 	return nil
 }
 func (c *mockClient) WalkPersistentVolumeClaims(f func(kubernetes.PersistentVolumeClaim) error) error {
+	for _, claim := range c.claims {
+		if err := f(claim); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 func (c *mockClient) WalkStorageClasses(f func(kubernetes.StorageClass) error) error {
 	return nil
 }
 func (c *mockClient) WalkVolumeSnapshots(f func(kubernetes.VolumeSnapshot) error) error {
+	for _, snapshot := range c.volumeSnapshots {
+		if err := f(snapshot); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 func (c *mockClient) WalkVolumeSnapshotData(f func(kubernetes.VolumeSnapshotData) error) error {
 	return nil
 }
 func (c *mockClient) WalkJobs(f func(kubernetes.Job) error) error {
+	for _, job := range c.jobs {
+		if err := f(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkCustomResources(f func(kubernetes.CustomResource) error) error {
+	for _, customResource := range c.customResources {
+		if err := f(customResource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) CustomResourceConfigs() []kubernetes.CustomResourceConfig {
 	return nil
 }
 func (*mockClient) WatchPods(func(kubernetes.Event, kubernetes.Pod)) {}
-func (c *mockClient) GetLogs(namespaceID, podName string, _ []string) (io.ReadCloser, error) {
+func (c *mockClient) GetLogs(namespaceID, podName string, _ []string, opts xfer.LogOptions) (io.ReadCloser, error) {
 	r, ok := c.logs[namespaceID+";"+podName]
 	if !ok {
 		return nil, fmt.Errorf("Not found")
 	}
+	c.logOptions = opts
 	return r, nil
 }
+func (c *mockClient) GetPodsLogs(namespaceID string, podContainers map[string][]string, opts xfer.LogOptions) (io.ReadCloser, error) {
+	c.logOptions = opts
+	c.logPods = podContainers
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
 func (c *mockClient) DeletePod(namespaceID, podID string) error {
 	return nil
 }
+func (c *mockClient) EvictPod(namespaceID, podID string) error {
+	return nil
+}
+func (c *mockClient) Exec(_ context.Context, namespaceID, podID, container string, command []string, stdin io.Reader, stdout io.Writer) error {
+	c.execs = append(c.execs, fmt.Sprintf("%s/%s/%s: %s", namespaceID, podID, container, strings.Join(command, " ")))
+	if stdin != nil {
+		_, err := io.Copy(ioutil.Discard, stdin)
+		return err
+	}
+	_, err := stdout.Write([]byte("archive"))
+	return err
+}
 func (c *mockClient) ScaleUp(namespaceID, id string) error {
 	return nil
 }
 func (c *mockClient) ScaleDown(namespaceID, id string) error {
 	return nil
 }
-func (c *mockClient) CloneVolumeSnapshot(namespaceID, VolumeSnapshotID, persistentVolumeClaimID, capacity string) error {
+func (c *mockClient) RestartWorkload(kind, namespaceID, id string, at time.Time) error {
+	c.restarted = append(c.restarted, fmt.Sprintf("%s %s/%s", kind, namespaceID, id))
+	return nil
+}
+func (c *mockClient) Scale(namespaceID, id string, replicas int32) error {
+	c.scaled = append(c.scaled, fmt.Sprintf("%s/%s: %d", namespaceID, id, replicas))
+	// Roll the deployment out at once
+	for i, d := range c.deployments {
+		if d.Namespace() == namespaceID && d.Name() == id {
+			c.deployments[i] = kubernetes.NewDeployment(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: id, UID: types.UID(d.UID()), Namespace: namespaceID},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{
+					Replicas:          replicas,
+					UpdatedReplicas:   replicas,
+					AvailableReplicas: replicas,
+				},
+			})
+		}
+	}
 	return nil
 }
+func (c *mockClient) CloneVolumeSnapshot(namespaceID, VolumeSnapshotID, persistentVolumeClaimID, capacity string) (string, error) {
+	name := "clone-" + persistentVolumeClaimID
+	c.claims = append(c.claims, kubernetes.NewPersistentVolumeClaim(&apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name), Namespace: namespaceID},
+		Status:     apiv1.PersistentVolumeClaimStatus{Phase: apiv1.ClaimBound},
+	}))
+	return name, nil
+}
 func (c *mockClient) CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capacity string) error {
+	c.snapshots = append(c.snapshots, fmt.Sprintf("%s/%s: %s", namespaceID, persistentVolumeClaimID, capacity))
 	return nil
 }
 func (c *mockClient) DeleteVolumeSnapshot(namespaceID, VolumeSnapshotID string) error {
@@ -210,7 +310,7 @@ func (c *mockClient) CordonNode(name string, desired bool) error {
 }
 
 func (c *mockClient) GetNodes() ([]apiv1.Node, error) {
-	return nil, nil
+	return c.nodes, nil
 }
 
 type mockPipeClient map[string]xfer.Pipe
@@ -231,7 +331,7 @@ func TestReporter(t *testing.T) {
 	pod2ID := report.MakePodNodeID(pod2UID)
 	serviceID := report.MakeServiceNodeID(serviceUID)
 	hr := controls.NewDefaultHandlerRegistry()
-	rpt, _ := kubernetes.NewReporter(newMockClient(), nil, "probe-id", "foo", nil, hr, nodeName).Report()
+	rpt, _ := kubernetes.NewReporter(newMockClient(), nil, "probe-id", "foo", nil, hr, nil, nodeName).Report()
 
 	// Reporter should have added the following pods
 	for _, pod := range []struct {
@@ -310,6 +410,159 @@ func TestReporter(t *testing.T) {
 
 }
 
+func ownedBy(kind, name, uid string) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{Kind: kind, Name: name, UID: types.UID(uid)}}
+}
+
+func TestReporterOwnerReferences(t *testing.T) {
+	deployment := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "pong", UID: "deployment1", Namespace: "ping"}}
+	replicaSet := appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "pong-1", UID: "replicaset1", Namespace: "ping",
+		Annotations:     map[string]string{"deployment.kubernetes.io/revision": "2"},
+		OwnerReferences: ownedBy("Deployment", "pong", "deployment1"),
+	}}
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name: "pong-job", UID: "job1", Namespace: "ping",
+		OwnerReferences: ownedBy("CronJob", "pong-cron", "cronjob1"),
+	}}
+	rsPod := apiPod1
+	rsPod.ObjectMeta.OwnerReferences = ownedBy("ReplicaSet", "pong-1", "replicaset1")
+	jobPod := apiPod2
+	jobPod.ObjectMeta.OwnerReferences = ownedBy("Job", "pong-job", "job1")
+
+	client := newMockClient()
+	client.pods = []kubernetes.Pod{kubernetes.NewPod(&rsPod), kubernetes.NewPod(&jobPod)}
+	client.deployments = []kubernetes.Deployment{kubernetes.NewDeployment(&deployment)}
+	client.replicaSets = []kubernetes.ReplicaSet{kubernetes.NewReplicaSet(&replicaSet)}
+	client.jobs = []kubernetes.Job{kubernetes.NewJob(&job)}
+	rpt, err := kubernetes.NewReporter(client, nil, "probe-id", "foo", nil, controls.NewDefaultHandlerRegistry(), nil, nodeName).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects are parented by their whole chain of known owners
+	for _, c := range []struct {
+		node    report.Node
+		parents map[string]string
+	}{
+		{rpt.Pod.Nodes[report.MakePodNodeID(pod1UID)], map[string]string{
+			report.ReplicaSet: report.MakeReplicaSetNodeID("replicaset1"),
+			report.Deployment: report.MakeDeploymentNodeID("deployment1"),
+		}},
+		{rpt.Pod.Nodes[report.MakePodNodeID(pod2UID)], map[string]string{
+			report.Job: report.MakeJobNodeID("job1"),
+		}},
+		{rpt.ReplicaSet.Nodes[report.MakeReplicaSetNodeID("replicaset1")], map[string]string{
+			report.Deployment: report.MakeDeploymentNodeID("deployment1"),
+		}},
+	} {
+		for topology, want := range c.parents {
+			if parents, ok := c.node.Parents.Lookup(topology); !ok || len(parents) != 1 || !parents.Contains(want) {
+				t.Errorf("Expected %s to have %s parent %q, got %v", c.node.ID, topology, want, parents)
+			}
+		}
+	}
+
+	node := rpt.ReplicaSet.Nodes[report.MakeReplicaSetNodeID("replicaset1")]
+	if have, ok := node.Latest.Lookup(kubernetes.Revision); !ok || have != "2" {
+		t.Errorf("Expected replica set revision 2, got %q", have)
+	}
+}
+
+func resources(cpu, memory string) apiv1.ResourceList {
+	list := apiv1.ResourceList{}
+	if cpu != "" {
+		list[apiv1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[apiv1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+func TestReporterResources(t *testing.T) {
+	bounded := apiPod1
+	bounded.Spec = apiv1.PodSpec{
+		NodeName:          nodeName,
+		PriorityClassName: "high",
+		InitContainers: []apiv1.Container{
+			{Name: "init", Resources: apiv1.ResourceRequirements{Requests: resources("1", "")}},
+		},
+		Containers: []apiv1.Container{
+			{Name: "app", Resources: apiv1.ResourceRequirements{
+				Requests: resources("250m", "64Mi"), Limits: resources("500m", "128Mi"),
+			}},
+			{Name: "sidecar", Resources: apiv1.ResourceRequirements{
+				Requests: resources("250m", "64Mi"), Limits: resources("", "64Mi"),
+			}},
+		},
+	}
+	bounded.Status.QOSClass = apiv1.PodQOSBurstable
+	unbounded := apiPod2
+	unbounded.Spec = apiv1.PodSpec{
+		NodeName:   nodeName,
+		Containers: []apiv1.Container{{Name: "app"}},
+	}
+	unbounded.Status.QOSClass = apiv1.PodQOSBestEffort
+	finished := apiPod2
+	finished.ObjectMeta.UID = "finished"
+	finished.Spec = bounded.Spec
+	finished.Status.Phase = apiv1.PodSucceeded
+
+	client := newMockClient()
+	client.pods = []kubernetes.Pod{kubernetes.NewPod(&bounded), kubernetes.NewPod(&unbounded), kubernetes.NewPod(&finished)}
+	client.nodes = []apiv1.Node{{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Status:     apiv1.NodeStatus{Allocatable: resources("4", "1Gi")},
+	}}
+	rpt, err := kubernetes.NewReporter(client, nil, "probe-id", "foo", nil, controls.NewDefaultHandlerRegistry(), nil, nodeName).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The init container requests more CPU than the others together, and the
+	// sidecar has no CPU limit, so neither has the pod.
+	node := rpt.Pod.Nodes[report.MakePodNodeID(pod1UID)]
+	for k, want := range map[string]string{
+		kubernetes.QoSClass:      "Burstable",
+		kubernetes.PriorityClass: "high",
+		kubernetes.CPURequest:    "1",
+		kubernetes.MemoryRequest: "128Mi",
+		kubernetes.MemoryLimit:   "192Mi",
+	} {
+		if have, ok := node.Latest.Lookup(k); !ok || have != want {
+			t.Errorf("Expected pod latest %q: %q, got %q", k, want, have)
+		}
+	}
+	if have, ok := node.Latest.Lookup(kubernetes.CPULimit); ok {
+		t.Errorf("Expected no CPU limit, got %q", have)
+	}
+	rows := node.ExtractMulticolumnTable(kubernetes.PodTableTemplates[kubernetes.ContainerResources])
+	if len(rows) != 2 {
+		t.Fatalf("Expected a row per container, got %v", rows)
+	}
+	for _, row := range rows {
+		if row.ID == "app" && row.Entries[kubernetes.CPULimit] != "500m" {
+			t.Errorf("Unexpected container resources %v", row)
+		}
+	}
+
+	// The finished pod doesn't count towards the requests on the host
+	host := rpt.Host.Nodes[report.MakeHostNodeID(nodeName)]
+	if have, ok := host.Latest.Lookup(kubernetes.AllocatableCPU); !ok || have != "4" {
+		t.Errorf("Expected allocatable CPU 4, got %q", have)
+	}
+	for k, want := range map[string]float64{
+		kubernetes.CPURequested:    25,
+		kubernetes.MemoryRequested: 128 * 1024 * 1024,
+	} {
+		metric, ok := host.Metrics.Lookup(k)
+		if !ok || len(metric.Samples) != 1 || metric.Samples[0].Value != want {
+			t.Errorf("Expected host metric %q: %v, got %v", k, want, metric)
+		}
+	}
+}
+
 func BenchmarkReporter(b *testing.B) {
 	hr := controls.NewDefaultHandlerRegistry()
 	mockK8s := newMockClient()
@@ -335,7 +588,7 @@ func BenchmarkReporter(b *testing.B) {
 		}
 		mockK8s.deployments = append(mockK8s.deployments, kubernetes.NewDeployment(&deployment))
 	}
-	reporter := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, hr, nodeName)
+	reporter := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, hr, nil, nodeName)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -401,7 +654,7 @@ func TestReporterGetLogs(t *testing.T) {
 	client := newMockClient()
 	pipes := mockPipeClient{}
 	hr := controls.NewDefaultHandlerRegistry()
-	reporter := kubernetes.NewReporter(client, pipes, "", "", nil, hr, nodeName)
+	reporter := kubernetes.NewReporter(client, pipes, "", "", nil, hr, nil, nodeName)
 
 	// Should error on invalid IDs
 	{
@@ -441,7 +694,21 @@ func TestReporterGetLogs(t *testing.T) {
 		return nil
 	}}
 
+	// Should reject bad log options
+	{
+		resp := reporter.CapturePod(reporter.GetLogs)(xfer.Request{
+			AppID:       "appID",
+			NodeID:      report.MakePodNodeID(pod1UID),
+			Control:     kubernetes.GetLogs,
+			ControlArgs: map[string]string{"tail": "lots"},
+		})
+		if resp.Error == "" {
+			t.Errorf("Expected an error on bad log options")
+		}
+	}
+
 	// Should create a new pipe for the stream
+	pod1Request.ControlArgs = map[string]string{"previous": "true", "since": "1h"}
 	resp := reporter.CapturePod(reporter.GetLogs)(pod1Request)
 	if resp.Pipe == "" {
 		t.Errorf("Expected pipe id to be returned, but got %#v", resp)
@@ -460,6 +727,9 @@ func TestReporterGetLogs(t *testing.T) {
 	if string(contents) != wantContents {
 		t.Errorf("Expected pipe to contain %q, but got %q", wantContents, string(contents))
 	}
+	if want := (xfer.LogOptions{Follow: true, Previous: true, Since: time.Hour, Tail: -1}); client.logOptions != want {
+		t.Errorf("Expected log options %+v, got %+v", want, client.logOptions)
+	}
 
 	// Should close the stream when the pipe closes
 	if err := pipe.Close(); err != nil {
@@ -469,3 +739,304 @@ func TestReporterGetLogs(t *testing.T) {
 		t.Errorf("Expected pipe to close the underlying log stream")
 	}
 }
+
+func TestReporterFileCopy(t *testing.T) {
+	apiPod := apiPod1
+	apiPod.Spec.Containers = []apiv1.Container{{Name: "app"}, {Name: "sidecar"}}
+	apiPod.Status.Phase = apiv1.PodRunning
+	client := newMockClient()
+	client.pods = []kubernetes.Pod{kubernetes.NewPod(&apiPod)}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(client, mockPipeClient{}, "", "", nil, hr, nil, nodeName)
+	defer reporter.Stop()
+
+	var pipe xfer.Pipe
+	oldNewBinaryPipe := controls.NewBinaryPipe
+	defer func() { controls.NewBinaryPipe = oldNewBinaryPipe }()
+	controls.NewBinaryPipe = func(controls.PipeClient, string) (string, xfer.Pipe, error) {
+		pipe = xfer.NewBinaryPipe()
+		return "pipe", pipe, nil
+	}
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if controls := rpt.Pod.Nodes[report.MakePodNodeID(pod1UID)].ActiveControls(); !reflect.DeepEqual(controls, []string{
+		kubernetes.GetLogs, kubernetes.DeletePod, kubernetes.Describe, kubernetes.DownloadFile, kubernetes.UploadFile,
+	}) {
+		t.Errorf("Unexpected active controls %v", controls)
+	}
+
+	request := func(control string, args map[string]string) xfer.Response {
+		return hr.HandleControlRequest(xfer.Request{
+			AppID:       "appID",
+			NodeID:      report.MakePodNodeID(pod1UID),
+			Control:     control,
+			ControlArgs: args,
+		})
+	}
+	if resp := request(kubernetes.DownloadFile, map[string]string{"path": "/tmp/dump", "container": "db"}); resp.Error != "Container not found: db" {
+		t.Errorf("Expected an error on an unknown container, got %+v", resp)
+	}
+
+	resp := request(kubernetes.DownloadFile, map[string]string{"path": "/tmp/dump"})
+	if !resp.BinaryPipe || resp.Filename != "dump.tar" {
+		t.Fatalf("Expected a binary pipe, got %+v", resp)
+	}
+	_, readWriter := pipe.Ends()
+	if contents, _ := ioutil.ReadAll(readWriter); string(contents) != "archive" {
+		t.Errorf("Unexpected download %q", contents)
+	}
+
+	resp = request(kubernetes.UploadFile, map[string]string{"path": "/etc/app", "container": "sidecar"})
+	if !resp.BinaryPipe {
+		t.Fatalf("Expected a binary pipe, got %+v", resp)
+	}
+	_, readWriter = pipe.Ends()
+	var archive bytes.Buffer
+	tar.NewWriter(&archive).Close()
+	go readWriter.Write(archive.Bytes())
+	if contents, _ := ioutil.ReadAll(readWriter); string(contents) != "uploaded 0 files (0 bytes) to /etc/app\n" {
+		t.Errorf("Unexpected upload progress %q", contents)
+	}
+
+	want := []string{
+		"ping/pong-a/app: /bin/sh -c " + controls.DownloadCommand(xfer.FileCopyOptions{Path: "/tmp/dump"})[2] + " sh /tmp dump",
+		"ping/pong-a/sidecar: tar xf - -C /etc/app",
+	}
+	if !reflect.DeepEqual(want, client.execs) {
+		t.Errorf("Expected execs %q, got %q", want, client.execs)
+	}
+}
+
+func TestReporterPortForward(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	apiPod := apiPod1
+	apiPod.Status.Phase = apiv1.PodRunning
+	apiPod.Status.PodIP = "127.0.0.1"
+	headless := apiService1
+	headless.ObjectMeta.UID = "headless"
+	headless.Spec.ClusterIP = apiv1.ClusterIPNone
+	client := newMockClient()
+	client.pods = []kubernetes.Pod{kubernetes.NewPod(&apiPod)}
+	client.services = []kubernetes.Service{kubernetes.NewService(&apiService1), kubernetes.NewService(&headless)}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(client, mockPipeClient{}, "", "", nil, hr, nil, nodeName)
+	defer reporter.Stop()
+
+	oldNewBinaryPipe := controls.NewBinaryPipe
+	defer func() { controls.NewBinaryPipe = oldNewBinaryPipe }()
+	controls.NewBinaryPipe = func(controls.PipeClient, string) (string, xfer.Pipe, error) {
+		return "pipe", xfer.NewBinaryPipe(), nil
+	}
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if controls := rpt.Pod.Nodes[report.MakePodNodeID(pod1UID)].ActiveControls(); !reflect.DeepEqual(controls, []string{
+		kubernetes.GetLogs, kubernetes.DeletePod, kubernetes.Describe, kubernetes.DownloadFile, kubernetes.UploadFile, kubernetes.PortForward,
+	}) {
+		t.Errorf("Unexpected pod controls %v", controls)
+	}
+	if controls := rpt.Service.Nodes[report.MakeServiceNodeID(serviceUID)].ActiveControls(); !reflect.DeepEqual(controls, []string{
+		kubernetes.GetWorkloadLogs, kubernetes.Describe, kubernetes.PortForward,
+	}) {
+		t.Errorf("Unexpected service controls %v", controls)
+	}
+	if controls := rpt.Service.Nodes[report.MakeServiceNodeID("headless")].ActiveControls(); !reflect.DeepEqual(controls, []string{
+		kubernetes.GetWorkloadLogs, kubernetes.Describe,
+	}) {
+		t.Errorf("Unexpected headless service controls %v", controls)
+	}
+
+	request := func(nodeID, port string) xfer.Response {
+		return hr.HandleControlRequest(xfer.Request{
+			AppID:       "appID",
+			NodeID:      nodeID,
+			Control:     kubernetes.PortForward,
+			ControlArgs: map[string]string{"port": port},
+		})
+	}
+	want := xfer.Response{Pipe: "pipe", BinaryPipe: true, Forward: "127.0.0.1:" + port}
+	if resp := request(report.MakePodNodeID(pod1UID), port); resp != want {
+		t.Errorf("Expected %+v, got %+v", want, resp)
+	}
+	if resp := request(report.MakeServiceNodeID("headless"), port); resp.Error != "Service pongservice has no cluster IP" {
+		t.Errorf("Expected an error on a headless service, got %+v", resp)
+	}
+	if resp := request(report.MakePodNodeID(pod1UID), "http"); resp.Error == "" {
+		t.Errorf("Expected an error on a bad port, got %+v", resp)
+	}
+}
+
+func TestReporterScaleAndSnapshot(t *testing.T) {
+	client := newMockClient()
+	client.deployments = []kubernetes.Deployment{kubernetes.NewDeployment(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong", UID: "deployment1", Namespace: "ping"},
+	})}
+	client.claims = []kubernetes.PersistentVolumeClaim{kubernetes.NewPersistentVolumeClaim(&apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", UID: "claim1", Namespace: "ping"},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			Resources: apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{apiv1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	})}
+	client.volumeSnapshots = []kubernetes.VolumeSnapshot{kubernetes.NewVolumeSnapshot(&snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snap", UID: "snapshot1", Namespace: "ping"},
+		Spec:       snapshotv1.VolumeSnapshotSpec{PersistentVolumeClaimName: "data"},
+	})}
+	hr := controls.NewDefaultHandlerRegistry()
+	jobs := controls.NewJobs("probe-id", hr, nil)
+	defer jobs.Stop()
+	reporter := kubernetes.NewReporter(client, mockPipeClient{}, "", "", nil, hr, jobs, nodeName)
+	defer reporter.Stop()
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale, ok := rpt.Deployment.Controls[kubernetes.Scale]
+	if !ok || len(scale.Args) != 1 || scale.Args[0].Name != "replicas" {
+		t.Errorf("Unexpected scale control %+v", scale)
+	}
+
+	request := func(nodeID, control string, args map[string]string) xfer.Response {
+		return hr.HandleControlRequest(xfer.Request{NodeID: nodeID, Control: control, ControlArgs: args})
+	}
+	checkJob := func(resp xfer.Response, progress string) {
+		if resp.Error != "" || resp.Job == "" {
+			t.Fatalf("Expected a job, got %+v", resp)
+		}
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			rpt, _ := jobs.Report()
+			if job := rpt.ControlJobs[resp.Job]; job.Done() {
+				if job.State != report.JobSucceeded || job.Progress != progress {
+					t.Errorf("Unexpected job %+v", job)
+				}
+				return
+			} else if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for the job, got %+v", job)
+			}
+		}
+	}
+	deploymentID := report.MakeDeploymentNodeID("deployment1")
+	checkJob(request(deploymentID, kubernetes.Scale, map[string]string{"replicas": "3"}), "3 of 3 replicas available")
+	if resp := request(deploymentID, kubernetes.Scale, map[string]string{"replicas": "-1"}); resp.Error == "" {
+		t.Error("Expected an error on a negative number of replicas")
+	}
+	claimID := report.MakePersistentVolumeClaimNodeID("claim1")
+	for _, capacity := range []string{"", "5Gi"} {
+		if resp := request(claimID, kubernetes.CreateVolumeSnapshot, map[string]string{"capacity": capacity}); resp.Error != "" {
+			t.Error(resp.Error)
+		}
+	}
+	if resp := request(claimID, kubernetes.CreateVolumeSnapshot, map[string]string{"capacity": "lots"}); resp.Error == "" {
+		t.Error("Expected an error on a bad capacity")
+	}
+	checkJob(request(report.MakeVolumeSnapshotNodeID("snapshot1"), kubernetes.CloneVolumeSnapshot, nil), "Claim clone-data is bound")
+	checkJob(request(report.MakeHostNodeID("spare"), kubernetes.DrainNode, nil), "node spare drained")
+
+	if want := []string{"ping/pong: 3"}; !reflect.DeepEqual(want, client.scaled) {
+		t.Errorf("Expected %v, got %v", want, client.scaled)
+	}
+	if want := []string{"ping/data: 1Gi", "ping/data: 5Gi"}; !reflect.DeepEqual(want, client.snapshots) {
+		t.Errorf("Expected %v, got %v", want, client.snapshots)
+	}
+}
+
+func TestReporterRestartWorkload(t *testing.T) {
+	client := newMockClient()
+	client.deployments = []kubernetes.Deployment{kubernetes.NewDeployment(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong", UID: "deployment1", Namespace: "ping"},
+	})}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(client, nil, "", "", nil, hr, nil, nodeName)
+	defer reporter.Stop()
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if control, ok := rpt.Deployment.Controls[kubernetes.RestartWorkload]; !ok || control.Confirmation == "" {
+		t.Errorf("Expected a restart control asking for confirmation, got %+v", control)
+	}
+	deploymentID := report.MakeDeploymentNodeID("deployment1")
+	if controls := rpt.Deployment.Nodes[deploymentID].ActiveControls(); !report.MakeStringSet(controls...).Contains(kubernetes.RestartWorkload) {
+		t.Errorf("Expected the restart control to be active, got %v", controls)
+	}
+
+	for _, c := range []struct {
+		nodeID string
+		err    bool
+	}{
+		{deploymentID, false},
+		{report.MakeDeploymentNodeID("missing"), true},
+		{report.MakePodNodeID(pod1UID), true},
+	} {
+		resp := hr.HandleControlRequest(xfer.Request{NodeID: c.nodeID, Control: kubernetes.RestartWorkload})
+		if (resp.Error != "") != c.err {
+			t.Errorf("%s: unexpected response %+v", c.nodeID, resp)
+		}
+	}
+	if want := []string{"deployment ping/pong"}; !reflect.DeepEqual(want, client.restarted) {
+		t.Errorf("Expected %v, got %v", want, client.restarted)
+	}
+}
+
+func TestReporterGetWorkloadLogs(t *testing.T) {
+	deployment := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "pong", UID: "deployment1", Namespace: "ping"}}
+	replicaSet := appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "pong-1", UID: "replicaset1", Namespace: "ping",
+		OwnerReferences: ownedBy("Deployment", "pong", "deployment1"),
+	}}
+	owned := apiPod1
+	owned.ObjectMeta.OwnerReferences = ownedBy("ReplicaSet", "pong-1", "replicaset1")
+	owned.Spec.Containers = []apiv1.Container{{Name: "app"}, {Name: "sidecar"}}
+
+	client := newMockClient()
+	client.pods = []kubernetes.Pod{kubernetes.NewPod(&owned), pod2}
+	client.deployments = []kubernetes.Deployment{kubernetes.NewDeployment(&deployment)}
+	client.replicaSets = []kubernetes.ReplicaSet{kubernetes.NewReplicaSet(&replicaSet)}
+	pipes := mockPipeClient{}
+	reporter := kubernetes.NewReporter(client, pipes, "", "", nil, controls.NewDefaultHandlerRegistry(), nil, nodeName)
+
+	for _, c := range []struct {
+		nodeID string
+		want   map[string][]string
+	}{
+		// pods of a controller are found through their owners
+		{report.MakeDeploymentNodeID("deployment1"), map[string][]string{"pong-a": {"app", "sidecar"}}},
+		// pods of a service through its selector
+		{report.MakeServiceNodeID(serviceUID), map[string][]string{"pong-a": {"app", "sidecar"}, "pong-b": {}}},
+	} {
+		resp := reporter.GetWorkloadLogs(xfer.Request{
+			AppID:       "appID",
+			NodeID:      c.nodeID,
+			Control:     kubernetes.GetWorkloadLogs,
+			ControlArgs: map[string]string{"tail": "10"},
+		})
+		if resp.Error != "" || resp.Pipe == "" {
+			t.Fatalf("Expected a pipe for %s, got %#v", c.nodeID, resp)
+		}
+		if !reflect.DeepEqual(c.want, client.logPods) {
+			t.Errorf("Expected logs of %v for %s, got %v", c.want, c.nodeID, client.logPods)
+		}
+		if client.logOptions.Tail != 10 {
+			t.Errorf("Expected the log options to be passed on, got %+v", client.logOptions)
+		}
+	}
+
+	resp := reporter.GetWorkloadLogs(xfer.Request{NodeID: report.MakeDeploymentNodeID("unknown")})
+	if resp.Error == "" {
+		t.Errorf("Expected an error for a workload without pods")
+	}
+}
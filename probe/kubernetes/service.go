@@ -63,9 +63,13 @@ func (s *service) GetNode(probeID string) report.Node {
 		}
 		latest[Ports] = portStr[:len(portStr)-1]
 	}
+	controls := []string{GetWorkloadLogs, Describe}
+	if s.Spec.ClusterIP != "" && s.Spec.ClusterIP != apiv1.ClusterIPNone {
+		controls = append(controls, PortForward)
+	}
 	return s.MetaNode(report.MakeServiceNodeID(s.UID())).
 		WithLatests(latest).
-		WithLatestActiveControls(Describe)
+		WithLatestActiveControls(controls...)
 }
 
 func (s *service) ClusterIP() string {
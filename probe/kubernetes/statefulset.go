@@ -4,8 +4,6 @@ import (
 	"fmt"
 
 	appsv1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/weaveworks/scope/report"
 )
@@ -13,7 +11,6 @@ import (
 // StatefulSet represents a Kubernetes statefulset
 type StatefulSet interface {
 	Meta
-	Selector() (labels.Selector, error)
 	GetNode(probeID string) report.Node
 }
 
@@ -30,14 +27,6 @@ func NewStatefulSet(s *appsv1.StatefulSet) StatefulSet {
 	}
 }
 
-func (s *statefulSet) Selector() (labels.Selector, error) {
-	selector, err := metav1.LabelSelectorAsSelector(s.Spec.Selector)
-	if err != nil {
-		return nil, err
-	}
-	return selector, nil
-}
-
 func (s *statefulSet) GetNode(probeID string) report.Node {
 	desiredReplicas := 1
 	if s.Spec.Replicas != nil {
@@ -52,5 +41,5 @@ func (s *statefulSet) GetNode(probeID string) report.Node {
 	}
 	return s.MetaNode(report.MakeStatefulSetNodeID(s.UID())).
 		WithLatests(latests).
-		WithLatestActiveControls(Describe)
+		WithLatestActiveControls(GetWorkloadLogs, Describe, RestartWorkload)
 }
@@ -2,6 +2,7 @@ package probe
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,6 +32,9 @@ type Probe struct {
 	ticksPerFullReport           int
 	noControls                   bool
 
+	id, hostname, version string
+	stats                 probeStats
+
 	tickers   []Ticker
 	reporters []Reporter
 	taggers   []Tagger
@@ -42,6 +46,28 @@ type Probe struct {
 	shortcutReports chan report.Report
 }
 
+// probeStats keeps track of how long the last run of each reporter and
+// tagger took, and of the modules whose last run failed.
+type probeStats struct {
+	sync.Mutex
+	reporterDurations map[string]time.Duration
+	taggerDurations   map[string]time.Duration
+	errors            map[string]string
+}
+
+func (s *probeStats) record(durations map[string]time.Duration, module string, d time.Duration, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if durations != nil {
+		durations[module] = d
+	}
+	if err != nil {
+		s.errors[module] = err.Error()
+	} else {
+		delete(s.errors, module)
+	}
+}
+
 // Tagger tags nodes with value-add node metadata.
 type Tagger interface {
 	Name() string
@@ -92,10 +118,21 @@ func New(
 		quit:               make(chan struct{}),
 		spiedReports:       make(chan report.Report, spiedReportBufferSize),
 		shortcutReports:    make(chan report.Report, shortcutReportBufferSize),
+		stats: probeStats{
+			reporterDurations: map[string]time.Duration{},
+			taggerDurations:   map[string]time.Duration{},
+			errors:            map[string]string{},
+		},
 	}
 	return result
 }
 
+// SetIdentity sets the details the probe publishes about itself, together
+// with its health, in every report.
+func (p *Probe) SetIdentity(id, hostname, version string) {
+	p.id, p.hostname, p.version = id, hostname, version
+}
+
 // AddTagger adds a new Tagger to the Probe
 func (p *Probe) AddTagger(ts ...Tagger) {
 	p.taggers = append(p.taggers, ts...)
@@ -153,6 +190,7 @@ func (p *Probe) tick() {
 	for _, ticker := range p.tickers {
 		t := time.Now()
 		err := ticker.Tick()
+		p.stats.record(nil, ticker.Name(), 0, err)
 		metrics.MeasureSinceWithLabels([]string{"duration", "seconds"}, t, []metrics.Label{
 			{Name: "operation", Value: "ticker"},
 			{Name: "module", Value: ticker.Name()},
@@ -173,6 +211,7 @@ func (p *Probe) report() report.Report {
 			if !timer.Stop() {
 				log.Warningf("%v reporter took %v (longer than %v)", rep.Name(), time.Now().Sub(t), p.spyInterval)
 			}
+			p.stats.record(p.stats.reporterDurations, rep.Name(), time.Since(t), err)
 			metrics.MeasureSinceWithLabels([]string{"duration", "seconds"}, t, []metrics.Label{
 				{Name: "operation", Value: "reporter"},
 				{Name: "module", Value: rep.Name()},
@@ -202,6 +241,7 @@ func (p *Probe) tag(r report.Report) report.Report {
 		if !timer.Stop() {
 			log.Warningf("%v tagger took %v (longer than %v)", tagger.Name(), time.Now().Sub(t), p.spyInterval)
 		}
+		p.stats.record(p.stats.taggerDurations, tagger.Name(), time.Since(t), err)
 		metrics.MeasureSinceWithLabels([]string{"duration", "seconds"}, t, []metrics.Label{
 			{Name: "operation", Value: "tagger"},
 			{Name: "module", Value: tagger.Name()},
@@ -233,9 +273,47 @@ ForLoop:
 			t.Controls = report.Controls{}
 		})
 	}
+	if p.id != "" {
+		rpt.Probes = report.ProbeSummaries{p.id: p.summary()}
+	}
 	return rpt, count
 }
 
+// summary describes this probe and its health.
+func (p *Probe) summary() report.ProbeSummary {
+	features := []string{}
+	for _, rep := range p.reporters {
+		features = append(features, rep.Name())
+	}
+	if !p.noControls {
+		features = append(features, "controls")
+	}
+	sort.Strings(features)
+
+	p.stats.Lock()
+	defer p.stats.Unlock()
+	summary := report.ProbeSummary{
+		ID:                p.id,
+		Hostname:          p.hostname,
+		Version:           p.version,
+		Features:          features,
+		ReporterDurations: make(map[string]time.Duration, len(p.stats.reporterDurations)),
+		TaggerDurations:   make(map[string]time.Duration, len(p.stats.taggerDurations)),
+		Errors:            make(map[string]string, len(p.stats.errors)),
+		Timestamp:         mtime.Now(),
+	}
+	for k, v := range p.stats.reporterDurations {
+		summary.ReporterDurations[k] = v
+	}
+	for k, v := range p.stats.taggerDurations {
+		summary.TaggerDurations[k] = v
+	}
+	for k, v := range p.stats.errors {
+		summary.Errors[k] = v
+	}
+	return summary
+}
+
 func (p *Probe) publishLoop() {
 	defer p.done.Done()
 	startTime := mtime.Now()
@@ -1,6 +1,7 @@
 package probe
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -82,3 +83,41 @@ func TestProbe(t *testing.T) {
 		return <-pub.have
 	})
 }
+
+type failingReporter struct{}
+
+func (failingReporter) Report() (report.Report, error) {
+	return report.MakeReport(), fmt.Errorf("failed")
+}
+
+func (failingReporter) Name() string { return "Failing" }
+
+func TestProbeSummary(t *testing.T) {
+	const probeID = "probeid"
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	p := New(0, 0, nil, 1, true)
+	p.SetIdentity(probeID, "hostname", "version")
+	p.AddReporter(mockReporter{report.MakeReport()}, failingReporter{})
+	p.spiedReports <- p.report()
+
+	rpt, _ := p.drainAndSanitise(report.MakeReport(), p.spiedReports)
+	summary, ok := rpt.Probes[probeID]
+	if !ok {
+		t.Fatalf("Expected a summary for probe %q, got %v", probeID, rpt.Probes)
+	}
+	if want, have := []string{"Failing", "Mock"}, summary.Features; !reflect.DeepEqual(want, have) {
+		t.Errorf("want features %v, have %v", want, have)
+	}
+	if _, ok := summary.ReporterDurations["Mock"]; !ok {
+		t.Errorf("Expected a duration for the Mock reporter, got %v", summary.ReporterDurations)
+	}
+	if want, have := map[string]string{"Failing": "failed"}, summary.Errors; !reflect.DeepEqual(want, have) {
+		t.Errorf("want errors %v, have %v", want, have)
+	}
+	if want, have := now, summary.Timestamp; !want.Equal(have) {
+		t.Errorf("want timestamp %v, have %v", want, have)
+	}
+}
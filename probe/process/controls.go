@@ -0,0 +1,235 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+// Control IDs used by the process reporter.
+const (
+	SignalProcess = "process_signal"
+	ReniceProcess = "process_renice"
+	DumpStack     = "process_dump_stack"
+)
+
+// Signals are the signals the signal control sends, by name.
+var Signals = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"KILL": syscall.SIGKILL,
+	"HUP":  syscall.SIGHUP,
+	"USR1": syscall.SIGUSR1,
+}
+
+// DumpStackDuration is how long the output of a process is streamed for,
+// after being sent SIGQUIT. Exported for testing.
+var DumpStackDuration = 5 * time.Second
+
+var controlTemplates = []report.Control{
+	{
+		ID:           SignalProcess,
+		Human:        "Send signal",
+		Icon:         "fa fa-bolt",
+		Confirmation: "Are you sure you want to signal this process?",
+		Rank:         0,
+		Args: []report.ControlArg{
+			{Name: "signal", Human: "Signal", Type: report.EnumArg, Choices: []string{"TERM", "KILL", "HUP", "USR1"}, Default: "TERM"},
+		},
+	},
+	{
+		ID:           ReniceProcess,
+		Human:        "Renice",
+		Icon:         "fa fa-sort-amount-down",
+		Confirmation: "Are you sure you want to change the priority of this process?",
+		Rank:         1,
+		Args: []report.ControlArg{
+			{Name: "priority", Human: "Niceness", Type: report.IntArg, Min: report.ArgLimit(-20), Max: report.ArgLimit(19), Required: true},
+		},
+	},
+	{
+		ID:           DumpStack,
+		Human:        "Dump stack",
+		Icon:         "fa fa-layer-group",
+		Confirmation: "Go programs, and others not handling SIGQUIT, exit after dumping their stack. Are you sure?",
+		Rank:         2,
+	},
+}
+
+func (r *Reporter) registerControls() {
+	r.handlerRegistry.Batch(nil, map[string]xfer.ControlHandlerFunc{
+		SignalProcess: r.capturePID(r.signalProcess),
+		ReniceProcess: r.capturePID(r.reniceProcess),
+		DumpStack:     r.capturePID(r.dumpStack),
+	})
+}
+
+func (r *Reporter) deregisterControls() {
+	r.handlerRegistry.Batch([]string{SignalProcess, ReniceProcess, DumpStack}, nil)
+}
+
+// capturePID parses the PID of the process node of a request, checking the
+// process is on this host, and still the one reported.
+func (r *Reporter) capturePID(f func(int, xfer.Request) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		hostID, pidStr, ok := report.ParseProcessNodeID(req.NodeID)
+		if !ok || hostID != r.scope {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || pid <= 0 {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		if !r.stillRunning(pid) {
+			return xfer.ResponseErrorf("Process no longer exists: %d", pid)
+		}
+		return f(pid, req)
+	}
+}
+
+// stillRunning checks the process with the PID is the one last walked,
+// started at the same time, rather than one which reused the PID since.
+func (r *Reporter) stillRunning(pid int) bool {
+	var (
+		walked Process
+		found  bool
+	)
+	r.walker.Walk(func(p, _ Process) {
+		if p.PID == pid {
+			walked, found = p, true
+		}
+	})
+	if !found {
+		return false
+	}
+	startTime, err := processStartTime(r.procRoot, pid)
+	return err == nil && startTime == walked.StartTime
+}
+
+func (r *Reporter) signalProcess(pid int, req xfer.Request) xfer.Response {
+	name := strings.TrimPrefix(strings.ToUpper(req.ControlArgs["signal"]), "SIG")
+	if name == "" {
+		name = "TERM"
+	}
+	signal, ok := Signals[name]
+	if !ok {
+		return xfer.ResponseErrorf("Bad parameter: signal (%q): must be one of TERM, KILL, HUP or USR1", req.ControlArgs["signal"])
+	}
+	log.Infof("Sending SIG%s to process %d", name, pid)
+	return xfer.ResponseError(syscall.Kill(pid, signal))
+}
+
+func (r *Reporter) reniceProcess(pid int, req xfer.Request) xfer.Response {
+	priority, err := strconv.Atoi(req.ControlArgs["priority"])
+	if err != nil || priority < -20 || priority > 19 {
+		return xfer.ResponseErrorf("Bad parameter: priority (%q): must be a niceness between -20 and 19", req.ControlArgs["priority"])
+	}
+	log.Infof("Renicing process %d to %d", pid, priority)
+	return xfer.ResponseError(setPriority(r.procRoot, pid, priority))
+}
+
+// dumpStack sends SIGQUIT to a process, which makes Go programs and JVMs
+// dump their stacks to stderr, and streams what the process writes to
+// stderr for DumpStackDuration. That can only be captured when stderr is a
+// file; otherwise the stack is to be found wherever stderr goes, e.g. the
+// logs of a container.
+func (r *Reporter) dumpStack(pid int, req xfer.Request) xfer.Response {
+	stderr, err := openStderr(r.procRoot, pid)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	id, pipe, err := controls.NewPipe(r.pipes, req.AppID)
+	if err != nil {
+		if stderr != nil {
+			stderr.Close()
+		}
+		return xfer.ResponseError(err)
+	}
+	local, _ := pipe.Ends()
+	ctx, cancel := context.WithTimeout(context.Background(), DumpStackDuration)
+	pipe.OnClose(cancel)
+
+	log.Infof("Dumping the stack of process %d", pid)
+	if err := syscall.Kill(pid, syscall.SIGQUIT); err != nil {
+		cancel()
+		pipe.Close()
+		if stderr != nil {
+			stderr.Close()
+		}
+		return xfer.ResponseError(err)
+	}
+	go func() {
+		defer pipe.Close()
+		defer cancel()
+		if stderr == nil {
+			fmt.Fprintf(local, "Sent SIGQUIT to process %d. Its stderr isn't a file, so look for the stack where its output goes, e.g. its logs.\n", pid)
+			return
+		}
+		defer stderr.Close()
+		if err := tail(ctx, stderr, local); err != nil && ctx.Err() == nil {
+			log.Errorf("Error reading the stack of process %d: %v", pid, err)
+		}
+	}()
+	return xfer.Response{
+		Pipe: id,
+	}
+}
+
+// openStderr opens the stderr of a process, positioned at its end, if it
+// is a file. It returns nil if stderr is anything else, such as a pipe or
+// a terminal, which can't be read without taking output from its reader.
+func openStderr(procRoot string, pid int) (*os.File, error) {
+	path := filepath.Join(procRoot, strconv.Itoa(pid), "fd", "2")
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// tail copies what is appended to f to w, until ctx is done.
+func tail(ctx context.Context, f *os.File, w io.Writer) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package process
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// sharesPIDNamespace is always true, as there are no PID namespaces.
+func sharesPIDNamespace(procRoot string) bool {
+	return true
+}
+
+// processStartTime is always 0, as walked processes have no start time.
+func processStartTime(procRoot string, pid int) (uint64, error) {
+	return 0, nil
+}
+
+func setPriority(procRoot string, pid, priority int) error {
+	return unix.Setpriority(unix.PRIO_PROCESS, pid, priority)
+}
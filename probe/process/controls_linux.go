@@ -0,0 +1,47 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// sharesPIDNamespace checks the probe is in the PID namespace of procRoot,
+// so the PIDs read there are those the probe signals and renices. The
+// self link of a proc filesystem names the PID of the reader in the
+// namespace of that filesystem.
+func sharesPIDNamespace(procRoot string) bool {
+	self, err := os.Readlink(filepath.Join(procRoot, "self"))
+	return err == nil && self == strconv.Itoa(os.Getpid())
+}
+
+// processStartTime reads the time a process started at, in clock ticks
+// since boot, as the walker does.
+func processStartTime(procRoot string, pid int) (uint64, error) {
+	_, _, _, startTime, _, _, err := readStats(path.Join(procRoot, strconv.Itoa(pid), "stat"))
+	return startTime, err
+}
+
+// setPriority sets the niceness of all the threads of a process, as on
+// Linux setpriority only sets that of the one thread.
+func setPriority(procRoot string, pid, priority int) error {
+	tasks, err := ioutil.ReadDir(filepath.Join(procRoot, strconv.Itoa(pid), "task"))
+	if err != nil {
+		return unix.Setpriority(unix.PRIO_PROCESS, pid, priority)
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		// Threads may have exited since
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, priority); err != nil && err != unix.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package process_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestControls(t *testing.T) {
+	stderr, err := ioutil.TempFile("", "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	cmd := exec.Command("/bin/sh", "-c", `trap 'echo goroutine 1 [running] >&2' QUIT; echo ready >&2; while :; do sleep 0.1; done`)
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer cmd.Process.Kill()
+	pid := cmd.Process.Pid
+	// Wait for the trap, lest SIGQUIT kill the shell
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if output, _ := ioutil.ReadFile(stderr.Name()); strings.Contains(string(output), "ready") {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("Shell didn't start")
+		}
+	}
+
+	hr := controls.NewDefaultHandlerRegistry()
+	walker := &mockWalker{processes: []process.Process{{PID: pid, Name: "sh", StartTime: startTime(t, pid)}}}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	reporter := process.NewReporter(walker, "/proc", "host", getDeltaTotalJiffies, false, controls.DummyPipeClient{}, hr)
+	defer reporter.Stop()
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeID := report.MakeProcessNodeID("host", strconv.Itoa(pid))
+	if controls := rpt.Process.Nodes[nodeID].ActiveControls(); len(controls) != 3 {
+		t.Errorf("Unexpected controls %v", controls)
+	}
+	request := func(control string, args map[string]string) xfer.Response {
+		return hr.HandleControlRequest(xfer.Request{NodeID: nodeID, Control: control, ControlArgs: args})
+	}
+
+	if resp := request(process.ReniceProcess, map[string]string{"priority": "5"}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if nice := niceness(t, pid); nice != "5" {
+		t.Errorf("Expected niceness 5, got %s", nice)
+	}
+	if resp := request(process.ReniceProcess, map[string]string{"priority": "20"}); resp.Error == "" {
+		t.Errorf("Expected an error on a bad priority")
+	}
+
+	pipe := xfer.NewPipe()
+	oldNewPipe := controls.NewPipe
+	defer func() { controls.NewPipe = oldNewPipe }()
+	controls.NewPipe = func(controls.PipeClient, string) (string, xfer.Pipe, error) {
+		return "pipe", pipe, nil
+	}
+	oldDuration := process.DumpStackDuration
+	defer func() { process.DumpStackDuration = oldDuration }()
+	process.DumpStackDuration = time.Second
+	if resp := request(process.DumpStack, nil); resp.Pipe != "pipe" {
+		t.Fatalf("Expected a pipe, got %+v", resp)
+	}
+	_, remote := pipe.Ends()
+	if output, _ := ioutil.ReadAll(remote); !strings.Contains(string(output), "goroutine 1 [running]") {
+		t.Errorf("Unexpected stack dump %q", output)
+	}
+
+	if resp := request(process.SignalProcess, map[string]string{"signal": "STOP"}); resp.Error == "" {
+		t.Errorf("Expected an error on an unsupported signal")
+	}
+	if resp := request(process.SignalProcess, map[string]string{"signal": "SIGKILL"}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected the process to be killed")
+	}
+
+	if resp := hr.HandleControlRequest(xfer.Request{
+		NodeID:  report.MakeProcessNodeID("otherhost", strconv.Itoa(pid)),
+		Control: process.SignalProcess,
+	}); resp.Error == "" {
+		t.Errorf("Expected an error on a process of another host")
+	}
+}
+
+func TestControlsOnReusedPID(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "while :; do sleep 0.1; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer cmd.Process.Kill()
+	pid := cmd.Process.Pid
+
+	// The process reported had the PID, but started earlier
+	hr := controls.NewDefaultHandlerRegistry()
+	walker := &mockWalker{processes: []process.Process{{PID: pid, Name: "sh", StartTime: startTime(t, pid) - 1}}}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	reporter := process.NewReporter(walker, "/proc", "host", getDeltaTotalJiffies, false, controls.DummyPipeClient{}, hr)
+	defer reporter.Stop()
+
+	request := func(pid int) xfer.Response {
+		return hr.HandleControlRequest(xfer.Request{
+			NodeID:      report.MakeProcessNodeID("host", strconv.Itoa(pid)),
+			Control:     process.SignalProcess,
+			ControlArgs: map[string]string{"signal": "KILL"},
+		})
+	}
+	want := "Process no longer exists: " + strconv.Itoa(pid)
+	if resp := request(pid); resp.Error != want {
+		t.Errorf("Expected %q, got %+v", want, resp)
+	}
+	// Nor is a process which wasn't walked signalled
+	walker.processes = nil
+	if resp := request(pid); resp.Error != want {
+		t.Errorf("Expected %q, got %+v", want, resp)
+	}
+	select {
+	case err := <-exited:
+		t.Errorf("Expected the process not to be signalled, but it exited: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestControlsInOtherPIDNamespace(t *testing.T) {
+	// The proc filesystem of another PID namespace, where the probe has
+	// another PID
+	procRoot, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(procRoot)
+	if err := os.Symlink(strconv.Itoa(os.Getpid()+1), filepath.Join(procRoot, "self")); err != nil {
+		t.Fatal(err)
+	}
+
+	hr := controls.NewDefaultHandlerRegistry()
+	walker := &mockWalker{processes: []process.Process{{PID: 1, Name: "init"}}}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	reporter := process.NewReporter(walker, procRoot, "host", getDeltaTotalJiffies, false, controls.DummyPipeClient{}, hr)
+	defer reporter.Stop()
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeID := report.MakeProcessNodeID("host", "1")
+	if controls, ok := rpt.Process.Nodes[nodeID].Latest.Lookup(report.NodeActiveControls); ok {
+		t.Errorf("Unexpected controls %v", controls)
+	}
+	if len(rpt.Process.Controls) != 0 {
+		t.Errorf("Unexpected controls %v", rpt.Process.Controls)
+	}
+	resp := hr.HandleControlRequest(xfer.Request{NodeID: nodeID, Control: process.SignalProcess})
+	if resp.Error == "" {
+		t.Error("Expected the signal control not to be registered")
+	}
+}
+
+// niceness reads the niceness of a process from /proc/<pid>/stat
+func niceness(t *testing.T, pid int) string {
+	return statField(t, pid, 18)
+}
+
+// startTime reads the start time of a process from /proc/<pid>/stat
+func startTime(t *testing.T, pid int) uint64 {
+	startTime, err := strconv.ParseUint(statField(t, pid, 21), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return startTime
+}
+
+// statField reads a field of /proc/<pid>/stat, counting from zero
+func statField(t *testing.T, pid, field int) string {
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fields are counted from after the command, which may contain spaces
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+2:]))
+	return fields[field-2]
+}
@@ -3,7 +3,10 @@ package process
 import (
 	"strconv"
 
+	log "github.com/sirupsen/logrus"
+
 	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/controls"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -39,20 +42,43 @@ var (
 type Reporter struct {
 	scope                  string
 	walker                 Walker
+	procRoot               string
 	jiffies                Jiffies
 	noCommandLineArguments bool
+	pipes                  controls.PipeClient
+	handlerRegistry        *controls.HandlerRegistry
 }
 
 // Jiffies is the type for the function used to fetch the elapsed jiffies.
 type Jiffies func() (uint64, float64, error)
 
-// NewReporter makes a new Reporter.
-func NewReporter(walker Walker, scope string, jiffies Jiffies, noCommandLineArguments bool) *Reporter {
-	return &Reporter{
+// NewReporter makes a new Reporter. Processes can be signalled, reniced and
+// have their stack dumped through controls when handlerRegistry is not nil,
+// and the PIDs of procRoot are those of the probe's PID namespace.
+func NewReporter(walker Walker, procRoot string, scope string, jiffies Jiffies, noCommandLineArguments bool, pipes controls.PipeClient, handlerRegistry *controls.HandlerRegistry) *Reporter {
+	if handlerRegistry != nil && !sharesPIDNamespace(procRoot) {
+		log.Warnf("Not enabling process controls: the processes in %s are not in the PID namespace of the probe", procRoot)
+		handlerRegistry = nil
+	}
+	r := &Reporter{
 		scope:                  scope,
 		walker:                 walker,
+		procRoot:               procRoot,
 		jiffies:                jiffies,
 		noCommandLineArguments: noCommandLineArguments,
+		pipes:                  pipes,
+		handlerRegistry:        handlerRegistry,
+	}
+	if handlerRegistry != nil {
+		r.registerControls()
+	}
+	return r
+}
+
+// Stop stops the reporter.
+func (r *Reporter) Stop() {
+	if r.handlerRegistry != nil {
+		r.deregisterControls()
 	}
 }
 
@@ -74,6 +100,11 @@ func (r *Reporter) processTopology() (report.Topology, error) {
 	t := report.MakeTopology().
 		WithMetadataTemplates(MetadataTemplates).
 		WithMetricTemplates(MetricTemplates)
+	if r.handlerRegistry != nil {
+		for _, c := range controlTemplates {
+			t.Controls.AddControl(c)
+		}
+	}
 	now := mtime.Now()
 	deltaTotal, maxCPU, err := r.jiffies()
 	if err != nil {
@@ -112,6 +143,9 @@ func (r *Reporter) processTopology() (report.Topology, error) {
 		}
 
 		node = node.WithMetrics(metrics)
+		if r.handlerRegistry != nil {
+			node = node.WithLatestActiveControls(SignalProcess, ReniceProcess, DumpStack)
+		}
 
 		t.AddNode(node)
 	})
@@ -36,7 +36,7 @@ func testReporter(t *testing.T, noCommandLineArguments bool, test func(report.Re
 	mtime.NowForce(now)
 	defer mtime.NowReset()
 
-	rpt, err := process.NewReporter(walker, "", getDeltaTotalJiffies, noCommandLineArguments).Report()
+	rpt, err := process.NewReporter(walker, "/proc", "", getDeltaTotalJiffies, noCommandLineArguments, nil, nil).Report()
 	if err != nil {
 		t.Error(err)
 	}
@@ -138,7 +138,7 @@ func TestCmdlineRemoval(t *testing.T) {
 func BenchmarkReporter(t *testing.B) {
 	walker := &mockWalker{processes: processes}
 	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
-	reporter := process.NewReporter(walker, "", getDeltaTotalJiffies, false)
+	reporter := process.NewReporter(walker, "/proc", "", getDeltaTotalJiffies, false, nil, nil)
 	t.ResetTimer()
 
 	for i := 0; i < t.N; i++ {
@@ -8,6 +8,7 @@ type Process struct {
 	Name              string
 	Cmdline           string
 	Threads           int
+	StartTime         uint64 // in clock ticks since boot
 	Jiffies           uint64
 	RSSBytes          uint64
 	RSSBytesLimit     uint64
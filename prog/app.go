@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -33,6 +34,7 @@ import (
 	"github.com/weaveworks/scope/common/weave"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
 )
 
 const (
@@ -57,7 +59,7 @@ func registerAppMetrics() {
 var registerAppMetricsOnce sync.Once
 
 // Router creates the mux for all the various app components.
-func router(collector app.Collector, controlRouter app.ControlRouter, pipeRouter app.PipeRouter, externalUI bool, capabilities map[string]bool, metricsGraphURL string) http.Handler {
+func router(collector app.Collector, controlRouter app.ControlRouter, pipeRouter app.PipeRouter, alerter *app.Alerter, replayer *app.Replayer, sessions *app.SessionRecorder, externalUI bool, capabilities map[string]bool, metricsGraphURL string) http.Handler {
 	router := mux.NewRouter().SkipClean(true)
 
 	// We pull in the http.DefaultServeMux to get the pprof routes
@@ -65,10 +67,19 @@ func router(collector app.Collector, controlRouter app.ControlRouter, pipeRouter
 	router.Path("/metrics").Handler(promhttp.Handler())
 
 	app.RegisterReportPostHandler(collector, router)
-	app.RegisterControlRoutes(router, controlRouter)
+	app.RegisterControlRoutes(router, controlRouter, pipeRouter, collector)
+	app.RegisterJobRoutes(router, collector, controlRouter)
 	app.RegisterPipeRoutes(router, pipeRouter)
+	app.RegisterForwardRoutes(router, pipeRouter)
 	app.RegisterTopologyRoutes(router, app.WebReporter{Reporter: collector, MetricsGraphURL: metricsGraphURL}, capabilities)
 	app.RegisterAdminRoutes(router, collector)
+	app.RegisterAlertRoutes(router, alerter)
+	if replayer != nil {
+		app.RegisterReplayRoutes(router, replayer)
+	}
+	if sessions != nil {
+		app.RegisterSessionRoutes(router, sessions)
+	}
 
 	uiHandler := http.FileServer(GetFS(externalUI))
 	router.PathPrefix("/ui").Name("static").Handler(
@@ -90,10 +101,18 @@ func router(collector app.Collector, controlRouter app.ControlRouter, pipeRouter
 }
 
 func collectorFactory(userIDer multitenant.UserIDer, collectorURL, s3URL string, tickInterval time.Duration, natsHostname string,
-	memcacheConfig multitenant.MemcacheConfig, window time.Duration, maxTopNodes int, createTables bool, collectorAddr string) (app.Collector, error) {
+	memcacheConfig multitenant.MemcacheConfig, window time.Duration, maxTopNodes int, createTables bool, collectorAddr string,
+	federateInterval time.Duration) (app.Collector, error) {
 	if collectorURL == "local" {
 		return app.NewCollector(window), nil
 	}
+	if strings.HasPrefix(collectorURL, app.FederateScheme) {
+		upstreams, err := app.ParseUpstreams(collectorURL)
+		if err != nil {
+			return nil, err
+		}
+		return app.NewFederatedCollector(upstreams, federateInterval, window), nil
+	}
 
 	var memcacheClient *multitenant.MemcacheClient
 	if memcacheConfig.Host != "" {
@@ -119,7 +138,11 @@ func collectorFactory(userIDer multitenant.UserIDer, collectorURL, s3URL string,
 
 	switch parsed.Scheme {
 	case "file":
-		return app.NewFileCollector(parsed.Path, window)
+		replayConfig, err := app.ParseReplayConfig(parsed.Query())
+		if err != nil {
+			return nil, err
+		}
+		return app.NewFileCollector(parsed.Path, window, replayConfig)
 	case "dynamodb":
 		s3, err := url.Parse(s3URL)
 		if err != nil {
@@ -260,11 +283,16 @@ func appMain(flags appFlags) {
 			Service:          flags.memcachedService,
 			CompressionLevel: flags.memcachedCompressionLevel,
 		},
-		flags.window, flags.maxTopNodes, flags.awsCreateTables, flags.collectorAddr)
+		flags.window, flags.maxTopNodes, flags.awsCreateTables, flags.collectorAddr, flags.federateInterval)
 	if err != nil {
 		log.Fatalf("Error creating collector: %v", err)
 		return
 	}
+	federation, federated := collector.(*app.FederatedCollector)
+	var replayer *app.Replayer
+	if replaying, ok := collector.(*app.ReplayingCollector); ok {
+		replayer = replaying.Replayer
+	}
 
 	if flags.BillingEmitterConfig.Enabled {
 		billingEmitter, err := emitterFactory(collector, flags.BillingClientConfig, userIDer, flags.BillingEmitterConfig)
@@ -274,6 +302,23 @@ func appMain(flags appFlags) {
 		}
 		collector = billingEmitter
 	}
+	// The probe inventory is not partitioned by user, so only keep one
+	// when running single-tenant.
+	if flags.userIDHeader == "" {
+		collector = app.NewProbeInventory(collector, flags.probeStaleAfter, flags.probeForgetAfter)
+	}
+	if flags.record.Dir != "" {
+		if flags.userIDHeader != "" {
+			log.Fatalf("Recording reports is not supported when multitenant")
+			return
+		}
+		recorder, err := report.NewRecorder(flags.record)
+		if err != nil {
+			log.Fatalf("Error recording reports: %v", err)
+			return
+		}
+		collector = app.NewRecordingCollector(collector, recorder)
+	}
 	defer collector.Close()
 
 	controlRouter, err := controlRouterFactory(userIDer, flags.controlRouterURL, flags.controlRPCTimeout)
@@ -288,6 +333,45 @@ func appMain(flags appFlags) {
 		return
 	}
 
+	if federated {
+		// Controls and pipes are served by the upstream apps
+		controlRouter = federation.ControlRouter()
+		pipeRouter.Stop()
+		pipeRouter = federation.PipeRouter()
+	}
+
+	var sessions *app.SessionRecorder
+	if flags.sessions.Dir != "" {
+		if flags.userIDHeader != "" {
+			log.Fatalf("Recording terminal sessions is not supported when multitenant")
+			return
+		}
+		sessions, err = app.NewSessionRecorder(flags.sessions)
+		if err != nil {
+			log.Fatalf("Error recording terminal sessions: %v", err)
+			return
+		}
+		defer sessions.Stop()
+		controlRouter = sessions.ControlRouter(controlRouter)
+		pipeRouter = sessions.PipeRouter(pipeRouter)
+	}
+
+	alerter, err := app.NewAlerter(app.AlerterConfig{
+		RulesFile:      flags.alertRulesFile,
+		WebhookURL:     flags.alertWebhookURL,
+		ExternalURL:    flags.alertExternalURL,
+		Interval:       flags.alertInterval,
+		ReloadInterval: flags.alertReloadInterval,
+	})
+	if err != nil {
+		log.Fatalf("Error loading alerting rules: %v", err)
+		return
+	}
+	if flags.alertRulesFile != "" {
+		alerter.Start(collector)
+		defer alerter.Stop()
+	}
+
 	// Start background version checking
 	checkpoint.CheckInterval(&checkpoint.CheckParams{
 		Product: "scope-app",
@@ -319,7 +403,7 @@ func appMain(flags appFlags) {
 		xfer.HistoricReportsCapability: collector.HasHistoricReports(),
 	}
 	logger := logging.Logrus(log.StandardLogger())
-	handler := router(collector, controlRouter, pipeRouter, flags.externalUI, capabilities, flags.metricsGraphURL)
+	handler := router(collector, controlRouter, pipeRouter, alerter, replayer, sessions, flags.externalUI, capabilities, flags.metricsGraphURL)
 	if flags.logHTTP {
 		handler = middleware.Log{
 			Log:               logger,
@@ -334,6 +418,19 @@ func appMain(flags appFlags) {
 		log.Infof("Basic authentication disabled")
 	}
 
+	var tlsConfig *tls.Config
+	if flags.tlsCertFile != "" {
+		tlsConfig, err = app.ServerTLSConfig(flags.tlsCertFile, flags.tlsKeyFile, flags.tlsClientCAFile)
+		if err != nil {
+			log.Fatalf("Error configuring TLS: %v", err)
+			return
+		}
+		if flags.tlsClientCAFile != "" {
+			log.Infof("Probe client certificates required")
+			handler = app.RequireProbeCertificates(handler)
+		}
+	}
+
 	server := &graceful.Server{
 		// we want to manage the stop condition ourselves below
 		NoSignalHandling: true,
@@ -347,7 +444,13 @@ func appMain(flags appFlags) {
 	}
 	go func() {
 		log.Infof("listening on %s", flags.listen)
-		if err := server.ListenAndServe(); err != nil {
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLSConfig(tlsConfig)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil {
 			log.Error(err)
 		}
 	}()
@@ -23,6 +23,7 @@ import (
 	"github.com/weaveworks/scope/probe/host"
 	"github.com/weaveworks/scope/probe/kubernetes"
 	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/weave/common"
 )
 
@@ -95,6 +96,7 @@ type flags struct {
 
 type probeFlags struct {
 	printOnStdout          bool
+	record                 report.RecorderConfig
 	basicAuth              bool
 	username               string
 	password               string
@@ -102,37 +104,45 @@ type probeFlags struct {
 	token                  string
 	httpListen             string
 	publishInterval        time.Duration
+	publishCompact         bool
 	ticksPerFullReport     int
 	spyInterval            time.Duration
 	pluginsRoot            string
 	insecure               bool
+	tlsCertFile            string
+	tlsKeyFile             string
+	tlsCAFile              string
 	logPrefix              string
 	logLevel               string
 	resolver               string
 	noApp                  bool
 	noControls             bool
+	maxFileCopySize        int64
 	noCommandLineArguments bool
 	noEnvironmentVariables bool
 
 	useConntrack        bool // Use conntrack for endpoint topo
 	conntrackBufferSize int  // Sie of kernel buffer for conntrack
 
-	spyProcs    bool // Associate endpoints with processes (must be root)
-	procEnabled bool // Produce process topology & process nodes in endpoint
-	useEbpfConn bool // Enable connection tracking with eBPF
-	procRoot    string
+	spyProcs        bool // Associate endpoints with processes (must be root)
+	procEnabled     bool // Produce process topology & process nodes in endpoint
+	processControls bool // Signal, renice and dump the stack of processes
+	useEbpfConn     bool // Enable connection tracking with eBPF
+	procRoot        string
 
-	dockerEnabled  bool
-	dockerInterval time.Duration
-	dockerBridge   string
+	dockerEnabled               bool
+	dockerInterval              time.Duration
+	dockerBridge                string
+	dockerRegistryCheckInterval time.Duration
 
 	criEnabled  bool
 	criEndpoint string
 
-	kubernetesEnabled      bool
-	kubernetesRole         string
-	kubernetesNodeName     string
-	kubernetesClientConfig kubernetes.ClientConfig
+	kubernetesEnabled             bool
+	kubernetesRole                string
+	kubernetesNodeName            string
+	kubernetesClientConfig        kubernetes.ClientConfig
+	kubernetesCustomResourcesFile string
 
 	ecsEnabled       bool
 	ecsCacheSize     int
@@ -159,6 +169,10 @@ type appFlags struct {
 	password         string
 	passwordFilename string
 
+	tlsCertFile     string
+	tlsKeyFile      string
+	tlsClientCAFile string
+
 	weaveEnabled   bool
 	weaveAddr      string
 	weaveHostname  string
@@ -170,6 +184,7 @@ type appFlags struct {
 	s3URL                     string
 	storeInterval             time.Duration
 	tickInterval              time.Duration
+	federateInterval          time.Duration
 	controlRouterURL          string
 	controlRPCTimeout         time.Duration
 	pipeRouterURL             string
@@ -186,6 +201,18 @@ type appFlags struct {
 
 	blockProfileRate int
 
+	alertRulesFile      string
+	alertWebhookURL     string
+	alertExternalURL    string
+	alertInterval       time.Duration
+	alertReloadInterval time.Duration
+
+	record   report.RecorderConfig
+	sessions app.SessionRecorderConfig
+
+	probeStaleAfter  time.Duration
+	probeForgetAfter time.Duration
+
 	awsCreateTables bool
 	consulInf       string
 
@@ -275,6 +302,15 @@ func makeBaseCheckpointFlags() map[string]string {
 	}
 }
 
+// registerRecordFlags registers the flags for recording reports, which are
+// the same for the probe and the app.
+func registerRecordFlags(config *report.RecorderConfig, prefix, what string) {
+	flag.StringVar(&config.Dir, prefix+".record-dir", "", fmt.Sprintf("Directory to record every %s report to, for replaying with --app.collector=file://<dir> (recording disabled if blank)", what))
+	flag.Int64Var(&config.MaxSegmentSize, prefix+".record.segment-size", 100<<20, "Bytes of recorded reports after which to start a new segment (0 for no limit)")
+	flag.DurationVar(&config.MaxSegmentAge, prefix+".record.segment-age", time.Hour, "Age after which to start a new segment of recorded reports (0 for no limit)")
+	flag.IntVar(&config.MaxSegments, prefix+".record.segments", 24, "Number of segments of recorded reports to keep (0 keeps them all)")
+}
+
 func setupFlags(flags *flags) {
 	flags.containerLabelFilterFlags = containerLabelFiltersFlag{exclude: false, filterIDPrefix: "containerLabelFilterExclude"}
 	flags.containerLabelFilterFlagsExclude = containerLabelFiltersFlag{exclude: true, filterIDPrefix: "containerLabelFilter"}
@@ -295,6 +331,7 @@ func setupFlags(flags *flags) {
 
 	// Probe flags
 	flag.BoolVar(&flags.probe.printOnStdout, "probe.publish.stdout", false, "Print reports on stdout instead of sending to app, for debugging")
+	registerRecordFlags(&flags.probe.record, "probe", "published")
 	flag.BoolVar(&flags.probe.basicAuth, "probe.basicAuth", false, "Use basic authentication to authenticate with app")
 	flag.StringVar(&flags.probe.username, "probe.basicAuth.username", "admin", "Username for basic authentication")
 	flag.StringVar(&flags.probe.password, "probe.basicAuth.password", "admin", "Password for basic authentication")
@@ -303,14 +340,19 @@ func setupFlags(flags *flags) {
 	flag.StringVar(&flags.probe.token, probeTokenFlag, "", "Token to authenticate with cloud.weave.works")
 	flag.StringVar(&flags.probe.httpListen, "probe.http.listen", "", "listen address for HTTP profiling and instrumentation server")
 	flag.DurationVar(&flags.probe.publishInterval, "probe.publish.interval", 3*time.Second, "publish (output) interval")
+	flag.BoolVar(&flags.probe.publishCompact, "probe.publish.compact", true, "publish reports in the compact encoding to apps which support it")
 	flag.DurationVar(&flags.probe.spyInterval, "probe.spy.interval", time.Second, "spy (scan) interval")
 	flag.IntVar(&flags.probe.ticksPerFullReport, "probe.full-report-every", 3, "publish full report every N times, deltas in between. Make sure N < (app.window / probe.publish.interval)")
 	flag.StringVar(&flags.probe.pluginsRoot, "probe.plugins.root", "/var/run/scope/plugins", "Root directory to search for plugins (disable plugins if blank)")
 	flag.BoolVar(&flags.probe.noControls, "probe.no-controls", false, "Disable controls (e.g. start/stop containers, terminals, logs ...)")
+	flag.Int64Var(&flags.probe.maxFileCopySize, "probe.files.max-size", 100<<20, "Most bytes copied by the controls downloading and uploading files")
 	flag.BoolVar(&flags.probe.noCommandLineArguments, "probe.omit.cmd-args", false, "Disable collection of command-line arguments")
 	flag.BoolVar(&flags.probe.noEnvironmentVariables, "probe.omit.env-vars", true, "Disable collection of environment variables")
 
 	flag.BoolVar(&flags.probe.insecure, "probe.insecure", false, "(SSL) explicitly allow \"insecure\" SSL connections and transfers")
+	flag.StringVar(&flags.probe.tlsCertFile, "probe.tls.cert-file", "", "(SSL) client certificate presented to the app, for mutual TLS")
+	flag.StringVar(&flags.probe.tlsKeyFile, "probe.tls.key-file", "", "(SSL) private key of the client certificate")
+	flag.StringVar(&flags.probe.tlsCAFile, "probe.tls.ca-file", "", "(SSL) CA certificates to verify the app with, instead of the well-known ones")
 	flag.StringVar(&flags.probe.resolver, "probe.resolver", "", "IP address & port of resolver to use.  Default is to use system resolver.")
 	flag.StringVar(&flags.probe.logPrefix, "probe.log.prefix", "<probe>", "prefix for each log line")
 	flag.StringVar(&flags.probe.logLevel, "probe.log.level", "info", "logging threshold level: debug|info|warn|error|fatal|panic")
@@ -321,12 +363,14 @@ func setupFlags(flags *flags) {
 	flag.BoolVar(&flags.probe.spyProcs, "probe.proc.spy", true, "associate endpoints with processes (needs root)")
 	flag.StringVar(&flags.probe.procRoot, "probe.proc.root", "/proc", "location of the proc filesystem")
 	flag.BoolVar(&flags.probe.procEnabled, "probe.processes", true, "produce process topology & include procspied connections")
+	flag.BoolVar(&flags.probe.processControls, "probe.processes.controls", false, "enable controls signalling, renicing and dumping the stack of processes")
 	flag.BoolVar(&flags.probe.useEbpfConn, "probe.ebpf.connections", true, "enable connection tracking with eBPF")
 
 	// Docker
 	flag.BoolVar(&flags.probe.dockerEnabled, "probe.docker", false, "collect Docker-related attributes for processes")
 	flag.DurationVar(&flags.probe.dockerInterval, "probe.docker.interval", 10*time.Second, "how often to update Docker attributes")
 	flag.StringVar(&flags.probe.dockerBridge, "probe.docker.bridge", "docker0", "the docker bridge name")
+	flag.DurationVar(&flags.probe.dockerRegistryCheckInterval, "probe.docker.registry-check-interval", 0, "how often to check the registry for newer images of running containers (0 to disable)")
 
 	// CRI
 	flag.BoolVar(&flags.probe.criEnabled, "probe.cri", false, "collect CRI-related attributes for processes")
@@ -349,6 +393,7 @@ func setupFlags(flags *flags) {
 	flag.StringVar(&flags.probe.kubernetesClientConfig.User, "probe.kubernetes.user", "", "The name of the kubeconfig user to use")
 	flag.StringVar(&flags.probe.kubernetesClientConfig.Username, "probe.kubernetes.username", "", "Username for basic authentication to the API server")
 	flag.StringVar(&flags.probe.kubernetesNodeName, "probe.kubernetes.node-name", "", "Name of this node, for filtering pods")
+	flag.StringVar(&flags.probe.kubernetesCustomResourcesFile, "probe.kubernetes.custom-resources", "", "YAML file listing the kinds of custom resources to report")
 
 	// AWS ECS
 	flag.BoolVar(&flags.probe.ecsEnabled, "probe.ecs", false, "Collect ecs-related attributes for containers on this node")
@@ -375,6 +420,10 @@ func setupFlags(flags *flags) {
 	flag.StringVar(&flags.app.password, "app.basicAuth.password", "admin", "Password for basic authentication")
 	flag.StringVar(&flags.app.passwordFilename, "app.basicAuth.password.filename", "", "Password filename for basic authentication. It overwrites app.basicAuth.password")
 
+	flag.StringVar(&flags.app.tlsCertFile, "app.tls.cert-file", "", "Serve HTTPS with this certificate (plain HTTP if blank)")
+	flag.StringVar(&flags.app.tlsKeyFile, "app.tls.key-file", "", "Private key of the HTTPS certificate")
+	flag.StringVar(&flags.app.tlsClientCAFile, "app.tls.client-ca-file", "", "CA certificates to verify client certificates with. If set, probes must present a certificate signed by them, and are identified by its common name")
+
 	flag.StringVar(&flags.app.weaveAddr, "app.weave.addr", app.DefaultWeaveURL, "Address on which to contact WeaveDNS")
 	flag.StringVar(&flags.app.weaveHostname, "app.weave.hostname", "", "Hostname to advertise in WeaveDNS")
 	flag.StringVar(&flags.app.containerName, "app.container.name", app.DefaultContainerName, "Name of this container (to lookup container ID)")
@@ -382,7 +431,8 @@ func setupFlags(flags *flags) {
 	flag.Var(&flags.containerLabelFilterFlags, "app.container-label-filter", "Add container label-based view filter, specified as title:label. Multiple flags are accepted. Example: --app.container-label-filter='Database Containers:role=db'")
 	flag.Var(&flags.containerLabelFilterFlagsExclude, "app.container-label-filter-exclude", "Add container label-based view filter that excludes containers with the given label, specified as title:label. Multiple flags are accepted. Example: --app.container-label-filter-exclude='Database Containers:role=db'")
 
-	flag.StringVar(&flags.app.collectorURL, "app.collector", "local", "Collector to use (local, multitenant-live, dynamodb, or file/directory)")
+	flag.StringVar(&flags.app.collectorURL, "app.collector", "local", "Collector to use (local, multitenant-live, dynamodb, file/directory, or federate://cluster=http://app:4040,... to pull reports from other apps)")
+	flag.DurationVar(&flags.app.federateInterval, "app.collector.federate-interval", 3*time.Second, "How often to pull reports from upstream apps, for the federated collector")
 	flag.StringVar(&flags.app.collectorAddr, "app.collector-addr", "", "Address to look up collectors when deployed as microservices")
 	flag.StringVar(&flags.app.s3URL, "app.collector.s3", "local", "S3 URL to use (when collector is dynamodb)")
 	flag.DurationVar(&flags.app.storeInterval, "app.collector.store-interval", 0, "How often to store merged incoming reports.")
@@ -403,6 +453,18 @@ func setupFlags(flags *flags) {
 
 	flag.IntVar(&flags.app.blockProfileRate, "app.block.profile.rate", 0, "If more than 0, enable block profiling. The profiler aims to sample an average of one blocking event per rate nanoseconds spent blocked.")
 
+	flag.StringVar(&flags.app.alertRulesFile, "app.alerts.rules", "", "YAML file with alerting rules to evaluate against each report (alerting disabled if blank)")
+	flag.StringVar(&flags.app.alertWebhookURL, "app.alerts.webhook", "", "URL to post firing and resolved alerts to, in Alertmanager webhook format")
+	flag.StringVar(&flags.app.alertExternalURL, "app.alerts.external-url", "", "URL under which this app is reachable, used to link alerts back to Scope")
+	flag.DurationVar(&flags.app.alertInterval, "app.alerts.interval", 5*time.Second, "How often to evaluate the alerting rules")
+	flag.DurationVar(&flags.app.alertReloadInterval, "app.alerts.reload-interval", 30*time.Second, "How often to check the alerting rules file for changes")
+	registerRecordFlags(&flags.app.record, "app", "received")
+	flag.StringVar(&flags.app.sessions.Dir, "app.sessions.record-dir", "", "Directory to record the terminal sessions of exec and attach controls to, served on /api/sessions (recording disabled if blank)")
+	flag.DurationVar(&flags.app.sessions.Retention, "app.sessions.retention", 30*24*time.Hour, "How long to keep recorded terminal sessions (0 keeps them forever)")
+	flag.StringVar(&flags.app.sessions.UserHeader, "app.sessions.user-header", "", "HTTP header naming the user to record against terminal sessions, e.g. as set by an authenticating proxy (defaults to the basic authentication user)")
+	flag.DurationVar(&flags.app.probeStaleAfter, "app.probes.stale-after", 15*time.Second, "Consider probes stale when they haven't reported for this long")
+	flag.DurationVar(&flags.app.probeForgetAfter, "app.probes.forget-after", 1*time.Hour, "Forget about stale probes after this long")
+
 	flag.BoolVar(&flags.app.awsCreateTables, "app.aws.create.tables", false, "Create the tables in DynamoDB")
 	flag.StringVar(&flags.app.consulInf, "app.consul.inf", "", "The interface who's address I should advertise myself under in consul")
 }
@@ -511,6 +573,8 @@ func main() {
 		appMain(flags.app)
 	case "probe":
 		probeMain(flags.probe, targets)
+	case "report":
+		reportMain(flag.Args())
 	case "version":
 		fmt.Println("Weave Scope version", version)
 	case "help":
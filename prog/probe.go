@@ -158,10 +158,22 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 		hostName = hostname.Get()
 		hostID   = hostName // TODO(pb): we should sanitize the hostname
 	)
+	if flags.tlsCertFile != "" {
+		// Apps verifying client certificates identify probes by them, so
+		// use the same ID in reports.
+		cn, err := appclient.ProbeConfig{TLSCertFile: flags.tlsCertFile, TLSKeyFile: flags.tlsKeyFile}.CertificateIdentity()
+		if err != nil {
+			log.Fatalf("Error reading client certificate: %v", err)
+		}
+		if cn != "" {
+			probeID = cn
+		}
+	}
 	log.Infof("probe starting, version %s, ID %s", version, probeID)
 	checkNewScopeVersion(flags)
 
 	handlerRegistry := controls.NewDefaultHandlerRegistry()
+	controls.MaxFileCopySize = flags.maxFileCopySize
 	clientFactory := func(hostname string, url url.URL) (appclient.AppClient, error) {
 		token := flags.token
 		if url.User != nil {
@@ -179,6 +191,11 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 			ProbeVersion: version,
 			ProbeID:      probeID,
 			Insecure:     flags.insecure,
+			TLSCertFile:  flags.tlsCertFile,
+			TLSKeyFile:   flags.tlsKeyFile,
+			TLSCAFile:    flags.tlsCAFile,
+
+			CompactReports: flags.publishCompact,
 		}
 		return appclient.NewAppClient(
 			probeConfig, hostname, url,
@@ -242,13 +259,28 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 		}
 		clients = multiClients
 	}
+	if flags.record.Dir != "" {
+		recorder, err := report.NewRecorder(flags.record)
+		if err != nil {
+			log.Fatalf("Failed to record reports: %v", err)
+			return
+		}
+		clients = struct {
+			probe.ReportPublisher
+			controls.PipeClient
+		}{recordingPublisher{clients, recorder}, clients}
+	}
 
 	p := probe.New(flags.spyInterval, flags.publishInterval, clients, flags.ticksPerFullReport, flags.noControls)
+	p.SetIdentity(probeID, hostName, version)
 	p.AddTagger(probe.NewTopologyTagger())
+	jobs := controls.NewJobs(probeID, handlerRegistry, p)
+	defer jobs.Stop()
+	p.AddReporter(jobs)
 	var processCache *process.CachingWalker
 
 	if flags.kubernetesRole != kubernetesRoleCluster {
-		hostReporter := host.NewReporter(hostID, hostName, probeID, version, clients, handlerRegistry)
+		hostReporter := host.NewReporter(hostID, hostName, probeID, version, clients, handlerRegistry, jobs)
 		defer hostReporter.Stop()
 		p.AddReporter(hostReporter)
 		p.AddTagger(host.NewTagger(hostID))
@@ -256,7 +288,13 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 		if flags.procEnabled {
 			processCache = process.NewCachingWalker(process.NewWalker(flags.procRoot, false))
 			p.AddTicker(processCache)
-			p.AddReporter(process.NewReporter(processCache, hostID, process.GetDeltaTotalJiffies, flags.noCommandLineArguments))
+			var processControls *controls.HandlerRegistry
+			if flags.processControls {
+				processControls = handlerRegistry
+			}
+			processReporter := process.NewReporter(processCache, flags.procRoot, hostID, process.GetDeltaTotalJiffies, flags.noCommandLineArguments, clients, processControls)
+			defer processReporter.Stop()
+			p.AddReporter(processReporter)
 		}
 
 		dnsSnooper, err := endpoint.NewDNSSnooper()
@@ -298,6 +336,8 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 			HandlerRegistry:        handlerRegistry,
 			NoCommandLineArguments: flags.noCommandLineArguments,
 			NoEnvironmentVariables: flags.noEnvironmentVariables,
+			Jobs:                   jobs,
+			RegistryCheckInterval:  flags.dockerRegistryCheckInterval,
 		}
 		if registry, err := docker.NewRegistry(options); err == nil {
 			defer registry.Stop()
@@ -320,9 +360,16 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 	}
 
 	if flags.kubernetesEnabled && flags.kubernetesRole != kubernetesRoleHost {
+		if flags.kubernetesCustomResourcesFile != "" {
+			customResources, err := kubernetes.LoadCustomResourceConfigs(flags.kubernetesCustomResourcesFile)
+			if err != nil {
+				log.Fatalf("Error loading custom resources: %v", err)
+			}
+			flags.kubernetesClientConfig.CustomResources = customResources
+		}
 		if client, err := kubernetes.NewClient(flags.kubernetesClientConfig); err == nil {
 			defer client.Stop()
-			reporter := kubernetes.NewReporter(client, clients, probeID, hostID, p, handlerRegistry, flags.kubernetesNodeName)
+			reporter := kubernetes.NewReporter(client, clients, probeID, hostID, p, handlerRegistry, jobs, flags.kubernetesNodeName)
 			defer reporter.Stop()
 			p.AddReporter(reporter)
 			if flags.kubernetesRole != kubernetesRoleCluster && flags.kubernetesNodeName == "" {
@@ -384,3 +431,16 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 		p,
 	)
 }
+
+// recordingPublisher records every report before publishing it.
+type recordingPublisher struct {
+	probe.ReportPublisher
+	recorder *report.Recorder
+}
+
+func (p recordingPublisher) Publish(rpt report.Report) error {
+	if err := p.recorder.Publish(rpt); err != nil {
+		log.Errorf("Error recording report: %v", err)
+	}
+	return p.ReportPublisher.Publish(rpt)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// reportCommand is a subcommand of 'scope report', working on report files.
+type reportCommand struct {
+	usage string
+	help  string
+	run   func(flags *flag.FlagSet, args []string) error
+}
+
+var reportCommands = map[string]reportCommand{
+	"anonymize": {
+		usage: "anonymize [-secret <secret>] <in> <out>",
+		help:  "Replace addresses, hostnames, names, labels and images with pseudonyms",
+		run:   anonymizeReport,
+	},
+	"inspect": {
+		usage: "inspect [-top <n>] <in>",
+		help:  "Print node counts per topology, and the largest nodes",
+		run:   inspectReport,
+	},
+	"validate": {
+		usage: "validate <in>",
+		help:  "Check the report for inconsistencies, and explain them",
+		run:   validateReport,
+	},
+	"stats": {
+		usage: "stats [-top <n>] <in>",
+		help:  "Print the encoded size of each topology and of each Latest key",
+		run:   reportStats,
+	},
+	"convert": {
+		usage: "convert <in> <out>",
+		help:  "Re-encode a report, e.g. from .msgpack.gz to .json",
+		run:   convertReport,
+	},
+	"merge": {
+		usage: "merge <in>... <out>",
+		help:  "Merge several reports into one",
+		run:   mergeReports,
+	},
+	"filter": {
+		usage: "filter [-host <host ID>] [-namespace <namespace>] <in> <out>",
+		help:  "Keep only the nodes of a host or namespace, and the nodes they refer to",
+		run:   filterReport,
+	},
+	"render": {
+		usage: "render <in> <topology> [<option>=<value>...]",
+		help:  "Render a topology, e.g. containers or pods, with the given options, and print node summaries",
+		run:   renderReport,
+	},
+}
+
+func reportUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: scope report <command> [<args>]\n\nCommands:\n")
+	for _, name := range sortedReportCommands() {
+		cmd := reportCommands[name]
+		fmt.Fprintf(os.Stderr, "  %s\n        %s\n", cmd.usage, cmd.help)
+	}
+	fmt.Fprintf(os.Stderr, "\nReport files are encoded according to their extension: .json or .msgpack, with an optional .gz\n")
+}
+
+func sortedReportCommands() []string {
+	names := make([]string, 0, len(reportCommands))
+	for name := range reportCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reportMain runs 'scope report', a set of tools to work on reports saved to
+// files, e.g. from /api/report.
+func reportMain(args []string) {
+	if len(args) == 0 {
+		reportUsage()
+		os.Exit(1)
+	}
+	cmd, ok := reportCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "report command '%s' not recognized\n\n", args[0])
+		reportUsage()
+		os.Exit(1)
+	}
+	flags := flag.NewFlagSet("report "+args[0], flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: scope report %s\n", cmd.usage)
+		flags.PrintDefaults()
+	}
+	if err := cmd.run(flags, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func readReport(path string) (report.Report, error) {
+	rpt, err := report.MakeFromFile(context.Background(), path)
+	if err != nil {
+		return report.Report{}, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return *rpt, nil
+}
+
+func parseReportArgs(flags *flag.FlagSet, args []string, n int) {
+	flags.Parse(args)
+	if flags.NArg() != n {
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// encodedSize is the size of v encoded as uncompressed msgpack, as sent by
+// probes before compression.
+func encodedSize(v interface{}) int {
+	var buf []byte
+	if err := codec.NewEncoderBytes(&buf, &codec.MsgpackHandle{}).Encode(v); err != nil {
+		return 0
+	}
+	return len(buf)
+}
+
+type sizeEntry struct {
+	name  string
+	count int
+	size  int
+}
+
+func sortBySize(entries []sizeEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].size != entries[j].size {
+			return entries[i].size > entries[j].size
+		}
+		return entries[i].name < entries[j].name
+	})
+}
+
+func inspectReport(flags *flag.FlagSet, args []string) error {
+	top := flags.Int("top", 10, "Number of largest nodes to print")
+	parseReportArgs(flags, args, 1)
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\t%s\n", rpt.ID)
+	fmt.Fprintf(w, "Window\t%v\n", rpt.Window)
+	fmt.Fprintf(w, "Plugins\t%d\n", len(rpt.Plugins.Keys()))
+	fmt.Fprintf(w, "DNS records\t%d\n", len(rpt.DNS))
+	fmt.Fprintf(w, "\nTOPOLOGY\tNODES\n")
+	var nodes []sizeEntry
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		if len(t.Nodes) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s\t%d\n", name, len(t.Nodes))
+		for id, n := range t.Nodes {
+			n := n
+			nodes = append(nodes, sizeEntry{name: name + " " + id, size: encodedSize(&n)})
+		}
+	})
+	sortBySize(nodes)
+	if len(nodes) > *top {
+		nodes = nodes[:*top]
+	}
+	fmt.Fprintf(w, "\nLARGEST NODES\tBYTES\n")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s\t%d\n", n.name, n.size)
+	}
+	return w.Flush()
+}
+
+// validationHints explain the errors found by Topology.ValidationErrors.
+var validationHints = map[string]string{
+	"invalid node ID":             "node IDs must contain a ';', and are made with the report.Make*NodeID functions",
+	"node missing from adjacency": "edges must point to nodes of the same topology; the probe reporting the edge should add its destination node",
+}
+
+func validateReport(flags *flag.FlagSet, args []string) error {
+	parseReportArgs(flags, args, 1)
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if rpt.Validate() == nil {
+		fmt.Println("report is valid")
+		return nil
+	}
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		for _, e := range t.ValidationErrors() {
+			fmt.Printf("%s: %s\n", name, e)
+			for prefix, hint := range validationHints {
+				if strings.HasPrefix(e, prefix) {
+					fmt.Printf("    %s\n", hint)
+				}
+			}
+		}
+	})
+	if rpt.Sampling.Count > rpt.Sampling.Total {
+		fmt.Printf("sampling count (%d) bigger than total (%d)\n", rpt.Sampling.Count, rpt.Sampling.Total)
+		fmt.Printf("    the probe can't have sampled more than what it saw\n")
+	}
+	return fmt.Errorf("report is invalid")
+}
+
+func reportStats(flags *flag.FlagSet, args []string) error {
+	top := flags.Int("top", 20, "Number of Latest keys to print")
+	parseReportArgs(flags, args, 1)
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var (
+		topologies []sizeEntry
+		keys       = map[string]*sizeEntry{}
+		total      = encodedSize(&rpt)
+	)
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		if len(t.Nodes) == 0 {
+			return
+		}
+		topologies = append(topologies, sizeEntry{name: name, count: len(t.Nodes), size: encodedSize(t)})
+		for _, n := range t.Nodes {
+			n.Latest.ForEach(func(key string, _ time.Time, value string) {
+				e, ok := keys[key]
+				if !ok {
+					e = &sizeEntry{name: key}
+					keys[key] = e
+				}
+				e.count++
+				// key, value and timestamp
+				e.size += len(key) + len(value) + 8
+			})
+		}
+	})
+	sortBySize(topologies)
+	latest := make([]sizeEntry, 0, len(keys))
+	for _, e := range keys {
+		latest = append(latest, *e)
+	}
+	sortBySize(latest)
+	if len(latest) > *top {
+		latest = latest[:*top]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "TOPOLOGY\tNODES\tBYTES\t%%\n")
+	for _, t := range topologies {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\n", t.name, t.count, t.size, percent(t.size, total))
+	}
+	fmt.Fprintf(w, "total\t\t%d\t\n", total)
+	fmt.Fprintf(w, "\nLATEST KEY\tENTRIES\tBYTES (approx.)\t%%\n")
+	for _, e := range latest {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\n", e.name, e.count, e.size, percent(e.size, total))
+	}
+	return w.Flush()
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+func convertReport(flags *flag.FlagSet, args []string) error {
+	parseReportArgs(flags, args, 2)
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return rpt.WriteToFile(flags.Arg(1))
+}
+
+func mergeReports(flags *flag.FlagSet, args []string) error {
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	paths := flags.Args()
+	reports := make([]report.Report, 0, len(paths)-1)
+	for _, path := range paths[:len(paths)-1] {
+		rpt, err := readReport(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, rpt)
+	}
+	merged := app.NewFastMerger().Merge(reports)
+	return merged.WriteToFile(paths[len(paths)-1])
+}
+
+func filterReport(flags *flag.FlagSet, args []string) error {
+	var (
+		host      = flags.String("host", "", "Keep the nodes reported by this host ID")
+		namespace = flags.String("namespace", "", "Keep the Kubernetes or Swarm objects in this namespace, and their containers")
+	)
+	parseReportArgs(flags, args, 2)
+	if *host == "" && *namespace == "" {
+		return fmt.Errorf("one of -host or -namespace is required")
+	}
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var keep []render.FilterFunc
+	if *host != "" {
+		hostNodeID := report.MakeHostNodeID(*host)
+		keep = append(keep, func(n report.Node) bool {
+			id, _ := n.Latest.Lookup(report.HostNodeID)
+			return n.ID == hostNodeID || id == hostNodeID
+		})
+	}
+	if *namespace != "" {
+		inNamespace := render.IsNamespace(*namespace)
+		keep = append(keep, func(n report.Node) bool {
+			if n.Topology == report.Namespace {
+				name, _ := n.Latest.Lookup(report.KubernetesName)
+				return name == *namespace
+			}
+			return inNamespace(n)
+		})
+	}
+	filter := render.ComposeFilterFuncs(keep...)
+	filtered := rpt.Filter(func(topology string, n report.Node) bool {
+		n.Topology = topology
+		return filter(n)
+	})
+	if *host != "" {
+		for id, probe := range filtered.Probes {
+			if probe.Hostname != *host {
+				delete(filtered.Probes, id)
+			}
+		}
+	}
+	return filtered.WriteToFile(flags.Arg(1))
+}
+
+func renderReport(flags *flag.FlagSet, args []string) error {
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	for _, option := range flags.Args()[2:] {
+		key, value := option, ""
+		if i := strings.Index(option, "="); i >= 0 {
+			key, value = option[:i], option[i+1:]
+		}
+		values.Add(key, value)
+	}
+
+	ctx := context.Background()
+	renderer, transformer, err := app.MakeRegistry().RendererForTopology(flags.Arg(1), values, rpt)
+	if err != nil {
+		return err
+	}
+	nodes := render.Render(ctx, rpt, renderer, transformer)
+	summaries := detailed.Summaries(ctx, detailed.RenderContext{Report: rpt}, nodes.Nodes)
+	ids := make([]string, 0, len(summaries))
+	for id := range summaries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := summaries[ids[i]], summaries[ids[j]]
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.ID < b.ID
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "LABEL\tMINOR LABEL\tRANK\tEDGES\tID\n")
+	for _, id := range ids {
+		s := summaries[id]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", s.Label, s.LabelMinor, s.Rank, len(s.Adjacency), s.ID)
+	}
+	fmt.Fprintf(w, "\n%d nodes, %d filtered\n", len(summaries), nodes.Filtered)
+	return w.Flush()
+}
+
+func anonymizeReport(flags *flag.FlagSet, args []string) error {
+	secret := flags.String("secret", "", "Secret to derive pseudonyms from, to get the same pseudonyms across reports (default random)")
+	parseReportArgs(flags, args, 2)
+	rpt, err := readReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	anonymized := report.NewAnonymizer([]byte(*secret), render.IsWellKnownName).Anonymize(rpt)
+	return anonymized.WriteToFile(flags.Arg(1))
+}
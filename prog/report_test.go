@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func runReportCommand(name string, args ...string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	return reportCommands[name].run(flags, args)
+}
+
+func TestReportCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scope-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	if err := fixture.Report.WriteToFile(path("fixture.msgpack.gz")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range [][]string{
+		{"inspect", path("fixture.msgpack.gz")},
+		{"validate", path("fixture.msgpack.gz")},
+		{"stats", path("fixture.msgpack.gz")},
+		{"render", path("fixture.msgpack.gz"), "containers", "system=all"},
+		{"convert", path("fixture.msgpack.gz"), path("fixture.json")},
+		{"merge", path("fixture.msgpack.gz"), path("fixture.json"), path("merged.json")},
+		{"filter", "-host", fixture.ClientHostID, path("fixture.json"), path("client.json")},
+		{"anonymize", path("fixture.json"), path("anonymized.json")},
+	} {
+		assert.NoError(t, runReportCommand(cmd[0], cmd[1:]...), "%v", cmd)
+	}
+
+	merged, err := report.MakeFromFile(context.Background(), path("merged.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(fixture.Report.Container.Nodes), len(merged.Container.Nodes))
+
+	client, err := report.MakeFromFile(context.Background(), path("client.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, client.Validate())
+	assert.Equal(t, 1, len(client.Host.Nodes))
+	assert.Contains(t, client.Host.Nodes, fixture.ClientHostNodeID)
+	assert.NotContains(t, client.Container.Nodes, fixture.ServerContainerNodeID)
+
+	assert.Error(t, runReportCommand("render", path("fixture.json"), "no-such-topology"))
+}
@@ -156,11 +156,14 @@ func (r containerWithImageNameRenderer) Render(ctx context.Context, rpt report.R
 var ContainerWithImageNameRenderer = Memoise(containerWithImageNameRenderer{})
 
 // ContainerImageRenderer produces a graph where each node is a container image
-// with the original containers as children
-var ContainerImageRenderer = Memoise(FilterEmpty(report.Container,
-	MakeMap(
-		MapContainerImage2Name,
-		containerImageRenderer{},
+// with the original containers as children, and the network and block I/O
+// metrics of the containers summed
+var ContainerImageRenderer = Memoise(PropagateSummedMetrics(report.Container, ContainerIOMetrics,
+	FilterEmpty(report.Container,
+		MakeMap(
+			MapContainerImage2Name,
+			containerImageRenderer{},
+		),
 	),
 ))
 
@@ -0,0 +1,112 @@
+package detailed
+
+import (
+	"strings"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+)
+
+// GroupControlPrefix prefixes the IDs of group controls.
+const GroupControlPrefix = "group_"
+
+// GroupControl is a control on a group node, such as a container image,
+// which runs ChildControl on all of the group's children in ChildTopology
+// having it. Group controls are run by the app, not a probe, so their
+// instances carry the API topology of the group node in place of a probe
+// ID.
+type GroupControl struct {
+	report.Control
+	ChildTopology string
+	ChildControl  string
+}
+
+var (
+	restartContainers = GroupControl{
+		Control: report.Control{
+			ID:           GroupControlPrefix + docker.RestartContainer,
+			Human:        "Restart all",
+			Icon:         "fa fa-redo",
+			Confirmation: "Are you sure you want to restart all of these containers?",
+			Rank:         20,
+		},
+		ChildTopology: report.Container,
+		ChildControl:  docker.RestartContainer,
+	}
+	stopContainers = GroupControl{
+		Control: report.Control{
+			ID:           GroupControlPrefix + docker.StopContainer,
+			Human:        "Stop all",
+			Icon:         "fa fa-stop",
+			Confirmation: "Are you sure you want to stop all of these containers?",
+			Rank:         21,
+		},
+		ChildTopology: report.Container,
+		ChildControl:  docker.StopContainer,
+	}
+	// groupControls are the group controls of each group topology.
+	groupControls = map[string][]GroupControl{
+		report.ContainerImage: {restartContainers, stopContainers},
+		render.MakeGroupNodeTopology(report.Container, report.DockerContainerHostname): {restartContainers, stopContainers},
+		report.SwarmService: {restartContainers, stopContainers},
+	}
+)
+
+// LookupGroupControl returns the group control with the given ID.
+func LookupGroupControl(id string) (GroupControl, bool) {
+	if !strings.HasPrefix(id, GroupControlPrefix) {
+		return GroupControl{}, false
+	}
+	for _, gcs := range groupControls {
+		for _, gc := range gcs {
+			if gc.ID == id {
+				return gc, true
+			}
+		}
+	}
+	return GroupControl{}, false
+}
+
+// GroupControlTargets returns the IDs of the children of the rendered group
+// node n on which gc's child control is active.
+func GroupControlTargets(r report.Report, n report.Node, gc GroupControl) []string {
+	t, ok := r.Topology(gc.ChildTopology)
+	if !ok {
+		return nil
+	}
+	result := []string{}
+	n.Children.ForEach(func(child report.Node) {
+		if child.Topology != gc.ChildTopology {
+			return
+		}
+		node, ok := t.Nodes[child.ID]
+		if !ok {
+			return
+		}
+		for _, id := range node.ActiveControls() {
+			if id == gc.ChildControl {
+				result = append(result, child.ID)
+				return
+			}
+		}
+	})
+	return result
+}
+
+// groupControlsFor returns instances of the group controls of n which
+// have at least one target.
+func groupControlsFor(topologyID string, r report.Report, n report.Node) []ControlInstance {
+	result := []ControlInstance{}
+	for _, gc := range groupControls[n.Topology] {
+		if len(GroupControlTargets(r, n, gc)) == 0 {
+			continue
+		}
+		result = append(result, ControlInstance{
+			ProbeID: topologyID,
+			NodeID:  n.ID,
+			Control: gc.Control,
+		})
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package detailed_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestGroupControls(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("img1"), map[string]string{
+		report.DockerImageID:   "img1",
+		report.DockerImageName: "nginx:latest",
+	}).WithTopology(report.ContainerImage))
+	for id, controls := range map[string][]string{
+		"c1": {docker.RestartContainer},
+		"c2": {docker.RestartContainer, docker.StopContainer},
+		"c3": {},
+	} {
+		rpt.Container.AddNode(report.MakeNodeWith(report.MakeContainerNodeID(id), map[string]string{
+			report.DockerContainerID: id,
+			report.DockerImageID:     "img1",
+			report.ControlProbeID:    "probe",
+		}).WithLatestActiveControls(controls...).WithTopology(report.Container))
+	}
+
+	imageID := report.MakeContainerImageNodeID("nginx")
+	nodes := render.ContainerImageRenderer.Render(context.Background(), rpt).Nodes
+	image, ok := nodes[imageID]
+	if !ok {
+		t.Fatalf("Image %s not rendered", imageID)
+	}
+	node := detailed.MakeNode("containers-by-image", detailed.RenderContext{Report: rpt}, nodes, image)
+
+	have := map[string]string{}
+	for _, c := range node.Controls {
+		if c.NodeID != imageID {
+			t.Errorf("Unexpected node %s for %s", c.NodeID, c.Control.ID)
+		}
+		have[c.Control.ID] = c.ProbeID
+	}
+	want := map[string]string{
+		"group_docker_restart_container": "containers-by-image",
+		"group_docker_stop_container":    "containers-by-image",
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected %v, got %v", want, have)
+	}
+
+	gc, ok := detailed.LookupGroupControl("group_docker_restart_container")
+	if !ok {
+		t.Fatal("Group control not found")
+	}
+	targets := detailed.GroupControlTargets(rpt, image, gc)
+	if len(targets) != 2 {
+		t.Errorf("Expected 2 targets, got %v", targets)
+	}
+	if _, ok := detailed.LookupGroupControl(docker.RestartContainer); ok {
+		t.Error("Expected only group controls to be found")
+	}
+}
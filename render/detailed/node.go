@@ -17,6 +17,7 @@ import (
 type Node struct {
 	NodeSummary
 	Controls    []ControlInstance    `json:"controls"`
+	Jobs        []report.ControlJob  `json:"jobs,omitempty"`
 	Children    []NodeSummaryGroup   `json:"children,omitempty"`
 	Connections []ConnectionsSummary `json:"connections,omitempty"`
 }
@@ -40,13 +41,14 @@ func (*ControlInstance) UnmarshalJSON(b []byte) error {
 }
 
 type wiredControlInstance struct {
-	ProbeID      string `json:"probeId"`
-	NodeID       string `json:"nodeId"`
-	ID           string `json:"id"`
-	Human        string `json:"human"`
-	Icon         string `json:"icon"`
-	Confirmation string `json:"confirmation,omitempty"`
-	Rank         int    `json:"rank"`
+	ProbeID      string              `json:"probeId"`
+	NodeID       string              `json:"nodeId"`
+	ID           string              `json:"id"`
+	Human        string              `json:"human"`
+	Icon         string              `json:"icon"`
+	Confirmation string              `json:"confirmation,omitempty"`
+	Rank         int                 `json:"rank"`
+	Args         []report.ControlArg `json:"args,omitempty"`
 }
 
 // CodecEncodeSelf marshals this ControlInstance. It takes the basic Metric
@@ -60,6 +62,7 @@ func (c *ControlInstance) CodecEncodeSelf(encoder *codec.Encoder) {
 		Icon:         c.Control.Icon,
 		Confirmation: c.Control.Confirmation,
 		Rank:         c.Control.Rank,
+		Args:         c.Control.Args,
 	})
 }
 
@@ -76,6 +79,7 @@ func (c *ControlInstance) CodecDecodeSelf(decoder *codec.Decoder) {
 			Icon:         in.Icon,
 			Confirmation: in.Confirmation,
 			Rank:         in.Rank,
+			Args:         in.Args,
 		},
 	}
 }
@@ -92,7 +96,8 @@ func MakeNode(topologyID string, rc RenderContext, ns report.Nodes, n report.Nod
 	summary, _ := MakeNodeSummary(rc, n)
 	return Node{
 		NodeSummary: summary,
-		Controls:    controls(rc.Report, n),
+		Controls:    append(controls(rc.Report, n), groupControlsFor(topologyID, rc.Report, n)...),
+		Jobs:        runningJobs(rc.Report, n),
 		Children:    children(rc, n),
 		Connections: []ConnectionsSummary{
 			incomingConnectionsSummary(topologyID, rc.Report, n, ns),
@@ -101,12 +106,8 @@ func MakeNode(topologyID string, rc RenderContext, ns report.Nodes, n report.Nod
 	}
 }
 
-func controlsFor(topology report.Topology, nodeID string) []ControlInstance {
+func controlsFor(topology report.Topology, node report.Node) []ControlInstance {
 	result := []ControlInstance{}
-	node, ok := topology.Nodes[nodeID]
-	if !ok {
-		return result
-	}
 	probeID, ok := node.Latest.Lookup(report.ControlProbeID)
 	if !ok {
 		return result
@@ -118,7 +119,7 @@ func controlsFor(topology report.Topology, nodeID string) []ControlInstance {
 			}
 			result = append(result, ControlInstance{
 				ProbeID: probeID,
-				NodeID:  nodeID,
+				NodeID:  node.ID,
 				Control: control,
 			})
 		}
@@ -126,11 +127,31 @@ func controlsFor(topology report.Topology, nodeID string) []ControlInstance {
 	return result
 }
 
+// controls returns the controls of n. Rendered nodes which aren't in the
+// report, such as the images of the containers by image view, have the
+// controls of the nodes they were made of, run by the probe of one of
+// them.
 func controls(r report.Report, n report.Node) []ControlInstance {
-	if t, ok := r.Topology(n.Topology); ok {
-		return controlsFor(t, n.ID)
+	t, ok := r.Topology(n.Topology)
+	if !ok {
+		return []ControlInstance{}
+	}
+	if node, ok := t.Nodes[n.ID]; ok {
+		return controlsFor(t, node)
 	}
-	return []ControlInstance{}
+	return controlsFor(t, n)
+}
+
+// runningJobs returns the jobs running on n, oldest first.
+func runningJobs(r report.Report, n report.Node) []report.ControlJob {
+	jobs := r.ControlJobs.Running(n.ID)
+	if len(jobs) == 0 {
+		return nil
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Started.Before(jobs[j].Started)
+	})
+	return jobs
 }
 
 // We only need to include topologies here where the nodes may appear
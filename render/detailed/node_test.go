@@ -419,3 +419,40 @@ func TestMakeDetailedPodNode(t *testing.T) {
 		t.Errorf("%s", test.Diff(want, have))
 	}
 }
+
+func TestMakeDetailedNodeJobs(t *testing.T) {
+	id := fixture.ServerContainerNodeID
+	rpt := fixture.Report.Copy()
+	rpt.ControlJobs = report.ControlJobs{
+		"a": {ID: "a", NodeID: id, State: report.JobRunning},
+		"b": {ID: "b", NodeID: id, State: report.JobSucceeded},
+		"c": {ID: "c", NodeID: fixture.ClientContainerNodeID, State: report.JobRunning},
+	}
+	renderableNodes := render.ContainerWithImageNameRenderer.Render(context.Background(), rpt).Nodes
+	have := detailed.MakeNode("containers", detailed.RenderContext{Report: rpt}, renderableNodes, renderableNodes[id])
+	if len(have.Jobs) != 1 || have.Jobs[0].ID != "a" {
+		t.Errorf("Expected only job a, got %v", have.Jobs)
+	}
+}
+
+func TestMakeDetailedImageNodeControls(t *testing.T) {
+	rpt := fixture.Report.Copy()
+	rpt.ContainerImage.Controls = report.Controls{}
+	rpt.ContainerImage.Controls.AddControls(docker.ContainerImageControls)
+	rpt.ContainerImage.Nodes[fixture.ServerContainerImageNodeID] = rpt.ContainerImage.Nodes[fixture.ServerContainerImageNodeID].
+		WithLatests(map[string]string{report.ControlProbeID: "probe"}).
+		WithLatestActiveControls(docker.PullImage)
+
+	// Images are rendered by name, with the controls of the images of that name
+	id := report.MakeContainerImageNodeID(fixture.ServerContainerImageName)
+	renderableNodes := render.ContainerImageRenderer.Render(context.Background(), rpt).Nodes
+	have := detailed.MakeNode("containers-by-image", detailed.RenderContext{Report: rpt}, renderableNodes, renderableNodes[id])
+	want := []detailed.ControlInstance{{
+		ProbeID: "probe",
+		NodeID:  id,
+		Control: docker.ContainerImageControls[0],
+	}}
+	if !reflect.DeepEqual(want, have.Controls) {
+		t.Errorf("Expected %v, got %v", want, have.Controls)
+	}
+}
@@ -17,9 +17,11 @@ var parentTopologies = []string{
 	report.ContainerImage,
 	report.Pod,
 	report.Deployment,
+	report.ReplicaSet,
 	report.DaemonSet,
 	report.StatefulSet,
 	report.CronJob,
+	report.CustomResource,
 	report.Service,
 	report.ECSTask,
 	report.ECSService,
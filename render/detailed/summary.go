@@ -75,10 +75,12 @@ var renderers = map[string]func(BasicNodeSummary, report.Node) BasicNodeSummary{
 	report.Pod:                   podNodeSummary,
 	report.Service:               podGroupNodeSummary,
 	report.Deployment:            podGroupNodeSummary,
+	report.ReplicaSet:            podGroupNodeSummary,
 	report.DaemonSet:             podGroupNodeSummary,
 	report.StatefulSet:           podGroupNodeSummary,
 	report.CronJob:               podGroupNodeSummary,
 	report.Job:                   podGroupNodeSummary,
+	report.CustomResource:        podGroupNodeSummary,
 	report.ECSTask:               ecsTaskNodeSummary,
 	report.ECSService:            ecsServiceNodeSummary,
 	report.SwarmService:          swarmServiceNodeSummary,
@@ -99,10 +101,12 @@ var primaryAPITopology = map[string]string{
 	report.ContainerImage:        "containers-by-image",
 	report.Pod:                   "pods",
 	report.Deployment:            "kube-controllers",
+	report.ReplicaSet:            "replica-sets",
 	report.DaemonSet:             "kube-controllers",
 	report.StatefulSet:           "kube-controllers",
 	report.CronJob:               "kube-controllers",
 	report.Job:                   "kube-controllers",
+	report.CustomResource:        "custom-resources",
 	report.Service:               "services",
 	report.ECSTask:               "ecs-tasks",
 	report.ECSService:            "ecs-services",
@@ -266,6 +270,9 @@ func containerNodeSummary(base BasicNodeSummary, n report.Node) BasicNodeSummary
 	)
 	base.Label = containerName
 	base.LabelMinor = hostName
+	if render.IsUnhealthy(n) {
+		base.Tag = report.Alert
+	}
 	if imageName != "" {
 		base.Rank = docker.ImageNameWithoutTag(imageName)
 	} else if hostName != "" {
@@ -320,6 +327,7 @@ func podNodeSummary(base BasicNodeSummary, n report.Node) BasicNodeSummary {
 
 var podGroupNodeTypeName = map[string]string{
 	report.Deployment:  "Deployment",
+	report.ReplicaSet:  "ReplicaSet",
 	report.DaemonSet:   "DaemonSet",
 	report.StatefulSet: "StatefulSet",
 	report.CronJob:     "CronJob",
@@ -332,7 +340,12 @@ func podGroupNodeSummary(base BasicNodeSummary, n report.Node) BasicNodeSummary
 	// NB: pods are the highest aggregation level for which we display
 	// counts.
 	count := pluralize(n, report.Pod, "pod", "pods")
-	if typeName, ok := podGroupNodeTypeName[n.Topology]; ok {
+	typeName, ok := podGroupNodeTypeName[n.Topology]
+	if n.Topology == report.CustomResource {
+		// Custom resources are all in the same topology, whatever their kind
+		typeName, ok = n.Latest.Lookup(kubernetes.NodeType)
+	}
+	if ok {
 		base.LabelMinor = fmt.Sprintf("%s of %s", typeName, count)
 	} else {
 		base.LabelMinor = count
@@ -0,0 +1,126 @@
+package detailed
+
+import (
+	"sort"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Comparison is returned by CompareTopologies. It describes how a rendered
+// topology changed between two points in time.
+type Comparison struct {
+	Added              []NodeSummary `json:"added"`
+	Removed            []NodeSummary `json:"removed"`
+	Changed            []NodeChange  `json:"changed"`
+	AddedAdjacencies   []Adjacency   `json:"addedAdjacencies"`
+	RemovedAdjacencies []Adjacency   `json:"removedAdjacencies"`
+}
+
+// NodeChange describes a node which exists at both points in time, but
+// whose metadata differs.
+type NodeChange struct {
+	ID       string           `json:"id"`
+	Label    string           `json:"label"`
+	Metadata []MetadataChange `json:"metadata"`
+}
+
+// MetadataChange is a single metadata field which changed. Before or After
+// are empty if the field was added or removed.
+type MetadataChange struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Adjacency is a directed edge between two rendered nodes.
+type Adjacency struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// CompareTopologies gives you the changes needed to get from A to B.
+func CompareTopologies(a, b NodeSummaries) Comparison {
+	c := Comparison{
+		Added:              []NodeSummary{},
+		Removed:            []NodeSummary{},
+		Changed:            []NodeChange{},
+		AddedAdjacencies:   []Adjacency{},
+		RemovedAdjacencies: []Adjacency{},
+	}
+
+	for id, before := range a {
+		after, ok := b[id]
+		if !ok {
+			c.Removed = append(c.Removed, before)
+			continue
+		}
+		if changes := compareMetadata(before.Metadata, after.Metadata); len(changes) > 0 {
+			c.Changed = append(c.Changed, NodeChange{ID: id, Label: after.Label, Metadata: changes})
+		}
+	}
+	for id, after := range b {
+		if _, ok := a[id]; !ok {
+			c.Added = append(c.Added, after)
+		}
+	}
+
+	edgesA, edgesB := adjacencies(a), adjacencies(b)
+	for edge := range edgesA {
+		if _, ok := edgesB[edge]; !ok {
+			c.RemovedAdjacencies = append(c.RemovedAdjacencies, edge)
+		}
+	}
+	for edge := range edgesB {
+		if _, ok := edgesA[edge]; !ok {
+			c.AddedAdjacencies = append(c.AddedAdjacencies, edge)
+		}
+	}
+
+	sort.Slice(c.Added, func(i, j int) bool { return c.Added[i].ID < c.Added[j].ID })
+	sort.Slice(c.Removed, func(i, j int) bool { return c.Removed[i].ID < c.Removed[j].ID })
+	sort.Slice(c.Changed, func(i, j int) bool { return c.Changed[i].ID < c.Changed[j].ID })
+	sortAdjacencies(c.AddedAdjacencies)
+	sortAdjacencies(c.RemovedAdjacencies)
+	return c
+}
+
+func compareMetadata(a, b []report.MetadataRow) []MetadataChange {
+	before := map[string]report.MetadataRow{}
+	for _, row := range a {
+		before[row.ID] = row
+	}
+	changes := []MetadataChange{}
+	for _, row := range b {
+		prev, ok := before[row.ID]
+		delete(before, row.ID)
+		if ok && prev.Value == row.Value {
+			continue
+		}
+		changes = append(changes, MetadataChange{ID: row.ID, Label: row.Label, Before: prev.Value, After: row.Value})
+	}
+	for _, row := range before {
+		changes = append(changes, MetadataChange{ID: row.ID, Label: row.Label, Before: row.Value})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes
+}
+
+func adjacencies(ns NodeSummaries) map[Adjacency]struct{} {
+	result := map[Adjacency]struct{}{}
+	for id, n := range ns {
+		for _, to := range n.Adjacency {
+			result[Adjacency{From: id, To: to}] = struct{}{}
+		}
+	}
+	return result
+}
+
+func sortAdjacencies(as []Adjacency) {
+	sort.Slice(as, func(i, j int) bool {
+		if as[i].From != as[j].From {
+			return as[i].From < as[j].From
+		}
+		return as[i].To < as[j].To
+	})
+}
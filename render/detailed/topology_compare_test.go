@@ -0,0 +1,71 @@
+package detailed_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestCompareTopologies(t *testing.T) {
+	nodea := detailed.NodeSummary{
+		BasicNodeSummary: detailed.BasicNodeSummary{ID: "nodea", Label: "Node A"},
+		Metadata: []report.MetadataRow{
+			{ID: "image", Label: "Image", Value: "nginx:1.0"},
+			{ID: "state", Label: "State", Value: "running"},
+		},
+		Adjacency: report.MakeIDList("nodeb"),
+	}
+	nodeap := nodea
+	nodeap.Metadata = []report.MetadataRow{
+		{ID: "image", Label: "Image", Value: "nginx:1.1"},
+		{ID: "command", Label: "Command", Value: "nginx"},
+	}
+	nodeap.Adjacency = report.MakeIDList("nodec")
+	nodeb := detailed.NodeSummary{BasicNodeSummary: detailed.BasicNodeSummary{ID: "nodeb", Label: "Node B"}}
+	nodec := detailed.NodeSummary{BasicNodeSummary: detailed.BasicNodeSummary{ID: "nodec", Label: "Node C"}}
+
+	nodes := func(ns ...detailed.NodeSummary) detailed.NodeSummaries {
+		r := detailed.NodeSummaries{}
+		for _, n := range ns {
+			r[n.ID] = n
+		}
+		return r
+	}
+
+	have := detailed.CompareTopologies(nodes(nodea, nodeb), nodes(nodeap, nodec))
+	want := detailed.Comparison{
+		Added:   []detailed.NodeSummary{nodec},
+		Removed: []detailed.NodeSummary{nodeb},
+		Changed: []detailed.NodeChange{
+			{
+				ID:    "nodea",
+				Label: "Node A",
+				Metadata: []detailed.MetadataChange{
+					{ID: "command", Label: "Command", After: "nginx"},
+					{ID: "image", Label: "Image", Before: "nginx:1.0", After: "nginx:1.1"},
+					{ID: "state", Label: "State", Before: "running"},
+				},
+			},
+		},
+		AddedAdjacencies:   []detailed.Adjacency{{From: "nodea", To: "nodec"}},
+		RemovedAdjacencies: []detailed.Adjacency{{From: "nodea", To: "nodeb"}},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	have = detailed.CompareTopologies(nodes(nodea, nodeb), nodes(nodea, nodeb))
+	want = detailed.Comparison{
+		Added:              []detailed.NodeSummary{},
+		Removed:            []detailed.NodeSummary{},
+		Changed:            []detailed.NodeChange{},
+		AddedAdjacencies:   []detailed.Adjacency{},
+		RemovedAdjacencies: []detailed.Adjacency{},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -2,6 +2,7 @@ package render
 
 import (
 	"context"
+	"strconv"
 	"strings"
 
 	"github.com/weaveworks/common/mtime"
@@ -237,6 +238,31 @@ func IsRunning(n report.Node) bool {
 // IsStopped checks if the node is *not* a running docker container
 var IsStopped = Complement(IsRunning)
 
+// HasNewerImage checks if the node is a container whose image tag now refers
+// to a newer image than the one it was started from.
+func HasNewerImage(n report.Node) bool {
+	_, ok := n.Latest.Lookup(report.DockerContainerNewerImage)
+	return ok
+}
+
+// RestartLoopExits is how many times a container must have recently exited
+// to be considered in a restart loop.
+const RestartLoopExits = 3
+
+// IsUnhealthy checks if the node is a container failing its healthcheck,
+// killed for running out of memory, or in a restart loop.
+func IsUnhealthy(n report.Node) bool {
+	if health, _ := n.Latest.Lookup(report.DockerContainerHealth); health == report.HealthUnhealthy {
+		return true
+	}
+	if _, ok := n.Latest.Lookup(report.DockerContainerOOMKilled); ok {
+		return true
+	}
+	exits, _ := n.Latest.Lookup(report.DockerContainerRecentExits)
+	count, _ := strconv.Atoi(exits)
+	return count >= RestartLoopExits
+}
+
 // IsApplication checks if the node is an "application" node
 func IsApplication(n report.Node) bool {
 	containerName, _ := n.Latest.Lookup(report.DockerContainerName)
@@ -296,6 +322,38 @@ func IsNotPseudo(n report.Node) bool {
 	return n.Topology != Pseudo || IsInternetNode(n) || strings.HasPrefix(n.ID, ServiceNodeIDPrefix)
 }
 
+// IsPodWithoutLimits checks if the node is a pod without CPU or memory
+// limits, letting nodes other than pods through.
+func IsPodWithoutLimits(n report.Node) bool {
+	if n.Topology != report.Pod {
+		return true
+	}
+	if _, ok := n.Latest.Lookup(report.KubernetesQoSClass); !ok {
+		// reported by a probe which doesn't know about resources
+		return false
+	}
+	_, hasCPULimit := n.Latest.Lookup(report.KubernetesCPULimit)
+	_, hasMemoryLimit := n.Latest.Lookup(report.KubernetesMemoryLimit)
+	return !hasCPULimit || !hasMemoryLimit
+}
+
+// IsCluster checks if the node comes from the specified federated cluster
+func IsCluster(cluster string) FilterFunc {
+	return func(n report.Node) bool {
+		got, _ := n.Latest.Lookup(report.FederationCluster)
+		return cluster == got
+	}
+}
+
+// IsKubernetesType checks if the node is a kubernetes object of the
+// specified type, e.g. the kind of a custom resource
+func IsKubernetesType(nodeType string) FilterFunc {
+	return func(n report.Node) bool {
+		got, _ := n.Latest.Lookup(report.KubernetesNodeType)
+		return nodeType == got
+	}
+}
+
 // IsNamespace checks if the node is a pod/service in the specified namespace
 func IsNamespace(namespace string) FilterFunc {
 	return func(n report.Node) bool {
@@ -346,3 +404,21 @@ var systemImagePrefixes = map[string]struct{}{
 	"openshift/origin-pod":           {},
 	"docker.io/openshift/origin-pod": {},
 }
+
+// IsWellKnownName checks if a name has a special meaning when rendering and
+// doesn't identify anything, like system containers and images, the
+// kube-system namespace and known services.
+func IsWellKnownName(name string) bool {
+	if _, ok := systemContainerNames[name]; ok {
+		return true
+	}
+	imagePrefix := strings.SplitN(name, ":", 2)[0]
+	if _, ok := systemImagePrefixes[imagePrefix]; ok || report.IsPauseImageName(imagePrefix) {
+		return true
+	}
+	switch name {
+	case "kube-system", "system", "podsandbox":
+		return true
+	}
+	return strings.HasPrefix(name, "kube-system/") || isKnownService(name)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/render"
 	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
 	"github.com/weaveworks/scope/test/reflect"
 )
 
@@ -100,3 +101,73 @@ func TestFilterUnconnectedSelf(t *testing.T) {
 		}
 	}
 }
+
+func TestIsPodWithoutLimits(t *testing.T) {
+	pod := func(id string, latests map[string]string) report.Node {
+		return report.MakeNodeWith(id, latests).WithTopology(report.Pod)
+	}
+	for _, c := range []struct {
+		node report.Node
+		want bool
+	}{
+		{pod("besteffort", map[string]string{report.KubernetesQoSClass: "BestEffort"}), true},
+		{pod("nocpulimit", map[string]string{report.KubernetesQoSClass: "Burstable", report.KubernetesMemoryLimit: "64Mi"}), true},
+		{pod("guaranteed", map[string]string{report.KubernetesQoSClass: "Guaranteed", report.KubernetesCPULimit: "1", report.KubernetesMemoryLimit: "64Mi"}), false},
+		{pod("unknown", map[string]string{}), false},
+		{report.MakeNode("claim").WithTopology(report.PersistentVolumeClaim), true},
+	} {
+		if have := render.IsPodWithoutLimits(c.node); have != c.want {
+			t.Errorf("%s: expected %v, got %v", c.node.ID, c.want, have)
+		}
+	}
+}
+
+func TestIsUnhealthy(t *testing.T) {
+	container := func(id string, latests map[string]string) report.Node {
+		return report.MakeNodeWith(id, latests).WithTopology(report.Container)
+	}
+	for _, c := range []struct {
+		node report.Node
+		want bool
+	}{
+		{container("healthy", map[string]string{report.DockerContainerHealth: report.HealthHealthy, report.DockerContainerRecentExits: "1"}), false},
+		{container("unhealthy", map[string]string{report.DockerContainerHealth: report.HealthUnhealthy}), true},
+		{container("oomkilled", map[string]string{report.DockerContainerOOMKilled: "true"}), true},
+		{container("restarting", map[string]string{report.DockerContainerRecentExits: "3"}), true},
+		{container("unknown", map[string]string{}), false},
+	} {
+		if have := render.IsUnhealthy(c.node); have != c.want {
+			t.Errorf("%s: expected %v, got %v", c.node.ID, c.want, have)
+		}
+	}
+}
+
+func TestAnonymizedReportRendersTheSame(t *testing.T) {
+	ctx := context.Background()
+	anonymized := report.NewAnonymizer(nil, render.IsWellKnownName).Anonymize(fixture.Report)
+	for name, renderer := range map[string]render.Renderer{
+		"processes":     render.ConnectedProcessRenderer,
+		"containers":    render.ContainerWithImageNameRenderer,
+		"images":        render.ContainerImageRenderer,
+		"pods":          render.PodRenderer,
+		"hosts":         render.HostRenderer,
+		"internet-only": render.MakeFilter(render.IsInternetNode, render.ConnectedProcessRenderer),
+		"applications":  render.MakeFilter(render.IsApplication, render.ContainerWithImageNameRenderer),
+	} {
+		want := render.Render(ctx, fixture.Report, renderer, render.Transformers(nil)).Nodes
+		have := render.Render(ctx, anonymized, renderer, render.Transformers(nil)).Nodes
+		if len(want) != len(have) {
+			t.Errorf("%s: rendered %d nodes from the anonymized report, want %d", name, len(have), len(want))
+		}
+		wantEdges, haveEdges := 0, 0
+		for _, n := range want {
+			wantEdges += len(n.Adjacency)
+		}
+		for _, n := range have {
+			haveEdges += len(n.Adjacency)
+		}
+		if wantEdges != haveEdges {
+			t.Errorf("%s: rendered %d edges from the anonymized report, want %d", name, haveEdges, wantEdges)
+		}
+	}
+}
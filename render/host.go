@@ -9,14 +9,17 @@ import (
 // HostRenderer is a Renderer which produces a renderable host
 // graph from the host topology.
 //
+// The network and block I/O metrics of the containers on the host are
+// summed.
+//
 // not memoised
-var HostRenderer = MakeReduce(
+var HostRenderer = PropagateSummedMetrics(report.Container, ContainerIOMetrics, MakeReduce(
 	CustomRenderer{RenderFunc: nodes2Hosts, Renderer: ProcessRenderer},
 	CustomRenderer{RenderFunc: nodes2Hosts, Renderer: ContainerRenderer},
 	CustomRenderer{RenderFunc: nodes2Hosts, Renderer: ContainerImageRenderer},
 	CustomRenderer{RenderFunc: nodes2Hosts, Renderer: PodRenderer},
 	MapEndpoints(endpoint2Host, report.Host),
-)
+))
 
 // nodes2Hosts maps any Nodes to host Nodes.
 //
@@ -42,3 +42,81 @@ func (p propagateSingleMetrics) Render(ctx context.Context, rpt report.Report) N
 	}
 	return Nodes{Nodes: outputs, Filtered: nodes.Filtered}
 }
+
+// ContainerNetworkMetrics are the container network metrics.
+var ContainerNetworkMetrics = []string{
+	report.DockerNetworkRxBytes,
+	report.DockerNetworkTxBytes,
+	report.DockerNetworkRxPackets,
+	report.DockerNetworkTxPackets,
+	report.DockerNetworkRxDropped,
+	report.DockerNetworkTxDropped,
+}
+
+// ContainerIOMetrics are the container network and block I/O metrics
+// summed up to the images, pods and hosts of the containers.
+var ContainerIOMetrics = append([]string{
+	report.DockerBlockIOReadBytes,
+	report.DockerBlockIOWriteBytes,
+}, ContainerNetworkMetrics...)
+
+// PropagateSummedMetrics creates a renderer which propagates the given
+// metrics from a node's children to the node. The children are selected
+// based on the specified topology. The metric of a single child is copied
+// as it is; the latest samples of several children are summed.
+func PropagateSummedMetrics(topology string, metrics []string, r Renderer) Renderer {
+	return propagateSummedMetrics{topology: topology, metrics: metrics, r: r}
+}
+
+type propagateSummedMetrics struct {
+	topology string
+	metrics  []string
+	r        Renderer
+}
+
+func (p propagateSummedMetrics) Render(ctx context.Context, rpt report.Report) Nodes {
+	nodes := p.r.Render(ctx, rpt)
+	outputs := make(report.Nodes, len(nodes.Nodes))
+	for id, n := range nodes.Nodes {
+		summed := report.Metrics{}
+		for _, key := range p.metrics {
+			var (
+				first report.Metric
+				found int
+				sum   report.Sample
+			)
+			n.Children.ForEach(func(child report.Node) {
+				if child.Topology != p.topology {
+					return
+				}
+				metric, ok := child.Metrics[key]
+				if !ok {
+					return
+				}
+				sample, ok := metric.LastSample()
+				if !ok {
+					return
+				}
+				if found == 0 {
+					first = metric
+				}
+				found++
+				sum.Value += sample.Value
+				if sample.Timestamp.After(sum.Timestamp) {
+					sum.Timestamp = sample.Timestamp
+				}
+			})
+			switch {
+			case found == 1:
+				summed[key] = first
+			case found > 1:
+				summed[key] = report.MakeSingletonMetric(sum.Timestamp, sum.Value)
+			}
+		}
+		if len(summed) > 0 {
+			n = n.WithMetrics(summed)
+		}
+		outputs[id] = n
+	}
+	return Nodes{Nodes: outputs, Filtered: nodes.Filtered}
+}
@@ -166,3 +166,39 @@ func TestPropagateSingleMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestPropagateSummedMetrics(t *testing.T) {
+	now := time.Now()
+	child := func(id, topology string, metrics report.Metrics) report.Node {
+		return report.MakeNode(id).WithTopology(topology).WithMetrics(metrics)
+	}
+	rxBytes := report.MakeMetric([]report.Sample{{Timestamp: now.Add(-time.Second), Value: 1}, {Timestamp: now, Value: 10}})
+	children := report.MakeNodeSet(
+		child("child1", report.Container, report.Metrics{
+			report.DockerNetworkRxBytes: rxBytes,
+			report.DockerNetworkTxBytes: report.MakeSingletonMetric(now.Add(-time.Second), 5),
+		}),
+		child("child2", report.Container, report.Metrics{
+			report.DockerNetworkTxBytes: report.MakeSingletonMetric(now, 7),
+			"docker_memory_usage":       report.MakeSingletonMetric(now, 100),
+		}),
+		child("child3", report.Process, report.Metrics{
+			report.DockerNetworkRxBytes: report.MakeSingletonMetric(now, 1000),
+		}),
+		child("child4", report.Container, report.Metrics{
+			report.DockerBlockIOReadBytes: report.MakeMetric(nil),
+		}),
+	)
+	input := report.MakeNode("a").WithChildren(children)
+
+	want := report.Nodes{
+		"a": report.MakeNode("a").WithMetrics(report.Metrics{
+			report.DockerNetworkRxBytes: rxBytes,
+			report.DockerNetworkTxBytes: report.MakeSingletonMetric(now, 12),
+		}).WithChildren(children),
+	}
+	have := render.PropagateSummedMetrics(report.Container, render.ContainerIOMetrics, mockRenderer{report.Nodes{input.ID: input}}).Render(context.Background(), report.Report{}).Nodes
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
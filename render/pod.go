@@ -22,6 +22,7 @@ func renderKubernetesTopologies(rpt report.Report) bool {
 		&rpt.Pod,
 		&rpt.Service,
 		&rpt.Deployment,
+		&rpt.ReplicaSet,
 		&rpt.DaemonSet,
 		&rpt.StatefulSet,
 		&rpt.CronJob,
@@ -29,6 +30,7 @@ func renderKubernetesTopologies(rpt report.Report) bool {
 		&rpt.PersistentVolumeClaim,
 		&rpt.StorageClass,
 		&rpt.Job,
+		&rpt.CustomResource,
 	}
 	for _, t := range topologies {
 		if len(t.Nodes) > 0 {
@@ -52,24 +54,69 @@ var PodRenderer = Memoise(ConditionalRenderer(renderKubernetesTopologies,
 			return !ok || !(state == report.StateDeleted || state == report.StateFailed)
 		},
 		MakeReduce(
-			PropagateSingleMetrics(report.Container,
-				MakeMap(propagatePodHost,
-					Map2Parent{topologies: []string{report.Pod}, noParentsPseudoID: UnmanagedID,
-						chainRenderer: MakeFilter(
-							ComposeFilterFuncs(
-								IsRunning,
-								Complement(isPauseContainer),
-							),
-							ContainerWithImageNameRenderer,
-						)},
+			podNetworkMetrics{
+				PropagateSummedMetrics(report.Container, ContainerIOMetrics,
+					PropagateSingleMetrics(report.Container,
+						MakeMap(propagatePodHost,
+							Map2Parent{topologies: []string{report.Pod}, noParentsPseudoID: UnmanagedID,
+								chainRenderer: MakeFilter(
+									ComposeFilterFuncs(
+										IsRunning,
+										Complement(isPauseContainer),
+									),
+									ContainerWithImageNameRenderer,
+								)},
+						),
+					),
 				),
-			),
+			},
 			ConnectionJoin(MapPod2IP, report.Pod),
 			KubernetesVolumesRenderer,
 		),
 	),
 ))
 
+// podNetworkMetrics adds the network metrics of the running pause
+// containers, which hold the network of the pods, to the pods.
+type podNetworkMetrics struct {
+	Renderer
+}
+
+func (p podNetworkMetrics) Render(ctx context.Context, rpt report.Report) Nodes {
+	pods := p.Renderer.Render(ctx, rpt)
+	containers := ContainerWithImageNameRenderer.Render(ctx, rpt)
+
+	metrics := map[string]report.Metrics{}
+	for _, c := range containers.Nodes {
+		if !isPauseContainer(c) || !IsRunning(c) {
+			continue
+		}
+		podIDs, _ := c.Parents.Lookup(report.Pod)
+		for _, podID := range podIDs {
+			for _, key := range ContainerNetworkMetrics {
+				if metric, ok := c.Metrics[key]; ok {
+					if metrics[podID] == nil {
+						metrics[podID] = report.Metrics{}
+					}
+					metrics[podID][key] = metric
+				}
+			}
+		}
+	}
+	if len(metrics) == 0 {
+		return pods
+	}
+
+	outputs := make(report.Nodes, len(pods.Nodes))
+	for id, n := range pods.Nodes {
+		if m, ok := metrics[id]; ok {
+			n = n.WithMetrics(m)
+		}
+		outputs[id] = n
+	}
+	return Nodes{Nodes: outputs, Filtered: pods.Filtered}
+}
+
 // Pods are not tagged with a Host parent, but their container children are.
 // If n doesn't already have a host, copy it from one of the children
 func propagatePodHost(n report.Node) report.Node {
@@ -116,6 +163,30 @@ var KubeControllerRenderer = ConditionalRenderer(renderKubernetesTopologies,
 	),
 )
 
+// ReplicaSetRenderer is a Renderer which produces a renderable kubernetes
+// replica sets graph by merging the pods graph and the replica set topology,
+// so the old and new replica sets of a rollout are shown side by side.
+//
+// not memoised
+var ReplicaSetRenderer = ConditionalRenderer(renderKubernetesTopologies,
+	renderParents(
+		report.Pod, []string{report.ReplicaSet}, "",
+		PodRenderer,
+	),
+)
+
+// CustomResourceRenderer is a Renderer which produces a renderable kubernetes
+// custom resources graph by merging the pods graph and the custom resource
+// topology. Pods which aren't part of a custom resource are dropped.
+//
+// not memoised
+var CustomResourceRenderer = ConditionalRenderer(renderKubernetesTopologies,
+	renderParents(
+		report.Pod, []string{report.CustomResource}, "",
+		PodRenderer,
+	),
+)
+
 // renderParents produces a 'standard' renderer for mapping from some child topology to some parent topologies,
 // by taking a child renderer, mapping to parents, propagating single metrics, and joining with full parent topology.
 // Other options are as per Map2Parent.
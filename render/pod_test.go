@@ -3,6 +3,7 @@ package render_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/render"
@@ -66,3 +67,34 @@ func TestPodServiceFilterRenderer(t *testing.T) {
 		t.Error(test.Diff(want, have))
 	}
 }
+
+func TestPodNetworkMetrics(t *testing.T) {
+	now := time.Now()
+	rxBytes := report.MakeSingletonMetric(now, 1024)
+	input := fixture.Report.Copy()
+	input.ID = "pod-network-metrics"
+	input.Container.AddNode(report.MakeNodeWith(report.MakeContainerNodeID("pause"), map[string]string{
+		report.DockerContainerID:    "pause",
+		report.DockerImageID:        "pause-image",
+		report.DockerContainerState: report.StateRunning,
+	}).WithTopology(report.Container).WithParents(report.MakeSets().
+		Add(report.Pod, report.MakeStringSet(fixture.ClientPodNodeID)),
+	).WithMetrics(report.Metrics{
+		report.DockerNetworkRxBytes: rxBytes,
+	}))
+	input.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("pause-image"), map[string]string{
+		report.DockerImageID:   "pause-image",
+		report.DockerImageName: "k8s.gcr.io/pause",
+	}).WithTopology(report.ContainerImage))
+
+	pod, ok := render.PodRenderer.Render(context.Background(), input).Nodes[fixture.ClientPodNodeID]
+	if !ok {
+		t.Fatalf("pod %s not rendered", fixture.ClientPodNodeID)
+	}
+	if have := pod.Metrics[report.DockerNetworkRxBytes]; !reflect.DeepEqual(rxBytes, have) {
+		t.Error(test.Diff(rxBytes, have))
+	}
+	if _, ok := pod.Children.Lookup(report.MakeContainerNodeID("pause")); ok {
+		t.Error("pause container rendered as a child of the pod")
+	}
+}
@@ -27,10 +27,12 @@ var (
 	SelectPod                   = TopologySelector(report.Pod)
 	SelectService               = TopologySelector(report.Service)
 	SelectDeployment            = TopologySelector(report.Deployment)
+	SelectReplicaSet            = TopologySelector(report.ReplicaSet)
 	SelectDaemonSet             = TopologySelector(report.DaemonSet)
 	SelectStatefulSet           = TopologySelector(report.StatefulSet)
 	SelectCronJob               = TopologySelector(report.CronJob)
 	SelectJob                   = TopologySelector(report.Job)
+	SelectCustomResource        = TopologySelector(report.CustomResource)
 	SelectECSTask               = TopologySelector(report.ECSTask)
 	SelectECSService            = TopologySelector(report.ECSService)
 	SelectSwarmService          = TopologySelector(report.SwarmService)
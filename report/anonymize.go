@@ -0,0 +1,330 @@
+package report
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var ipv4Matcher = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+
+// Anonymizer replaces the identifying information in reports, such as
+// addresses, hostnames, names, labels and images, with pseudonyms. The same
+// value always gets the same pseudonym, so anonymized reports still render
+// the same graph:
+//   - IP addresses are remapped preserving their prefixes, so addresses in
+//     the same subnet stay in the same (remapped) subnet. The first octet of
+//     IPv4 addresses is kept, as are loopback addresses.
+//   - hostnames, names, label values and image names are replaced by a
+//     keyed hash.
+//   - environment variables are removed.
+type Anonymizer struct {
+	secret []byte
+	keep   func(string) bool
+
+	mtx   sync.Mutex
+	cache map[string]string
+}
+
+// NewAnonymizer makes a new Anonymizer. Pseudonyms are derived from the
+// secret, so Anonymizers with the same one give the same pseudonyms; a random
+// one is used if it is empty. An Anonymizer caches the pseudonyms it gives,
+// for as long as it is used. Names for which keep returns true are left
+// alone, which is useful for names that have a special meaning when
+// rendering, and don't identify anything, like well-known images.
+func NewAnonymizer(secret []byte, keep func(name string) bool) *Anonymizer {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+	if keep == nil {
+		keep = func(string) bool { return false }
+	}
+	return &Anonymizer{
+		secret: secret,
+		keep:   keep,
+		cache:  map[string]string{},
+	}
+}
+
+// Anonymize returns an anonymized copy of the report.
+func (a *Anonymizer) Anonymize(rpt Report) Report {
+	result := rpt.Copy()
+	result.WalkNamedTopologies(func(name string, t *Topology) {
+		nodes := make(Nodes, len(t.Nodes))
+		for _, n := range t.Nodes {
+			n = a.node(name, n)
+			nodes[n.ID] = n
+		}
+		t.Nodes = nodes
+	})
+
+	if rpt.DNS != nil {
+		result.DNS = make(DNSRecords, len(rpt.DNS))
+		for addr, record := range rpt.DNS {
+			result.DNS[a.address(addr)] = DNSRecord{
+				Forward: a.stringSet(record.Forward, a.hostname),
+				Reverse: a.stringSet(record.Reverse, a.hostname),
+			}
+		}
+	}
+
+	if rpt.Probes != nil {
+		result.Probes = make(ProbeSummaries, len(rpt.Probes))
+		for id, summary := range rpt.Probes {
+			summary.Hostname = a.hostname(summary.Hostname)
+			result.Probes[id] = summary
+		}
+	}
+	// Jobs name the nodes they act on, and their progress is free text
+	result.ControlJobs = nil
+	return result
+}
+
+func (a *Anonymizer) node(topology string, n Node) Node {
+	n.ID = a.nodeID(topology, n.ID)
+
+	adjacency := MakeIDList()
+	for _, id := range n.Adjacency {
+		adjacency = adjacency.Add(a.nodeID(topology, id))
+	}
+	n.Adjacency = adjacency
+
+	parents := MakeSets()
+	for _, key := range n.Parents.Keys() {
+		ids, _ := n.Parents.Lookup(key)
+		parents = parents.Add(key, a.stringSet(ids, func(id string) string {
+			return a.nodeID(key, id)
+		}))
+	}
+	n.Parents = parents
+
+	sets := MakeSets()
+	for _, key := range n.Sets.Keys() {
+		values, _ := n.Sets.Lookup(key)
+		switch key {
+		case ReverseDNSNames, SnoopedDNSNames:
+			sets = sets.Add(key, a.stringSet(values, a.hostname))
+		case DockerContainerNetworks:
+			sets = sets.Add(key, a.stringSet(values, a.name))
+		default:
+			sets = sets.Add(key, a.stringSet(values, a.address))
+		}
+	}
+	n.Sets = sets
+
+	var (
+		latest  = make(StringLatestMap, 0, len(n.Latest))
+		renamed []stringLatestEntry
+	)
+	for _, entry := range n.Latest {
+		if IsEnvironmentVarsEntry(entry.key) {
+			continue
+		}
+		entry.Value = a.latest(entry.key, entry.Value)
+		if key := a.key(entry.key); key != entry.key {
+			entry.key = key
+			renamed = append(renamed, entry)
+			continue
+		}
+		latest = append(latest, entry)
+	}
+	// Renamed keys no longer sort where they were
+	for _, entry := range renamed {
+		latest = latest.Set(entry.key, entry.Timestamp, entry.Value)
+	}
+	n.Latest = latest
+	return n
+}
+
+// key anonymizes the names in latest keys: the rows of the container
+// resources table are keyed by container name.
+func (a *Anonymizer) key(key string) string {
+	if rest, ok := WithoutPrefix(key, KubernetesContainerResources); ok {
+		if parts := strings.SplitN(rest, tableEntryKeySeparator, 2); len(parts) == 2 {
+			return KubernetesContainerResources + a.name(parts[0]) + tableEntryKeySeparator + parts[1]
+		}
+	}
+	return key
+}
+
+func (a *Anonymizer) latest(key, value string) string {
+	switch key {
+	case HostNodeID:
+		return a.nodeID(Host, value)
+	case CopyOf:
+		return a.nodeID(Endpoint, value)
+	case HostName, DockerContainerHostname, WeavePeerNickName:
+		return a.hostname(value)
+	case DockerImageName:
+		return a.image(value)
+	case Name, Cmdline, DockerContainerName, DockerContainerCommand, DockerImageTag,
+		DockerImageEntrypoint, DockerContainerHealthOutput,
+		DockerServiceName, DockerStackNamespace, KubernetesName, KubernetesNamespace,
+		KubernetesVolumeClaim, KubernetesStorageClassName, KubernetesVolumeName,
+		KubernetesVolumeSnapshotName, KubernetesSnapshotData, KubernetesMessage,
+		KubernetesPriorityClass, KubernetesContainerName, ECSCluster, ECSTaskFamily:
+		return a.name(value)
+	}
+	if strings.HasPrefix(key, DockerLabelPrefix) ||
+		strings.HasPrefix(key, DockerImageLabelPrefix) ||
+		strings.HasPrefix(key, KubernetesLabelPrefix) ||
+		strings.HasPrefix(key, KubernetesCustomPrefix) ||
+		(strings.HasPrefix(key, KubernetesContainerResources) &&
+			strings.HasSuffix(key, tableEntryKeySeparator+KubernetesContainerName)) {
+		return a.name(value)
+	}
+	return a.address(value)
+}
+
+func (a *Anonymizer) nodeID(topology, id string) string {
+	switch topology {
+	case Endpoint:
+		if scope, addr, port, ok := ParseEndpointNodeID(id); ok {
+			return MakeScopedEndpointNodeID(a.scope(scope), a.ip(addr), port)
+		}
+	case Host:
+		if hostID, ok := ParseHostNodeID(id); ok {
+			return MakeHostNodeID(a.hostname(hostID))
+		}
+	case Process:
+		if hostID, pid, ok := ParseProcessNodeID(id); ok {
+			return MakeProcessNodeID(a.hostname(hostID), pid)
+		}
+	case ECSService:
+		if cluster, service, ok := ParseECSServiceNodeID(id); ok {
+			return MakeECSServiceNodeID(a.name(cluster), a.name(service))
+		}
+	case ECSTask:
+		if arn, ok := ParseECSTaskNodeID(id); ok {
+			return MakeECSTaskNodeID(a.name(arn))
+		}
+	}
+	return id
+}
+
+// scope anonymizes the scope of an address, which is empty or a host ID.
+func (a *Anonymizer) scope(scope string) string {
+	if scope == "" {
+		return ""
+	}
+	return a.hostname(scope)
+}
+
+// address anonymizes values which may contain addresses: IPs, networks,
+// scoped addresses, or any text with IPv4 addresses in it.
+func (a *Anonymizer) address(value string) string {
+	if value == "" {
+		return value
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return a.ip(value)
+	}
+	if _, ipnet, err := net.ParseCIDR(value); err == nil {
+		ip := net.ParseIP(a.ip(ipnet.IP.String()))
+		return (&net.IPNet{IP: ip.Mask(ipnet.Mask), Mask: ipnet.Mask}).String()
+	}
+	if scope, addr, ok := split2(value, ScopeDelim); ok && net.ParseIP(addr) != nil {
+		return MakeScopedAddressNodeID(a.scope(scope), a.ip(addr))
+	}
+	return ipv4Matcher.ReplaceAllStringFunc(value, a.ip)
+}
+
+// ip remaps an IP address, such that addresses sharing a prefix are mapped
+// to addresses sharing a prefix of the same length.
+func (a *Anonymizer) ip(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+		return addr
+	}
+	return a.memoise("ip", addr, func() string {
+		keep := 16 // bits
+		if v4 := ip.To4(); v4 != nil {
+			ip, keep = v4, 8
+		}
+		result := make(net.IP, len(ip))
+		copy(result, ip[:keep/8])
+		mac := hmac.New(sha256.New, a.secret)
+		prefix := make([]byte, len(ip))
+		for i := keep; i < len(ip)*8; i++ {
+			// Whether to flip a bit only depends on the bits before it.
+			copy(prefix, ip[:i/8])
+			prefix[i/8] = ip[i/8] & ^byte(0xff>>uint(i%8))
+			mac.Reset()
+			mac.Write([]byte{byte(i)})
+			mac.Write(prefix[:i/8+1])
+			flip := mac.Sum(nil)[0] & 1
+			bit := (ip[i/8] >> uint(7-i%8)) & 1
+			result[i/8] |= (bit ^ flip) << uint(7-i%8)
+		}
+		return result.String()
+	})
+}
+
+// image anonymizes an image name, keeping its structure of registry,
+// repository and tag.
+func (a *Anonymizer) image(image string) string {
+	if image == "" || a.keep(image) {
+		return image
+	}
+	parts := strings.Split(image, "/")
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			if name, tag, ok := split2(part, ":"); ok {
+				parts[i] = a.name(name) + ":" + a.name(tag)
+				continue
+			}
+		}
+		parts[i] = a.name(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (a *Anonymizer) hostname(hostname string) string {
+	return a.hash("host", hostname)
+}
+
+func (a *Anonymizer) name(name string) string {
+	return a.hash("name", name)
+}
+
+func (a *Anonymizer) hash(kind, value string) string {
+	if value == "" || a.keep(value) {
+		return value
+	}
+	return a.memoise(kind, value, func() string {
+		mac := hmac.New(sha256.New, a.secret)
+		mac.Write([]byte(kind + ":" + value))
+		return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:8]
+	})
+}
+
+func (a *Anonymizer) memoise(kind, value string, f func() string) string {
+	key := kind + ":" + value
+	a.mtx.Lock()
+	result, ok := a.cache[key]
+	a.mtx.Unlock()
+	if ok {
+		return result
+	}
+	result = f()
+	a.mtx.Lock()
+	a.cache[key] = result
+	a.mtx.Unlock()
+	return result
+}
+
+func (a *Anonymizer) stringSet(values StringSet, f func(string) string) StringSet {
+	result := MakeStringSet()
+	for _, value := range values {
+		result = result.Add(f(value))
+	}
+	return result
+}
@@ -0,0 +1,143 @@
+package report_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestAnonymize(t *testing.T) {
+	var (
+		now      = time.Now()
+		hostID   = report.MakeHostNodeID("secret-host")
+		endpoint = report.MakeEndpointNodeID("secret-host", "", "10.32.1.2", "80")
+		peer     = report.MakeEndpointNodeID("", "", "10.32.7.9", "5432")
+		process  = report.MakeProcessNodeID("secret-host", "42")
+		rpt      = report.MakeReport()
+	)
+	rpt.Endpoint.AddNode(report.MakeNode(endpoint).
+		WithAdjacent(peer).
+		WithParent(report.Process, process).
+		WithLatests(map[string]string{report.HostNodeID: hostID}))
+	rpt.Endpoint.AddNode(report.MakeNode(peer))
+	rpt.Host.AddNode(report.MakeNodeWith(hostID, map[string]string{
+		report.HostName: "secret-host",
+	}).WithSets(report.MakeSets().Add(report.HostLocalNetworks, report.MakeStringSet("10.32.0.0/12"))))
+	rpt.Container.AddNode(report.MakeNode("c1").WithLatests(map[string]string{
+		report.DockerContainerName:                    "billing-db",
+		report.DockerImageName:                        "registry.example.com/acme/billing:v1.2",
+		report.DockerLabelPrefix + "owner":            "alice",
+		report.DockerEnvPrefix + "PASSWORD":           "hunter2",
+		report.DockerContainerState:                   report.StateRunning,
+		report.DockerContainerHostname:                "secret-host",
+		report.DockerLabelPrefix + "works.weave.role": "system",
+	}).WithLatest(report.DockerContainerCreated, now, "2017-01-01"))
+	rpt.Pod.AddNode(report.MakeNode("p1").WithLatests(map[string]string{
+		report.KubernetesName:                         "billing-pod",
+		report.KubernetesCustomPrefix + "backup_host": "backup.acme.example.com",
+		report.KubernetesPriorityClass:                "billing-critical",
+	}).AddPrefixMulticolumnTable(report.KubernetesContainerResources, []report.Row{
+		{ID: "billing-api", Entries: map[string]string{
+			report.KubernetesContainerName: "billing-api",
+			report.KubernetesCPURequest:    "100m",
+		}},
+	}))
+	rpt.DNS = report.DNSRecords{"10.32.7.9": {Forward: report.MakeStringSet("db.internal")}}
+
+	anonymizer := report.NewAnonymizer([]byte("secret"), func(name string) bool { return name == "system" })
+	out := anonymizer.Anonymize(rpt)
+
+	// Nothing identifying is left, besides the kept names.
+	var encoded []byte
+	if err := codec.NewEncoderBytes(&encoded, &codec.JsonHandle{}).Encode(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-host", "10.32.1.2", "10.32.7.9", "billing", "acme", "alice", "hunter2", "db.internal"} {
+		if strings.Contains(string(encoded), secret) {
+			t.Errorf("anonymized report still contains %q", secret)
+		}
+	}
+
+	// IDs are consistent across topologies, parents, adjacencies and DNS.
+	if len(out.Endpoint.Nodes) != 2 || len(out.Host.Nodes) != 1 {
+		t.Fatalf("unexpected nodes: %v, %v", out.Endpoint.Nodes, out.Host.Nodes)
+	}
+	var newHostID string
+	for id, node := range out.Host.Nodes {
+		newHostID = id
+		name, _ := node.Latest.Lookup(report.HostName)
+		if report.MakeHostNodeID(name) != id {
+			t.Errorf("host name %q doesn't match ID %q", name, id)
+		}
+	}
+	var newPeer string
+	for id, node := range out.Endpoint.Nodes {
+		if len(node.Adjacency) == 0 {
+			continue
+		}
+		newPeer = node.Adjacency[0]
+		if _, ok := out.Endpoint.Nodes[newPeer]; !ok {
+			t.Errorf("adjacency %q is dangling", newPeer)
+		}
+		if got, _ := node.Latest.Lookup(report.HostNodeID); got != newHostID {
+			t.Errorf("%s: host %q, want %q", id, got, newHostID)
+		}
+		hostname, _ := report.ParseHostNodeID(newHostID)
+		processes, _ := node.Parents.Lookup(report.Process)
+		if want := report.MakeProcessNodeID(hostname, "42"); len(processes) != 1 || processes[0] != want {
+			t.Errorf("%s: process parents %v, want %s", id, processes, want)
+		}
+	}
+	_, peerIP, _, _ := report.ParseEndpointNodeID(newPeer)
+	if _, ok := out.DNS[peerIP]; !ok {
+		t.Errorf("DNS records %v don't contain %s", out.DNS, peerIP)
+	}
+
+	// Subnets are preserved.
+	networks, _ := out.Host.Nodes[newHostID].Sets.Lookup(report.HostLocalNetworks)
+	_, ipnet, err := net.ParseCIDR(networks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ipnet.Contains(net.ParseIP(peerIP)) {
+		t.Errorf("%s is not in %s", peerIP, ipnet)
+	}
+	if ipnet.String() == "10.32.0.0/12" || !strings.HasPrefix(peerIP, "10.") {
+		t.Errorf("unexpected network %s and address %s", ipnet, peerIP)
+	}
+
+	// Labels values, names and images are replaced, environment dropped.
+	container := out.Container.Nodes["c1"]
+	if _, ok := container.Latest.Lookup(report.DockerEnvPrefix + "PASSWORD"); ok {
+		t.Errorf("environment variables weren't dropped")
+	}
+	if image, _ := container.Latest.Lookup(report.DockerImageName); strings.Count(image, "/") != 2 || !strings.Contains(image, ":") {
+		t.Errorf("image %q lost its structure", image)
+	}
+	if role, _ := container.Latest.Lookup(report.DockerLabelPrefix + "works.weave.role"); role != "system" {
+		t.Errorf("kept name was anonymized: %q", role)
+	}
+	if state, _ := container.Latest.Lookup(report.DockerContainerState); state != report.StateRunning {
+		t.Errorf("state was anonymized: %q", state)
+	}
+
+	// Table rows keyed by name are renamed, keeping their other columns.
+	rows, _ := out.Pod.Nodes["p1"].ExtractTable(report.TableTemplate{
+		Type:   report.MulticolumnTableType,
+		Prefix: report.KubernetesContainerResources,
+	})
+	if len(rows) != 1 || rows[0].ID != rows[0].Entries[report.KubernetesContainerName] || rows[0].Entries[report.KubernetesCPURequest] != "100m" {
+		t.Errorf("unexpected container resources %v", rows)
+	}
+
+	// The same anonymizer gives the same pseudonyms.
+	again := anonymizer.Anonymize(rpt)
+	if _, ok := again.Host.Nodes[newHostID]; !ok {
+		t.Errorf("pseudonyms aren't stable")
+	}
+}
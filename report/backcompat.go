@@ -59,9 +59,14 @@ func (n *Node) CodecEncodeSelf(encoder *codec.Encoder) {
 }
 
 // Upgrade returns a new report based on a report received from the old probe.
-//
+// Reports of the current schema version are returned as they are.
 func (r Report) Upgrade() Report {
-	return r.upgradePodNodes().upgradeNamespaces().upgradeDNSRecords()
+	if r.SchemaVersion >= CurrentSchemaVersion {
+		return r
+	}
+	r = r.upgradePodNodes().upgradeNamespaces().upgradeDNSRecords()
+	r.SchemaVersion = CurrentSchemaVersion
+	return r
 }
 
 func (r Report) upgradePodNodes() Report {
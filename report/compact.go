@@ -0,0 +1,402 @@
+package report
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+// CompactContentType is the Content-Type of reports in the compact encoding.
+const CompactContentType = "application/x-scope-compact-report"
+
+// CurrentSchemaVersion is the version of the report schema produced by this
+// code. Reports of an older version may need an Upgrade().
+const CurrentSchemaVersion = 1
+
+// compactMagic starts every report in the compact encoding. Neither JSON nor
+// msgpack reports can start with a zero byte, so the encodings can be told
+// apart.
+var compactMagic = []byte("\x00SCR")
+
+// compactFormatVersion is the version of the compact encoding itself, as
+// opposed to the version of the schema of the report it carries.
+const compactFormatVersion = 1
+
+var errCompactTruncated = errors.New("compact report: truncated or corrupt")
+
+// The compact encoding is:
+//
+//   magic, format version, schema version
+//   the report without its nodes, as msgpack
+//   the string table
+//   for each topology with nodes: its name and nodes
+//
+// All the strings of the nodes (IDs, keys and values) are references into
+// the string table, so each is sent, and decoded, once per report. Numbers
+// are varints, and timestamps are deltas from the previous timestamp, which
+// are mostly zero.
+
+// IsCompact tells whether the (uncompressed) data is a report in the compact
+// encoding.
+func IsCompact(data []byte) bool {
+	return bytes.HasPrefix(data, compactMagic)
+}
+
+// WriteCompact writes a Report in the compact encoding, gzipped, into a
+// bytes.Buffer.
+func (rep Report) WriteCompact() (*bytes.Buffer, error) {
+	w := &bytes.Buffer{}
+	gzwriter := gzipWriterPool.Get().(*gzip.Writer)
+	gzwriter.Reset(w)
+	defer gzipWriterPool.Put(gzwriter)
+	if err := rep.EncodeCompact(gzwriter); err != nil {
+		return nil, err
+	}
+	gzwriter.Close() // otherwise the content won't get flushed to the output stream
+	return w, nil
+}
+
+// EncodeCompact writes a Report in the compact encoding, uncompressed.
+func (rep Report) EncodeCompact(w io.Writer) error {
+	e := compactEncoder{index: map[string]uint64{}}
+
+	// Nodes are encoded separately, so drop them from the rest of the report.
+	// Topologies are values, so this doesn't modify rep's.
+	header := rep
+	var topologies []string
+	header.WalkNamedTopologies(func(name string, t *Topology) {
+		if len(t.Nodes) > 0 {
+			topologies = append(topologies, name)
+		}
+		t.Nodes = nil
+	})
+	var headerBytes []byte
+	if err := codec.NewEncoderBytes(&headerBytes, &codec.MsgpackHandle{}).Encode(&header); err != nil {
+		return err
+	}
+
+	e.uvarint(uint64(len(topologies)))
+	for _, name := range topologies {
+		t := rep.topology(name)
+		e.str(name)
+		e.uvarint(uint64(len(t.Nodes)))
+		for _, n := range t.Nodes {
+			e.node(n)
+		}
+	}
+
+	var out compactEncoder
+	out.body.Write(compactMagic)
+	out.uvarint(compactFormatVersion)
+	out.uvarint(uint64(rep.SchemaVersion))
+	out.uvarint(uint64(len(headerBytes)))
+	out.body.Write(headerBytes)
+	out.uvarint(uint64(len(e.strings)))
+	for _, s := range e.strings {
+		out.uvarint(uint64(len(s)))
+	}
+	for _, s := range e.strings {
+		out.body.WriteString(s)
+	}
+	if _, err := w.Write(out.body.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(e.body.Bytes())
+	return err
+}
+
+type compactEncoder struct {
+	body     bytes.Buffer
+	index    map[string]uint64
+	strings  []string
+	lastTime int64
+	scratch  [binary.MaxVarintLen64]byte
+}
+
+func (e *compactEncoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.body.Write(e.scratch[:n])
+}
+
+func (e *compactEncoder) varint(v int64) {
+	n := binary.PutVarint(e.scratch[:], v)
+	e.body.Write(e.scratch[:n])
+}
+
+func (e *compactEncoder) float(f float64) {
+	binary.LittleEndian.PutUint64(e.scratch[:8], math.Float64bits(f))
+	e.body.Write(e.scratch[:8])
+}
+
+func (e *compactEncoder) str(s string) {
+	i, ok := e.index[s]
+	if !ok {
+		i = uint64(len(e.strings))
+		e.index[s] = i
+		e.strings = append(e.strings, s)
+	}
+	e.uvarint(i)
+}
+
+func (e *compactEncoder) time(t time.Time) {
+	var nanos int64
+	if !t.IsZero() {
+		nanos = t.UnixNano()
+	}
+	e.varint(nanos - e.lastTime)
+	e.lastTime = nanos
+}
+
+func (e *compactEncoder) strs(ss []string) {
+	e.uvarint(uint64(len(ss)))
+	for _, s := range ss {
+		e.str(s)
+	}
+}
+
+func (e *compactEncoder) sets(s Sets) {
+	keys := s.Keys()
+	e.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		values, _ := s.Lookup(key)
+		e.str(key)
+		e.strs(values)
+	}
+}
+
+func (e *compactEncoder) node(n Node) {
+	e.str(n.ID)
+	e.str(n.Topology)
+	e.uvarint(uint64(len(n.Latest)))
+	for _, entry := range n.Latest {
+		e.str(entry.key)
+		e.time(entry.Timestamp)
+		e.str(entry.Value)
+	}
+	e.sets(n.Sets)
+	e.strs(n.Adjacency)
+	e.sets(n.Parents)
+	e.uvarint(uint64(len(n.Metrics)))
+	for key, metric := range n.Metrics {
+		e.str(key)
+		e.uvarint(uint64(len(metric.Samples)))
+		for _, sample := range metric.Samples {
+			e.time(sample.Timestamp)
+			e.float(sample.Value)
+		}
+		e.float(metric.Min)
+		e.float(metric.Max)
+	}
+	e.uvarint(uint64(n.Children.Size()))
+	n.Children.ForEach(e.node)
+}
+
+// decodeCompact decodes an uncompressed report in the compact encoding.
+func decodeCompact(data []byte) (*Report, error) {
+	d := compactDecoder{buf: data[len(compactMagic):]}
+	if version := d.uvarint(); d.err == nil && version != compactFormatVersion {
+		return nil, fmt.Errorf("compact report: unsupported format version %d", version)
+	}
+	schemaVersion := d.uvarint()
+	headerBytes := d.bytes(d.uvarint())
+	if d.err != nil {
+		return nil, d.err
+	}
+	rep := MakeReport()
+	if err := codec.NewDecoderBytes(headerBytes, &codec.MsgpackHandle{}).Decode(&rep); err != nil {
+		return nil, err
+	}
+	rep.SchemaVersion = int(schemaVersion)
+	rep.WalkTopologies(func(t *Topology) {
+		t.Nodes = Nodes{}
+	})
+
+	// Convert all the strings at once, so they share the same memory.
+	lengths := make([]uint64, d.count())
+	total := uint64(0)
+	for i := range lengths {
+		lengths[i] = d.uvarint()
+		total += lengths[i]
+	}
+	all := string(d.bytes(total))
+	d.strings = make([]string, len(lengths))
+	for i, l := range lengths {
+		if l > uint64(len(all)) {
+			d.fail()
+			break
+		}
+		d.strings[i], all = all[:l], all[l:]
+	}
+
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		t := rep.topology(d.str())
+		if t == nil && d.err == nil {
+			d.err = fmt.Errorf("compact report: unknown topology")
+		}
+		for j, m := 0, d.count(); j < m && d.err == nil; j++ {
+			node := d.node()
+			t.Nodes[node.ID] = node
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &rep, nil
+}
+
+type compactDecoder struct {
+	buf      []byte
+	strings  []string
+	lastTime int64
+	err      error
+}
+
+func (d *compactDecoder) fail() {
+	if d.err == nil {
+		d.err = errCompactTruncated
+	}
+}
+
+func (d *compactDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *compactDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count reads the number of items which follow. Every item takes at least a
+// byte, which bounds the allocations corrupt data can cause.
+func (d *compactDecoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *compactDecoder) bytes(n uint64) []byte {
+	if n > uint64(len(d.buf)) {
+		d.fail()
+	}
+	if d.err != nil {
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *compactDecoder) float() float64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (d *compactDecoder) str() string {
+	i := d.uvarint()
+	if i >= uint64(len(d.strings)) {
+		d.fail()
+		return ""
+	}
+	return d.strings[i]
+}
+
+func (d *compactDecoder) time() time.Time {
+	d.lastTime += d.varint()
+	if d.lastTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, d.lastTime).UTC()
+}
+
+func (d *compactDecoder) strs() []string {
+	n := d.count()
+	if n == 0 {
+		return nil
+	}
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = d.str()
+	}
+	if !sort.StringsAreSorted(ss) {
+		sort.Strings(ss)
+	}
+	return ss
+}
+
+func (d *compactDecoder) sets() Sets {
+	s := MakeSets()
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		key := d.str()
+		s = Sets{psMap: s.psMap.Set(key, StringSet(d.strs()))}
+	}
+	return s
+}
+
+func (d *compactDecoder) node() Node {
+	n := Node{
+		ID:       d.str(),
+		Topology: d.str(),
+	}
+	if count := d.count(); count > 0 {
+		n.Latest = make(StringLatestMap, count)
+		for i := range n.Latest {
+			n.Latest[i] = stringLatestEntry{key: d.str(), Timestamp: d.time(), Value: d.str()}
+		}
+		if !sort.SliceIsSorted(n.Latest, func(i, j int) bool { return n.Latest[i].key < n.Latest[j].key }) {
+			sort.Slice(n.Latest, func(i, j int) bool { return n.Latest[i].key < n.Latest[j].key })
+		}
+	}
+	n.Sets = d.sets()
+	n.Adjacency = IDList(d.strs())
+	n.Parents = d.sets()
+	if count := d.count(); count > 0 {
+		n.Metrics = make(Metrics, count)
+		for i := 0; i < count && d.err == nil; i++ {
+			key := d.str()
+			samples := make([]Sample, d.count())
+			for j := range samples {
+				samples[j] = Sample{Timestamp: d.time(), Value: d.float()}
+			}
+			n.Metrics[key] = Metric{Samples: samples, Min: d.float(), Max: d.float()}
+		}
+	}
+	if count := d.count(); count > 0 {
+		children := make([]Node, 0, count)
+		for i := 0; i < count && d.err == nil; i++ {
+			children = append(children, d.node())
+		}
+		n.Children = MakeNodeSet(children...)
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package report_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/report"
+	s_reflect "github.com/weaveworks/scope/test/reflect"
+)
+
+func TestCompactRoundtrip(t *testing.T) {
+	for _, r1 := range []report.Report{report.MakeReport(), makeTestReport()} {
+		buf, err := r1.WriteCompact()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r2, err := report.MakeFromBinary(context.Background(), buf, true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s_reflect.DeepEqual(r1, *r2) {
+			t.Error(test.Diff(r1, *r2))
+		}
+	}
+}
+
+func TestCompactSchemaVersion(t *testing.T) {
+	r1 := report.MakeReport()
+	r1.SchemaVersion = 0
+	buf, _ := r1.WriteCompact()
+	r2, err := report.MakeFromBinary(context.Background(), buf, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r2.SchemaVersion != 0 {
+		t.Errorf("schema version %d, want 0", r2.SchemaVersion)
+	}
+}
+
+func TestCompactCorrupt(t *testing.T) {
+	rpt := makeTestReport()
+	buf, _ := rpt.WriteCompact()
+	gz, _ := gzip.NewReader(buf)
+	data, _ := ioutil.ReadAll(gz)
+	for _, n := range []int{5, len(data) / 2, len(data) - 1} {
+		if _, err := report.MakeFromBinary(context.Background(), bytes.NewReader(data[:n]), false, true); err == nil {
+			t.Errorf("expected an error decoding %d of %d bytes", n, len(data))
+		}
+	}
+}
+
+func makeBenchmarkReport(containers int) report.Report {
+	now := time.Date(2019, 10, 14, 14, 36, 1, 0, time.UTC)
+	hostID := report.MakeHostNodeID("host")
+	rpt := report.MakeReport()
+	for i := 0; i < containers; i++ {
+		id := report.MakeContainerNodeID(fmt.Sprintf("%064d", i))
+		rpt.Container.AddNode(report.MakeNode(id).
+			WithTopology(report.Container).
+			WithLatest(report.DockerContainerID, now, fmt.Sprintf("%064d", i)).
+			WithLatest(report.DockerContainerName, now, fmt.Sprintf("container-%d", i)).
+			WithLatest(report.DockerContainerState, now, report.StateRunning).
+			WithLatest(report.DockerImageID, now, "sha256:0123456789abcdef").
+			WithLatest(report.DockerLabelPrefix+"io.kubernetes.pod.namespace", now, "default").
+			WithLatest(report.HostNodeID, now, hostID).
+			WithParent(report.Host, hostID).
+			WithParent(report.ContainerImage, report.MakeContainerImageNodeID("sha256:0123456789abcdef")).
+			WithMetrics(report.Metrics{
+				"docker_cpu_total_usage": report.MakeSingletonMetric(now, float64(i)),
+			}))
+	}
+	return rpt
+}
+
+func TestCompactSize(t *testing.T) {
+	rpt := makeBenchmarkReport(500)
+	msgpack, _ := rpt.WriteBinary()
+	compact, _ := rpt.WriteCompact()
+	if compact.Len() >= msgpack.Len() {
+		t.Errorf("compact encoding (%d bytes) isn't smaller than msgpack (%d bytes)", compact.Len(), msgpack.Len())
+	}
+}
+
+func benchmarkDecode(b *testing.B, write func(report.Report) (*bytes.Buffer, error)) {
+	buf, err := write(makeBenchmarkReport(500))
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := report.MakeFromBinary(context.Background(), bytes.NewReader(data), true, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeMsgpack(b *testing.B) {
+	benchmarkDecode(b, report.Report.WriteBinary)
+}
+
+func BenchmarkDecodeCompact(b *testing.B) {
+	benchmarkDecode(b, report.Report.WriteCompact)
+}
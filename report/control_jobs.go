@@ -0,0 +1,81 @@
+package report
+
+import (
+	"time"
+)
+
+// CancelJob is the control to cancel a running job, given its ID as the
+// node ID.
+const CancelJob = "cancel_job"
+
+// States of jobs
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// ControlJob describes a long-running control, run asynchronously by a probe.
+type ControlJob struct {
+	ID       string    `json:"id"`
+	ProbeID  string    `json:"probeId"`
+	NodeID   string    `json:"nodeId"`
+	Control  string    `json:"control"`
+	State    string    `json:"state"`
+	Progress string    `json:"progress,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Done returns true if the job has finished, one way or another.
+func (j ControlJob) Done() bool {
+	return j.State != JobRunning
+}
+
+// ControlJobs contains the jobs of all the probes which contributed to a report,
+// keyed by job ID.
+type ControlJobs map[string]ControlJob
+
+// Copy makes a copy of the ControlJobs
+func (j ControlJobs) Copy() ControlJobs {
+	if j == nil {
+		return nil
+	}
+	cp := make(ControlJobs, len(j))
+	for k, v := range j {
+		cp[k] = v
+	}
+	return cp
+}
+
+// Merge merges the other object into this one, and returns the result object.
+// The most recently updated state of each job wins. The original is not
+// modified.
+func (j ControlJobs) Merge(other ControlJobs) ControlJobs {
+	if len(other) == 0 {
+		return j
+	}
+	if len(j) == 0 {
+		return other
+	}
+	cp := j.Copy()
+	for k, v := range other {
+		if existing, ok := cp[k]; !ok || v.Updated.After(existing.Updated) {
+			cp[k] = v
+		}
+	}
+	return cp
+}
+
+// Running returns the jobs running on the node with the given ID.
+func (j ControlJobs) Running(nodeID string) []ControlJob {
+	result := []ControlJob{}
+	for _, job := range j {
+		if job.NodeID == nodeID && !job.Done() {
+			result = append(result, job)
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package report_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestControlJobsMerge(t *testing.T) {
+	var (
+		t0      = time.Now()
+		t1      = t0.Add(time.Second)
+		running = report.ControlJob{ID: "a", NodeID: "n", State: report.JobRunning, Updated: t0}
+		done    = report.ControlJob{ID: "a", NodeID: "n", State: report.JobSucceeded, Updated: t1}
+		other   = report.ControlJob{ID: "b", NodeID: "m", State: report.JobRunning, Updated: t0}
+	)
+	a := report.ControlJobs{"a": running}
+	b := report.ControlJobs{"a": done, "b": other}
+	want := report.ControlJobs{"a": done, "b": other}
+	if have := a.Merge(b); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected %v, got %v", want, have)
+	}
+	if have := b.Merge(a); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected %v, got %v", want, have)
+	}
+	if a["a"].State != report.JobRunning {
+		t.Errorf("Merge modified the original")
+	}
+
+	if have := a.Running("n"); len(have) != 1 || have[0].ID != "a" {
+		t.Errorf("Expected job a running on n, got %v", have)
+	}
+	if have := want.Running("n"); len(have) != 0 {
+		t.Errorf("Expected no jobs running on n, got %v", have)
+	}
+}
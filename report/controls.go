@@ -1,5 +1,12 @@
 package report
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // Controls describe the control tags within the Nodes
 type Controls map[string]Control
 
@@ -11,6 +18,100 @@ type Control struct {
 	Confirmation string `json:"confirmation,omitempty"`
 	Rank         int    `json:"rank"`
 	ProbeID      string `json:"probeId,omitempty"`
+	// Args are the arguments the control takes, in the order to ask for
+	// them. Controls without any declared take any arguments.
+	Args []ControlArg `json:"args,omitempty"`
+}
+
+// Types of control arguments
+const (
+	StringArg = "string"
+	IntArg    = "int"
+	BoolArg   = "bool"
+	EnumArg   = "enum"
+)
+
+// ControlArg describes an argument of a control, for the UI to ask for it,
+// and the app to check it before the control is run.
+type ControlArg struct {
+	Name     string   `json:"name"`
+	Human    string   `json:"human"`
+	Type     string   `json:"type"`
+	Choices  []string `json:"choices,omitempty"` // of enum arguments
+	Default  string   `json:"default,omitempty"`
+	Min      *int     `json:"min,omitempty"` // of int arguments
+	Max      *int     `json:"max,omitempty"`
+	Required bool     `json:"required,omitempty"`
+}
+
+// ArgLimit makes the Min or Max of a ControlArg.
+func ArgLimit(n int) *int {
+	return &n
+}
+
+// CheckArgs checks args against the arguments of c, returning them with the
+// default of those missing added.
+func (c Control) CheckArgs(args map[string]string) (map[string]string, error) {
+	if len(c.Args) == 0 {
+		return args, nil
+	}
+	result := map[string]string{}
+	known := map[string]struct{}{}
+	for _, arg := range c.Args {
+		known[arg.Name] = struct{}{}
+		value, ok := args[arg.Name]
+		if !ok || value == "" {
+			if arg.Required && arg.Default == "" {
+				return nil, fmt.Errorf("Missing parameter: %s", arg.Name)
+			}
+			if arg.Default != "" {
+				result[arg.Name] = arg.Default
+			}
+			continue
+		}
+		if err := arg.check(value); err != nil {
+			return nil, err
+		}
+		result[arg.Name] = value
+	}
+	var unknown []string
+	for name := range args {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("Unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
+
+func (a ControlArg) check(value string) error {
+	switch a.Type {
+	case IntArg:
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			return fmt.Errorf("Bad parameter: %s (%q): must be a number", a.Name, value)
+		case a.Min != nil && n < *a.Min:
+			return fmt.Errorf("Bad parameter: %s (%q): must be at least %d", a.Name, value, *a.Min)
+		case a.Max != nil && n > *a.Max:
+			return fmt.Errorf("Bad parameter: %s (%q): must be at most %d", a.Name, value, *a.Max)
+		}
+	case BoolArg:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("Bad parameter: %s (%q): must be true or false", a.Name, value)
+		}
+	case EnumArg:
+		for _, choice := range a.Choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("Bad parameter: %s (%q): must be one of %s", a.Name, value, strings.Join(a.Choices, ", "))
+	}
+	return nil
 }
 
 // Merge merges other with cs, returning a fresh Controls.
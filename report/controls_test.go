@@ -0,0 +1,60 @@
+package report_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestControlCheckArgs(t *testing.T) {
+	control := report.Control{
+		ID: "scale",
+		Args: []report.ControlArg{
+			{Name: "replicas", Type: report.IntArg, Min: report.ArgLimit(0), Max: report.ArgLimit(10), Required: true},
+			{Name: "strategy", Type: report.EnumArg, Choices: []string{"fast", "slow"}, Default: "slow"},
+			{Name: "wait", Type: report.BoolArg},
+			{Name: "reason", Type: report.StringArg},
+		},
+	}
+	for _, c := range []struct {
+		args map[string]string
+		want map[string]string
+		err  string
+	}{
+		{
+			args: map[string]string{"replicas": "3"},
+			want: map[string]string{"replicas": "3", "strategy": "slow"},
+		},
+		{
+			args: map[string]string{"replicas": "0", "strategy": "fast", "wait": "true", "reason": "load"},
+			want: map[string]string{"replicas": "0", "strategy": "fast", "wait": "true", "reason": "load"},
+		},
+		{args: map[string]string{}, err: "Missing parameter: replicas"},
+		{args: map[string]string{"replicas": "three"}, err: `Bad parameter: replicas ("three"): must be a number`},
+		{args: map[string]string{"replicas": "-1"}, err: `Bad parameter: replicas ("-1"): must be at least 0`},
+		{args: map[string]string{"replicas": "11"}, err: `Bad parameter: replicas ("11"): must be at most 10`},
+		{args: map[string]string{"replicas": "1", "strategy": "now"}, err: `Bad parameter: strategy ("now"): must be one of fast, slow`},
+		{args: map[string]string{"replicas": "1", "wait": "maybe"}, err: `Bad parameter: wait ("maybe"): must be true or false`},
+		{args: map[string]string{"replicas": "1", "force": "true", "dry": "1"}, err: "Unknown parameters: dry, force"},
+	} {
+		have, err := control.CheckArgs(c.args)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%v: expected error %q, got %v", c.args, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", c.args, err)
+		} else if !reflect.DeepEqual(c.want, have) {
+			t.Errorf("%v: expected %v, got %v", c.args, c.want, have)
+		}
+	}
+
+	// Controls without arguments declared take any
+	args := map[string]string{"pipeID": "pipe"}
+	if have, err := (report.Control{ID: "resize"}).CheckArgs(args); err != nil || !reflect.DeepEqual(args, have) {
+		t.Errorf("Expected %v, got %v, %v", args, have, err)
+	}
+}
@@ -0,0 +1,97 @@
+package report
+
+// Filter returns a copy of the report with only the nodes for which keep
+// returns true, and the nodes those refer to as parents or as their host.
+// References to the nodes which were dropped are removed, so the result is
+// as consistent as the original report.
+func (r Report) Filter(keep func(topology string, n Node) bool) Report {
+	kept := map[string]map[string]struct{}{}
+	add := func(topology, id string) bool {
+		ids, ok := kept[topology]
+		if !ok {
+			ids = map[string]struct{}{}
+			kept[topology] = ids
+		}
+		if _, ok := ids[id]; ok {
+			return false
+		}
+		ids[id] = struct{}{}
+		return true
+	}
+	isKept := func(topology, id string) bool {
+		_, ok := kept[topology][id]
+		return ok
+	}
+
+	var queue []Node
+	r.WalkNamedTopologies(func(name string, t *Topology) {
+		for _, n := range t.Nodes {
+			if keep(name, n) && add(name, n.ID) {
+				queue = append(queue, n)
+			}
+		}
+	})
+	// Parents have their own parents, e.g. pod -> replica set -> deployment.
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		var refs [][2]string
+		if hostID, ok := n.Latest.Lookup(HostNodeID); ok {
+			refs = append(refs, [2]string{Host, hostID})
+		}
+		for _, topology := range n.Parents.Keys() {
+			ids, _ := n.Parents.Lookup(topology)
+			for _, id := range ids {
+				refs = append(refs, [2]string{topology, id})
+			}
+		}
+		for _, ref := range refs {
+			t, ok := r.Topology(ref[0])
+			if !ok {
+				continue
+			}
+			if parent, ok := t.Nodes[ref[1]]; ok && add(ref[0], ref[1]) {
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	result := r.Copy()
+	result.WalkNamedTopologies(func(name string, t *Topology) {
+		nodes := Nodes{}
+		for id, n := range t.Nodes {
+			if !isKept(name, id) {
+				continue
+			}
+			adjacency := MakeIDList()
+			for _, dst := range n.Adjacency {
+				if isKept(name, dst) {
+					adjacency = adjacency.Add(dst)
+				}
+			}
+			n.Adjacency = adjacency
+			parents := MakeSets()
+			for _, topology := range n.Parents.Keys() {
+				ids, _ := n.Parents.Lookup(topology)
+				if _, ok := r.Topology(topology); !ok {
+					// not a topology of the report, so nothing to check against
+					parents = parents.Add(topology, ids)
+					continue
+				}
+				keptIDs := MakeStringSet()
+				for _, id := range ids {
+					if isKept(topology, id) {
+						keptIDs = keptIDs.Add(id)
+					}
+				}
+				if len(keptIDs) > 0 {
+					parents = parents.Add(topology, keptIDs)
+				}
+			}
+			n.Parents = parents
+			nodes[id] = n
+		}
+		t.Nodes = nodes
+	})
+	return result
+}
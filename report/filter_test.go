@@ -0,0 +1,57 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestFilter(t *testing.T) {
+	var (
+		hostA = report.MakeHostNodeID("a")
+		hostB = report.MakeHostNodeID("b")
+		pod   = report.MakePodNodeID("pod")
+		c1    = report.MakeContainerNodeID("c1")
+		c2    = report.MakeContainerNodeID("c2")
+		rpt   = report.MakeReport()
+	)
+	rpt.Host.AddNode(report.MakeNode(hostA))
+	rpt.Host.AddNode(report.MakeNode(hostB))
+	rpt.Pod.AddNode(report.MakeNodeWith(pod, map[string]string{report.HostNodeID: hostB}))
+	rpt.Container.AddNode(report.MakeNodeWith(c1, map[string]string{report.HostNodeID: hostA}).
+		WithParent(report.Pod, pod).
+		WithParent(report.ContainerImage, "image").
+		WithAdjacent(c2))
+	rpt.Container.AddNode(report.MakeNodeWith(c2, map[string]string{report.HostNodeID: hostB}).
+		WithAdjacent(c1))
+
+	have := rpt.Filter(func(topology string, n report.Node) bool {
+		return topology == report.Container && n.ID == c1
+	})
+
+	// The container's pod, and its host, are kept too.
+	for topology, want := range map[string][]string{
+		report.Container: {c1},
+		report.Pod:       {pod},
+		report.Host:      {hostA, hostB},
+	} {
+		nodes, _ := have.Topology(topology)
+		if len(nodes.Nodes) != len(want) {
+			t.Errorf("%s: have %v, want %v", topology, nodes.Nodes, want)
+		}
+		for _, id := range want {
+			if _, ok := nodes.Nodes[id]; !ok {
+				t.Errorf("%s: %s missing", topology, id)
+			}
+		}
+	}
+	if adjacency := have.Container.Nodes[c1].Adjacency; len(adjacency) != 0 {
+		t.Errorf("dangling adjacency: %v", adjacency)
+	}
+	if images, ok := have.Container.Nodes[c1].Parents.Lookup(report.ContainerImage); ok {
+		t.Errorf("dangling parents: %v", images)
+	}
+	if err := have.Validate(); err != nil {
+		t.Error(err)
+	}
+}
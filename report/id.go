@@ -162,6 +162,12 @@ var (
 	// ParseJobNodeID parses a job node ID
 	ParseJobNodeID = parseSingleComponentID("job")
 
+	// MakeCustomResourceNodeID produces a custom resource node ID from its composite parts.
+	MakeCustomResourceNodeID = makeSingleComponentID("custom_resource")
+
+	// ParseCustomResourceNodeID parses a custom resource node ID
+	ParseCustomResourceNodeID = parseSingleComponentID("custom_resource")
+
 	// MakeNamespaceNodeID produces a namespace node ID from its composite parts.
 	MakeNamespaceNodeID = makeSingleComponentID("namespace")
 
@@ -24,6 +24,11 @@ const (
 	DockerImageTag               = "docker_image_tag"
 	DockerImageSize              = "docker_image_size"
 	DockerImageVirtualSize       = "docker_image_virtual_size"
+	DockerImageCreated           = "docker_image_created"
+	DockerImageLayers            = "docker_image_layers"
+	DockerImagePorts             = "docker_image_ports"
+	DockerImageEntrypoint        = "docker_image_entrypoint"
+	DockerImageDigest            = "docker_image_digest"
 	DockerIsInHostNetwork        = "docker_is_in_host_network"
 	DockerServiceName            = "service_name"
 	DockerStackNamespace         = "stack_namespace"
@@ -36,6 +41,13 @@ const (
 	DockerRemoveContainer        = "docker_remove_container"
 	DockerAttachContainer        = "docker_attach_container"
 	DockerExecContainer          = "docker_exec_container"
+	DockerGetLogs                = "docker_get_logs"
+	DockerDownloadFile           = "docker_download_file"
+	DockerUploadFile             = "docker_upload_file"
+	DockerPortForward            = "docker_port_forward"
+	DockerPullImage              = "docker_pull_image"
+	DockerRemoveImage            = "docker_remove_image"
+	DockerPruneImages            = "docker_prune_images"
 	DockerContainerName          = "docker_container_name"
 	DockerContainerCommand       = "docker_container_command"
 	DockerContainerPorts         = "docker_container_ports"
@@ -49,6 +61,22 @@ const (
 	DockerContainerUptime        = "docker_container_uptime"
 	DockerContainerRestartCount  = "docker_container_restart_count"
 	DockerContainerNetworkMode   = "docker_container_network_mode"
+	DockerContainerNewerImage    = "docker_container_newer_image"
+	DockerContainerHealth        = "docker_container_health"
+	DockerContainerHealthOutput  = "docker_container_health_output"
+	DockerContainerExitCode      = "docker_container_exit_code"
+	DockerContainerOOMKilled     = "docker_container_oom_killed"
+	DockerContainerLastRestart   = "docker_container_last_restart"
+	DockerContainerRecentExits   = "docker_container_recent_exits"
+	DockerContainerRecentOOMs    = "docker_container_recent_ooms"
+	DockerNetworkRxBytes         = "docker_network_rx_bytes"
+	DockerNetworkTxBytes         = "docker_network_tx_bytes"
+	DockerNetworkRxPackets       = "docker_network_rx_packets"
+	DockerNetworkTxPackets       = "docker_network_tx_packets"
+	DockerNetworkRxDropped       = "docker_network_rx_dropped"
+	DockerNetworkTxDropped       = "docker_network_tx_dropped"
+	DockerBlockIOReadBytes       = "docker_blkio_read_bytes"
+	DockerBlockIOWriteBytes      = "docker_blkio_write_bytes"
 	DockerEnvPrefix              = "docker_env_"
 	// probe/kubernetes
 	KubernetesName                 = "kubernetes_name"
@@ -63,6 +91,8 @@ const (
 	KubernetesDeletePod            = "kubernetes_delete_pod"
 	KubernetesScaleUp              = "kubernetes_scale_up"
 	KubernetesScaleDown            = "kubernetes_scale_down"
+	KubernetesScale                = "kubernetes_scale"
+	KubernetesRestartWorkload      = "kubernetes_restart_workload"
 	KubernetesUpdatedReplicas      = "kubernetes_updated_replicas"
 	KubernetesAvailableReplicas    = "kubernetes_available_replicas"
 	KubernetesUnavailableReplicas  = "kubernetes_unavailable_replicas"
@@ -97,6 +127,25 @@ const (
 	KubernetesDescribe             = "kubernetes_describe"
 	KubernetesCordonNode           = "kubernetes_cordon_node"
 	KubernetesUncordonNode         = "kubernetes_uncordon_node"
+	KubernetesDrainNode            = "kubernetes_drain_node"
+	KubernetesGetWorkloadLogs      = "kubernetes_get_workload_logs"
+	KubernetesDownloadFile         = "kubernetes_download_file"
+	KubernetesUploadFile           = "kubernetes_upload_file"
+	KubernetesPortForward          = "kubernetes_port_forward"
+	KubernetesCustomPrefix         = "kubernetes_custom_"
+	KubernetesRevision             = "kubernetes_revision"
+	KubernetesQoSClass             = "kubernetes_qos_class"
+	KubernetesPriorityClass        = "kubernetes_priority_class"
+	KubernetesCPURequest           = "kubernetes_cpu_request"
+	KubernetesCPULimit             = "kubernetes_cpu_limit"
+	KubernetesMemoryRequest        = "kubernetes_memory_request"
+	KubernetesMemoryLimit          = "kubernetes_memory_limit"
+	KubernetesContainerResources   = "kubernetes_container_resources_"
+	KubernetesContainerName        = "kubernetes_container_name"
+	KubernetesAllocatableCPU       = "kubernetes_allocatable_cpu"
+	KubernetesAllocatableMemory    = "kubernetes_allocatable_memory"
+	KubernetesCPURequested         = "kubernetes_cpu_requested"
+	KubernetesMemoryRequested      = "kubernetes_memory_requested"
 	// probe/awsecs
 	ECSCluster             = "ecs_cluster"
 	ECSCreatedAt           = "ecs_created_at"
@@ -116,9 +165,12 @@ const (
 	HostCPUUsage      = "host_cpu_usage_percent"
 	HostMemoryUsage   = "host_mem_usage_bytes"
 	ScopeVersion      = "host_scope_version"
+	ProbeLastSeen     = "host_probe_last_seen"
 	// probe/overlay/weave
 	WeavePeerName     = "weave_peer_name"
 	WeavePeerNickName = "weave_peer_nick_name"
+	// app federation
+	FederationCluster = "federation_cluster"
 )
 
 /* Lookup table to allow msgpack/json decoder to avoid heap allocation
@@ -148,6 +200,7 @@ var commonKeys = map[string]string{
 	StorageClass:          StorageClass,
 	VolumeSnapshot:        VolumeSnapshot,
 	VolumeSnapshotData:    VolumeSnapshotData,
+	CustomResource:        CustomResource,
 
 	HostNodeID:             HostNodeID,
 	ControlProbeID:         ControlProbeID,
@@ -169,6 +222,11 @@ var commonKeys = map[string]string{
 	DockerImageTag:               DockerImageTag,
 	DockerImageSize:              DockerImageSize,
 	DockerImageVirtualSize:       DockerImageVirtualSize,
+	DockerImageCreated:           DockerImageCreated,
+	DockerImageLayers:            DockerImageLayers,
+	DockerImagePorts:             DockerImagePorts,
+	DockerImageEntrypoint:        DockerImageEntrypoint,
+	DockerImageDigest:            DockerImageDigest,
 	DockerIsInHostNetwork:        DockerIsInHostNetwork,
 	DockerServiceName:            DockerServiceName,
 	DockerStackNamespace:         DockerStackNamespace,
@@ -180,6 +238,13 @@ var commonKeys = map[string]string{
 	DockerRemoveContainer:        DockerRemoveContainer,
 	DockerAttachContainer:        DockerAttachContainer,
 	DockerExecContainer:          DockerExecContainer,
+	DockerGetLogs:                DockerGetLogs,
+	DockerDownloadFile:           DockerDownloadFile,
+	DockerUploadFile:             DockerUploadFile,
+	DockerPortForward:            DockerPortForward,
+	DockerPullImage:              DockerPullImage,
+	DockerRemoveImage:            DockerRemoveImage,
+	DockerPruneImages:            DockerPruneImages,
 	DockerContainerName:          DockerContainerName,
 	DockerContainerCommand:       DockerContainerCommand,
 	DockerContainerPorts:         DockerContainerPorts,
@@ -193,6 +258,22 @@ var commonKeys = map[string]string{
 	DockerContainerUptime:        DockerContainerUptime,
 	DockerContainerRestartCount:  DockerContainerRestartCount,
 	DockerContainerNetworkMode:   DockerContainerNetworkMode,
+	DockerContainerNewerImage:    DockerContainerNewerImage,
+	DockerContainerHealth:        DockerContainerHealth,
+	DockerContainerHealthOutput:  DockerContainerHealthOutput,
+	DockerContainerExitCode:      DockerContainerExitCode,
+	DockerContainerOOMKilled:     DockerContainerOOMKilled,
+	DockerContainerLastRestart:   DockerContainerLastRestart,
+	DockerContainerRecentExits:   DockerContainerRecentExits,
+	DockerContainerRecentOOMs:    DockerContainerRecentOOMs,
+	DockerNetworkRxBytes:         DockerNetworkRxBytes,
+	DockerNetworkTxBytes:         DockerNetworkTxBytes,
+	DockerNetworkRxPackets:       DockerNetworkRxPackets,
+	DockerNetworkTxPackets:       DockerNetworkTxPackets,
+	DockerNetworkRxDropped:       DockerNetworkRxDropped,
+	DockerNetworkTxDropped:       DockerNetworkTxDropped,
+	DockerBlockIOReadBytes:       DockerBlockIOReadBytes,
+	DockerBlockIOWriteBytes:      DockerBlockIOWriteBytes,
 
 	KubernetesName:                 KubernetesName,
 	KubernetesNamespace:            KubernetesNamespace,
@@ -206,6 +287,8 @@ var commonKeys = map[string]string{
 	KubernetesDeletePod:            KubernetesDeletePod,
 	KubernetesScaleUp:              KubernetesScaleUp,
 	KubernetesScaleDown:            KubernetesScaleDown,
+	KubernetesScale:                KubernetesScale,
+	KubernetesRestartWorkload:      KubernetesRestartWorkload,
 	KubernetesUpdatedReplicas:      KubernetesUpdatedReplicas,
 	KubernetesAvailableReplicas:    KubernetesAvailableReplicas,
 	KubernetesUnavailableReplicas:  KubernetesUnavailableReplicas,
@@ -222,6 +305,17 @@ var commonKeys = map[string]string{
 	KubernetesActiveJobs:           KubernetesActiveJobs,
 	KubernetesType:                 KubernetesType,
 	KubernetesPorts:                KubernetesPorts,
+	KubernetesRevision:             KubernetesRevision,
+	KubernetesQoSClass:             KubernetesQoSClass,
+	KubernetesPriorityClass:        KubernetesPriorityClass,
+	KubernetesCPURequest:           KubernetesCPURequest,
+	KubernetesCPULimit:             KubernetesCPULimit,
+	KubernetesMemoryRequest:        KubernetesMemoryRequest,
+	KubernetesMemoryLimit:          KubernetesMemoryLimit,
+	KubernetesAllocatableCPU:       KubernetesAllocatableCPU,
+	KubernetesAllocatableMemory:    KubernetesAllocatableMemory,
+	KubernetesCPURequested:         KubernetesCPURequested,
+	KubernetesMemoryRequested:      KubernetesMemoryRequested,
 
 	ECSCluster:             ECSCluster,
 	ECSCreatedAt:           ECSCreatedAt,
@@ -241,9 +335,11 @@ var commonKeys = map[string]string{
 	HostCPUUsage:      HostCPUUsage,
 	HostMemoryUsage:   HostMemoryUsage,
 	ScopeVersion:      ScopeVersion,
+	ProbeLastSeen:     ProbeLastSeen,
 
 	WeavePeerName:     WeavePeerName,
 	WeavePeerNickName: WeavePeerNickName,
+	FederationCluster: FederationCluster,
 }
 
 func lookupCommonKey(b []byte) string {
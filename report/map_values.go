@@ -4,6 +4,7 @@ package report
 const (
 	DockerLabelPrefix      = "docker_label_"
 	DockerImageLabelPrefix = "docker_image_label_"
+	KubernetesLabelPrefix  = "kubernetes_labels_"
 
 	StateCreated    = "created"
 	StateDead       = "dead"
@@ -13,4 +14,8 @@ const (
 	StateRunning    = "running"
 	StateDeleted    = "deleted"
 	StateFailed     = "Failed"
+
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
 )
@@ -75,7 +75,8 @@ var gzipWriterPool = &sync.Pool{
 // MakeFromBinary constructs a Report from binary data.
 //
 // Will decompress the binary if gzipped is true, and decode as
-// msgpack if true, otherwise JSON
+// msgpack if true, otherwise JSON. Reports in the compact encoding
+// are recognised whatever msgpack is.
 func MakeFromBinary(ctx context.Context, r io.Reader, gzipped bool, msgpack bool) (*Report, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "report.ReadBinary")
 	defer span.Finish()
@@ -109,7 +110,12 @@ func MakeFromBinary(ctx context.Context, r io.Reader, gzipped bool, msgpack bool
 		float32(compressedSize)/float32(uncompressedSize)*100,
 	)
 	span.LogFields(otlog.Uint64("compressedSize", compressedSize), otlog.Int64("uncompressedSize", uncompressedSize))
+	if IsCompact(buf.Bytes()) {
+		return decodeCompact(buf.Bytes())
+	}
 	rep := MakeReport()
+	// Reports without a schema version come from older probes
+	rep.SchemaVersion = 0
 	if err := codec.NewDecoderBytes(buf.Bytes(), codecHandle(msgpack)).Decode(&rep); err != nil {
 		return nil, err
 	}
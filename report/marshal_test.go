@@ -106,6 +106,7 @@ func TestControlsCompat(t *testing.T) {
 	nowTime := time.Date(2019, 10, 14, 14, 36, 1, 0, time.UTC)
 	mtime.NowForce(nowTime)
 	expected := report.MakeReport()
+	expected.SchemaVersion = 0 // from an old probe
 	expected.Container.AddNode(report.MakeNode(report.MakeContainerNodeID("031d")).
 		WithTopology("container").
 		WithLatestActiveControls("docker_remove_container").
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"time"
+)
+
+// ProbeSummary describes the state of the probe which generated a report.
+type ProbeSummary struct {
+	ID                string                   `json:"id"`
+	Hostname          string                   `json:"hostname"`
+	Version           string                   `json:"version"`
+	Features          []string                 `json:"features,omitempty"`
+	ReporterDurations map[string]time.Duration `json:"reporterDurations,omitempty"`
+	TaggerDurations   map[string]time.Duration `json:"taggerDurations,omitempty"`
+	Errors            map[string]string        `json:"errors,omitempty"`
+	Timestamp         time.Time                `json:"timestamp"`
+}
+
+// ProbeSummaries contains the summaries of all the probes which contributed
+// to a report, keyed by probe ID.
+type ProbeSummaries map[string]ProbeSummary
+
+// Copy makes a copy of the ProbeSummaries
+func (p ProbeSummaries) Copy() ProbeSummaries {
+	if p == nil {
+		return nil
+	}
+	cp := make(ProbeSummaries, len(p))
+	for k, v := range p {
+		cp[k] = v
+	}
+	return cp
+}
+
+// Merge merges the other object into this one, and returns the result object.
+// The most recent summary of each probe wins. The original is not modified.
+func (p ProbeSummaries) Merge(other ProbeSummaries) ProbeSummaries {
+	if len(other) == 0 {
+		return p
+	}
+	if len(p) == 0 {
+		return other
+	}
+	cp := p.Copy()
+	for k, v := range other {
+		if existing, ok := cp[k]; !ok || v.Timestamp.After(existing.Timestamp) {
+			cp[k] = v
+		}
+	}
+	return cp
+}
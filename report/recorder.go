@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+)
+
+// RecorderConfig says where a Recorder writes reports, and when it rotates
+// them.
+type RecorderConfig struct {
+	Dir string
+
+	// Reports are written to segments: subdirectories of Dir named after
+	// the time of their first report. A new segment is started once the
+	// current one holds MaxSegmentSize bytes, or is MaxSegmentAge old.
+	// Zero means no limit.
+	MaxSegmentSize int64
+	MaxSegmentAge  time.Duration
+
+	// MaxSegments is the number of segments kept; the oldest are deleted
+	// to make way for new ones. Zero keeps them all.
+	MaxSegments int
+}
+
+// Recorder writes reports to a directory, named after the nanoseconds since
+// the epoch at which they were recorded, e.g. 1488557088545489008.msgpack.gz.
+// That's the layout the app's file collector replays.
+type Recorder struct {
+	config RecorderConfig
+
+	mtx          sync.Mutex
+	segments     []string
+	segmentStart time.Time
+	segmentSize  int64
+}
+
+// NewRecorder makes a Recorder, creating its directory if need be. Segments
+// already in the directory count towards MaxSegments, but are never
+// appended to.
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{config: config}
+	for _, entry := range entries {
+		if _, err := strconv.ParseInt(entry.Name(), 10, 64); err == nil && entry.IsDir() {
+			r.segments = append(r.segments, entry.Name())
+		}
+	}
+	sort.Slice(r.segments, func(i, j int) bool { return segmentTime(r.segments[i]) < segmentTime(r.segments[j]) })
+	return r, nil
+}
+
+func segmentTime(name string) int64 {
+	t, _ := strconv.ParseInt(name, 10, 64)
+	return t
+}
+
+// Publish records a report as of now. It implements probe.ReportPublisher.
+func (r *Recorder) Publish(rep Report) error {
+	return r.Record(rep, mtime.Now())
+}
+
+// Record writes a report, as received or published at t.
+func (r *Recorder) Record(rep Report, t time.Time) error {
+	buf, err := rep.WriteBinary()
+	if err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.segmentStart.IsZero() ||
+		(r.config.MaxSegmentSize > 0 && r.segmentSize >= r.config.MaxSegmentSize) ||
+		(r.config.MaxSegmentAge > 0 && t.Sub(r.segmentStart) >= r.config.MaxSegmentAge) {
+		if err := r.rotate(t); err != nil {
+			return err
+		}
+	}
+	segment := r.segments[len(r.segments)-1]
+	filename := filepath.Join(r.config.Dir, segment, fmt.Sprintf("%d.msgpack.gz", t.UnixNano()))
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	r.segmentSize += int64(buf.Len())
+	return nil
+}
+
+// rotate starts a new segment, deleting the oldest ones beyond MaxSegments.
+func (r *Recorder) rotate(t time.Time) error {
+	segment := strconv.FormatInt(t.UnixNano(), 10)
+	if err := os.Mkdir(filepath.Join(r.config.Dir, segment), 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	r.segments = append(r.segments, segment)
+	r.segmentStart = t
+	r.segmentSize = 0
+	for r.config.MaxSegments > 0 && len(r.segments) > r.config.MaxSegments {
+		if err := os.RemoveAll(filepath.Join(r.config.Dir, r.segments[0])); err != nil {
+			return err
+		}
+		r.segments = r.segments[1:]
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package report_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestRecorderRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scope-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder, err := report.NewRecorder(report.RecorderConfig{
+		Dir:           dir,
+		MaxSegmentAge: time.Minute,
+		MaxSegments:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1500000000, 0)
+	for i := 0; i < 6; i++ {
+		// Two reports per segment
+		if err := recorder.Record(report.MakeReport(), start.Add(time.Duration(i)*30*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %v", segments)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*.msgpack.gz"))
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 reports, got %v", files)
+	}
+	oldest := filepath.Join(dir, "1500000060000000000", "1500000060000000000.msgpack.gz")
+	if _, err := report.MakeFromFile(context.Background(), oldest); err != nil {
+		t.Error(err)
+	}
+
+	// Segments are rotated by size too, and segments left by earlier
+	// recorders count towards the limit.
+	recorder, err = report.NewRecorder(report.RecorderConfig{
+		Dir:            dir,
+		MaxSegmentSize: 1,
+		MaxSegments:    3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := recorder.Record(report.MakeReport(), start.Add(time.Hour+time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segments, _ = filepath.Glob(filepath.Join(dir, "*"))
+	if len(segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %v", segments)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1500000060000000000")); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest segment to be deleted")
+	}
+}
@@ -37,6 +37,7 @@ const (
 	VolumeSnapshot        = "volume_snapshot"
 	VolumeSnapshotData    = "volume_snapshot_data"
 	Job                   = "job"
+	CustomResource        = "custom_resource"
 
 	// Shapes used for different nodes
 	Circle         = "circle"
@@ -53,6 +54,9 @@ const (
 	Camera         = "camera"
 	DottedTriangle = "dottedtriangle"
 
+	// Tag of nodes needing attention, such as unhealthy containers
+	Alert = "alert"
+
 	// Used when counting the number of containers
 	ContainersKey = "containers"
 )
@@ -82,6 +86,7 @@ var topologyNames = []string{
 	VolumeSnapshot,
 	VolumeSnapshotData,
 	Job,
+	CustomResource,
 }
 
 // Report is the core data type. It's produced by probes, and consumed and
@@ -194,6 +199,10 @@ type Report struct {
 	// Job represent all Kubernetes Job on hosts running probes.
 	Job Topology
 
+	// CustomResource nodes represent the Kubernetes custom resources the
+	// probes were configured to report, of any kind.
+	CustomResource Topology
+
 	DNS DNSRecords `json:"DNS,omitempty" deepequal:"nil==empty"`
 	// Backwards-compatibility for an accident in commit 951629a / release 1.11.6.
 	BugDNS DNSRecords `json:"nodes,omitempty"`
@@ -215,6 +224,19 @@ type Report struct {
 
 	Plugins xfer.PluginSpecs
 
+	// Probes summarises the state of the probes which contributed to
+	// this report.
+	Probes ProbeSummaries `json:"Probes,omitempty" deepequal:"nil==empty"`
+
+	// ControlJobs are the long-running controls the probes are running, or
+	// ran recently.
+	ControlJobs ControlJobs `json:"ControlJobs,omitempty" deepequal:"nil==empty"`
+
+	// SchemaVersion is the version of the schema of the report, to tell
+	// which Upgrade()s it needs. Zero means the report comes from a probe
+	// which predates schema versions.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
 	// ID a random identifier for this report, used when caching
 	// rendered views of the report.  Reports with the same id
 	// must be equal, but we don't require that equal reports have
@@ -315,25 +337,35 @@ func MakeReport() Report {
 			WithShape(DottedTriangle).
 			WithLabel("job", "jobs"),
 
+		CustomResource: MakeTopology().
+			WithShape(Octagon).
+			WithLabel("custom resource", "custom resources"),
+
 		DNS: DNSRecords{},
 
 		Sampling: Sampling{},
 		Window:   0,
 		Plugins:  xfer.MakePluginSpecs(),
-		ID:       fmt.Sprintf("%d", rand.Int63()),
+
+		SchemaVersion: CurrentSchemaVersion,
+		ID:            fmt.Sprintf("%d", rand.Int63()),
 	}
 }
 
 // Copy returns a value copy of the report.
 func (r Report) Copy() Report {
 	newReport := Report{
-		TS:       r.TS,
-		DNS:      r.DNS.Copy(),
-		Sampling: r.Sampling,
-		Window:   r.Window,
-		Shortcut: r.Shortcut,
-		Plugins:  r.Plugins.Copy(),
-		ID:       fmt.Sprintf("%d", rand.Int63()),
+		TS:          r.TS,
+		DNS:         r.DNS.Copy(),
+		Sampling:    r.Sampling,
+		Window:      r.Window,
+		Shortcut:    r.Shortcut,
+		Plugins:     r.Plugins.Copy(),
+		Probes:      r.Probes.Copy(),
+		ControlJobs: r.ControlJobs.Copy(),
+
+		SchemaVersion: r.SchemaVersion,
+		ID:            fmt.Sprintf("%d", rand.Int63()),
 	}
 	newReport.WalkPairedTopologies(&r, func(newTopology, oldTopology *Topology) {
 		*newTopology = oldTopology.Copy()
@@ -351,6 +383,12 @@ func (r *Report) UnsafeMerge(other Report) {
 	r.Sampling = r.Sampling.Merge(other.Sampling)
 	r.Window = r.Window + other.Window
 	r.Plugins = r.Plugins.Merge(other.Plugins)
+	r.Probes = r.Probes.Merge(other.Probes)
+	r.ControlJobs = r.ControlJobs.Merge(other.ControlJobs)
+	// The merged report needs the upgrades any of its parts need
+	if other.SchemaVersion < r.SchemaVersion {
+		r.SchemaVersion = other.SchemaVersion
+	}
 	r.WalkPairedTopologies(&other, func(ourTopology, theirTopology *Topology) {
 		ourTopology.UnsafeMerge(*theirTopology)
 	})
@@ -463,6 +501,8 @@ func (r *Report) topology(name string) *Topology {
 		return &r.VolumeSnapshotData
 	case Job:
 		return &r.Job
+	case CustomResource:
+		return &r.CustomResource
 	}
 	return nil
 }
@@ -83,6 +83,7 @@ func TestReportUpgrade(t *testing.T) {
 		WithParents(report.MakeSets().Add(report.ReplicaSet, report.MakeStringSet("bar")))
 	expectedPodNode := podNode.PruneParents().WithParents(parentsWithDeployment)
 	rpt := report.MakeReport()
+	rpt.SchemaVersion = 0 // from an old probe
 	rpt.ReplicaSet.AddNode(rsNode)
 	rpt.Pod.AddNode(podNode)
 	namespaceNode := report.MakeNode(namespaceID).
@@ -95,6 +96,12 @@ func TestReportUpgrade(t *testing.T) {
 	if !s_reflect.DeepEqual(expected, got) {
 		t.Error(test.Diff(expected, got))
 	}
+
+	// Reports of the current schema don't need upgrading.
+	rpt.SchemaVersion = report.CurrentSchemaVersion
+	if got := rpt.Upgrade(); len(got.Namespace.Nodes) != 0 {
+		t.Errorf("current report was upgraded: %v", got.Namespace.Nodes)
+	}
 }
 
 func TestReportUnMerge(t *testing.T) {
@@ -122,7 +129,8 @@ func TestReportUnMerge(t *testing.T) {
 	// r2 should be the same as r1 with one extra node
 	r2.UnsafeUnMerge(r1)
 	expected = report.Report{
-		ID: r2.ID,
+		ID:            r2.ID,
+		SchemaVersion: report.CurrentSchemaVersion,
 		Container: report.Topology{
 			Nodes: report.Nodes{
 				"foo2": n2,
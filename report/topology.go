@@ -287,6 +287,14 @@ func (n *Nodes) UnsafeUnMerge(other Nodes) {
 
 // Validate checks the topology for various inconsistencies.
 func (t Topology) Validate() error {
+	if errs := t.ValidationErrors(); len(errs) > 0 {
+		return fmt.Errorf("%d error(s): %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidationErrors returns all the inconsistencies found in the topology.
+func (t Topology) ValidationErrors() []string {
 	errs := []string{}
 
 	// Check all nodes are valid, and the keys are parseable, i.e.
@@ -304,9 +312,5 @@ func (t Topology) Validate() error {
 		}
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("%d error(s): %s", len(errs), strings.Join(errs, "; "))
-	}
-
-	return nil
+	return errs
 }